@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <dirA> <dirB>",
+	Short: "Compare two directory trees",
+	Long: `Compare two directory trees and report added, removed, modified,
+type-changed and symlink-retargeted paths. File content is compared by checksum.
+
+With --ops, print the synthfs operations that would transform dirA into dirB
+instead of the differences.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+	// Execute prints the error once; usage is no help for a runtime error
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	diffCmd.Flags().Bool("json", false, "Print the result as JSON")
+	diffCmd.Flags().Bool("ops", false, "Print the operations that transform dirA into dirB")
+	diffCmd.Flags().Bool("ignore-modes", false, "Do not compare permissions")
+	diffCmd.Flags().StringSlice("exclude", nil, "Glob patterns for paths to skip (repeatable)")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	for _, dir := range args {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+
+	asJSON, _ := cmd.Flags().GetBool("json")
+	showOps, _ := cmd.Flags().GetBool("ops")
+	ignoreModes, _ := cmd.Flags().GetBool("ignore-modes")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+
+	a := filesystem.NewOSFileSystem(args[0])
+	b := filesystem.NewOSFileSystem(args[1])
+	diff, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{
+		IgnoreModes: ignoreModes,
+		Exclude:     exclude,
+	})
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if !showOps {
+		if asJSON {
+			return diff.WriteJSON(out)
+		}
+		return diff.WriteText(out)
+	}
+
+	ops, err := diff.Operations(synthfs.New(), b)
+	if err != nil {
+		return err
+	}
+	return writeDiffOperations(out, ops, asJSON)
+}
+
+// diffOperation is the printable form of an operation produced by a diff.
+type diffOperation struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func writeDiffOperations(w io.Writer, ops []synthfs.Operation, asJSON bool) error {
+	printable := make([]diffOperation, 0, len(ops))
+	for _, op := range ops {
+		desc := op.Describe()
+		entry := diffOperation{ID: string(op.ID()), Type: desc.Type, Path: desc.Path}
+		if p, ok := desc.Details["path"].(string); ok {
+			entry.Path = p
+		}
+		if detail, ok := desc.Details["description"].(string); ok {
+			entry.Detail = detail
+		}
+		printable = append(printable, entry)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]interface{}{"operations": printable})
+	}
+	for _, op := range printable {
+		line := fmt.Sprintf("%-16s %s", op.Type, op.Path)
+		if op.Detail != "" {
+			line += "  # " + op.Detail
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffCmd(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	writeTestFile(t, filepath.Join(dirA, "kept.txt"), "same")
+	writeTestFile(t, filepath.Join(dirA, "changed.txt"), "before")
+	writeTestFile(t, filepath.Join(dirA, "removed.txt"), "gone")
+	writeTestFile(t, filepath.Join(dirB, "kept.txt"), "same")
+	writeTestFile(t, filepath.Join(dirB, "changed.txt"), "after")
+	writeTestFile(t, filepath.Join(dirB, "added.txt"), "new")
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs(append([]string{"diff"}, args...))
+		t.Cleanup(func() {
+			rootCmd.SetOut(nil)
+			rootCmd.SetArgs(nil)
+			for _, name := range []string{"json", "ops", "ignore-modes"} {
+				_ = diffCmd.Flags().Set(name, "false")
+			}
+		})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("diff command failed: %v", err)
+		}
+		return out.String()
+	}
+
+	t.Run("text output", func(t *testing.T) {
+		out := run(t, dirA, dirB)
+		expected := "A  added.txt (file)\nM  changed.txt (content)\nD  removed.txt (file)\n"
+		if out != expected {
+			t.Errorf("unexpected output:\n%s\nexpected:\n%s", out, expected)
+		}
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		out := run(t, "--json", dirA, dirB)
+		if !strings.Contains(out, `"kind": "added"`) || !strings.Contains(out, `"old_checksum"`) {
+			t.Errorf("unexpected JSON output:\n%s", out)
+		}
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		out := run(t, "--ops", dirA, dirB)
+		for _, want := range []string{"delete           removed.txt", "create_file      added.txt", "changed.txt"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in operations output:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("runtime errors print neither usage nor the error", func(t *testing.T) {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&out)
+		rootCmd.SetArgs([]string{"diff", dirA, filepath.Join(dirB, "missing")})
+		defer func() {
+			rootCmd.SetOut(nil)
+			rootCmd.SetErr(nil)
+			rootCmd.SetArgs(nil)
+		}()
+		if err := rootCmd.Execute(); err == nil {
+			t.Fatal("expected diff of a missing directory to fail")
+		}
+		if out.Len() != 0 {
+			t.Errorf("expected Execute to leave printing the error to the caller, got:\n%s", out.String())
+		}
+	})
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/spf13/cobra"
+)
+
+// outputFormats are the values of --output, for commands that run operations.
+var outputFormats = []string{"text", "table", "json", "junit", "markdown"}
+
+// addOutputFlag adds --output to cmd.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "text",
+		"Output format: "+strings.Join(outputFormats, ", "))
+}
+
+// outputFormat returns the validated value of --output.
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	for _, known := range outputFormats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// writeResult writes result to w in format. The text format is the listing
+// written by writeText; the others are renderings of synthfs.ResultReport.
+func writeResult(w io.Writer, format string, result *synthfs.Result, writeText func(io.Writer) error) error {
+	report := synthfs.NewResultReport(result)
+	switch format {
+	case "table":
+		return report.WriteTable(w)
+	case "json":
+		return report.WriteJSON(w)
+	case "junit":
+		return report.WriteJUnit(w)
+	case "markdown":
+		return report.WriteMarkdown(w)
+	default:
+		return writeText(w)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <plan.json>",
+	Short: "Apply a saved restore plan",
+	Long: `Apply a restore plan saved with synthfs.RestorePlan.WriteJSON, undoing the
+run it was made from. Paths in the plan are relative to --dir, which must be
+the root the run used.
+
+With --dry-run, print the steps and check that they apply without changing
+anything.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().String("dir", ".", "Root directory the plan's paths are relative to")
+	restoreCmd.Flags().Bool("dry-run", false, "Report what would be restored without changing anything")
+	addOutputFlag(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	plan, err := synthfs.ReadRestorePlan(file)
+	if err != nil {
+		return fmt.Errorf("failed to read restore plan %s: %w", args[0], err)
+	}
+
+	options := synthfs.DefaultPipelineOptions()
+	options.DryRun = dryRun
+	result, err := plan.ExecuteWithOptions(cmd.Context(), filesystem.NewOSFileSystem(dir), options)
+	if err != nil && format == "text" {
+		return err
+	}
+
+	writeErr := writeResult(cmd.OutOrStdout(), format, result, plan.WriteText)
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
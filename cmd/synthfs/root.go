@@ -38,6 +38,11 @@ func init() {
 
 	// Add version command
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(undoCmd)
 
 	// Plan commands temporarily removed during v2 migration
 	// rootCmd.AddCommand(newPlanCommand())
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <src> <dst>",
+	Short: "Make a directory mirror another",
+	Long: `Copy new and changed files from src to dst, creating dst if needed.
+
+Files are compared by size and modification time, or by checksum with
+--checksum. With --delete, entries of dst that are not in src are removed.
+Progress is drawn on stderr while the sync runs. With --output, the result
+is reported as a table, JSON, JUnit XML or Markdown instead of a listing.
+With --undo-log, the sync is recorded so "synthfs undo" can revert it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSync,
+}
+
+func init() {
+	syncCmd.Flags().Bool("delete", false, "Remove destination entries that are not in the source")
+	syncCmd.Flags().Bool("checksum", false, "Compare files by checksum instead of size and modification time")
+	syncCmd.Flags().StringSlice("exclude", nil, "Glob patterns for paths to skip (repeatable)")
+	syncCmd.Flags().Bool("dry-run", false, "Report what would change without changing anything")
+	syncCmd.Flags().Bool("progress", true, "Show progress on stderr")
+	addOutputFlag(syncCmd)
+	addUndoLogFlag(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	deleteExtra, _ := cmd.Flags().GetBool("delete")
+	checksum, _ := cmd.Flags().GetBool("checksum")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	showProgress, _ := cmd.Flags().GetBool("progress")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	root, paths, err := commonRoot(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	options := synthfs.SyncOptions{
+		Delete:           deleteExtra,
+		Exclude:          exclude,
+		PreserveMetadata: true,
+	}
+	if checksum {
+		options.Compare = synthfs.SyncCompareChecksum
+	}
+
+	pipelineOptions := synthfs.DefaultPipelineOptions()
+	pipelineOptions.DryRun = dryRun
+	pipelineOptions.RollbackOnError = true
+	if showProgress {
+		bus := synthfs.NewEventBus()
+		renderer := synthfs.NewProgressRenderer(cmd.ErrOrStderr())
+		renderer.Attach(bus)
+		defer renderer.Detach()
+		pipelineOptions.EventBus = bus
+	}
+
+	log := undoLog(cmd)
+	if log != nil {
+		pipelineOptions.Restorable = true
+	}
+
+	fs := filesystem.NewOSFileSystem(root)
+	result, err := synthfs.RunWithOptions(cmd.Context(), fs, pipelineOptions,
+		synthfs.New().Sync(paths[0], paths[1], options))
+	if err == nil && log != nil && !dryRun {
+		// Recorded by hand, to keep the root the paths are relative to
+		result.Metadata = map[string]interface{}{"root": root}
+		if result.RunID, err = log.Record(fs, result); err != nil {
+			return fmt.Errorf("failed to record the sync in the undo log: %w", err)
+		}
+		if result.RunID != "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "recorded as run %s\n", result.RunID)
+		}
+	}
+	if err != nil && (result == nil || format == "text") {
+		return err
+	}
+
+	// Structured reports are written for failed runs too, for CI
+	writeErr := writeResult(cmd.OutOrStdout(), format, result, func(out io.Writer) error {
+		for _, op := range result.Operations {
+			desc := op.Operation.(synthfs.Operation).Describe()
+			if _, err := fmt.Fprintf(out, "%-10s %s\n", desc.Type, strings.TrimPrefix(desc.Path, paths[1]+"/")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// commonRoot returns the deepest directory containing every path, and the
+// paths relative to it, so they can be reached through one filesystem.
+func commonRoot(paths ...string) (string, []string, error) {
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return "", nil, err
+		}
+		abs[i] = a
+	}
+
+	root := filepath.Dir(abs[0])
+	for _, a := range abs {
+		for !strings.HasPrefix(a, root+string(filepath.Separator)) && root != filepath.Dir(root) {
+			root = filepath.Dir(root)
+		}
+	}
+
+	rel := make([]string, len(abs))
+	for i, a := range abs {
+		r, err := filepath.Rel(root, a)
+		if err != nil {
+			return "", nil, err
+		}
+		rel[i] = filepath.ToSlash(r)
+	}
+	return root, rel, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncCmd(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "sub", "new.txt"), "new")
+	writeTestFile(t, filepath.Join(dst, "stale.txt"), "stale")
+
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	rootCmd.SetArgs([]string{"sync", "--delete", src, dst})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		_ = syncCmd.Flags().Set("delete", "false")
+	})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("sync command failed: %v", err)
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dst, "sub", "new.txt")); err != nil || string(content) != "new" {
+		t.Errorf("new file not synced: %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "stale.txt")); !os.IsNotExist(err) {
+		t.Errorf("stale file not deleted: %v", err)
+	}
+	for _, want := range []string{"prune      stale.txt", "sync_file  sub/new.txt"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+	if !strings.Contains(errOut.String(), "done: 3 operations") {
+		t.Errorf("expected a progress summary on stderr:\n%s", errOut.String())
+	}
+}
+
+func TestCommonRoot(t *testing.T) {
+	root, paths, err := commonRoot("/data/site/build", "/data/www/public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != "/data" || paths[0] != "site/build" || paths[1] != "www/public" {
+		t.Errorf("commonRoot = %q %v", root, paths)
+	}
+}
+
+func TestSyncCmdOutput(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+
+	for format, want := range map[string]string{
+		"json":     `"status": "SUCCESS"`,
+		"junit":    `<testcase name=`,
+		"markdown": "| SUCCESS |",
+		"table":    "Succeeded in ",
+	} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetArgs([]string{"sync", "--progress=false", "--output", format, src, filepath.Join(dir, "dst-"+format)})
+			t.Cleanup(func() {
+				rootCmd.SetOut(nil)
+				rootCmd.SetArgs(nil)
+				_ = syncCmd.Flags().Set("progress", "true")
+				_ = syncCmd.Flags().Set("output", "text")
+			})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("sync command failed: %v", err)
+			}
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("expected %q in output:\n%s", want, out.String())
+			}
+		})
+	}
+
+	rootCmd.SetArgs([]string{"sync", "--output", "yaml", src, filepath.Join(dir, "dst")})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		_ = syncCmd.Flags().Set("output", "text")
+	})
+	rootCmd.SilenceUsage, rootCmd.SilenceErrors = true, true
+	defer func() { rootCmd.SilenceUsage, rootCmd.SilenceErrors = false, false }()
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+}
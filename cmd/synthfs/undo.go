@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/spf13/cobra"
+)
+
+// undoLogEnv names the environment variable holding the default undo log.
+const undoLogEnv = "SYNTHFS_UNDO_LOG"
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the runs recorded in the undo log",
+	Long: `List the runs recorded in the undo log, latest first, with the operations
+each can undo.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+	// Execute prints the error once; usage is no help for a runtime error
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [run-id]",
+	Short: "Undo a run recorded in the undo log",
+	Long: `Undo a run recorded in the undo log, given its ID or --last, and remove it
+from the log.
+
+The undo is refused if paths the run touched have changed since, unless
+--force is given. With --dry-run, check the undo without changing anything.`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          runUndo,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{historyCmd, undoCmd} {
+		addUndoLogFlag(cmd)
+	}
+	undoCmd.Flags().Bool("last", false, "Undo the latest run")
+	undoCmd.Flags().Bool("force", false, "Undo even if paths changed since the run")
+	undoCmd.Flags().Bool("dry-run", false, "Report what would be undone without changing anything")
+	undoCmd.Flags().String("dir", "", "Root directory of the run (default: the one it was recorded with)")
+	addOutputFlag(undoCmd)
+}
+
+// addUndoLogFlag adds --undo-log to cmd.
+func addUndoLogFlag(cmd *cobra.Command) {
+	cmd.Flags().String("undo-log", os.Getenv(undoLogEnv),
+		"Directory of the undo log (default $"+undoLogEnv+")")
+}
+
+// undoLog returns the log named by --undo-log, or nil if there is none.
+func undoLog(cmd *cobra.Command) *synthfs.UndoLog {
+	dir, _ := cmd.Flags().GetString("undo-log")
+	if dir == "" {
+		return nil
+	}
+	return synthfs.NewUndoLog(dir)
+}
+
+func requireUndoLog(cmd *cobra.Command) (*synthfs.UndoLog, error) {
+	if log := undoLog(cmd); log != nil {
+		return log, nil
+	}
+	return nil, fmt.Errorf("no undo log: use --undo-log or set %s", undoLogEnv)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	log, err := requireUndoLog(cmd)
+	if err != nil {
+		return err
+	}
+	history, err := log.History()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tTIME\tROOT\tOPERATIONS")
+	for _, entry := range history {
+		root, _ := entry.Metadata["root"].(string)
+		var ops []string
+		for _, op := range entry.Operations {
+			ops = append(ops, op.Type+" "+op.Path)
+		}
+		if entry.Partial {
+			ops = append(ops, fmt.Sprintf("(partial: %d operations cannot be undone)", len(entry.Skipped)))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.ID, entry.Time.Local().Format(time.DateTime), root, strings.Join(ops, ", "))
+	}
+	return tw.Flush()
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	last, _ := cmd.Flags().GetBool("last")
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	dir, _ := cmd.Flags().GetString("dir")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if last == (len(args) == 1) {
+		return errors.New("give either a run ID or --last")
+	}
+	log, err := requireUndoLog(cmd)
+	if err != nil {
+		return err
+	}
+
+	var entry synthfs.UndoEntry
+	if last {
+		entry, err = log.Last()
+	} else {
+		entry, err = findRun(log, args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		dir, _ = entry.Metadata["root"].(string)
+	}
+	if dir == "" {
+		return fmt.Errorf("run %s was not recorded with a root directory: use --dir", entry.ID)
+	}
+	plan, err := log.Plan(entry.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := log.UndoWithOptions(cmd.Context(), filesystem.NewOSFileSystem(dir), entry.ID,
+		synthfs.UndoOptions{DryRun: dryRun, Force: force})
+	if err != nil && (result == nil || format == "text") {
+		return err
+	}
+
+	writeErr := writeResult(cmd.OutOrStdout(), format, result, func(out io.Writer) error {
+		if err := plan.WriteText(out); err != nil {
+			return err
+		}
+		for _, op := range entry.Skipped {
+			if _, err := fmt.Fprintf(out, "cannot undo %s %s\n", op.Type, op.Path); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			_, err := fmt.Fprintf(out, "run %s can be undone\n", entry.ID)
+			return err
+		}
+		_, err := fmt.Fprintf(out, "undid run %s\n", entry.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// findRun returns the run of log whose ID is, or starts with, id.
+func findRun(log *synthfs.UndoLog, id string) (synthfs.UndoEntry, error) {
+	history, err := log.History()
+	if err != nil {
+		return synthfs.UndoEntry{}, err
+	}
+	var matches []synthfs.UndoEntry
+	for _, entry := range history {
+		if entry.ID == id {
+			return entry, nil
+		}
+		if strings.HasPrefix(entry.ID, id) {
+			matches = append(matches, entry)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return synthfs.UndoEntry{}, fmt.Errorf("%w: %s", synthfs.ErrRunNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return synthfs.UndoEntry{}, fmt.Errorf("run ID %s is ambiguous: %d runs match", id, len(matches))
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUndoCmd(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	log := filepath.Join(t.TempDir(), "undo")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "new.txt"), "new")
+	writeTestFile(t, filepath.Join(dst, "stale.txt"), "stale")
+
+	execute := func(args ...string) string {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&errOut)
+		rootCmd.SetArgs(args)
+		defer func() {
+			rootCmd.SetOut(nil)
+			rootCmd.SetErr(nil)
+			rootCmd.SetArgs(nil)
+		}()
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%s failed: %v\n%s", args[0], err, errOut.String())
+		}
+		return out.String() + errOut.String()
+	}
+	t.Cleanup(func() {
+		for _, flags := range [][2]string{{"delete", "false"}, {"progress", "true"}, {"undo-log", ""}} {
+			_ = syncCmd.Flags().Set(flags[0], flags[1])
+		}
+		_ = historyCmd.Flags().Set("undo-log", "")
+		_ = undoCmd.Flags().Set("undo-log", "")
+		_ = undoCmd.Flags().Set("last", "false")
+	})
+
+	if out := execute("sync", "--delete", "--progress=false", "--undo-log", log, src, dst); !strings.Contains(out, "recorded as run ") {
+		t.Fatalf("sync was not recorded:\n%s", out)
+	}
+	if out := execute("history", "--undo-log", log); !strings.Contains(out, "sync_file dst/new.txt") || !strings.Contains(out, dir) {
+		t.Errorf("unexpected history:\n%s", out)
+	}
+
+	out := execute("undo", "--last", "--undo-log", log)
+	if !strings.Contains(out, "undid run ") {
+		t.Errorf("unexpected undo output:\n%s", out)
+	}
+	if content, err := os.ReadFile(filepath.Join(dst, "stale.txt")); err != nil || string(content) != "stale" {
+		t.Errorf("stale file not restored: %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("synced file not removed: %v", err)
+	}
+	if out := execute("history", "--undo-log", log); strings.Contains(out, "new.txt") {
+		t.Errorf("undone run still listed:\n%s", out)
+	}
+}
+
+func TestUndoCmdErrors(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "undo")
+	t.Cleanup(func() {
+		_ = historyCmd.Flags().Set("undo-log", "")
+		_ = undoCmd.Flags().Set("undo-log", "")
+	})
+
+	for _, args := range [][]string{
+		{"history"},
+		{"undo", "--undo-log", log, "no-such-run"},
+	} {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&out)
+		rootCmd.SetArgs(args)
+		err := rootCmd.Execute()
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		if err == nil {
+			t.Errorf("%v: expected an error", args)
+			continue
+		}
+		if out.Len() != 0 {
+			t.Errorf("%v: expected Execute to leave printing the error to the caller, got:\n%s", args, out.String())
+		}
+	}
+}
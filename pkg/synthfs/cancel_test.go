@@ -0,0 +1,114 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestCancellation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+
+	// stallOnStart returns a bus whose handler holds up the named operation
+	// type, after it started, for longer than the tests' timeouts
+	stallOnStart := func(opType string) synthfs.EventBus {
+		bus := synthfs.NewEventBus()
+		bus.Subscribe(core.EventOperationStarted, synthfs.EventHandlerFunc(func(ctx context.Context, event synthfs.Event) error {
+			if event.(*core.OperationStartedEvent).Operation.OperationType == opType {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return nil
+		}))
+		return bus
+	}
+
+	t.Run("cancelling the run stops it and rolls back", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		bus := synthfs.NewEventBus()
+		bus.Subscribe(core.EventOperationCompleted, synthfs.EventHandlerFunc(func(ctx context.Context, event synthfs.Event) error {
+			cancel()
+			return nil
+		}))
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+		options.RollbackOnError = true
+		options.ContinueOnError = true
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateDir("app", 0755),
+			sfs.CreateFile("app/a.txt", []byte("a"), 0644),
+			sfs.CreateFile("b.txt", []byte("b"), 0644),
+		)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected a cancellation error, got %v", err)
+		}
+		if len(result.Operations) != 2 || result.Operations[0].Status != synthfs.StatusSuccess ||
+			result.Operations[1].Status != synthfs.StatusCancelled {
+			t.Fatalf("unexpected results: %+v", result.Operations)
+		}
+		for _, p := range []string{"app", "b.txt"} {
+			if _, err := fsys.Stat(p); err == nil {
+				t.Errorf("%s exists after a cancelled run", p)
+			}
+		}
+	})
+
+	t.Run("an operation that runs out of time is cancelled", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("a.txt", []byte("a"), 0644))
+		sfs := synthfs.New()
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = stallOnStart("copy")
+		options.OperationTimeout = time.Millisecond
+		options.ContinueOnError = true
+
+		result, err := synthfs.RunWithOptions(context.Background(), fsys, options,
+			sfs.Copy("a.txt", "b.txt"),
+			sfs.CreateFile("c.txt", []byte("c"), 0644),
+		)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected a deadline error, got %v", err)
+		}
+		if result.Operations[0].Status != synthfs.StatusCancelled {
+			t.Errorf("expected the copy to be cancelled, got %s", result.Operations[0].Status)
+		}
+		// A timeout fails one operation; the run goes on
+		if len(result.Operations) != 2 || result.Operations[1].Status != synthfs.StatusSuccess {
+			t.Errorf("expected the run to continue after the timeout: %+v", result.Operations)
+		}
+		if _, err := fsys.Stat("b.txt"); err == nil {
+			t.Error("copy was written despite timing out")
+		}
+	})
+
+	t.Run("Timeout overrides the run's timeout", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("a.txt", []byte("a"), 0644))
+		sfs := synthfs.New()
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = stallOnStart("copy")
+		options.OperationTimeout = time.Millisecond
+
+		result, err := synthfs.RunWithOptions(context.Background(), fsys, options,
+			synthfs.Timeout(sfs.Copy("a.txt", "b.txt"), time.Minute),
+		)
+		if err != nil || result.Operations[0].Status != synthfs.StatusSuccess {
+			t.Fatalf("expected the copy to succeed with its own timeout: %v", err)
+		}
+		if got := readString(t, fsys, "b.txt"); got != "a" {
+			t.Errorf("b.txt = %q", got)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package synthfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// Predicate reports whether an operation should run. It is given the state of
+// the filesystem right before the operation would run, projected from the
+// earlier operations when planning, and can read the outputs of operations
+// that ran before with Output.
+type Predicate = operations.Condition
+
+// errConditionUnknown is returned by predicates that cannot be evaluated yet,
+// while planning, because they read files an earlier operation will write.
+var errConditionUnknown = errors.New("condition cannot be evaluated before the run")
+
+// conditionMarker is implemented by operations that can be made conditional.
+type conditionMarker interface {
+	SetCondition(condition Predicate)
+	Condition() Predicate
+}
+
+// When makes op run only if predicate holds and returns it. The predicate is
+// checked when planning, against the projected filesystem, and again right
+// before op runs. An operation whose predicate does not hold is not validated,
+// does not run and is reported as StatusConditionUnmet. A predicate that
+// needs outputs of operations that have not run yet is assumed to hold when
+// planning. Expandable operations check the predicate once, before their
+// first child runs.
+//
+// Example:
+//
+//	synthfs.Run(ctx, fs,
+//	    synthfs.When(sfs.CreateFile("config.yml", defaults, 0644), synthfs.NotExists("config.yml")),
+//	    synthfs.Unless(sfs.ShellCommand("make deploy"), synthfs.DryRun()),
+//	)
+func When(op Operation, predicate Predicate) Operation {
+	if marker, ok := op.(conditionMarker); ok {
+		marker.SetCondition(predicate)
+	}
+	return op
+}
+
+// Unless makes op run only if predicate does not hold and returns it. See
+// When.
+func Unless(op Operation, predicate Predicate) Operation {
+	return When(op, Not(predicate))
+}
+
+// Not holds when predicate does not.
+func Not(predicate Predicate) Predicate {
+	return func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		holds, err := predicate(ctx, fs)
+		return !holds, err
+	}
+}
+
+// Exists holds when path exists.
+func Exists(path string) Predicate {
+	return func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		return pathExists(fs, path)
+	}
+}
+
+// NotExists holds when path does not exist.
+func NotExists(path string) Predicate {
+	return Not(Exists(path))
+}
+
+// ContentMatches holds when path is a file whose content matches re.
+//
+// Example, running a migration only for schema versions below 3:
+//
+//	synthfs.When(migrate, synthfs.ContentMatches("schema_version", regexp.MustCompile(`^[0-2]\b`)))
+func ContentMatches(path string, re *regexp.Regexp) Predicate {
+	return func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		content, found, err := conditionContent(fs, path)
+		if err != nil || !found {
+			return false, err
+		}
+		return re.Match(content), nil
+	}
+}
+
+// ChecksumEquals holds when path is a file whose checksum with algorithm is
+// want, in hexadecimal.
+func ChecksumEquals(path string, algorithm ChecksumAlgorithm, want string) Predicate {
+	return func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		content, found, err := conditionContent(fs, path)
+		if err != nil || !found {
+			return false, err
+		}
+		hasher, err := newHasher(algorithm)
+		if err != nil {
+			return false, err
+		}
+		_, _ = hasher.Write(content)
+		return fmt.Sprintf("%x", hasher.Sum(nil)) == want, nil
+	}
+}
+
+// OutputEquals holds when the output key of op is want. It fails with
+// core.ErrOutputNotAvailable when op has not stored the output.
+func OutputEquals[T comparable](op Operation, key string, want T) Predicate {
+	return func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		value, ok := Output[T](op, key)
+		if !ok {
+			return false, fmt.Errorf("%w: %q of operation %s", core.ErrOutputNotAvailable, key, op.ID())
+		}
+		return value == want, nil
+	}
+}
+
+// DryRun holds in runs with PipelineOptions.DryRun set. Use it with Unless to
+// skip operations, such as shell commands, that a dry run cannot simulate.
+func DryRun() Predicate {
+	return func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+		return dryRun, nil
+	}
+}
+
+// dryRunKey marks the context of a dry run.
+type dryRunKey struct{}
+
+// pathExists reports whether path exists in fs.
+func pathExists(fs filesystem.FileSystem, path string) (bool, error) {
+	if _, err := fs.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// conditionContent reads path for a predicate. found is false when path does
+// not exist. While planning, files that earlier operations touch cannot be
+// read yet.
+func conditionContent(fs filesystem.FileSystem, path string) (content []byte, found bool, err error) {
+	if projected, ok := fs.(*ProjectedFileSystem); ok && projected.touched(path) {
+		return nil, false, errConditionUnknown
+	}
+	if found, err := pathExists(fs, path); err != nil || !found {
+		return nil, false, err
+	}
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = file.Close() }()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, file); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// conditionHolds reports whether the condition of op, if it has one, holds.
+func conditionHolds(ctx context.Context, fs filesystem.FileSystem, op Operation) (bool, error) {
+	marker, ok := op.(conditionMarker)
+	if !ok || marker.Condition() == nil {
+		return true, nil
+	}
+	return marker.Condition()(ctx, fs)
+}
+
+// plannedConditionHolds reports whether the condition of op holds when
+// planning. Conditions that cannot be evaluated before the run are assumed
+// to hold; they are checked again before op runs.
+func plannedConditionHolds(ctx context.Context, fs filesystem.FileSystem, op Operation) (bool, error) {
+	holds, err := conditionHolds(ctx, fs, op)
+	if errors.Is(err, errConditionUnknown) || errors.Is(err, core.ErrOutputNotAvailable) {
+		return true, nil
+	}
+	return holds, err
+}
+
+// inheritCondition gives children the condition of their parent, evaluated
+// once, when the first child runs, for all of them.
+func inheritCondition(parent Operation, children []Operation) {
+	marker, ok := parent.(conditionMarker)
+	if !ok || marker.Condition() == nil {
+		return
+	}
+	condition := marker.Condition()
+	var evaluated bool
+	var holds bool
+	var err error
+	shared := func(ctx context.Context, fs filesystem.FileSystem) (bool, error) {
+		if !evaluated {
+			holds, err = condition(ctx, fs)
+			evaluated = true
+		}
+		return holds, err
+	}
+	for _, child := range children {
+		if childMarker, ok := child.(conditionMarker); ok {
+			childMarker.SetCondition(shared)
+		}
+	}
+}
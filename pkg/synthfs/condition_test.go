@@ -0,0 +1,123 @@
+package synthfs_test
+
+import (
+	"context"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestConditions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+
+	statuses := func(result *synthfs.Result) []synthfs.OperationStatus {
+		var statuses []synthfs.OperationStatus
+		for _, op := range result.Operations {
+			statuses = append(statuses, op.Status)
+		}
+		return statuses
+	}
+
+	t.Run("filesystem predicates", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("config.yml", []byte("custom"), 0644))
+		mustDo(t, fsys.WriteFile("schema_version", []byte("2\n"), 0644))
+		sfs := synthfs.New()
+
+		result, err := synthfs.Run(ctx, fsys,
+			// Would fail validation if it were not skipped
+			synthfs.When(sfs.CreateFile("config.yml", []byte("defaults"), 0644), synthfs.NotExists("config.yml")),
+			synthfs.When(sfs.CreateFile("migrated", nil, 0644), synthfs.ContentMatches("schema_version", regexp.MustCompile(`^[0-2]\b`))),
+			synthfs.When(sfs.CreateFile("checked", nil, 0644), synthfs.ChecksumEquals("config.yml", synthfs.MD5, "8b9035807842a4e4dbe009f3f1478127")),
+			// Sees the file created just before
+			synthfs.When(sfs.CreateFile("after", nil, 0644), synthfs.Exists("migrated")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		want := []synthfs.OperationStatus{synthfs.StatusConditionUnmet, synthfs.StatusSuccess, synthfs.StatusSuccess, synthfs.StatusSuccess}
+		if got := statuses(result); !equalStatuses(got, want) {
+			t.Errorf("statuses = %v, want %v", got, want)
+		}
+		if got := readString(t, fsys, "config.yml"); got != "custom" {
+			t.Errorf("config.yml overwritten: %q", got)
+		}
+	})
+
+	t.Run("output predicates are checked before the operation runs", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		version := sfs.ShellCommand("echo 3", synthfs.WithCaptureOutput())
+
+		result, err := synthfs.Run(ctx, fsys, version,
+			synthfs.When(sfs.CreateFile("v2", nil, 0644), synthfs.OutputEquals(version, "stdout", "2\n")),
+			synthfs.When(sfs.CreateFile("v3", nil, 0644), synthfs.OutputEquals(version, "stdout", "3\n")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		want := []synthfs.OperationStatus{synthfs.StatusSuccess, synthfs.StatusConditionUnmet, synthfs.StatusSuccess}
+		if got := statuses(result); !equalStatuses(got, want) {
+			t.Errorf("statuses = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("dry runs can be detected", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		options := synthfs.DefaultPipelineOptions()
+		options.DryRun = true
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			synthfs.Unless(sfs.ShellCommand("touch ran"), synthfs.DryRun()),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if result.Operations[0].Status != synthfs.StatusConditionUnmet {
+			t.Errorf("expected the command to be skipped, got %s", result.Operations[0].Status)
+		}
+	})
+
+	t.Run("expanded operations check their condition once", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("src", 0755))
+		mustDo(t, fsys.WriteFile("src/a.txt", []byte("a"), 0644))
+		mustDo(t, fsys.WriteFile("src/b.txt", []byte("b"), 0644))
+		sfs := synthfs.New()
+
+		// The first copy creates dst, which must not skip the second
+		result, err := synthfs.Run(ctx, fsys,
+			synthfs.When(sfs.Sync("src", "dst", synthfs.SyncOptions{}), synthfs.NotExists("dst/a.txt")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		for _, op := range result.Operations {
+			if op.Status != synthfs.StatusSuccess {
+				t.Errorf("%s: %s", op.OperationID, op.Status)
+			}
+		}
+		if got := readString(t, fsys, "dst/b.txt"); got != "b" {
+			t.Errorf("dst/b.txt = %q", got)
+		}
+	})
+}
+
+func equalStatuses(a, b []synthfs.OperationStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package synthfs
+
+import (
+	"context"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// ConfigFormat names a structured file format: JSON, YAML, TOML or INI.
+type ConfigFormat = operations.ConfigFormat
+
+// ConfigEdit is one change to a structured config file.
+type ConfigEdit = operations.ConfigEdit
+
+const (
+	ConfigFormatJSON = operations.ConfigFormatJSON
+	ConfigFormatYAML = operations.ConfigFormatYAML
+	ConfigFormatTOML = operations.ConfigFormatTOML
+	ConfigFormatINI  = operations.ConfigFormatINI
+)
+
+// ConfigSet sets the value at a key path such as "server.port" or
+// "plugins[0].name", creating intermediate tables as needed.
+func ConfigSet(path string, value interface{}) ConfigEdit {
+	return operations.ConfigSet(path, value)
+}
+
+// ConfigDelete removes the value at a key path. A missing path is not an error.
+func ConfigDelete(path string) ConfigEdit {
+	return operations.ConfigDelete(path)
+}
+
+// ConfigMerge deep-merges a map fragment into the table at path, or into the
+// document root when path is empty. Lists and scalars replace existing values.
+func ConfigMerge(path string, fragment interface{}) ConfigEdit {
+	return operations.ConfigMerge(path, fragment)
+}
+
+// ConfigAppend appends value to the list at path unless an equal value is already present.
+func ConfigAppend(path string, value interface{}) ConfigEdit {
+	return operations.ConfigAppend(path, value)
+}
+
+// EditConfig creates an operation that applies edits to a JSON, YAML, TOML or
+// INI file, chosen by extension. Key order is preserved, and comments too in
+// YAML, TOML and INI. Edits that change nothing leave the file untouched, and
+// the original is backed up for rollback and restore.
+//
+// Example:
+//
+//	sfs.EditConfig("package.json",
+//	    synthfs.ConfigSet("scripts.lint", "eslint ."),
+//	    synthfs.ConfigAppend("keywords", "cli"),
+//	)
+func (s *SynthFS) EditConfig(path string, edits ...ConfigEdit) Operation {
+	return s.EditConfigAs(path, "", edits...)
+}
+
+// EditConfigAs creates a config edit operation for a file whose format cannot
+// be inferred from its extension.
+func (s *SynthFS) EditConfigAs(path string, format ConfigFormat, edits ...ConfigEdit) Operation {
+	id := s.idGen("edit_config", path)
+	return operations.NewEditConfigOperation(id, path, format, edits...)
+}
+
+// EditConfigWithID creates a config edit operation with an explicit ID.
+func (s *SynthFS) EditConfigWithID(id string, path string, edits ...ConfigEdit) Operation {
+	return operations.NewEditConfigOperation(core.OperationID(id), path, "", edits...)
+}
+
+// EditConfig applies edits to a config file immediately.
+func EditConfig(ctx context.Context, fs FileSystem, path string, edits ...ConfigEdit) error {
+	_, err := Run(ctx, fs, New().EditConfig(path, edits...))
+	return err
+}
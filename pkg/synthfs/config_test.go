@@ -0,0 +1,65 @@
+package synthfs_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestEditConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	t.Run("edits a file created earlier in the run", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		result, err := synthfs.Run(ctx, fsys,
+			sfs.CreateFile("app/config.yaml", []byte("name: app\n"), 0644),
+			sfs.EditConfig("app/config.yaml", synthfs.ConfigSet("port", 8080)),
+			sfs.EditConfig("app/config.yaml", synthfs.ConfigAppend("tags", "web")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if !result.Success {
+			t.Fatalf("run was not successful: %v", result.Errors)
+		}
+		want := "name: app\nport: 8080\ntags:\n  - web\n"
+		if got := readString(t, fsys, "app/config.yaml"); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("restorable run records the backup", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("package.json", []byte("{\n  \"name\": \"app\"\n}\n"), 0644))
+
+		options := synthfs.DefaultPipelineOptions()
+		options.Restorable = true
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.EditConfigWithID("version", "package.json", synthfs.ConfigSet("version", "1.0.0")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if result.Operations[0].BackupData == nil {
+			t.Error("expected the original package.json to be backed up")
+		}
+		if len(result.RestoreOps) != 1 {
+			t.Errorf("expected one restore operation, got %d", len(result.RestoreOps))
+		}
+	})
+
+	t.Run("editing a directory fails validation", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("conf.json", 0755))
+		if err := synthfs.EditConfig(ctx, fsys, "conf.json", synthfs.ConfigSet("a", 1)); err == nil {
+			t.Error("expected an error editing a directory")
+		}
+	})
+}
@@ -42,6 +42,12 @@ const (
 	StatusFailure = core.StatusFailure
 	// StatusValidation indicates the operation failed during validation.
 	StatusValidation = core.StatusValidation
+	// StatusSkipped indicates the operation was skipped because its desired state already held.
+	StatusSkipped = core.StatusSkipped
+	// StatusCancelled indicates the operation was interrupted by cancellation or a timeout.
+	StatusCancelled = core.StatusCancelled
+	// StatusConditionUnmet indicates the operation's When condition did not hold, so it did not run.
+	StatusConditionUnmet = core.StatusConditionUnmet
 )
 
 // --- Item Type Constants ---
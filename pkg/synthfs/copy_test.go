@@ -0,0 +1,84 @@
+package synthfs_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestCopyDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	setup := func(t *testing.T) (synthfs.FileSystem, filesystem.LinkFS) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		linkFS := fsys.(filesystem.LinkFS)
+		mustDo(t, fsys.MkdirAll("cache/a", 0755))
+		mustDo(t, fsys.WriteFile("cache/a/blob", []byte("v1"), 0644))
+		mustDo(t, linkFS.Link("cache/a/blob", "cache/b"))
+		mustDo(t, fsys.WriteFile("cache/c", []byte("solo"), 0600))
+		mustDo(t, fsys.Symlink("cache/c", "cache/link"))
+		return fsys, linkFS
+	}
+
+	t.Run("copies the whole tree", func(t *testing.T) {
+		fsys, linkFS := setup(t)
+		if _, err := synthfs.Run(ctx, fsys, sfs.Copy("cache", "copy")); err != nil {
+			t.Fatalf("copy failed: %v", err)
+		}
+		for name, want := range map[string]string{"copy/a/blob": "v1", "copy/b": "v1", "copy/c": "solo", "copy/link": "solo"} {
+			if got := readString(t, fsys, name); got != want {
+				t.Errorf("%s: expected %q, got %q", name, want, got)
+			}
+		}
+		if info, err := fsys.Stat("copy/c"); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("expected copy/c to keep mode 0600, got %v (%v)", info, err)
+		}
+		if target, err := fsys.Readlink("copy/link"); err != nil || target != "cache/c" {
+			t.Errorf("expected copy/link to point at cache/c, got %q (%v)", target, err)
+		}
+		blob, _ := linkFS.FileID("copy/a/blob")
+		b, _ := linkFS.FileID("copy/b")
+		if blob == b {
+			t.Errorf("expected hard links to become separate files without PreserveHardlinks")
+		}
+	})
+
+	t.Run("preserves hard link groups when asked", func(t *testing.T) {
+		fsys, linkFS := setup(t)
+		op := sfs.CopyWithOptions("cache", "copy", synthfs.CopyOptions{PreserveHardlinks: true})
+		if _, err := synthfs.Run(ctx, fsys, op); err != nil {
+			t.Fatalf("copy failed: %v", err)
+		}
+		blob, _ := linkFS.FileID("copy/a/blob")
+		b, _ := linkFS.FileID("copy/b")
+		c, _ := linkFS.FileID("copy/c")
+		source, _ := linkFS.FileID("cache/a/blob")
+		if blob != b || blob == c || blob == source {
+			t.Errorf("expected copy/b to be linked to copy/a/blob only")
+		}
+	})
+
+	t.Run("rollback removes the copied tree", func(t *testing.T) {
+		fsys, _ := setup(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.RollbackOnError = true
+		_, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.Copy("cache", "copy"),
+			sfs.Copy("cache/c", "copy/c/nested"),
+		)
+		if err == nil {
+			t.Fatal("expected the second copy to fail")
+		}
+		if _, err := fsys.Stat("copy"); err == nil {
+			t.Error("expected rollback to remove the copied directory")
+		}
+	})
+}
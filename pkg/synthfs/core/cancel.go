@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// OperationTimeout returns the timeout op runs with: its own, when it has
+// one, or the run's default. Zero means no timeout.
+func OperationTimeout(op interface{}, defaultTimeout time.Duration) time.Duration {
+	if timed, ok := op.(interface{ Timeout() time.Duration }); ok && timed.Timeout() > 0 {
+		return timed.Timeout()
+	}
+	return defaultTimeout
+}
+
+// OperationContext returns the context an operation executes in: ctx, bounded
+// by timeout when it is positive.
+func OperationContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// CancellationError returns the error to report for an operation that ran in
+// opCtx and returned err, when the operation was cut short by cancellation
+// or its timeout, and nil otherwise. The result wraps context.Canceled or
+// context.DeadlineExceeded.
+func CancellationError(opCtx context.Context, err error) error {
+	ctxErr := opCtx.Err()
+	if err == nil || ctxErr == nil {
+		return nil
+	}
+	if errors.Is(err, ctxErr) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ctxErr, err)
+}
+
+// ContextReader returns r, failing reads with ctx.Err() once ctx is done, so
+// operations that stream data stop when their run is cancelled.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx == nil || ctx.Done() == nil {
+		return r
+	}
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(b []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(b)
+}
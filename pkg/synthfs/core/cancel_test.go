@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancellation(t *testing.T) {
+	t.Run("Operations can override the run's timeout", func(t *testing.T) {
+		if got := OperationTimeout(struct{}{}, time.Second); got != time.Second {
+			t.Errorf("Expected the run's timeout, got %v", got)
+		}
+		op := timedOp(time.Minute)
+		if got := OperationTimeout(op, time.Second); got != time.Minute {
+			t.Errorf("Expected the operation's timeout, got %v", got)
+		}
+	})
+
+	t.Run("Reads fail once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := ContextReader(ctx, strings.NewReader("abc"))
+		buf := make([]byte, 1)
+		if _, err := r.Read(buf); err != nil {
+			t.Fatalf("Unexpected error before cancelling: %v", err)
+		}
+		cancel()
+		if _, err := io.ReadAll(r); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Only errors of interrupted operations are cancellations", func(t *testing.T) {
+		failure := errors.New("disk full")
+		if err := CancellationError(context.Background(), failure); err != nil {
+			t.Errorf("Expected no cancellation for a live context, got %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := CancellationError(ctx, nil); err != nil {
+			t.Errorf("Expected no cancellation for a finished operation, got %v", err)
+		}
+		if err := CancellationError(ctx, failure); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the error to wrap context.Canceled, got %v", err)
+		}
+	})
+}
+
+type timedOp time.Duration
+
+func (op timedOp) Timeout() time.Duration { return time.Duration(op) }
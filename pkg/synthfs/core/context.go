@@ -1,5 +1,10 @@
 package core
 
+import (
+	"context"
+	"time"
+)
+
 // Logger interface defines logging capabilities
 type Logger interface {
 	Info() LogEvent
@@ -26,5 +31,29 @@ type ExecutionContext struct {
 	Logger   Logger
 	Budget   *BackupBudget
 	EventBus EventBus
+	// AsyncEvents publishes events in the background instead of before
+	// execution continues
+	AsyncEvents bool
+	// ProgressInterval is the shortest time between two progress events of
+	// an operation; DefaultProgressInterval when zero
+	ProgressInterval time.Duration
+	// Middleware wraps the validation, execution and rollback of each
+	// operation; see RunStep
+	Middleware []Middleware
 	// Note: FileSystem will be passed separately to avoid import cycles
 }
+
+// Publish sends event to the event bus, if there is one. Handlers run before
+// Publish returns, in publishing order, unless AsyncEvents is set.
+func (c *ExecutionContext) Publish(ctx context.Context, event Event) {
+	if c == nil || c.EventBus == nil {
+		return
+	}
+	if c.AsyncEvents {
+		c.EventBus.PublishAsync(ctx, event)
+		return
+	}
+	if err := c.EventBus.Publish(ctx, event); err != nil && c.Logger != nil {
+		c.Logger.Warn().Str("event_type", event.Type()).Err(err).Msg("event publishing failed")
+	}
+}
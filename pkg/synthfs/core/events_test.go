@@ -192,6 +192,52 @@ func TestMemoryEventBus(t *testing.T) {
 	})
 }
 
+func TestExecutionContextPublish(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Publishes synchronously by default", func(t *testing.T) {
+		bus := NewMemoryEventBus(&mockLogger{})
+		var received []string
+		bus.Subscribe("test.event", EventHandlerFunc(func(ctx context.Context, event Event) error {
+			received = append(received, event.Data().(string))
+			return nil
+		}))
+
+		execCtx := &ExecutionContext{Logger: &mockLogger{}, EventBus: bus}
+		for _, data := range []string{"first", "second", "third"} {
+			execCtx.Publish(ctx, NewBaseEvent("test.event", data))
+		}
+
+		if len(received) != 3 || received[0] != "first" || received[2] != "third" {
+			t.Errorf("Expected events in publishing order, got %v", received)
+		}
+	})
+
+	t.Run("Publishes in the background with AsyncEvents", func(t *testing.T) {
+		bus := NewMemoryEventBus(&mockLogger{})
+		handlerCalled := make(chan bool, 1)
+		bus.Subscribe("test.event", EventHandlerFunc(func(ctx context.Context, event Event) error {
+			handlerCalled <- true
+			return nil
+		}))
+
+		execCtx := &ExecutionContext{Logger: &mockLogger{}, EventBus: bus, AsyncEvents: true}
+		execCtx.Publish(ctx, NewBaseEvent("test.event", nil))
+
+		select {
+		case <-handlerCalled:
+		case <-time.After(time.Second):
+			t.Error("Async handler was not called within timeout")
+		}
+	})
+
+	t.Run("Without an event bus nothing is published", func(t *testing.T) {
+		var nilCtx *ExecutionContext
+		nilCtx.Publish(ctx, NewBaseEvent("test.event", nil))
+		(&ExecutionContext{}).Publish(ctx, NewBaseEvent("test.event", nil))
+	})
+}
+
 // mockLogger is a simple mock implementation of Logger for testing
 type mockLogger struct{}
 
@@ -19,6 +19,10 @@ type PipelineOptions struct {
 	// executing subsequent operations even if one fails.
 	ContinueOnError bool
 
+	// Ensure, if true, skips every operation that can tell that the state it
+	// would produce already holds, reporting it as StatusSkipped. Operations
+	// can also be put in ensure mode one at a time.
+	Ensure bool
 
 	// Restorable, if true, enables the backup mechanism for rollback.
 	Restorable bool
@@ -31,6 +35,44 @@ type PipelineOptions struct {
 
 	// UseSimpleBatch, if true, uses the simple batch execution model.
 	UseSimpleBatch bool
+
+	// EventBus, if set, receives the operation and lifecycle events of the
+	// run. Handlers run synchronously, in the order the events happen.
+	EventBus EventBus
+
+	// AsyncEvents, if true, publishes events in the background instead, so
+	// slow handlers do not hold up the run. Events may then arrive out of
+	// order, or after the run has returned.
+	AsyncEvents bool
+
+	// ProgressInterval is the shortest time between two progress events of
+	// an operation. Zero uses DefaultProgressInterval.
+	ProgressInterval time.Duration
+
+	// OperationTimeout, if positive, bounds the execution of each operation.
+	// An operation that runs out of time is interrupted and reported as
+	// StatusCancelled. Operations can override it one at a time.
+	OperationTimeout time.Duration
+
+	// RetryPolicy, if set, runs failed operations again. Operations can
+	// override it one at a time.
+	RetryPolicy *RetryPolicy
+
+	// Middleware wraps the validation, execution and rollback of every
+	// operation, the first middleware outermost.
+	Middleware []Middleware
+
+	// UndoLog, if set, records successful runs so they can be undone later,
+	// and makes them restorable. The ID of the run is set in Result.RunID.
+	UndoLog UndoRecorder
+}
+
+// UndoRecorder records successful runs so they can be undone later.
+// synthfs.UndoLog implements it.
+type UndoRecorder interface {
+	// RecordRun records result, a restorable run on fs, and returns its ID,
+	// or an empty ID if there is nothing to undo.
+	RecordRun(ctx context.Context, result *Result, fs interface{}) (string, error)
 }
 
 // OperationResult holds the outcome of a single operation's execution
@@ -43,6 +85,8 @@ type OperationResult struct {
 	BackupData   *BackupData // Backup data for restoration (only if restorable=true)
 	BackupSizeMB float64     // Actual backup size consumed
 	Metadata     map[string]interface{} // User-defined metadata for the operation
+	Attempts     int                    // Times the operation ran, retries included
+	ReverseOps   []interface{}          // Operations that undo this one, in order (only if restorable=true)
 }
 
 // Result holds the overall outcome of running a pipeline of operations
@@ -55,6 +99,7 @@ type Result struct {
 
 	// Enhanced restoration functionality
 	Budget     *BackupBudget // Backup budget information (only if restorable=true)
-	RestoreOps []interface{} // Generated reverse operations for restoration
+	RestoreOps []interface{} // Reverse operations of the successful operations, in the order those ran
 	Metadata   map[string]interface{} // User-defined metadata for the batch/pipeline
+	RunID      string                 // ID of the run in PipelineOptions.UndoLog, if recorded
 }
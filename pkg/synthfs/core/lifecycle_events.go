@@ -0,0 +1,189 @@
+package core
+
+import (
+	"time"
+)
+
+// Lifecycle event types, published around the operations of a run
+const (
+	EventPipelineStarted   = "pipeline.started"
+	EventPipelineFinished  = "pipeline.finished"
+	EventValidationStarted = "validation.started"
+	EventValidationFailed  = "validation.failed"
+	EventValidationPassed  = "validation.passed"
+	EventRollbackStarted   = "rollback.started"
+	EventRollbackStep      = "rollback.step"
+	EventRollbackCompleted = "rollback.completed"
+	EventBackupCaptured    = "backup.captured"
+	EventBudgetExhausted   = "budget.exhausted"
+)
+
+// PipelineStartedEvent is emitted before a run validates its operations
+type PipelineStartedEvent struct {
+	*BaseEvent
+	OperationCount int
+}
+
+// NewPipelineStartedEvent creates a new pipeline started event
+func NewPipelineStartedEvent(operationCount int) *PipelineStartedEvent {
+	return &PipelineStartedEvent{
+		BaseEvent:      NewBaseEvent(EventPipelineStarted, operationCount),
+		OperationCount: operationCount,
+	}
+}
+
+// PipelineFinishedEvent is emitted when a run returns, after any rollback
+type PipelineFinishedEvent struct {
+	*BaseEvent
+	Success  bool
+	Errors   []error
+	Duration time.Duration
+}
+
+// NewPipelineFinishedEvent creates a new pipeline finished event
+func NewPipelineFinishedEvent(success bool, errs []error, duration time.Duration) *PipelineFinishedEvent {
+	event := &PipelineFinishedEvent{
+		Success:  success,
+		Errors:   errs,
+		Duration: duration,
+	}
+	event.BaseEvent = NewBaseEvent(EventPipelineFinished, event)
+	return event
+}
+
+// ValidationStartedEvent is emitted before operations are validated
+type ValidationStartedEvent struct {
+	*BaseEvent
+	OperationCount int
+}
+
+// NewValidationStartedEvent creates a new validation started event
+func NewValidationStartedEvent(operationCount int) *ValidationStartedEvent {
+	return &ValidationStartedEvent{
+		BaseEvent:      NewBaseEvent(EventValidationStarted, operationCount),
+		OperationCount: operationCount,
+	}
+}
+
+// ValidationFailedEvent is emitted when validation stops a run. Operation is
+// empty when the error does not name an operation.
+type ValidationFailedEvent struct {
+	*BaseEvent
+	Operation OperationEventData
+	Error     error
+}
+
+// NewValidationFailedEvent creates a new validation failed event
+func NewValidationFailedEvent(op OperationEventData, err error) *ValidationFailedEvent {
+	event := &ValidationFailedEvent{
+		Operation: op,
+		Error:     err,
+	}
+	event.BaseEvent = NewBaseEvent(EventValidationFailed, event)
+	return event
+}
+
+// ValidationPassedEvent is emitted once every operation is valid.
+// OperationCount is the number of operations that will run, after expandable
+// operations are replaced by their children.
+type ValidationPassedEvent struct {
+	*BaseEvent
+	OperationCount int
+}
+
+// NewValidationPassedEvent creates a new validation passed event
+func NewValidationPassedEvent(operationCount int) *ValidationPassedEvent {
+	return &ValidationPassedEvent{
+		BaseEvent:      NewBaseEvent(EventValidationPassed, operationCount),
+		OperationCount: operationCount,
+	}
+}
+
+// RollbackStartedEvent is emitted before completed operations are rolled back
+type RollbackStartedEvent struct {
+	*BaseEvent
+	OperationCount int
+}
+
+// NewRollbackStartedEvent creates a new rollback started event
+func NewRollbackStartedEvent(operationCount int) *RollbackStartedEvent {
+	return &RollbackStartedEvent{
+		BaseEvent:      NewBaseEvent(EventRollbackStarted, operationCount),
+		OperationCount: operationCount,
+	}
+}
+
+// RollbackStepEvent is emitted after each operation is rolled back. Error is
+// nil when the rollback of the operation succeeded.
+type RollbackStepEvent struct {
+	*BaseEvent
+	Operation OperationEventData
+	Error     error
+}
+
+// NewRollbackStepEvent creates a new rollback step event
+func NewRollbackStepEvent(op OperationEventData, err error) *RollbackStepEvent {
+	event := &RollbackStepEvent{
+		Operation: op,
+		Error:     err,
+	}
+	event.BaseEvent = NewBaseEvent(EventRollbackStep, event)
+	return event
+}
+
+// RollbackCompletedEvent is emitted once a rollback has finished
+type RollbackCompletedEvent struct {
+	*BaseEvent
+	Errors   []error
+	Duration time.Duration
+}
+
+// NewRollbackCompletedEvent creates a new rollback completed event
+func NewRollbackCompletedEvent(errs []error, duration time.Duration) *RollbackCompletedEvent {
+	event := &RollbackCompletedEvent{
+		Errors:   errs,
+		Duration: duration,
+	}
+	event.BaseEvent = NewBaseEvent(EventRollbackCompleted, event)
+	return event
+}
+
+// BackupCapturedEvent is emitted when a restorable run backs up the state an
+// operation is about to change
+type BackupCapturedEvent struct {
+	*BaseEvent
+	Operation   OperationEventData
+	SizeMB      float64
+	RemainingMB float64
+}
+
+// NewBackupCapturedEvent creates a new backup captured event
+func NewBackupCapturedEvent(op OperationEventData, sizeMB, remainingMB float64) *BackupCapturedEvent {
+	event := &BackupCapturedEvent{
+		Operation:   op,
+		SizeMB:      sizeMB,
+		RemainingMB: remainingMB,
+	}
+	event.BaseEvent = NewBaseEvent(EventBackupCaptured, event)
+	return event
+}
+
+// BudgetExhaustedEvent is emitted when the backup budget cannot hold the
+// backup of an operation. The operation still runs, without a full backup.
+type BudgetExhaustedEvent struct {
+	*BaseEvent
+	Operation   OperationEventData
+	Error       error
+	RemainingMB float64
+}
+
+// NewBudgetExhaustedEvent creates a new budget exhausted event
+func NewBudgetExhaustedEvent(op OperationEventData, err error, remainingMB float64) *BudgetExhaustedEvent {
+	event := &BudgetExhaustedEvent{
+		Operation:   op,
+		Error:       err,
+		RemainingMB: remainingMB,
+	}
+	event.BaseEvent = NewBaseEvent(EventBudgetExhausted, event)
+	return event
+}
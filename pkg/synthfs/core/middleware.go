@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Phase is the step of an operation that middleware wraps.
+type Phase string
+
+const (
+	// PhaseValidate is the validation of an operation, before the run starts
+	PhaseValidate Phase = "validate"
+	// PhaseExecute is an attempt at running an operation
+	PhaseExecute Phase = "execute"
+	// PhaseRollback is the undoing of an operation after a failure
+	PhaseRollback Phase = "rollback"
+)
+
+// ErrSkip is returned by middleware to skip a step of an operation without
+// failing it. A skipped validation leaves the operation out of the projected
+// state, a skipped execution reports the operation as StatusSkipped, and a
+// skipped rollback leaves the operation's effect in place.
+var ErrSkip = errors.New("skipped by middleware")
+
+// Call is one step of one operation, as middleware sees it.
+type Call struct {
+	Phase     Phase
+	ID        OperationID
+	Operation interface{} // The operation (interface to avoid circular dep)
+	Desc      OperationDesc
+	ExecCtx   *ExecutionContext
+	FS        interface{} // The filesystem the step runs against
+}
+
+// ExecFunc runs one step of an operation.
+type ExecFunc func(ctx context.Context, call *Call) error
+
+// Middleware wraps every step of every operation of a run. It can act before
+// and after calling next, pass next a different context, or return without
+// calling next: with ErrSkip to skip the step, or with an error to fail it.
+type Middleware func(next ExecFunc) ExecFunc
+
+// RunStep runs step, one phase of op against fsys, through the middleware of
+// c, the first middleware outermost. Without middleware it just calls step.
+func (c *ExecutionContext) RunStep(ctx context.Context, phase Phase, op interface{}, fsys interface{}, step func(context.Context) error) error {
+	if c == nil || len(c.Middleware) == 0 {
+		return step(ctx)
+	}
+
+	call := &Call{Phase: phase, Operation: op, ExecCtx: c, FS: fsys}
+	if identified, ok := op.(interface{ ID() OperationID }); ok {
+		call.ID = identified.ID()
+	}
+	if describer, ok := op.(interface{ Describe() OperationDesc }); ok {
+		call.Desc = describer.Describe()
+	}
+	next := ExecFunc(func(ctx context.Context, call *Call) error {
+		return step(ctx)
+	})
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		next = c.Middleware[i](next)
+	}
+	return next(ctx, call)
+}
+
+// ExecuteOperation runs execute, the execution of op against fsys, through
+// the middleware of c, with the timeout and retry policy of op or, when it
+// has none, those of opts. Each attempt gets the full timeout, and each retry
+// is logged and published as an OperationRetryEvent. ExecuteOperation returns
+// the number of attempts, the error of the last one and whether that error
+// comes from a cancellation or timeout.
+func (c *ExecutionContext) ExecuteOperation(ctx context.Context, op interface{}, fsys interface{}, opts PipelineOptions, execute func(context.Context) error) (int, bool, error) {
+	var data OperationEventData
+	if identified, ok := op.(interface{ ID() OperationID }); ok {
+		data.OperationID = identified.ID()
+	}
+	if describer, ok := op.(interface{ Describe() OperationDesc }); ok {
+		desc := describer.Describe()
+		data.OperationType, data.Path, data.Details = desc.Type, desc.Path, desc.Details
+	}
+
+	policy := OperationRetryPolicy(op, opts.RetryPolicy)
+	timeout := OperationTimeout(op, opts.OperationTimeout)
+	var cancelErr error
+	attempts, err := policy.Retry(ctx, func(int) error {
+		opCtx, cancel := OperationContext(ctx, timeout)
+		defer cancel()
+		err := c.RunStep(opCtx, PhaseExecute, op, fsys, execute)
+		if cancelErr = CancellationError(opCtx, err); cancelErr != nil {
+			return cancelErr
+		}
+		return err
+	}, func(attempt int, err error, delay time.Duration) {
+		if c != nil && c.Logger != nil {
+			c.Logger.Info().
+				Str("op_id", string(data.OperationID)).
+				Int("attempt", attempt).
+				Dur("delay", delay).
+				Err(err).
+				Msg("operation failed - retrying")
+		}
+		c.Publish(ctx, NewOperationRetryEvent(data, attempt, policy.MaxAttempts, err, delay))
+	})
+	return attempts, cancelErr != nil, err
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrOutputNotAvailable is returned when an operation needs an output that
+// another operation has not stored, usually because it has not run yet.
+var ErrOutputNotAvailable = errors.New("output not available")
+
+// ResolveInputs resolves the lazy inputs of op, if it has any, from the
+// outputs of the operations it depends on. Executors call it right before
+// op runs.
+func ResolveInputs(ctx context.Context, op interface{}) error {
+	if resolver, ok := op.(interface{ ResolveInputs(context.Context) error }); ok {
+		return resolver.ResolveInputs(ctx)
+	}
+	return nil
+}
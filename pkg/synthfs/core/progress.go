@@ -0,0 +1,204 @@
+package core
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventOperationProgress is published while an operation moves bytes
+const EventOperationProgress = "operation.progress"
+
+// DefaultProgressInterval is the shortest time between two progress events of
+// an operation when no interval is configured
+const DefaultProgressInterval = 100 * time.Millisecond
+
+// OperationProgressEvent reports how far a running operation has got. Totals
+// are zero when they are not known; ETA is zero until it can be estimated.
+type OperationProgressEvent struct {
+	*BaseEvent
+	Operation    OperationEventData
+	BytesDone    int64
+	BytesTotal   int64
+	EntriesDone  int
+	EntriesTotal int
+	CurrentPath  string
+	ETA          time.Duration
+}
+
+// NewOperationProgressEvent creates a new operation progress event
+func NewOperationProgressEvent(op OperationEventData, bytesDone, bytesTotal int64, entriesDone, entriesTotal int, currentPath string, eta time.Duration) *OperationProgressEvent {
+	event := &OperationProgressEvent{
+		Operation:    op,
+		BytesDone:    bytesDone,
+		BytesTotal:   bytesTotal,
+		EntriesDone:  entriesDone,
+		EntriesTotal: entriesTotal,
+		CurrentPath:  currentPath,
+		ETA:          eta,
+	}
+	event.BaseEvent = NewBaseEvent(EventOperationProgress, event)
+	return event
+}
+
+// Progress tracks the bytes and entries an operation has processed and
+// publishes progress events, at most one per interval. A nil *Progress is
+// valid and ignores every call, so operations report progress without
+// checking whether anyone listens.
+type Progress struct {
+	mu           sync.Mutex
+	ctx          context.Context
+	execCtx      *ExecutionContext
+	op           OperationEventData
+	interval     time.Duration
+	start        time.Time
+	published    time.Time
+	reported     bool
+	bytesDone    int64
+	bytesTotal   int64
+	entriesDone  int
+	entriesTotal int
+	current      string
+}
+
+// NewProgress creates the progress tracker of op. It returns nil when execCtx
+// has no event bus to publish to.
+func NewProgress(ctx context.Context, execCtx *ExecutionContext, op OperationEventData) *Progress {
+	if execCtx == nil || execCtx.EventBus == nil {
+		return nil
+	}
+	interval := execCtx.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	return &Progress{
+		ctx:      ctx,
+		execCtx:  execCtx,
+		op:       op,
+		interval: interval,
+		start:    time.Now(),
+	}
+}
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx carrying p.
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFromContext returns the progress tracker carried by ctx, or nil.
+func ProgressFromContext(ctx context.Context) *Progress {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// AddTotal adds to the number of bytes and entries the operation will process.
+func (p *Progress) AddTotal(bytes int64, entries int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bytesTotal += bytes
+	p.entriesTotal += entries
+}
+
+// StartEntry records the path the operation is now working on.
+func (p *Progress) StartEntry(path string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = path
+	p.publish(false)
+}
+
+// Add records n more bytes processed.
+func (p *Progress) Add(n int64) {
+	if p == nil || n == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bytesDone += n
+	p.publish(false)
+}
+
+// EntryDone records one more entry processed.
+func (p *Progress) EntryDone() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entriesDone++
+	p.publish(false)
+}
+
+// Finish publishes the final state, regardless of the interval, if any
+// progress was reported.
+func (p *Progress) Finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reported {
+		p.publish(true)
+	}
+}
+
+// Reader returns r, counting the bytes read from it as processed.
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+// publish sends a progress event unless one was sent less than an interval
+// ago. The caller holds p.mu.
+func (p *Progress) publish(force bool) {
+	p.reported = true
+	now := time.Now()
+	if !force && !p.published.IsZero() && now.Sub(p.published) < p.interval {
+		return
+	}
+	p.published = now
+	p.execCtx.Publish(p.ctx, NewOperationProgressEvent(p.op, p.bytesDone, p.bytesTotal,
+		p.entriesDone, p.entriesTotal, p.current, p.eta(now)))
+}
+
+// eta extrapolates the time left from the rate so far, by bytes when their
+// total is known and by entries otherwise.
+func (p *Progress) eta(now time.Time) time.Duration {
+	done, total := float64(p.bytesDone), float64(p.bytesTotal)
+	if total <= 0 {
+		done, total = float64(p.entriesDone), float64(p.entriesTotal)
+	}
+	if done <= 0 || total <= done {
+		return 0
+	}
+	elapsed := now.Sub(p.start)
+	return time.Duration(float64(elapsed) * (total - done) / done)
+}
+
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	ctx := context.Background()
+	op := OperationEventData{OperationID: "copy-1", OperationType: "copy", Path: "big.bin"}
+
+	newProgress := func(interval time.Duration) (*Progress, *[]*OperationProgressEvent) {
+		bus := NewMemoryEventBus(&mockLogger{})
+		var events []*OperationProgressEvent
+		bus.Subscribe(EventOperationProgress, EventHandlerFunc(func(ctx context.Context, event Event) error {
+			events = append(events, event.(*OperationProgressEvent))
+			return nil
+		}))
+		execCtx := &ExecutionContext{Logger: &mockLogger{}, EventBus: bus, ProgressInterval: interval}
+		return NewProgress(ctx, execCtx, op), &events
+	}
+
+	t.Run("Throttles events but always publishes the final state", func(t *testing.T) {
+		progress, events := newProgress(time.Hour)
+		progress.AddTotal(100, 1)
+		progress.StartEntry("big.bin")
+		for i := 0; i < 10; i++ {
+			progress.Add(10)
+		}
+		progress.EntryDone()
+		progress.Finish()
+
+		if len(*events) != 2 {
+			t.Fatalf("Expected the first and the final event, got %d events", len(*events))
+		}
+		final := (*events)[1]
+		if final.BytesDone != 100 || final.BytesTotal != 100 || final.EntriesDone != 1 || final.EntriesTotal != 1 {
+			t.Errorf("Unexpected final state: %+v", final)
+		}
+		if final.CurrentPath != "big.bin" || final.Operation.OperationID != "copy-1" {
+			t.Errorf("Unexpected path or operation: %q %q", final.CurrentPath, final.Operation.OperationID)
+		}
+	})
+
+	t.Run("Counts bytes read through Reader", func(t *testing.T) {
+		progress, events := newProgress(time.Nanosecond)
+		progress.AddTotal(26, 0)
+		data, err := io.ReadAll(progress.Reader(strings.NewReader("abcdefghijklmnopqrstuvwxyz")))
+		if err != nil || len(data) != 26 {
+			t.Fatalf("Reader changed the data: %q, %v", data, err)
+		}
+		progress.Finish()
+		if last := (*events)[len(*events)-1]; last.BytesDone != 26 {
+			t.Errorf("Expected 26 bytes done, got %d", last.BytesDone)
+		}
+	})
+
+	t.Run("Estimates the time left from the rate so far", func(t *testing.T) {
+		progress, _ := newProgress(time.Hour)
+		progress.AddTotal(100, 0)
+		progress.bytesDone = 25
+		eta := progress.eta(progress.start.Add(time.Second))
+		if eta != 3*time.Second {
+			t.Errorf("Expected an ETA of 3s, got %v", eta)
+		}
+	})
+
+	t.Run("Nothing is published without an event bus", func(t *testing.T) {
+		progress := NewProgress(ctx, &ExecutionContext{}, op)
+		if progress != nil {
+			t.Fatal("Expected a nil progress without an event bus")
+		}
+		progress.AddTotal(1, 1)
+		progress.StartEntry("x")
+		progress.Add(1)
+		progress.EntryDone()
+		progress.Finish()
+		if ProgressFromContext(WithProgress(ctx, progress)) != nil {
+			t.Error("Expected no progress in the context")
+		}
+	})
+}
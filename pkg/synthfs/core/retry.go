@@ -0,0 +1,165 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"syscall"
+	"time"
+)
+
+// EventOperationRetry is published when a failed operation is about to run
+// again.
+const EventOperationRetry = "operation.retry"
+
+// DefaultRetryDelay is the wait before the second attempt of an operation
+// when RetryPolicy.InitialDelay is not set.
+const DefaultRetryDelay = 100 * time.Millisecond
+
+// RetryPolicy decides whether, and after how long, a failed operation runs
+// again. A nil policy runs operations once.
+type RetryPolicy struct {
+	// MaxAttempts is the most times an operation runs, the first included.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the second attempt. Zero uses
+	// DefaultRetryDelay.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the wait between two attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Multiplier grows the wait after each attempt. Values below 1 use 2.
+	Multiplier float64
+
+	// Jitter spreads each wait randomly by up to this fraction of it either
+	// way, from 0 to 1, so runs sharing a resource do not retry in step.
+	Jitter float64
+
+	// Retryable reports whether an error is worth another attempt. Nil
+	// retries the errors IsTransient accepts.
+	Retryable func(error) bool
+}
+
+// transientErrors are the filesystem errors that commonly clear up on their
+// own: busy or locked files and stale network mounts.
+var transientErrors = []error{
+	syscall.EAGAIN,
+	syscall.EBUSY,
+	syscall.EINTR,
+	syscall.ESTALE,
+	syscall.ETIMEDOUT,
+	syscall.ETXTBSY,
+}
+
+// IsTransient reports whether err is a filesystem error that may not happen
+// again, such as EBUSY on a locked file or ESTALE on an NFS mount.
+func IsTransient(err error) bool {
+	for _, transient := range transientErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// OperationRetryPolicy returns the retry policy op runs with: its own, when
+// it has one, or the run's default.
+func OperationRetryPolicy(op interface{}, defaultPolicy *RetryPolicy) *RetryPolicy {
+	if retrying, ok := op.(interface{ RetryPolicy() *RetryPolicy }); ok && retrying.RetryPolicy() != nil {
+		return retrying.RetryPolicy()
+	}
+	return defaultPolicy
+}
+
+// Retry calls attempt, with the attempt number starting at 1, until it
+// succeeds, fails with an error the policy does not retry, ctx is done or
+// MaxAttempts is reached, waiting between attempts. onRetry, if not nil, is
+// called before each wait. Retry returns the number of attempts made and the
+// error of the last one. Cancellations, timeouts and skips are never retried.
+func (p *RetryPolicy) Retry(ctx context.Context, attempt func(n int) error, onRetry func(n int, err error, delay time.Duration)) (int, error) {
+	for n := 1; ; n++ {
+		err := attempt(n)
+		if err == nil || !p.shouldRetry(ctx, n, err) {
+			return n, err
+		}
+
+		delay := p.Delay(n)
+		if onRetry != nil {
+			onRetry(n, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return n, err
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry reports whether attempt n, which failed with err, is followed
+// by another.
+func (p *RetryPolicy) shouldRetry(ctx context.Context, n int, err error) bool {
+	if p == nil || n >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrSkip) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransient(err)
+}
+
+// Delay returns the wait after attempt n fails: InitialDelay, grown by
+// Multiplier for each attempt after the first, capped at MaxDelay and spread
+// by Jitter.
+func (p *RetryPolicy) Delay(n int) time.Duration {
+	if p == nil {
+		return 0
+	}
+	delay := float64(p.InitialDelay)
+	if delay <= 0 {
+		delay = float64(DefaultRetryDelay)
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay *= math.Pow(multiplier, float64(n-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if jitter := math.Min(p.Jitter, 1); jitter > 0 {
+		delay *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// OperationRetryEvent is emitted when an attempt of an operation failed with
+// an error worth retrying, before waiting for the next attempt.
+type OperationRetryEvent struct {
+	*BaseEvent
+	Operation   OperationEventData
+	Attempt     int // The attempt that failed, starting at 1
+	MaxAttempts int
+	Error       error
+	Delay       time.Duration // The wait before the next attempt
+}
+
+// NewOperationRetryEvent creates a new operation retry event
+func NewOperationRetryEvent(op OperationEventData, attempt, maxAttempts int, err error, delay time.Duration) *OperationRetryEvent {
+	event := &OperationRetryEvent{
+		Operation:   op,
+		Attempt:     attempt,
+		MaxAttempts: maxAttempts,
+		Error:       err,
+		Delay:       delay,
+	}
+	event.BaseEvent = NewBaseEvent(EventOperationRetry, event)
+	return event
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Delays grow exponentially up to the cap", func(t *testing.T) {
+		policy := &RetryPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+		for n, want := range []time.Duration{10, 20, 40, 50, 50} {
+			if got := policy.Delay(n + 1); got != want*time.Millisecond {
+				t.Errorf("Delay(%d) = %v, want %v", n+1, got, want*time.Millisecond)
+			}
+		}
+	})
+
+	t.Run("Jitter stays within its fraction", func(t *testing.T) {
+		policy := &RetryPolicy{InitialDelay: 100 * time.Millisecond, Multiplier: 1, Jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			if got := policy.Delay(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+				t.Fatalf("Delay with jitter out of range: %v", got)
+			}
+		}
+	})
+
+	t.Run("Cancellation ends the retries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := &RetryPolicy{MaxAttempts: 5, InitialDelay: time.Hour}
+		attempts, err := policy.Retry(ctx, func(int) error {
+			return syscall.EBUSY
+		}, func(int, error, time.Duration) { cancel() })
+		if attempts != 1 || !errors.Is(err, syscall.EBUSY) {
+			t.Errorf("Expected one attempt, got %d: %v", attempts, err)
+		}
+	})
+
+	t.Run("A nil policy runs once", func(t *testing.T) {
+		var policy *RetryPolicy
+		attempts, err := policy.Retry(context.Background(), func(int) error { return syscall.EBUSY }, nil)
+		if attempts != 1 || err == nil {
+			t.Errorf("Expected one failed attempt, got %d: %v", attempts, err)
+		}
+	})
+
+	t.Run("Transient errors are recognised when wrapped", func(t *testing.T) {
+		if !IsTransient(fmt.Errorf("open a.txt: %w", syscall.EBUSY)) {
+			t.Error("Expected EBUSY to be transient")
+		}
+		if IsTransient(syscall.ENOENT) || IsTransient(context.DeadlineExceeded) {
+			t.Error("Expected ENOENT and timeouts not to be transient")
+		}
+	})
+}
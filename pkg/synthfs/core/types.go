@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"time"
@@ -26,6 +27,15 @@ const (
 	StatusFailure OperationStatus = "FAILURE"
 	// StatusValidation indicates the operation failed during validation
 	StatusValidation OperationStatus = "VALIDATION_FAILURE"
+	// StatusSkipped indicates the operation was not executed because, in
+	// ensure mode, the state it would produce already held
+	StatusSkipped OperationStatus = "SKIPPED"
+	// StatusCancelled indicates the operation was interrupted, or not started,
+	// because the run was cancelled or the operation timed out
+	StatusCancelled OperationStatus = "CANCELLED"
+	// StatusConditionUnmet indicates the operation was not executed because
+	// the condition it was given with When did not hold
+	StatusConditionUnmet OperationStatus = "CONDITION_UNMET"
 )
 
 // PathStateType represents the type of a filesystem object in the projected state
@@ -91,10 +101,14 @@ type BackupBudget struct {
 	UsedMB      float64
 }
 
+// ErrBudgetExceeded is wrapped by the errors returned when a backup does not
+// fit in the remaining budget.
+var ErrBudgetExceeded = errors.New("backup budget exceeded")
+
 // ConsumeBackup reduces the remaining budget by the specified amount
 func (b *BackupBudget) ConsumeBackup(sizeMB float64) error {
 	if sizeMB > b.RemainingMB {
-		return fmt.Errorf("backup size %.2fMB exceeds remaining budget %.2fMB", sizeMB, b.RemainingMB)
+		return fmt.Errorf("%w: backup size %.2fMB exceeds remaining budget %.2fMB", ErrBudgetExceeded, sizeMB, b.RemainingMB)
 	}
 	b.RemainingMB -= sizeMB
 	b.UsedMB += sizeMB
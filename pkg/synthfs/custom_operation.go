@@ -81,7 +81,7 @@ func (op *CustomOperation) WithDescription(description string) *CustomOperation
 // StoreOutput is a helper method that custom operations can use to store output.
 // This allows custom operations to make their output available after execution.
 func (op *CustomOperation) StoreOutput(key string, value interface{}) {
-	op.SetDescriptionDetail(key, value)
+	op.SetOutput(key, value)
 }
 
 // executeInternal runs the custom operation's execute function with event handling.
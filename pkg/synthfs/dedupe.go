@@ -0,0 +1,47 @@
+package synthfs
+
+import (
+	"context"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// DedupeStrategy selects what Dedupe does with duplicate files.
+type DedupeStrategy = operations.DedupeStrategy
+
+const (
+	// DedupeHardlink replaces duplicates with hard links to the first copy.
+	DedupeHardlink = operations.DedupeHardlink
+	// DedupeSymlink replaces duplicates with symlinks to the first copy.
+	DedupeSymlink = operations.DedupeSymlink
+	// DedupeReport only reports duplicates in the operation's outputs.
+	DedupeReport = operations.DedupeReport
+)
+
+// Dedupe creates an operation that finds files with identical content under
+// root. Files are compared by size, then checksum, then byte for byte; the
+// first file of each group in path order is kept. With DedupeHardlink or
+// DedupeSymlink the run expands into one "dedupe_file" child per duplicate,
+// each reversed by copying the content back. With DedupeReport nothing is
+// changed and the "duplicates", "duplicate_files" and "reclaimable_bytes"
+// outputs describe what was found.
+//
+// Example:
+//
+//	op := sfs.Dedupe("build/vendor", synthfs.DedupeHardlink)
+func (s *SynthFS) Dedupe(root string, strategy DedupeStrategy) Operation {
+	id := s.idGen("dedupe", root)
+	return operations.NewDedupeOperation(id, root, strategy)
+}
+
+// DedupeWithID creates a dedupe operation with an explicit ID.
+func (s *SynthFS) DedupeWithID(id string, root string, strategy DedupeStrategy) Operation {
+	return operations.NewDedupeOperation(core.OperationID(id), root, strategy)
+}
+
+// Dedupe deduplicates the files under root immediately.
+func Dedupe(ctx context.Context, fs FileSystem, root string, strategy DedupeStrategy) error {
+	_, err := Run(ctx, fs, New().Dedupe(root, strategy))
+	return err
+}
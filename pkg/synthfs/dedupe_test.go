@@ -0,0 +1,115 @@
+package synthfs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestDedupe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	setup := func(t *testing.T) synthfs.FileSystem {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("build/a", 0755))
+		mustDo(t, fsys.MkdirAll("build/b", 0755))
+		mustDo(t, fsys.WriteFile("build/a/vendor.js", []byte("module.exports = 1\n"), 0644))
+		mustDo(t, fsys.WriteFile("build/b/vendor.js", []byte("module.exports = 1\n"), 0644))
+		mustDo(t, fsys.WriteFile("build/b/app.js", []byte("module.exports = 2\n"), 0644))
+		return fsys
+	}
+
+	t.Run("hard links duplicates", func(t *testing.T) {
+		fsys := setup(t)
+		result, err := synthfs.Run(ctx, fsys, sfs.DedupeWithID("dedupe", "build", synthfs.DedupeHardlink))
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if ids := operationIDs(result); len(ids) != 1 || ids[0] != "dedupe:dedupe:b/vendor.js" {
+			t.Fatalf("unexpected operations: %v", ids)
+		}
+		linkFS := fsys.(filesystem.LinkFS)
+		a, _ := linkFS.FileID("build/a/vendor.js")
+		b, _ := linkFS.FileID("build/b/vendor.js")
+		if a != b {
+			t.Error("expected build/b/vendor.js to be a hard link")
+		}
+	})
+
+	t.Run("symlinks duplicates and restores them", func(t *testing.T) {
+		fsys := setup(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.Restorable = true
+		result, err := synthfs.RunWithOptions(ctx, fsys, options, sfs.Dedupe("build", synthfs.DedupeSymlink))
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if target, err := fsys.Readlink("build/b/vendor.js"); err != nil || target != "build/a/vendor.js" {
+			t.Fatalf("expected a symlink to build/a/vendor.js, got %q (%v)", target, err)
+		}
+		if len(result.RestoreOps) != 1 {
+			t.Fatalf("expected one restore operation, got %d", len(result.RestoreOps))
+		}
+		mustDo(t, result.RestoreOps[0].(synthfs.Operation).Execute(ctx, nil, fsys))
+		if _, err := fsys.Readlink("build/b/vendor.js"); err == nil {
+			t.Error("expected restore to replace the symlink with a file")
+		}
+		if got := readString(t, fsys, "build/b/vendor.js"); got != "module.exports = 1\n" {
+			t.Errorf("content after restore = %q", got)
+		}
+	})
+
+	t.Run("symlinks resolve under a relative root", func(t *testing.T) {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := filepath.Rel(wd, t.TempDir())
+		if err != nil {
+			t.Skipf("temp dir is not reachable from the working directory: %v", err)
+		}
+		fsys := filesystem.NewOSFileSystem(root)
+		mustDo(t, fsys.MkdirAll("build/a", 0755))
+		mustDo(t, fsys.MkdirAll("build/b/c/d", 0755))
+		mustDo(t, fsys.WriteFile("build/a/x.txt", []byte("same"), 0644))
+		mustDo(t, fsys.WriteFile("build/b/c/d/x.txt", []byte("same"), 0644))
+
+		if _, err := synthfs.Run(ctx, fsys, sfs.Dedupe("build", synthfs.DedupeSymlink)); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if _, err := fsys.Readlink("build/b/c/d/x.txt"); err != nil {
+			t.Errorf("build/b/c/d/x.txt was not linked: %v", err)
+		}
+		if got := readString(t, fsys, "build/b/c/d/x.txt"); got != "same" {
+			t.Errorf("build/b/c/d/x.txt = %q", got)
+		}
+	})
+
+	t.Run("report only fills outputs", func(t *testing.T) {
+		fsys := setup(t)
+		op := sfs.Dedupe("build", synthfs.DedupeReport)
+		if _, err := synthfs.Run(ctx, fsys, op); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if got := synthfs.GetOperationOutputValue(op, "reclaimable_bytes"); got != int64(19) {
+			t.Errorf("reclaimable_bytes = %v, want 19", got)
+		}
+		duplicates := synthfs.GetOperationOutputValue(op, "duplicates").(map[string][]string)
+		if got := duplicates["build/a/vendor.js"]; len(got) != 1 || got[0] != "build/b/vendor.js" {
+			t.Errorf("duplicates = %v", duplicates)
+		}
+		if _, err := fsys.Readlink("build/b/vendor.js"); err == nil {
+			t.Error("report should not change files")
+		}
+	})
+}
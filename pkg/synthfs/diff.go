@@ -0,0 +1,465 @@
+package synthfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
+)
+
+// DiffKind classifies how a path differs between two filesystems.
+type DiffKind string
+
+const (
+	// DiffAdded marks a path that exists only in the second filesystem.
+	DiffAdded DiffKind = "added"
+	// DiffRemoved marks a path that exists only in the first filesystem.
+	DiffRemoved DiffKind = "removed"
+	// DiffModified marks a file whose content or mode changed, or a directory whose mode changed.
+	DiffModified DiffKind = "modified"
+	// DiffTypeChanged marks a path that changed between file, directory and symlink.
+	DiffTypeChanged DiffKind = "type_changed"
+	// DiffSymlinkRetargeted marks a symlink that points somewhere else.
+	DiffSymlinkRetargeted DiffKind = "symlink_retargeted"
+)
+
+// DiffOptions controls how two filesystems are compared.
+type DiffOptions struct {
+	// IgnoreModes skips permission comparisons.
+	IgnoreModes bool
+	// Exclude lists path.Match patterns, matched against the full path and the
+	// base name. A matching directory is skipped with everything below it.
+	Exclude []string
+}
+
+// DiffEntry describes a single difference. Old fields describe the first
+// filesystem and New fields the second; fields that don't apply are empty.
+type DiffEntry struct {
+	Path           string      `json:"path"`
+	Kind           DiffKind    `json:"kind"`
+	OldType        string      `json:"old_type,omitempty"`
+	NewType        string      `json:"new_type,omitempty"`
+	OldMode        fs.FileMode `json:"old_mode,omitempty"`
+	NewMode        fs.FileMode `json:"new_mode,omitempty"`
+	OldChecksum    string      `json:"old_checksum,omitempty"` // MD5, set when both sides are files
+	NewChecksum    string      `json:"new_checksum,omitempty"`
+	OldTarget      string      `json:"old_target,omitempty"`
+	NewTarget      string      `json:"new_target,omitempty"`
+	ContentChanged bool        `json:"content_changed,omitempty"`
+	ModeChanged    bool        `json:"mode_changed,omitempty"`
+}
+
+// FSDiff is the result of comparing two filesystems, sorted by path.
+type FSDiff struct {
+	Entries []DiffEntry `json:"entries"`
+}
+
+// diffNode is the state of a single path on one side of a diff.
+type diffNode struct {
+	itemType string
+	mode     fs.FileMode
+	target   string
+}
+
+// DiffFileSystems compares every path in a against b. File content is
+// compared with checksums from the validation package.
+func DiffFileSystems(a, b FileSystem, opts DiffOptions) (*FSDiff, error) {
+	oldTree, err := scanDiffTree(a, opts)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := scanDiffTree(b, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &FSDiff{}
+	for p, oldNode := range oldTree {
+		newNode, ok := newTree[p]
+		if !ok {
+			diff.Entries = append(diff.Entries, DiffEntry{
+				Path:      p,
+				Kind:      DiffRemoved,
+				OldType:   oldNode.itemType,
+				OldMode:   oldNode.mode,
+				OldTarget: oldNode.target,
+			})
+			continue
+		}
+		entry, changed, err := compareDiffNodes(a, b, p, oldNode, newNode, opts)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			diff.Entries = append(diff.Entries, entry)
+		}
+	}
+	for p, newNode := range newTree {
+		if _, ok := oldTree[p]; !ok {
+			diff.Entries = append(diff.Entries, DiffEntry{
+				Path:      p,
+				Kind:      DiffAdded,
+				NewType:   newNode.itemType,
+				NewMode:   newNode.mode,
+				NewTarget: newNode.target,
+			})
+		}
+	}
+
+	sort.Slice(diff.Entries, func(i, j int) bool {
+		return diff.Entries[i].Path < diff.Entries[j].Path
+	})
+	return diff, nil
+}
+
+func compareDiffNodes(a, b FileSystem, p string, oldNode, newNode diffNode, opts DiffOptions) (DiffEntry, bool, error) {
+	entry := DiffEntry{
+		Path:      p,
+		OldType:   oldNode.itemType,
+		NewType:   newNode.itemType,
+		OldMode:   oldNode.mode,
+		NewMode:   newNode.mode,
+		OldTarget: oldNode.target,
+		NewTarget: newNode.target,
+	}
+
+	switch {
+	case oldNode.itemType != newNode.itemType:
+		entry.Kind = DiffTypeChanged
+		return entry, true, nil
+	case oldNode.itemType == ItemTypeSymlink:
+		entry.Kind = DiffSymlinkRetargeted
+		return entry, oldNode.target != newNode.target, nil
+	}
+
+	entry.Kind = DiffModified
+	entry.ModeChanged = !opts.IgnoreModes && oldNode.mode != newNode.mode
+	if oldNode.itemType == ItemTypeFile {
+		oldSum, err := validation.ComputeFileChecksum(a, p)
+		if err != nil {
+			return entry, false, err
+		}
+		newSum, err := validation.ComputeFileChecksum(b, p)
+		if err != nil {
+			return entry, false, err
+		}
+		entry.OldChecksum, entry.NewChecksum = oldSum.MD5, newSum.MD5
+		entry.ContentChanged = oldSum.Size != newSum.Size || oldSum.MD5 != newSum.MD5
+	}
+	return entry, entry.ContentChanged || entry.ModeChanged, nil
+}
+
+// scanDiffTree records the type, mode and symlink target of every path in fsys.
+func scanDiffTree(fsys FileSystem, opts DiffOptions) (map[string]diffNode, error) {
+	tree := make(map[string]diffNode)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		if isDiffExcluded(p, opts.Exclude) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		node := diffNode{mode: info.Mode().Perm()}
+		if opts.IgnoreModes {
+			node.mode = 0
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			node.itemType = ItemTypeSymlink
+			node.mode = 0
+			if node.target, err = fsys.Readlink(p); err != nil {
+				return err
+			}
+		case d.IsDir():
+			node.itemType = ItemTypeDirectory
+		default:
+			node.itemType = ItemTypeFile
+		}
+		tree[p] = node
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan filesystem for diff: %w", err)
+	}
+	return tree, nil
+}
+
+func isDiffExcluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(p)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty returns true if the two filesystems were identical.
+func (d *FSDiff) IsEmpty() bool {
+	return len(d.Entries) == 0
+}
+
+// Filter returns the entries of the given kinds.
+func (d *FSDiff) Filter(kinds ...DiffKind) []DiffEntry {
+	var entries []DiffEntry
+	for _, entry := range d.Entries {
+		for _, kind := range kinds {
+			if entry.Kind == kind {
+				entries = append(entries, entry)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// String renders the diff as text, one line per entry.
+func (d *FSDiff) String() string {
+	var b strings.Builder
+	_ = d.WriteText(&b)
+	return b.String()
+}
+
+// WriteText renders the diff as text, one line per entry:
+//
+//	A  docs/new.md (file)
+//	D  old.txt (file)
+//	M  config.yaml (content, mode 0644 -> 0600)
+//	T  build (directory -> file)
+//	L  current (v1 -> v2)
+func (d *FSDiff) WriteText(w io.Writer) error {
+	for _, entry := range d.Entries {
+		if _, err := fmt.Fprintln(w, entry.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON serializes the diff as JSON.
+func (d *FSDiff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// diffEntryJSON is the JSON form of a DiffEntry. Modes are octal strings, as
+// in restore plans and the undo log.
+type diffEntryJSON struct {
+	diffEntryFields
+	OldMode string `json:"old_mode,omitempty"`
+	NewMode string `json:"new_mode,omitempty"`
+}
+
+// diffEntryFields has the fields of DiffEntry without its JSON methods.
+type diffEntryFields DiffEntry
+
+// MarshalJSON implements json.Marshaler.
+func (e DiffEntry) MarshalJSON() ([]byte, error) {
+	doc := diffEntryJSON{diffEntryFields: diffEntryFields(e)}
+	if e.OldMode != 0 {
+		doc.OldMode = formatMode(e.OldMode)
+	}
+	if e.NewMode != 0 {
+		doc.NewMode = formatMode(e.NewMode)
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *DiffEntry) UnmarshalJSON(data []byte) error {
+	var doc diffEntryJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	oldMode, err := parseMode(doc.OldMode)
+	if err != nil {
+		return err
+	}
+	newMode, err := parseMode(doc.NewMode)
+	if err != nil {
+		return err
+	}
+	*e = DiffEntry(doc.diffEntryFields)
+	e.OldMode, e.NewMode = oldMode, newMode
+	return nil
+}
+
+// String renders a single entry as a line of text.
+func (e DiffEntry) String() string {
+	switch e.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("A  %s (%s)", e.Path, e.NewType)
+	case DiffRemoved:
+		return fmt.Sprintf("D  %s (%s)", e.Path, e.OldType)
+	case DiffTypeChanged:
+		return fmt.Sprintf("T  %s (%s -> %s)", e.Path, e.OldType, e.NewType)
+	case DiffSymlinkRetargeted:
+		return fmt.Sprintf("L  %s (%s -> %s)", e.Path, e.OldTarget, e.NewTarget)
+	default:
+		var changes []string
+		if e.ContentChanged {
+			changes = append(changes, "content")
+		}
+		if e.ModeChanged {
+			changes = append(changes, fmt.Sprintf("mode %04o -> %04o", e.OldMode, e.NewMode))
+		}
+		return fmt.Sprintf("M  %s (%s)", e.Path, strings.Join(changes, ", "))
+	}
+}
+
+// Operations returns the operations that transform the first filesystem of the
+// diff into the second. Content for new and changed files is read from b, the
+// second filesystem. Removals come first, then additions and replacements in
+// path order so parents are created before their children.
+//
+// Paths that must be replaced in place (changed files, type changes and
+// retargeted symlinks) become custom "replace" operations, since the simple
+// API does not allow a path to be deleted and recreated in the same run.
+// Directory mode changes are reported but produce no operation, as FileSystem
+// has no way to change permissions.
+func (d *FSDiff) Operations(s *SynthFS, b FileSystem) ([]Operation, error) {
+	var removals, changes []Operation
+	var replaced []string // Directories whose old contents are removed with them
+
+	for _, entry := range d.Entries {
+		if entry.Kind == DiffRemoved && underAny(entry.Path, replaced) {
+			continue
+		}
+
+		switch entry.Kind {
+		case DiffRemoved:
+			removals = append(removals, s.Delete(entry.Path))
+			if entry.OldType == ItemTypeDirectory {
+				replaced = append(replaced, entry.Path)
+			}
+		case DiffAdded:
+			op, err := s.diffCreateOperation(b, entry)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, op)
+		case DiffModified:
+			if entry.NewType == ItemTypeDirectory {
+				continue
+			}
+			changes = append(changes, s.diffReplaceOperation(b, entry))
+		case DiffTypeChanged, DiffSymlinkRetargeted:
+			changes = append(changes, s.diffReplaceOperation(b, entry))
+			if entry.OldType == ItemTypeDirectory {
+				replaced = append(replaced, entry.Path)
+			}
+		}
+	}
+
+	return append(removals, changes...), nil
+}
+
+// diffCreateOperation creates the path described by an added entry.
+func (s *SynthFS) diffCreateOperation(b FileSystem, entry DiffEntry) (Operation, error) {
+	switch entry.NewType {
+	case ItemTypeDirectory:
+		return s.CreateDir(entry.Path, diffMode(entry.NewMode, 0755)), nil
+	case ItemTypeSymlink:
+		return s.CreateSymlink(entry.NewTarget, entry.Path), nil
+	default:
+		content, err := fs.ReadFile(b, entry.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Path, err)
+		}
+		return s.CreateFile(entry.Path, content, diffMode(entry.NewMode, 0644)), nil
+	}
+}
+
+// diffReplaceOperation replaces whatever is at entry.Path with its state in b.
+// Rollback restores a replaced file or symlink; a replaced directory is
+// recreated empty.
+func (s *SynthFS) diffReplaceOperation(b FileSystem, entry DiffEntry) Operation {
+	id := s.idGen("replace", entry.Path)
+
+	var backup []byte
+	op := NewCustomOperation(string(id), func(ctx context.Context, fsys filesystem.FileSystem) error {
+		if entry.OldType == ItemTypeFile {
+			data, err := fs.ReadFile(fsys, entry.Path)
+			if err != nil {
+				return fmt.Errorf("failed to back up %s: %w", entry.Path, err)
+			}
+			backup = data
+		}
+		if err := fsys.RemoveAll(entry.Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		return applyDiffState(fsys, b, entry.Path, entry.NewType, entry.NewTarget, entry.NewMode)
+	})
+	op.WithRollback(func(ctx context.Context, fsys filesystem.FileSystem) error {
+		if err := fsys.RemoveAll(entry.Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		switch entry.OldType {
+		case ItemTypeFile:
+			return fsys.WriteFile(entry.Path, backup, diffMode(entry.OldMode, 0644))
+		case ItemTypeSymlink:
+			return fsys.Symlink(entry.OldTarget, entry.Path)
+		default:
+			return fsys.MkdirAll(entry.Path, diffMode(entry.OldMode, 0755))
+		}
+	})
+	op.WithDescription(entry.String())
+	op.SetDescriptionDetail("path", entry.Path)
+	op.SetDescriptionDetail("kind", string(entry.Kind))
+	return op
+}
+
+// applyDiffState creates p in fsys with the given type, reading file content from src.
+func applyDiffState(fsys, src FileSystem, p, itemType, target string, mode fs.FileMode) error {
+	switch itemType {
+	case ItemTypeDirectory:
+		return fsys.MkdirAll(p, diffMode(mode, 0755))
+	case ItemTypeSymlink:
+		return fsys.Symlink(target, p)
+	default:
+		content, err := fs.ReadFile(src, p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		return fsys.WriteFile(p, content, diffMode(mode, 0644))
+	}
+}
+
+// diffMode returns mode, or fallback if modes were not compared.
+func diffMode(mode, fallback fs.FileMode) fs.FileMode {
+	if mode == 0 {
+		return fallback
+	}
+	return mode
+}
+
+// underAny returns true if p is strictly below any of dirs.
+func underAny(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
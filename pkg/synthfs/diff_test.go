@@ -0,0 +1,199 @@
+package synthfs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+// setupDiffTrees builds two real directory trees covering every kind of difference.
+func setupDiffTrees(t *testing.T) (a, b synthfs.FileSystem) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+
+	helperA := testutil.NewRealFSTestHelper(t)
+	a = helperA.FileSystem()
+	mustDo(t, a.MkdirAll("docs", 0755))
+	mustDo(t, a.MkdirAll("old/nested", 0755))
+	mustDo(t, a.MkdirAll("build", 0755))
+	mustDo(t, a.WriteFile("same.txt", []byte("same"), 0644))
+	mustDo(t, a.WriteFile("content.txt", []byte("before"), 0644))
+	mustDo(t, a.WriteFile("mode.sh", []byte("#!/bin/sh"), 0644))
+	mustDo(t, a.WriteFile("old/nested/gone.txt", []byte("gone"), 0644))
+	mustDo(t, a.WriteFile("build/out.o", []byte("obj"), 0644))
+	mustDo(t, a.WriteFile("cache.tmp", []byte("tmp"), 0644))
+	helperA.CreateSymlink("same.txt", "current")
+
+	helperB := testutil.NewRealFSTestHelper(t)
+	b = helperB.FileSystem()
+	mustDo(t, b.MkdirAll("docs/new", 0755))
+	mustDo(t, b.WriteFile("same.txt", []byte("same"), 0644))
+	mustDo(t, b.WriteFile("content.txt", []byte("after"), 0644))
+	mustDo(t, b.WriteFile("mode.sh", []byte("#!/bin/sh"), 0755))
+	mustDo(t, b.WriteFile("docs/new/readme.md", []byte("# New"), 0644))
+	mustDo(t, b.WriteFile("build", []byte("not a directory anymore"), 0644))
+	helperB.CreateSymlink("content.txt", "current")
+
+	return a, b
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffFileSystems(t *testing.T) {
+	t.Run("reports every kind of change", func(t *testing.T) {
+		a, b := setupDiffTrees(t)
+
+		diff, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{Exclude: []string{"*.tmp"}})
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+
+		expected := strings.Join([]string{
+			"T  build (directory -> file)",
+			"D  build/out.o (file)",
+			"M  content.txt (content)",
+			"L  current (same.txt -> content.txt)",
+			"A  docs/new (directory)",
+			"A  docs/new/readme.md (file)",
+			"M  mode.sh (mode 0644 -> 0755)",
+			"D  old (directory)",
+			"D  old/nested (directory)",
+			"D  old/nested/gone.txt (file)",
+		}, "\n") + "\n"
+		if diff.String() != expected {
+			t.Errorf("unexpected diff:\n%s\nexpected:\n%s", diff.String(), expected)
+		}
+
+		modified := diff.Filter(synthfs.DiffModified)
+		if len(modified) != 2 || modified[0].OldChecksum == modified[0].NewChecksum {
+			t.Errorf("expected content change to carry differing checksums, got %+v", modified)
+		}
+		if modified[1].OldChecksum != modified[1].NewChecksum || modified[1].ContentChanged {
+			t.Errorf("mode-only change should have equal checksums, got %+v", modified[1])
+		}
+	})
+
+	t.Run("identical trees produce an empty diff", func(t *testing.T) {
+		a, _ := setupDiffTrees(t)
+
+		diff, err := synthfs.DiffFileSystems(a, a, synthfs.DiffOptions{})
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+		if !diff.IsEmpty() {
+			t.Errorf("expected empty diff, got:\n%s", diff)
+		}
+	})
+
+	t.Run("ignores modes when asked", func(t *testing.T) {
+		a, b := setupDiffTrees(t)
+
+		diff, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{IgnoreModes: true})
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+		for _, entry := range diff.Entries {
+			if entry.Path == "mode.sh" {
+				t.Errorf("mode-only change should be ignored, got %v", entry)
+			}
+		}
+	})
+
+	t.Run("serializes to JSON", func(t *testing.T) {
+		a, b := setupDiffTrees(t)
+
+		diff, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{})
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := diff.WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+
+		var decoded synthfs.FSDiff
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("failed to decode JSON: %v", err)
+		}
+		if decoded.String() != diff.String() {
+			t.Errorf("JSON round trip changed the diff:\n%s\nvs\n%s", decoded.String(), diff.String())
+		}
+		if !strings.Contains(buf.String(), `"kind": "symlink_retargeted"`) {
+			t.Errorf("expected symlink_retargeted kind in JSON:\n%s", buf.String())
+		}
+		if !strings.Contains(buf.String(), `"old_mode": "0644"`) {
+			t.Errorf("expected modes as octal strings in JSON:\n%s", buf.String())
+		}
+	})
+}
+
+func TestFSDiffOperations(t *testing.T) {
+	a, b := setupDiffTrees(t)
+
+	diff, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{})
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	ops, err := diff.Operations(synthfs.New(), b)
+	if err != nil {
+		t.Fatalf("Operations failed: %v", err)
+	}
+
+	if _, err := synthfs.Run(context.Background(), a, ops...); err != nil {
+		t.Fatalf("applying diff operations failed: %v", err)
+	}
+
+	remaining, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{})
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if !remaining.IsEmpty() {
+		t.Errorf("trees still differ after applying operations:\n%s", remaining)
+	}
+}
+
+func TestFSDiffOperationsRollback(t *testing.T) {
+	a, b := setupDiffTrees(t)
+
+	diff, err := synthfs.DiffFileSystems(a, b, synthfs.DiffOptions{})
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	// Replacements roll back on their own; Delete has no rollback yet
+	replacements := &synthfs.FSDiff{Entries: diff.Filter(synthfs.DiffModified, synthfs.DiffSymlinkRetargeted)}
+	ops, err := replacements.Operations(synthfs.New(), b)
+	if err != nil {
+		t.Fatalf("Operations failed: %v", err)
+	}
+
+	before, err := testutil.TakeSnapshot(a, testutil.SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	result, err := synthfs.Run(context.Background(), a, ops...)
+	if err != nil {
+		t.Fatalf("applying diff operations failed: %v", err)
+	}
+	if err := result.Rollback(context.Background()); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+
+	after, err := testutil.TakeSnapshot(a, testutil.SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	testutil.AssertSnapshotEqual(t, before, after)
+}
@@ -1,39 +1,105 @@
 package synthfs
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
+	"path"
+	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 )
 
 // DryRunFS is a filesystem wrapper that simulates operations without
 // actually writing to the underlying filesystem.
+//
+// When created with NewDryRunOverlay, reads fall through to a base filesystem
+// for paths the dry run has not touched, so operations that read existing
+// content (copies, syncs) can be simulated. Writes and removals are only
+// recorded in memory. Directory listings are not merged; opening a directory
+// returns the in-memory one if present, otherwise the base one.
 type DryRunFS struct {
-	memFS *filesystem.TestFileSystem
+	memFS   *filesystem.TestFileSystem
+	base    filesystem.FileSystem
+	deleted map[string]bool
 }
 
 // NewDryRunFS creates a new DryRunFS.
 func NewDryRunFS() *DryRunFS {
 	return &DryRunFS{
-		memFS: filesystem.NewTestFileSystem(),
+		memFS:   filesystem.NewTestFileSystem(),
+		deleted: make(map[string]bool),
+	}
+}
+
+// NewDryRunOverlay creates a DryRunFS that reads through to base.
+func NewDryRunOverlay(base filesystem.FileSystem) *DryRunFS {
+	dfs := NewDryRunFS()
+	dfs.base = base
+	return dfs
+}
+
+// inMemory reports whether name has been written during the dry run.
+func (fs *DryRunFS) inMemory(name string) bool {
+	_, ok := fs.memFS.MapFS[name]
+	return ok
+}
+
+// fromBase reports whether reads of name should go to the base filesystem.
+func (fs *DryRunFS) fromBase(name string) bool {
+	if fs.base == nil || fs.inMemory(name) {
+		return false
+	}
+	for p := name; ; p = path.Dir(p) {
+		if fs.deleted[p] {
+			return false
+		}
+		if p == "." || p == "/" {
+			return true
+		}
+	}
+}
+
+// markDeleted hides name and everything below it from the base filesystem.
+func (fs *DryRunFS) markDeleted(name string) {
+	if fs.base != nil {
+		fs.deleted[name] = true
 	}
 }
 
 // Open opens the named file for reading.
 func (fs *DryRunFS) Open(name string) (fs.File, error) {
+	if fs.fromBase(name) {
+		return fs.base.Open(name)
+	}
 	return fs.memFS.Open(name)
 }
 
 // Stat returns a FileInfo describing the named file.
 func (fs *DryRunFS) Stat(name string) (fs.FileInfo, error) {
+	if fs.fromBase(name) {
+		return fs.base.Stat(name)
+	}
 	return fs.memFS.Stat(name)
 }
 
 // ReadFile reads the file named by filename and returns the contents.
 func (fs *DryRunFS) ReadFile(filename string) ([]byte, error) {
+	if fs.fromBase(filename) {
+		return fs.readBase(filename)
+	}
 	return fs.memFS.ReadFile(filename)
 }
 
+func (fs *DryRunFS) readBase(name string) ([]byte, error) {
+	return readFileFrom(fs.base, name)
+}
+
+// readFileFrom is a package-level helper because DryRunFS methods shadow the fs package.
+func readFileFrom(fsys filesystem.FileSystem, name string) ([]byte, error) {
+	return fs.ReadFile(fsys, name)
+}
+
 // WriteFile writes data to a file named by filename.
 func (fs *DryRunFS) WriteFile(filename string, data []byte, perm fs.FileMode) error {
 	return fs.memFS.WriteFile(filename, data, perm)
@@ -53,17 +119,53 @@ func (fs *DryRunFS) MkdirAll(path string, perm fs.FileMode) error {
 
 // Remove removes the named file or (empty) directory.
 func (fs *DryRunFS) Remove(name string) error {
+	if fs.fromBase(name) {
+		if _, err := fs.base.Stat(name); err != nil {
+			return err
+		}
+		fs.markDeleted(name)
+		return nil
+	}
+	fs.markDeleted(name)
 	return fs.memFS.Remove(name)
 }
 
 // RemoveAll removes path and any children it contains.
 func (fs *DryRunFS) RemoveAll(path string) error {
+	fs.markDeleted(path)
 	return fs.memFS.RemoveAll(path)
 }
 
 // Rename renames (moves) oldpath to newpath.
+// Entries that only exist in the base filesystem are copied into memory
+// first; a base directory is recreated empty at newpath.
 func (fs *DryRunFS) Rename(oldpath, newpath string) error {
-	return fs.memFS.Rename(oldpath, newpath)
+	if fs.fromBase(oldpath) {
+		info, err := fs.base.Stat(oldpath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := fs.memFS.MkdirAll(newpath, info.Mode().Perm()); err != nil {
+				return err
+			}
+		} else {
+			data, err := fs.readBase(oldpath)
+			if err != nil {
+				return err
+			}
+			if err := fs.memFS.WriteFile(newpath, data, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		fs.markDeleted(oldpath)
+		return nil
+	}
+	if err := fs.memFS.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	fs.markDeleted(oldpath)
+	return nil
 }
 
 // Symlink creates a new symbolic link.
@@ -73,6 +175,64 @@ func (fs *DryRunFS) Symlink(oldname, newname string) error {
 
 // Readlink returns the destination of the named symbolic link.
 func (fs *DryRunFS) Readlink(name string) (string, error) {
+	if fs.fromBase(name) {
+		return fs.base.Readlink(name)
+	}
 	return fs.memFS.Readlink(name)
 }
 
+// Link records a hard link in memory. A target from the base filesystem is
+// copied in first, so the link and the target share content from then on.
+func (fs *DryRunFS) Link(oldname, newname string) error {
+	if fs.fromBase(oldname) {
+		info, err := fs.base.Stat(oldname)
+		if err != nil {
+			return err
+		}
+		data, err := fs.readBase(oldname)
+		if err != nil {
+			return err
+		}
+		if err := fs.memFS.WriteFile(oldname, data, info.Mode()); err != nil {
+			return err
+		}
+	}
+	if err := fs.memFS.Link(oldname, newname); err != nil {
+		return err
+	}
+	delete(fs.deleted, newname)
+	return nil
+}
+
+// FileID returns the identity of name in the base filesystem for untouched
+// paths, and of the in-memory entry otherwise.
+func (fs *DryRunFS) FileID(name string) (filesystem.FileID, error) {
+	if fs.fromBase(name) {
+		linkFS, ok := fs.base.(filesystem.LinkFS)
+		if !ok {
+			return filesystem.FileID{}, fmt.Errorf("fileid %s: %w", name, errors.ErrUnsupported)
+		}
+		return linkFS.FileID(name)
+	}
+	return fs.memFS.FileID(name)
+}
+
+// Chmod records permission changes for entries written during the dry run.
+// Modes of untouched base entries are left as they are.
+func (fs *DryRunFS) Chmod(name string, mode fs.FileMode) error {
+	if fs.fromBase(name) {
+		_, err := fs.base.Stat(name)
+		return err
+	}
+	return fs.memFS.Chmod(name, mode)
+}
+
+// Chtimes records modification times for entries written during the dry run.
+// Times of untouched base entries are left as they are.
+func (fs *DryRunFS) Chtimes(name string, atime, mtime time.Time) error {
+	if fs.fromBase(name) {
+		_, err := fs.base.Stat(name)
+		return err
+	}
+	return fs.memFS.Chtimes(name, atime, mtime)
+}
@@ -3,6 +3,7 @@ package synthfs
 import (
 	"testing"
 
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +43,47 @@ func TestDryRunFS_Remove(t *testing.T) {
 	_, err = dryRunFS.Stat("test.txt")
 	assert.Error(t, err)
 }
+
+func TestDryRunFS_Overlay(t *testing.T) {
+	base := filesystem.NewTestFileSystem()
+	assert.NoError(t, base.MkdirAll("dir", 0755))
+	assert.NoError(t, base.WriteFile("dir/a.txt", []byte("base"), 0644))
+	dryRunFS := NewDryRunOverlay(base)
+
+	// Reads fall through to the base filesystem
+	content, err := dryRunFS.ReadFile("dir/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("base"), content)
+
+	// Writes shadow the base without modifying it
+	assert.NoError(t, dryRunFS.WriteFile("dir/a.txt", []byte("dry"), 0644))
+	content, err = dryRunFS.ReadFile("dir/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("dry"), content)
+	content, err = base.ReadFile("dir/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("base"), content)
+
+	// Removing a directory hides base entries below it
+	assert.NoError(t, dryRunFS.RemoveAll("dir"))
+	_, err = dryRunFS.Stat("dir/a.txt")
+	assert.Error(t, err)
+	_, err = base.Stat("dir/a.txt")
+	assert.NoError(t, err)
+}
+
+func TestDryRunFS_Link(t *testing.T) {
+	base := filesystem.NewTestFileSystem()
+	assert.NoError(t, base.WriteFile("blob", []byte("base"), 0644))
+	dryRunFS := NewDryRunOverlay(base)
+
+	assert.NoError(t, dryRunFS.Link("blob", "link"))
+	assert.NoError(t, dryRunFS.WriteFile("link", []byte("changed"), 0644))
+
+	content, err := dryRunFS.ReadFile("blob")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("changed"), content)
+	baseContent, err := base.ReadFile("blob")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("base"), baseContent)
+}
@@ -0,0 +1,80 @@
+package synthfs
+
+import (
+	"context"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// ensureMarker is implemented by operations that can be put in ensure mode
+// one at a time.
+type ensureMarker interface {
+	SetEnsure(ensure bool)
+	EnsureMode() bool
+}
+
+// Ensure puts op in ensure mode and returns it: before running, the operation
+// checks whether the state it would produce already holds (same content and
+// mode, same link target, path already absent for a delete) and is reported
+// as StatusSkipped instead of acting. Otherwise the operation converges on
+// that state: a file created over an existing file with other content
+// overwrites it, and rollback restores the old file. Expandable operations pass the mode on
+// to their children. Operations that cannot check their state, such as
+// archives and shell commands, always run.
+//
+// Set PipelineOptions.Ensure to put every operation of a run in ensure mode.
+//
+// Example:
+//
+//	synthfs.Run(ctx, fs,
+//	    synthfs.Ensure(sfs.CreateDir("app", 0755)),
+//	    synthfs.Ensure(sfs.CreateFile("app/config.yml", config, 0644)),
+//	)
+func Ensure(op Operation) Operation {
+	if marker, ok := op.(ensureMarker); ok {
+		marker.SetEnsure(true)
+	}
+	return op
+}
+
+// ensureRequested reports whether op runs in ensure mode, for the whole run
+// or on its own.
+func ensureRequested(op Operation, ensureAll bool) bool {
+	if ensureAll {
+		return true
+	}
+	marker, ok := op.(ensureMarker)
+	return ok && marker.EnsureMode()
+}
+
+// ensureSatisfied reports whether op runs in ensure mode and its desired
+// state already holds in fs, so it should be skipped.
+func ensureSatisfied(ctx context.Context, fs filesystem.FileSystem, op Operation, ensureAll bool) (bool, error) {
+	ensurable, ok := op.(EnsurableOperation)
+	if !ok || !ensureRequested(op, ensureAll) {
+		return false, nil
+	}
+	return ensurable.Satisfied(ctx, fs)
+}
+
+// ensurePaths returns the paths whose state decides whether op is satisfied.
+func ensurePaths(op Operation) []string {
+	desc := op.Describe()
+	paths := []string{desc.Path}
+	src, dst := op.GetPaths()
+	paths = append(paths, src, dst)
+	if target, ok := desc.Details["target"].(string); ok {
+		paths = append(paths, target)
+	}
+	if srcGetter, ok := op.(interface{ GetSrcPath() string }); ok {
+		paths = append(paths, srcGetter.GetSrcPath())
+	}
+
+	result := paths[:0]
+	for _, p := range paths {
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package synthfs_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestEnsure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+
+	provision := func(sfs *synthfs.SynthFS) []synthfs.Operation {
+		return []synthfs.Operation{
+			sfs.CreateDir("app", 0755),
+			sfs.CreateFile("app/config.yml", []byte("port: 80\n"), 0644),
+			sfs.CreateSymlink("app/config.yml", "current.yml"),
+			sfs.AppendFile("app/hosts", []byte("127.0.0.1 app\n")),
+			sfs.Delete("setup.tmp"),
+		}
+	}
+	statuses := func(result *synthfs.Result) []synthfs.OperationStatus {
+		var out []synthfs.OperationStatus
+		for _, op := range result.Operations {
+			out = append(out, op.Status)
+		}
+		return out
+	}
+
+	t.Run("re-running a batch skips what already holds", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("setup.tmp", []byte("x"), 0644))
+		options := synthfs.DefaultPipelineOptions()
+		options.Ensure = true
+
+		first, err := synthfs.RunWithOptions(ctx, fsys, options, provision(synthfs.New())...)
+		if err != nil {
+			t.Fatalf("first run failed: %v", err)
+		}
+		for i, status := range statuses(first) {
+			if status != synthfs.StatusSuccess {
+				t.Errorf("first run: operation %d is %s", i, status)
+			}
+		}
+
+		second, err := synthfs.RunWithOptions(ctx, fsys, options, provision(synthfs.New())...)
+		if err != nil {
+			t.Fatalf("second run failed: %v", err)
+		}
+		if !second.Success || len(second.Operations) != 5 {
+			t.Fatalf("second run: success=%v, %d operations", second.Success, len(second.Operations))
+		}
+		for i, status := range statuses(second) {
+			if status != synthfs.StatusSkipped {
+				t.Errorf("second run: operation %d is %s, want %s", i, status, synthfs.StatusSkipped)
+			}
+		}
+		if got := readString(t, fsys, "app/hosts"); got != "127.0.0.1 app\n" {
+			t.Errorf("append ran twice: %q", got)
+		}
+	})
+
+	t.Run("without ensure a re-run conflicts", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("app", 0755))
+		mustDo(t, fsys.WriteFile("app/config.yml", []byte("port: 80\n"), 0644))
+		sfs := synthfs.New()
+		if _, err := synthfs.Run(ctx, fsys, sfs.CreateFile("app/config.yml", []byte("port: 80\n"), 0644)); err == nil {
+			t.Fatal("expected a conflict for an existing file")
+		}
+		result, err := synthfs.Run(ctx, fsys, synthfs.Ensure(sfs.CreateFile("app/config.yml", []byte("port: 80\n"), 0644)))
+		if err != nil {
+			t.Fatalf("ensure run failed: %v", err)
+		}
+		if got := result.Operations[0].Status; got != synthfs.StatusSkipped {
+			t.Errorf("status = %s, want %s", got, synthfs.StatusSkipped)
+		}
+	})
+
+	t.Run("a file with different content is overwritten", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("app", 0755))
+		mustDo(t, fsys.WriteFile("app/config.yml", []byte("port: 8080\n"), 0600))
+		sfs := synthfs.New()
+		result, err := synthfs.Run(ctx, fsys, synthfs.Ensure(sfs.CreateFile("app/config.yml", []byte("port: 80\n"), 0644)))
+		if err != nil {
+			t.Fatalf("ensure run failed: %v", err)
+		}
+		if got := result.Operations[0].Status; got != synthfs.StatusSuccess {
+			t.Errorf("status = %s, want %s", got, synthfs.StatusSuccess)
+		}
+		if got := readString(t, fsys, "app/config.yml"); got != "port: 80\n" {
+			t.Errorf("config = %q", got)
+		}
+		info, err := fsys.Stat("app/config.yml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != 0644 {
+			t.Errorf("mode = %o, want 644", got)
+		}
+	})
+
+	t.Run("rollback restores the overwritten file", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("app", 0755))
+		mustDo(t, fsys.WriteFile("app/config.yml", []byte("port: 8080\n"), 0600))
+		sfs := synthfs.New()
+		options := synthfs.DefaultPipelineOptions()
+		options.Ensure = true
+		options.RollbackOnError = true
+		_, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateFile("app/config.yml", []byte("port: 80\n"), 0644),
+			sfs.Copy("app/config.yml", "app/config.yml/nested"),
+		)
+		if err == nil {
+			t.Fatal("expected the copy to fail")
+		}
+		if got := readString(t, fsys, "app/config.yml"); got != "port: 8080\n" {
+			t.Errorf("config = %q, want the original content", got)
+		}
+		info, err := fsys.Stat("app/config.yml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != 0600 {
+			t.Errorf("mode = %o, want 600", got)
+		}
+	})
+
+	t.Run("state is checked again when the operation runs", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("log", []byte("a\n"), 0644))
+		sfs := synthfs.New()
+		options := synthfs.DefaultPipelineOptions()
+		options.Ensure = true
+		// The second append is satisfied before the run, but not once the first has run
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.AppendFile("log", []byte("b\n")),
+			sfs.AppendFile("log", []byte("a\n")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		for i, status := range statuses(result) {
+			if status != synthfs.StatusSuccess {
+				t.Errorf("operation %d is %s", i, status)
+			}
+		}
+		if got := readString(t, fsys, "log"); got != "a\nb\na\n" {
+			t.Errorf("log = %q", got)
+		}
+	})
+
+	t.Run("skipped operations are not rolled back", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("app", 0755))
+		sfs := synthfs.New()
+		options := synthfs.DefaultPipelineOptions()
+		options.Ensure = true
+		options.RollbackOnError = true
+		_, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateDir("app", 0755),
+			sfs.CreateFile("app/new.txt", []byte("x"), 0644),
+			sfs.Copy("app/new.txt", "app/new.txt/nested"),
+		)
+		if err == nil {
+			t.Fatal("expected the copy to fail")
+		}
+		if _, err := fsys.Stat("app/new.txt"); err == nil {
+			t.Error("expected the created file to be rolled back")
+		}
+		if _, err := fsys.Stat("app"); err != nil {
+			t.Errorf("the existing directory was removed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package synthfs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// Event types are defined in the core package
+type Event = core.Event
+type EventBus = core.EventBus
+type EventHandlerFunc = core.EventHandlerFunc
+
+// NewEventBus returns an in-memory event bus to pass in PipelineOptions.EventBus.
+//
+// Example:
+//
+//	bus := synthfs.NewEventBus()
+//	bus.Subscribe(core.EventOperationCompleted, synthfs.EventHandlerFunc(
+//	    func(ctx context.Context, event synthfs.Event) error {
+//	        log.Printf("done: %v", event.(*core.OperationCompletedEvent).Operation.Path)
+//	        return nil
+//	    }))
+//	options := synthfs.DefaultPipelineOptions()
+//	options.EventBus = bus
+//	result, err := synthfs.RunWithOptions(ctx, fs, options, ops...)
+func NewEventBus() EventBus {
+	logger := DefaultLogger()
+	return core.NewMemoryEventBus(NewLoggerAdapter(&logger))
+}
+
+// newExecutionContext creates the execution context of a simple API run,
+// publishing to the event bus of options.
+func newExecutionContext(options PipelineOptions) *core.ExecutionContext {
+	logger := DefaultLogger()
+	return &core.ExecutionContext{
+		Logger:           NewLoggerAdapter(&logger),
+		EventBus:         options.EventBus,
+		AsyncEvents:      options.AsyncEvents,
+		ProgressInterval: options.ProgressInterval,
+		Middleware:       options.Middleware,
+	}
+}
+
+// operationEventData describes op for lifecycle events.
+func operationEventData(op Operation) core.OperationEventData {
+	desc := op.Describe()
+	return core.OperationEventData{
+		OperationID:   op.ID(),
+		OperationType: desc.Type,
+		Path:          desc.Path,
+		Details:       desc.Details,
+	}
+}
+
+// validationFailedEvent describes a planning error, naming the operation
+// when the error carries one.
+func validationFailedEvent(err error) core.Event {
+	var data core.OperationEventData
+	var validationErr *core.ValidationError
+	if errors.As(err, &validationErr) {
+		data = core.OperationEventData{
+			OperationID:   validationErr.OperationID,
+			OperationType: validationErr.OperationDesc.Type,
+			Path:          validationErr.OperationDesc.Path,
+			Details:       validationErr.OperationDesc.Details,
+		}
+	}
+	return core.NewValidationFailedEvent(data, err)
+}
+
+// rollbackOperations rolls back ops in reverse order and returns the errors
+// met, publishing a rollback step event for each operation. Unless keepGoing
+// is set it stops at the first error.
+func rollbackOperations(ctx context.Context, fs filesystem.FileSystem, execCtx *core.ExecutionContext, ops []Operation, keepGoing bool) []error {
+	start := time.Now()
+	execCtx.Publish(ctx, core.NewRollbackStartedEvent(len(ops)))
+
+	var errs []error
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		err := execCtx.RunStep(ctx, core.PhaseRollback, op, fs, func(ctx context.Context) error {
+			return op.Rollback(ctx, fs)
+		})
+		if errors.Is(err, core.ErrSkip) {
+			err = nil
+		}
+		execCtx.Publish(ctx, core.NewRollbackStepEvent(operationEventData(ops[i]), err))
+		if err != nil {
+			errs = append(errs, err)
+			if !keepGoing {
+				break
+			}
+		}
+	}
+
+	execCtx.Publish(ctx, core.NewRollbackCompletedEvent(errs, time.Since(start)))
+	return errs
+}
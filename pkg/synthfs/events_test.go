@@ -0,0 +1,200 @@
+package synthfs_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+// recordEvents subscribes to every event type on a new bus and returns the
+// bus with the events it received so far.
+func recordEvents() (synthfs.EventBus, *[]synthfs.Event) {
+	bus := synthfs.NewEventBus()
+	var events []synthfs.Event
+	record := synthfs.EventHandlerFunc(func(ctx context.Context, event synthfs.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	for _, eventType := range []string{
+		core.EventPipelineStarted, core.EventPipelineFinished,
+		core.EventValidationStarted, core.EventValidationFailed, core.EventValidationPassed,
+		core.EventOperationStarted, core.EventOperationCompleted, core.EventOperationFailed,
+		core.EventRollbackStarted, core.EventRollbackStep, core.EventRollbackCompleted,
+		core.EventBackupCaptured, core.EventBudgetExhausted,
+	} {
+		bus.Subscribe(eventType, record)
+	}
+	return bus, &events
+}
+
+func eventTypes(events []synthfs.Event) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type())
+	}
+	return types
+}
+
+func TestExecutorEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+	executor := synthfs.NewExecutor()
+	var completed []string
+	executor.EventBus().Subscribe(core.EventOperationCompleted, synthfs.EventHandlerFunc(func(ctx context.Context, event synthfs.Event) error {
+		completed = append(completed, string(event.(*core.OperationCompletedEvent).Operation.OperationID))
+		return nil
+	}))
+
+	pipeline := synthfs.NewMemPipeline()
+	sfs := synthfs.New()
+	mustDo(t, pipeline.Add(sfs.CreateDirWithID("dir", "app", 0755), sfs.CreateFileWithID("file", "app/config.yml", nil, 0644)))
+	if result := executor.Run(context.Background(), pipeline, fsys); !result.Success {
+		t.Fatalf("run failed: %v", result.Errors)
+	}
+	if !reflect.DeepEqual(completed, []string{"dir", "file"}) {
+		t.Errorf("completed events for %v", completed)
+	}
+}
+
+func TestRunEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+
+	t.Run("events arrive in order before the run returns", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		bus, events := recordEvents()
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+		sfs := synthfs.New()
+
+		if _, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateDir("app", 0755),
+			sfs.CreateFile("app/config.yml", []byte("port: 80\n"), 0644),
+		); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+
+		want := []string{
+			core.EventPipelineStarted,
+			core.EventValidationStarted,
+			core.EventValidationPassed,
+			core.EventOperationStarted, core.EventOperationCompleted,
+			core.EventOperationStarted, core.EventOperationCompleted,
+			core.EventPipelineFinished,
+		}
+		if got := eventTypes(*events); !reflect.DeepEqual(got, want) {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+		finished := (*events)[len(*events)-1].(*core.PipelineFinishedEvent)
+		if !finished.Success || len(finished.Errors) != 0 {
+			t.Errorf("finished event: success=%v errors=%v", finished.Success, finished.Errors)
+		}
+	})
+
+	t.Run("validation failure names the operation", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		bus, events := recordEvents()
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+
+		if _, err := synthfs.RunWithOptions(ctx, fsys, options, synthfs.New().Copy("missing.txt", "copy.txt")); err == nil {
+			t.Fatal("expected validation to fail")
+		}
+
+		want := []string{core.EventPipelineStarted, core.EventValidationStarted, core.EventValidationFailed, core.EventPipelineFinished}
+		if got := eventTypes(*events); !reflect.DeepEqual(got, want) {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+		failed := (*events)[2].(*core.ValidationFailedEvent)
+		if failed.Operation.Path != "missing.txt" || failed.Error == nil {
+			t.Errorf("validation failed event: path=%q error=%v", failed.Operation.Path, failed.Error)
+		}
+		if finished := (*events)[3].(*core.PipelineFinishedEvent); finished.Success {
+			t.Error("finished event reports success")
+		}
+	})
+
+	t.Run("rollback publishes a step per operation", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		bus, events := recordEvents()
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+		options.RollbackOnError = true
+		sfs := synthfs.New()
+
+		if _, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateDir("app", 0755),
+			sfs.CreateFile("app/new.txt", []byte("x"), 0644),
+			sfs.Copy("app/new.txt", "app/new.txt/nested"),
+		); err == nil {
+			t.Fatal("expected the copy to fail")
+		}
+
+		var steps []string
+		var rollback []string
+		for _, event := range *events {
+			switch e := event.(type) {
+			case *core.RollbackStepEvent:
+				steps = append(steps, e.Operation.Path)
+				if e.Error != nil {
+					t.Errorf("rollback of %s failed: %v", e.Operation.Path, e.Error)
+				}
+				rollback = append(rollback, e.Type())
+			case *core.RollbackStartedEvent, *core.RollbackCompletedEvent:
+				rollback = append(rollback, e.Type())
+			}
+		}
+		wantRollback := []string{core.EventRollbackStarted, core.EventRollbackStep, core.EventRollbackStep, core.EventRollbackCompleted}
+		if !reflect.DeepEqual(rollback, wantRollback) {
+			t.Errorf("rollback events = %v, want %v", rollback, wantRollback)
+		}
+		if want := []string{"app/new.txt", "app"}; !reflect.DeepEqual(steps, want) {
+			t.Errorf("rolled back %v, want %v", steps, want)
+		}
+	})
+
+	t.Run("backups and budget exhaustion", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("small.txt", []byte("small"), 0644))
+		mustDo(t, fsys.WriteFile("large.bin", bytes.Repeat([]byte("x"), 2<<20), 0644))
+		bus, events := recordEvents()
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+		options.Restorable = true
+		options.MaxBackupSizeMB = 1
+		sfs := synthfs.New()
+
+		if _, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.Delete("small.txt"),
+			sfs.Delete("large.bin"),
+		); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+
+		var captured, exhausted []string
+		for _, event := range *events {
+			switch e := event.(type) {
+			case *core.BackupCapturedEvent:
+				captured = append(captured, e.Operation.Path)
+			case *core.BudgetExhaustedEvent:
+				exhausted = append(exhausted, e.Operation.Path)
+			}
+		}
+		if want := []string{"small.txt"}; !reflect.DeepEqual(captured, want) {
+			t.Errorf("backups captured for %v, want %v", captured, want)
+		}
+		if want := []string{"large.bin"}; !reflect.DeepEqual(exhausted, want) {
+			t.Errorf("budget exhausted for %v, want %v", exhausted, want)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package execution
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -63,6 +64,12 @@ type PipelineInterface interface {
 	Validate(ctx context.Context, fs interface{}) error
 }
 
+// contextValidator is implemented by pipelines that can validate their
+// operations within an execution context, through its middleware.
+type contextValidator interface {
+	ValidateWithContext(ctx context.Context, execCtx *core.ExecutionContext, fs interface{}) error
+}
+
 // Run runs all operations in the pipeline with default options
 func (e *Executor) Run(ctx context.Context, pipeline PipelineInterface, fs interface{}) *core.Result {
 	return e.RunWithOptions(ctx, pipeline, fs, DefaultPipelineOptions())
@@ -120,11 +127,18 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			Msg("backup budget initialized for restorable execution")
 	}
 
-	// Create execution context
+	// Create execution context, publishing to the caller's event bus if given
+	eventBus := e.eventBus
+	if opts.EventBus != nil {
+		eventBus = opts.EventBus
+	}
 	execCtx := &core.ExecutionContext{
-		Logger:   e.logger,
-		Budget:   budget,
-		EventBus: e.eventBus,
+		Logger:           e.logger,
+		Budget:           budget,
+		EventBus:         eventBus,
+		AsyncEvents:      opts.AsyncEvents,
+		ProgressInterval: opts.ProgressInterval,
+		Middleware:       opts.Middleware,
 	}
 
 	// Resolve prerequisites if enabled
@@ -162,7 +176,13 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 
 	// Validate the pipeline
 	e.logger.Info().Msg("validating operation pipeline")
-	if err := pipelineInterface.Validate(ctx, fs); err != nil {
+	validate := pipelineInterface.Validate
+	if validator, ok := pipelineInterface.(contextValidator); ok {
+		validate = func(ctx context.Context, fs interface{}) error {
+			return validator.ValidateWithContext(ctx, execCtx, fs)
+		}
+	}
+	if err := validate(ctx, fs); err != nil {
 		e.logger.Info().Err(err).Msg("pipeline validation failed")
 		result.Success = false
 		result.Errors = append(result.Errors, fmt.Errorf("pipeline validation failed: %w", err))
@@ -189,6 +209,24 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			continue
 		}
 
+		// Once the run is cancelled, nothing else starts
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			e.logger.Info().
+				Str("op_id", string(op.ID())).
+				Err(ctxErr).
+				Msg("run cancelled - operation not started")
+
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusCancelled,
+				Error:       ctxErr,
+			})
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s cancelled: %w", op.ID(), ctxErr))
+			break
+		}
+
 		e.logger.Info().
 			Str("op_id", string(op.ID())).
 			Str("op_type", op.Describe().Type).
@@ -197,6 +235,28 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			Int("total_operations", len(operationInterfaces)).
 			Msg("executing operation")
 
+		// Lazy inputs read the outputs of the operations that ran before
+		if inputErr := core.ResolveInputs(ctx, op); inputErr != nil {
+			e.logger.Info().
+				Str("op_id", string(op.ID())).
+				Err(inputErr).
+				Msg("failed to resolve operation inputs")
+
+			inputErr = fmt.Errorf("failed to resolve inputs: %w", inputErr)
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusFailure,
+				Error:       inputErr,
+			})
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s failed: %w", op.ID(), inputErr))
+			if !opts.ContinueOnError {
+				break
+			}
+			continue
+		}
+
 		// Generate reverse operations if restorable mode is enabled
 		var reverseOps []interface{}
 		var backupData *core.BackupData
@@ -224,8 +284,11 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			}
 		}
 
+		// Validation is not repeated between attempts
 		opStart := time.Now()
-		err := op.Execute(ctx, execCtx, fs)
+		attempts, cancelled, err := execCtx.ExecuteOperation(ctx, op, fs, opts, func(ctx context.Context) error {
+			return op.Execute(ctx, execCtx, fs)
+		})
 		opDuration := time.Since(opStart)
 
 		opResult := core.OperationResult{
@@ -234,13 +297,27 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			Duration:     opDuration,
 			BackupData:   backupData,
 			BackupSizeMB: 0,
+			Attempts:     attempts,
 		}
 
 		if backupData != nil {
 			opResult.BackupSizeMB = backupData.SizeMB
 		}
 
-		if err != nil {
+		if errors.Is(err, core.ErrSkip) {
+			e.logger.Info().
+				Str("op_id", string(op.ID())).
+				Str("op_type", op.Describe().Type).
+				Str("path", op.Describe().Path).
+				Msg("operation skipped by middleware")
+
+			opResult.Status = core.StatusSkipped
+			err = nil
+
+			if opts.Restorable && backupData != nil && budget != nil {
+				budget.RestoreBackup(backupData.SizeMB)
+			}
+		} else if err != nil {
 			e.logger.Info().
 				Str("op_id", string(op.ID())).
 				Str("op_type", op.Describe().Type).
@@ -252,7 +329,12 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			opResult.Status = core.StatusFailure
 			opResult.Error = err
 			result.Success = false
-			result.Errors = append(result.Errors, fmt.Errorf("operation %s failed: %w", op.ID(), err))
+			if cancelled {
+				opResult.Status = core.StatusCancelled
+				result.Errors = append(result.Errors, fmt.Errorf("operation %s cancelled: %w", op.ID(), err))
+			} else {
+				result.Errors = append(result.Errors, fmt.Errorf("operation %s failed: %w", op.ID(), err))
+			}
 
 			// Restore budget if operation failed and backup was created
 			if opts.Restorable && backupData != nil && budget != nil {
@@ -276,6 +358,7 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 
 			// Add reverse operations to result if available
 			if opts.Restorable && reverseOps != nil {
+				opResult.ReverseOps = reverseOps
 				result.RestoreOps = append(result.RestoreOps, reverseOps...)
 				e.logger.Debug().
 					Str("op_id", string(op.ID())).
@@ -286,14 +369,15 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 
 		result.Operations = append(result.Operations, opResult)
 		
-		// Break after recording the failed operation if we should not continue on error
-		if err != nil && !opts.ContinueOnError {
+		// Break after recording the failed operation if we should not continue
+		// on error. Cancelling the run stops it regardless.
+		if err != nil && (!opts.ContinueOnError || ctx.Err() != nil) {
 			break
 		}
 	}
 
 	result.Duration = time.Since(start)
-	result.Rollback = e.createRollbackFunc(rollbackOps, fs)
+	result.Rollback = e.createRollbackFunc(execCtx, rollbackOps, fs)
 
 	// Execute rollback if needed
 	if !result.Success && opts.RollbackOnError && len(rollbackOps) > 0 {
@@ -301,7 +385,7 @@ func (e *Executor) RunWithOptionsAndResolver(ctx context.Context, pipeline inter
 			Int("operations_to_rollback", len(rollbackOps)).
 			Msg("executing rollback due to operation failure")
 
-		if rollbackErr := result.Rollback(ctx); rollbackErr != nil {
+		if rollbackErr := result.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
 			e.logger.Error().
 				Err(rollbackErr).
 				Msg("rollback encountered errors")
@@ -371,7 +455,7 @@ func (pa *pipelineAdapter) Validate(ctx context.Context, fs interface{}) error {
 }
 
 // createRollbackFunc creates a rollback function that can undo executed operations
-func (e *Executor) createRollbackFunc(executedOps []OperationInterface, fsys interface{}) func(context.Context) error {
+func (e *Executor) createRollbackFunc(execCtx *core.ExecutionContext, executedOps []OperationInterface, fsys interface{}) func(context.Context) error {
 	if len(executedOps) == 0 {
 		return func(ctx context.Context) error { return nil }
 	}
@@ -381,7 +465,10 @@ func (e *Executor) createRollbackFunc(executedOps []OperationInterface, fsys int
 		var rollbackErrors []error
 		for i := len(executedOps) - 1; i >= 0; i-- {
 			op := executedOps[i]
-			if err := op.Rollback(ctx, fsys); err != nil {
+			err := execCtx.RunStep(ctx, core.PhaseRollback, op, fsys, func(ctx context.Context) error {
+				return op.Rollback(ctx, fsys)
+			})
+			if err != nil && !errors.Is(err, core.ErrSkip) {
 				rollbackErrors = append(rollbackErrors, fmt.Errorf("rollback failed for operation %s: %w", op.ID(), err))
 			}
 		}
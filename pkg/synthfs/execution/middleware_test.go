@@ -0,0 +1,81 @@
+package execution_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/execution"
+)
+
+// TestExecutor_Middleware tests middleware around validation, execution and rollback
+func TestExecutor_Middleware(t *testing.T) {
+	// record returns middleware logging each step as "phase id", skipping
+	// the execution of skipID
+	record := func(calls *[]string, skipID core.OperationID) core.Middleware {
+		return func(next core.ExecFunc) core.ExecFunc {
+			return func(ctx context.Context, call *core.Call) error {
+				*calls = append(*calls, string(call.Phase)+" "+string(call.ID))
+				if call.Phase == core.PhaseExecute && call.ID == skipID {
+					return core.ErrSkip
+				}
+				return next(ctx, call)
+			}
+		}
+	}
+
+	t.Run("Middleware wraps every step and can skip", func(t *testing.T) {
+		executor := execution.NewExecutor(NewMockLogger())
+		pipeline := execution.NewMemPipeline(NewMockLogger())
+		if err := pipeline.Add(NewMockOperation("op1", "create_file", "a.txt"), NewMockOperation("op2", "create_file", "b.txt")); err != nil {
+			t.Fatal(err)
+		}
+		var calls []string
+		opts := execution.DefaultPipelineOptions()
+		opts.Middleware = []core.Middleware{record(&calls, "op2")}
+
+		result := executor.RunWithOptions(context.Background(), pipeline, NewMockFileSystem(), opts)
+		if !result.Success {
+			t.Fatalf("Expected success, got %v", result.Errors)
+		}
+		want := []string{"validate op1", "validate op2", "execute op1", "execute op2"}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+		if result.Operations[1].Status != core.StatusSkipped {
+			t.Errorf("Expected op2 to be skipped, got %s", result.Operations[1].Status)
+		}
+	})
+
+	t.Run("Middleware errors fail the operation and rollbacks pass through it", func(t *testing.T) {
+		executor := execution.NewExecutor(NewMockLogger())
+		pipeline := NewMockPipelineInterface()
+		pipeline.AddOperations(NewMockOperation("op1", "create_file", "a.txt"), NewMockOperation("op2", "create_file", "b.txt"))
+		denied := errors.New("denied by policy")
+		var calls []string
+		opts := execution.DefaultPipelineOptions()
+		opts.RollbackOnError = true
+		opts.Middleware = []core.Middleware{
+			record(&calls, ""),
+			func(next core.ExecFunc) core.ExecFunc {
+				return func(ctx context.Context, call *core.Call) error {
+					if call.Phase == core.PhaseExecute && call.Desc.Path == "b.txt" {
+						return denied
+					}
+					return next(ctx, call)
+				}
+			},
+		}
+
+		result := executor.RunWithOptions(context.Background(), pipeline, NewMockFileSystem(), opts)
+		if result.Success || !errors.Is(result.Errors[0], denied) {
+			t.Fatalf("Expected the middleware error, got %v", result.Errors)
+		}
+		want := []string{"execute op1", "execute op2", "rollback op1"}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+	})
+}
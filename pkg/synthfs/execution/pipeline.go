@@ -2,6 +2,7 @@ package execution
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
@@ -250,6 +251,13 @@ func (mp *memPipeline) ResolvePrerequisites(resolver core.PrerequisiteResolver,
 
 // Validate checks if all operations in the pipeline are valid
 func (mp *memPipeline) Validate(ctx context.Context, fs interface{}) error {
+	return mp.ValidateWithContext(ctx, &core.ExecutionContext{Logger: mp.logger}, fs)
+}
+
+// ValidateWithContext checks if all operations in the pipeline are valid,
+// validating each through the middleware of execCtx. Operations whose
+// validation the middleware skips count as valid.
+func (mp *memPipeline) ValidateWithContext(ctx context.Context, execCtx *core.ExecutionContext, fs interface{}) error {
 	mp.logger.Debug().
 		Int("total_operations", len(mp.ops)).
 		Bool("resolved", mp.resolved).
@@ -268,7 +276,10 @@ func (mp *memPipeline) Validate(ctx context.Context, fs interface{}) error {
 			Str("path", op.Describe().Path).
 			Msg("validating individual operation")
 
-		if err := op.Validate(ctx, &core.ExecutionContext{Logger: mp.logger}, fs); err != nil {
+		err := execCtx.RunStep(ctx, core.PhaseValidate, op, fs, func(ctx context.Context) error {
+			return op.Validate(ctx, execCtx, fs)
+		})
+		if err != nil && !errors.Is(err, core.ErrSkip) {
 			mp.logger.Debug().
 				Str("op_id", string(op.ID())).
 				Str("op_type", op.Describe().Type).
@@ -3,6 +3,7 @@ package execution
 import (
 	"fmt"
 	"io/fs"
+	"sort"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
 )
@@ -93,6 +94,17 @@ func (pst *PathStateTracker) UpdateState(op OperationInterface) error {
 
 	switch desc.Type {
 	case "create_file":
+		if converge, _ := desc.Details["converge"].(bool); converge {
+			// An ensure-mode create overwrites an existing file to converge on it
+			state, err := pst.GetState(desc.Path)
+			if err != nil {
+				return err
+			}
+			if state.WillExist && state.WillBeType == core.PathStateFile {
+				state.ModifiedBy = append(state.ModifiedBy, opID)
+				return nil
+			}
+		}
 		return pst.updateStateForCreate(opID, desc.Path, core.PathStateFile)
 	case "create_directory":
 		return pst.updateStateForCreate(opID, desc.Path, core.PathStateDir)
@@ -100,6 +112,34 @@ func (pst *PathStateTracker) UpdateState(op OperationInterface) error {
 		return pst.updateStateForCreate(opID, desc.Path, core.PathStateSymlink)
 	case "create_archive":
 		return pst.updateStateForCreate(opID, desc.Path, core.PathStateFile) // Archives are files
+	case "create_hardlink":
+		// The target must be projected to be a regular file. A path removed
+		// earlier in the run may be linked again, which is how sync relinks.
+		target, _ := desc.Details["target"].(string)
+		if pathGetter, ok := op.(interface{ GetSrcPath() string }); ok && target == "" {
+			target = pathGetter.GetSrcPath()
+		}
+		targetState, err := pst.GetState(target)
+		if err != nil {
+			return err
+		}
+		if !targetState.WillExist {
+			return fmt.Errorf("validation conflict for %s: hard link target %s is not projected to exist", opID, target)
+		}
+		if targetState.WillBeType != core.PathStateFile {
+			return fmt.Errorf("validation conflict for %s: hard link target %s is not a regular file", opID, target)
+		}
+		state, err := pst.GetState(desc.Path)
+		if err != nil {
+			return err
+		}
+		if state.WillExist {
+			return fmt.Errorf("operation %s conflicts with existing state: cannot create %s because it is projected to already exist", opID, desc.Path)
+		}
+		state.WillExist = true
+		state.WillBeType = core.PathStateFile
+		state.CreatedBy = opID
+		state.DeletedBy = ""
 
 	case "delete":
 		state, err := pst.GetState(desc.Path)
@@ -190,6 +230,74 @@ func (pst *PathStateTracker) UpdateState(op OperationInterface) error {
 		// Update destination to be created
 		return pst.updateStateForCreate(opID, dstPath, srcState.WillBeType)
 
+	case "sync_file":
+		// Sync children overwrite their destination, so an existing path is not a conflict
+		srcPath, _ := desc.Details["src"].(string)
+		if pathGetter, ok := op.(interface{ GetSrcPath() string }); ok && srcPath == "" {
+			srcPath = pathGetter.GetSrcPath()
+		}
+		srcState, err := pst.GetState(srcPath)
+		if err != nil {
+			return err
+		}
+		state, err := pst.GetState(desc.Path)
+		if err != nil {
+			return err
+		}
+		if state.WillExist {
+			state.ModifiedBy = append(state.ModifiedBy, opID)
+		} else {
+			state.CreatedBy = opID
+		}
+		state.WillExist = true
+		state.WillBeType = srcState.WillBeType
+		state.DeletedBy = ""
+
+	case "write_template", "edit_config", "edit_text", "append_file", "apply_patch":
+		// Template writes and in-place edits overwrite files, so only an existing directory is a conflict
+		state, err := pst.GetState(desc.Path)
+		if err != nil {
+			return err
+		}
+		if deletes, _ := desc.Details["deletes_file"].(bool); deletes {
+			// A patch against /dev/null removes the file it empties
+			if !state.WillExist || state.WillBeType != core.PathStateFile {
+				return fmt.Errorf("operation %s cannot delete %s with a patch: no file is projected to exist", opID, desc.Path)
+			}
+			state.WillExist = false
+			state.DeletedBy = opID
+			return nil
+		}
+		if state.WillExist && state.WillBeType == core.PathStateDir {
+			return fmt.Errorf("operation %s conflicts with existing state: cannot write %s to directory %s", opID, desc.Type, desc.Path)
+		}
+		if state.WillExist {
+			state.ModifiedBy = append(state.ModifiedBy, opID)
+		} else {
+			state.CreatedBy = opID
+		}
+		state.WillExist = true
+		state.WillBeType = core.PathStateFile
+		state.DeletedBy = ""
+
+	case "prune":
+		state, err := pst.GetState(desc.Path)
+		if err != nil {
+			return err
+		}
+		state.WillExist = false
+		state.DeletedBy = opID
+
+	case "chmod", "truncate", "dedupe_file", "materialize":
+		state, err := pst.GetState(desc.Path)
+		if err != nil {
+			return err
+		}
+		if !state.WillExist {
+			return fmt.Errorf("validation conflict for %s: path %s to be changed is not projected to exist", opID, desc.Path)
+		}
+		state.ModifiedBy = append(state.ModifiedBy, opID)
+
 	case "unarchive":
 		// This is more complex as it affects an unknown number of paths
 		// For now, we'll just check the source archive exists and treat the destination as modified
@@ -217,6 +325,16 @@ func (pst *PathStateTracker) UpdateState(op OperationInterface) error {
 	return nil
 }
 
+// Paths returns every path the tracker holds state for, in sorted order.
+func (pst *PathStateTracker) Paths() []string {
+	paths := make([]string, 0, len(pst.states))
+	for p := range pst.states {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 // IsDeleted returns true if the path is scheduled for deletion by any operation
 func (pst *PathStateTracker) IsDeleted(path string) bool {
 	state, err := pst.GetState(path)
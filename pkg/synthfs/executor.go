@@ -49,7 +49,12 @@ func (e *Executor) Run(ctx context.Context, pipeline Pipeline, fs FileSystem) *R
 }
 
 // RunWithOptions runs all operations in the pipeline with specified options.
+// Events go to opts.EventBus, or to the executor's event bus if it is nil.
 func (e *Executor) RunWithOptions(ctx context.Context, pipeline Pipeline, fs FileSystem, opts PipelineOptions) *Result {
+	if opts.EventBus == nil {
+		opts.EventBus = e.EventBus()
+	}
+
 	// Resolve pipeline dependencies (maintaining executor contract)
 	if err := pipeline.Resolve(); err != nil {
 		return &Result{
@@ -60,8 +65,19 @@ func (e *Executor) RunWithOptions(ctx context.Context, pipeline Pipeline, fs Fil
 		}
 	}
 
-	// Validate pipeline (maintaining executor contract)
-	if err := pipeline.Validate(ctx, fs); err != nil {
+	// Validate pipeline (maintaining executor contract). In ensure mode the
+	// operations whose state already holds are left out. Middleware may skip
+	// validations, so with middleware the run validates on its own.
+	validate := pipeline.Validate
+	if opts.Ensure {
+		validate = func(ctx context.Context, fs FileSystem) error {
+			return validateOperations(ctx, fs, pipeline.Operations(), true)
+		}
+	}
+	if len(opts.Middleware) > 0 {
+		validate = func(ctx context.Context, fs FileSystem) error { return nil }
+	}
+	if err := validate(ctx, fs); err != nil {
 		return &Result{
 			Success:    false,
 			Operations: []core.OperationResult{},
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filesystem
+
+import "io/fs"
+
+// osFileID is not available on this platform.
+func osFileID(info fs.FileInfo) (FileID, bool) {
+	return FileID{}, false
+}
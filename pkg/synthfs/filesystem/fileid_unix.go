@@ -0,0 +1,17 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// osFileID reads the device and inode numbers of an os.Lstat result.
+func osFileID(info fs.FileInfo) (FileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, false
+	}
+	return FileID{Device: uint64(stat.Dev), Inode: uint64(stat.Ino)}, true
+}
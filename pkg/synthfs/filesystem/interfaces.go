@@ -2,6 +2,7 @@ package filesystem
 
 import (
 	"io/fs"
+	"time"
 )
 
 // ReadFS is an alias for fs.FS, representing a read-only file system.
@@ -32,4 +33,33 @@ type FileSystem interface {
 	Rename(oldpath, newpath string) error
 }
 
+// ChtimesFS is implemented by filesystems that can set modification times.
+// It is optional; callers should type-assert and skip the step when it's missing.
+type ChtimesFS interface {
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// ChmodFS is implemented by filesystems that can change permission bits.
+// Like ChtimesFS it is optional.
+type ChmodFS interface {
+	Chmod(name string, mode fs.FileMode) error
+}
+
+// FileID identifies the file a path refers to. Paths with equal IDs are hard
+// links to the same file; Device tells whether two paths share a filesystem.
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// LinkFS is implemented by filesystems that support hard links. Like ChmodFS
+// it is optional.
+type LinkFS interface {
+	// Link creates newname as a hard link to the existing file oldname.
+	Link(oldname, newname string) error
+	// FileID returns the identity of the file at name without following a
+	// final symlink.
+	FileID(name string) (FileID, error)
+}
+
 // Phase 2: Legacy type aliases have been removed - use FileSystem directly
@@ -1,10 +1,12 @@
 package filesystem
 
 import (
+	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // OSFileSystem implements FileSystem using the OS filesystem
@@ -76,7 +78,9 @@ func (osfs *OSFileSystem) Symlink(oldname, newname string) error {
 	if !fs.ValidPath(oldname) || !fs.ValidPath(newname) {
 		return &fs.PathError{Op: "symlink", Path: newname, Err: fs.ErrInvalid}
 	}
-	oldPath := filepath.Join(osfs.root, oldname)
+	// The target is resolved from the link's directory, so it must not be
+	// relative to the working directory
+	oldPath := filepath.Join(osfs.absRoot(), oldname)
 	newPath := filepath.Join(osfs.root, newname)
 	return os.Symlink(oldPath, newPath)
 }
@@ -93,7 +97,7 @@ func (osfs *OSFileSystem) Readlink(name string) (string, error) {
 	}
 	// Convert absolute path back to relative if it's within our root
 	if filepath.IsAbs(target) {
-		rel, err := filepath.Rel(osfs.root, target)
+		rel, err := filepath.Rel(osfs.absRoot(), target)
 		if err == nil && !strings.HasPrefix(rel, "..") {
 			return rel, nil
 		}
@@ -101,6 +105,15 @@ func (osfs *OSFileSystem) Readlink(name string) (string, error) {
 	return target, nil
 }
 
+// absRoot returns the root as an absolute path, or as given if it cannot be
+// made absolute.
+func (osfs *OSFileSystem) absRoot() string {
+	if root, err := filepath.Abs(osfs.root); err == nil {
+		return root
+	}
+	return osfs.root
+}
+
 // Rename implements WriteFS
 func (osfs *OSFileSystem) Rename(oldpath, newpath string) error {
 	if !fs.ValidPath(oldpath) || !fs.ValidPath(newpath) {
@@ -110,3 +123,47 @@ func (osfs *OSFileSystem) Rename(oldpath, newpath string) error {
 	newFullPath := filepath.Join(osfs.root, newpath)
 	return os.Rename(oldFullPath, newFullPath)
 }
+
+// Chtimes implements ChtimesFS
+func (osfs *OSFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrInvalid}
+	}
+	fullPath := filepath.Join(osfs.root, name)
+	return os.Chtimes(fullPath, atime, mtime)
+}
+
+// Chmod implements ChmodFS
+func (osfs *OSFileSystem) Chmod(name string, mode fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrInvalid}
+	}
+	fullPath := filepath.Join(osfs.root, name)
+	return os.Chmod(fullPath, mode)
+}
+
+// Link implements LinkFS
+func (osfs *OSFileSystem) Link(oldname, newname string) error {
+	if !fs.ValidPath(oldname) || !fs.ValidPath(newname) {
+		return &fs.PathError{Op: "link", Path: newname, Err: fs.ErrInvalid}
+	}
+	oldPath := filepath.Join(osfs.root, oldname)
+	newPath := filepath.Join(osfs.root, newname)
+	return os.Link(oldPath, newPath)
+}
+
+// FileID implements LinkFS
+func (osfs *OSFileSystem) FileID(name string) (FileID, error) {
+	if !fs.ValidPath(name) {
+		return FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := os.Lstat(filepath.Join(osfs.root, name))
+	if err != nil {
+		return FileID{}, err
+	}
+	id, ok := osFileID(info)
+	if !ok {
+		return FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: errors.ErrUnsupported}
+	}
+	return id, nil
+}
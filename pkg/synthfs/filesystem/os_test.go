@@ -5,8 +5,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 )
@@ -127,6 +129,45 @@ func TestOSFileSystem(t *testing.T) {
 		}
 	})
 
+	t.Run("Chtimes", func(t *testing.T) {
+		path := "chtimes.txt"
+		if err := osfs.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := osfs.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+
+		info, err := osfs.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("Expected mtime %v, got %v", mtime, info.ModTime())
+		}
+	})
+
+	t.Run("Chmod", func(t *testing.T) {
+		path := "chmod.sh"
+		if err := osfs.WriteFile(path, []byte("#!/bin/sh"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+
+		if err := osfs.Chmod(path, 0755); err != nil {
+			t.Fatalf("Chmod failed: %v", err)
+		}
+
+		info, err := osfs.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("Expected mode 0755, got %o", info.Mode().Perm())
+		}
+	})
+
 	t.Run("Invalid paths", func(t *testing.T) {
 		invalidPath := "../../../etc/passwd"
 
@@ -770,3 +811,104 @@ func TestNewOSFileSystem(t *testing.T) {
 		}
 	})
 }
+
+func TestOSFileSystem_Link(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	osfs := filesystem.NewOSFileSystem(t.TempDir())
+	if err := osfs.WriteFile("target.txt", []byte("shared"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := osfs.Link("target.txt", "link.txt"); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+
+	targetID, err := osfs.FileID("target.txt")
+	if err != nil {
+		t.Fatalf("FileID failed: %v", err)
+	}
+	linkID, err := osfs.FileID("link.txt")
+	if err != nil {
+		t.Fatalf("FileID failed: %v", err)
+	}
+	if targetID != linkID {
+		t.Errorf("expected the link to share the target's ID, got %v and %v", targetID, linkID)
+	}
+
+	if err := osfs.WriteFile("link.txt", []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := fs.ReadFile(osfs, "target.txt"); string(data) != "changed" {
+		t.Errorf("target content = %q, want the content written through the link", data)
+	}
+
+	if err := osfs.Link("target.txt", "link.txt"); err == nil {
+		t.Error("expected linking over an existing path to fail")
+	}
+	if err := osfs.Link("../outside", "x"); err == nil {
+		t.Error("expected an invalid path to be rejected")
+	}
+}
+
+func TestOSFileSystem_SymlinkTargets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+
+	roots := map[string]func(t *testing.T) string{
+		"absolute root": func(t *testing.T) string { return t.TempDir() },
+		"relative root": func(t *testing.T) string {
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := filepath.Rel(wd, t.TempDir())
+			if err != nil {
+				t.Skipf("temp dir is not reachable from the working directory: %v", err)
+			}
+			return root
+		},
+	}
+
+	for name, makeRoot := range roots {
+		t.Run(name, func(t *testing.T) {
+			root := makeRoot(t)
+			osfs := filesystem.NewOSFileSystem(root)
+			if err := osfs.MkdirAll("a", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := osfs.WriteFile("target.txt", []byte("target content"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// Symlink takes root-relative targets and must resolve them from
+			// any link directory
+			if err := osfs.Symlink("target.txt", "a/link.txt"); err != nil {
+				t.Fatalf("Symlink failed: %v", err)
+			}
+			if data, err := fs.ReadFile(osfs, "a/link.txt"); err != nil || string(data) != "target content" {
+				t.Errorf("link does not resolve to the target: %q, %v", data, err)
+			}
+			if target, err := osfs.Readlink("a/link.txt"); err != nil || target != "target.txt" {
+				t.Errorf("Readlink = %q, %v", target, err)
+			}
+
+			// Links created outside synthfs keep relative targets and absolute
+			// targets outside the root as they are
+			if err := os.Symlink("../target.txt", filepath.Join(root, "a", "relative.txt")); err != nil {
+				t.Fatal(err)
+			}
+			if target, err := osfs.Readlink("a/relative.txt"); err != nil || target != "../target.txt" {
+				t.Errorf("Readlink of a relative target = %q, %v", target, err)
+			}
+			outside := filepath.Join(t.TempDir(), "outside.txt")
+			if err := os.Symlink(outside, filepath.Join(root, "a", "outside.txt")); err != nil {
+				t.Fatal(err)
+			}
+			if target, err := osfs.Readlink("a/outside.txt"); err != nil || target != outside {
+				t.Errorf("Readlink of an absolute target outside the root = %q, %v", target, err)
+			}
+		})
+	}
+}
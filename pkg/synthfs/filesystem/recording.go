@@ -0,0 +1,368 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Journal method names
+const (
+	JournalOpen      = "Open"
+	JournalStat      = "Stat"
+	JournalWriteFile = "WriteFile"
+	JournalMkdirAll  = "MkdirAll"
+	JournalRemove    = "Remove"
+	JournalRemoveAll = "RemoveAll"
+	JournalSymlink   = "Symlink"
+	JournalReadlink  = "Readlink"
+	JournalRename    = "Rename"
+	JournalChmod     = "Chmod"
+	JournalChtimes   = "Chtimes"
+	JournalLink      = "Link"
+	JournalFileID    = "FileID"
+)
+
+// JournalEntry records a single FileSystem call.
+type JournalEntry struct {
+	Seq      int           `json:"seq"`
+	Method   string        `json:"method"`
+	Path     string        `json:"path"`
+	NewPath  string        `json:"new_path,omitempty"` // Rename destination
+	Target   string        `json:"target,omitempty"`   // Symlink or Link target, or Readlink result
+	Data     []byte        `json:"data,omitempty"`     // WriteFile content
+	Mode     fs.FileMode   `json:"mode,omitempty"`     // WriteFile/MkdirAll/Chmod permissions
+	Times    []time.Time   `json:"times,omitempty"`    // Chtimes access and modification times
+	Error    string        `json:"error,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// journalEntryJSON is the JSON form of a JournalEntry. The mode is an octal
+// string, as in synthfs diffs, restore plans and the undo log.
+type journalEntryJSON struct {
+	journalEntryFields
+	Mode string `json:"mode,omitempty"`
+}
+
+// journalEntryFields has the fields of JournalEntry without its JSON methods.
+type journalEntryFields JournalEntry
+
+// MarshalJSON implements json.Marshaler.
+func (e JournalEntry) MarshalJSON() ([]byte, error) {
+	doc := journalEntryJSON{journalEntryFields: journalEntryFields(e)}
+	if e.Mode != 0 {
+		doc.Mode = fmt.Sprintf("%04o", uint32(e.Mode))
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *JournalEntry) UnmarshalJSON(data []byte) error {
+	var doc journalEntryJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	var mode uint64
+	if doc.Mode != "" {
+		var err error
+		if mode, err = strconv.ParseUint(doc.Mode, 8, 32); err != nil {
+			return fmt.Errorf("invalid mode %q", doc.Mode)
+		}
+	}
+	*e = JournalEntry(doc.journalEntryFields)
+	e.Mode = fs.FileMode(mode)
+	return nil
+}
+
+// IsWrite returns true if the entry records a call that changes filesystem state.
+func (e JournalEntry) IsWrite() bool {
+	switch e.Method {
+	case JournalWriteFile, JournalMkdirAll, JournalRemove, JournalRemoveAll, JournalSymlink, JournalRename,
+		JournalChmod, JournalChtimes, JournalLink:
+		return true
+	default:
+		return false
+	}
+}
+
+// Paths returns every path the entry touches.
+func (e JournalEntry) Paths() []string {
+	if e.NewPath != "" {
+		return []string{e.Path, e.NewPath}
+	}
+	return []string{e.Path}
+}
+
+// Journal is an ordered log of FileSystem calls.
+type Journal struct {
+	Entries []JournalEntry `json:"entries"`
+}
+
+// Writes returns the entries that change filesystem state.
+func (j *Journal) Writes() []JournalEntry {
+	return j.Filter(JournalEntry.IsWrite)
+}
+
+// Filter returns the entries for which keep returns true.
+func (j *Journal) Filter(keep func(JournalEntry) bool) []JournalEntry {
+	var entries []JournalEntry
+	for _, entry := range j.Entries {
+		if keep(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// WriteJSON serializes the journal as JSON.
+func (j *Journal) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(j)
+}
+
+// ReadJournal deserializes a journal written by WriteJSON.
+func ReadJournal(r io.Reader) (*Journal, error) {
+	var journal Journal
+	if err := json.NewDecoder(r).Decode(&journal); err != nil {
+		return nil, fmt.Errorf("failed to decode journal: %w", err)
+	}
+	return &journal, nil
+}
+
+// Replay applies every successful write in the journal to fsys, in order.
+// Reads and calls that failed when they were recorded are skipped.
+func (j *Journal) Replay(fsys FileSystem) error {
+	for _, entry := range j.Writes() {
+		if entry.Error != "" {
+			continue
+		}
+		if err := replayEntry(fsys, entry); err != nil {
+			return fmt.Errorf("replay of %s %s (seq %d) failed: %w", entry.Method, entry.Path, entry.Seq, err)
+		}
+	}
+	return nil
+}
+
+func replayEntry(fsys FileSystem, entry JournalEntry) error {
+	switch entry.Method {
+	case JournalWriteFile:
+		return fsys.WriteFile(entry.Path, entry.Data, entry.Mode)
+	case JournalMkdirAll:
+		return fsys.MkdirAll(entry.Path, entry.Mode)
+	case JournalRemove:
+		return fsys.Remove(entry.Path)
+	case JournalRemoveAll:
+		return fsys.RemoveAll(entry.Path)
+	case JournalSymlink:
+		return fsys.Symlink(entry.Target, entry.Path)
+	case JournalRename:
+		return fsys.Rename(entry.Path, entry.NewPath)
+	case JournalChmod:
+		chmodFS, ok := fsys.(ChmodFS)
+		if !ok {
+			return errors.ErrUnsupported
+		}
+		return chmodFS.Chmod(entry.Path, entry.Mode)
+	case JournalChtimes:
+		chtimesFS, ok := fsys.(ChtimesFS)
+		if !ok {
+			return errors.ErrUnsupported
+		}
+		if len(entry.Times) != 2 {
+			return fmt.Errorf("chtimes entry needs 2 times, got %d", len(entry.Times))
+		}
+		return chtimesFS.Chtimes(entry.Path, entry.Times[0], entry.Times[1])
+	case JournalLink:
+		linkFS, ok := fsys.(LinkFS)
+		if !ok {
+			return errors.ErrUnsupported
+		}
+		return linkFS.Link(entry.Target, entry.Path)
+	default:
+		return fmt.Errorf("unknown journal method: %s", entry.Method)
+	}
+}
+
+// RecordingFileSystem wraps a FileSystem and records every call in a Journal.
+// WriteFile content is recorded so the journal can be replayed.
+type RecordingFileSystem struct {
+	fs      FileSystem
+	mu      sync.Mutex
+	journal Journal
+}
+
+// NewRecordingFileSystem creates a recording wrapper around fsys.
+func NewRecordingFileSystem(fsys FileSystem) *RecordingFileSystem {
+	return &RecordingFileSystem{fs: fsys}
+}
+
+// Journal returns a copy of the calls recorded so far.
+func (r *RecordingFileSystem) Journal() *Journal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Journal{Entries: append([]JournalEntry(nil), r.journal.Entries...)}
+}
+
+// Reset clears the recorded calls.
+func (r *RecordingFileSystem) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal.Entries = nil
+}
+
+// record appends an entry with the call's timing and error.
+func (r *RecordingFileSystem) record(entry JournalEntry, start time.Time, err error) {
+	entry.Start = start
+	entry.Duration = time.Since(start)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.Seq = len(r.journal.Entries) + 1
+	r.journal.Entries = append(r.journal.Entries, entry)
+}
+
+// Open implements fs.FS
+func (r *RecordingFileSystem) Open(name string) (fs.File, error) {
+	start := time.Now()
+	file, err := r.fs.Open(name)
+	r.record(JournalEntry{Method: JournalOpen, Path: name}, start, err)
+	return file, err
+}
+
+// Stat implements FileSystem
+func (r *RecordingFileSystem) Stat(name string) (fs.FileInfo, error) {
+	start := time.Now()
+	info, err := r.fs.Stat(name)
+	r.record(JournalEntry{Method: JournalStat, Path: name}, start, err)
+	return info, err
+}
+
+// WriteFile implements WriteFS
+func (r *RecordingFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	start := time.Now()
+	err := r.fs.WriteFile(name, data, perm)
+	r.record(JournalEntry{
+		Method: JournalWriteFile,
+		Path:   name,
+		Data:   append([]byte(nil), data...),
+		Mode:   perm,
+	}, start, err)
+	return err
+}
+
+// MkdirAll implements WriteFS
+func (r *RecordingFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	start := time.Now()
+	err := r.fs.MkdirAll(path, perm)
+	r.record(JournalEntry{Method: JournalMkdirAll, Path: path, Mode: perm}, start, err)
+	return err
+}
+
+// Remove implements WriteFS
+func (r *RecordingFileSystem) Remove(name string) error {
+	start := time.Now()
+	err := r.fs.Remove(name)
+	r.record(JournalEntry{Method: JournalRemove, Path: name}, start, err)
+	return err
+}
+
+// RemoveAll implements WriteFS
+func (r *RecordingFileSystem) RemoveAll(name string) error {
+	start := time.Now()
+	err := r.fs.RemoveAll(name)
+	r.record(JournalEntry{Method: JournalRemoveAll, Path: name}, start, err)
+	return err
+}
+
+// Symlink implements WriteFS
+func (r *RecordingFileSystem) Symlink(oldname, newname string) error {
+	start := time.Now()
+	err := r.fs.Symlink(oldname, newname)
+	r.record(JournalEntry{Method: JournalSymlink, Path: newname, Target: oldname}, start, err)
+	return err
+}
+
+// Readlink implements WriteFS
+func (r *RecordingFileSystem) Readlink(name string) (string, error) {
+	start := time.Now()
+	target, err := r.fs.Readlink(name)
+	r.record(JournalEntry{Method: JournalReadlink, Path: name, Target: target}, start, err)
+	return target, err
+}
+
+// Rename implements WriteFS
+func (r *RecordingFileSystem) Rename(oldpath, newpath string) error {
+	start := time.Now()
+	err := r.fs.Rename(oldpath, newpath)
+	r.record(JournalEntry{Method: JournalRename, Path: oldpath, NewPath: newpath}, start, err)
+	return err
+}
+
+// Chmod implements ChmodFS. It fails with errors.ErrUnsupported when the
+// wrapped filesystem does not.
+func (r *RecordingFileSystem) Chmod(name string, mode fs.FileMode) error {
+	start := time.Now()
+	var err error
+	if chmodFS, ok := r.fs.(ChmodFS); ok {
+		err = chmodFS.Chmod(name, mode)
+	} else {
+		err = &fs.PathError{Op: "chmod", Path: name, Err: errors.ErrUnsupported}
+	}
+	r.record(JournalEntry{Method: JournalChmod, Path: name, Mode: mode}, start, err)
+	return err
+}
+
+// Chtimes implements ChtimesFS. It fails with errors.ErrUnsupported when the
+// wrapped filesystem does not.
+func (r *RecordingFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	start := time.Now()
+	var err error
+	if chtimesFS, ok := r.fs.(ChtimesFS); ok {
+		err = chtimesFS.Chtimes(name, atime, mtime)
+	} else {
+		err = &fs.PathError{Op: "chtimes", Path: name, Err: errors.ErrUnsupported}
+	}
+	r.record(JournalEntry{Method: JournalChtimes, Path: name, Times: []time.Time{atime, mtime}}, start, err)
+	return err
+}
+
+// Link implements LinkFS. It fails with errors.ErrUnsupported when the
+// wrapped filesystem does not.
+func (r *RecordingFileSystem) Link(oldname, newname string) error {
+	start := time.Now()
+	var err error
+	if linkFS, ok := r.fs.(LinkFS); ok {
+		err = linkFS.Link(oldname, newname)
+	} else {
+		err = &fs.PathError{Op: "link", Path: newname, Err: errors.ErrUnsupported}
+	}
+	r.record(JournalEntry{Method: JournalLink, Path: newname, Target: oldname}, start, err)
+	return err
+}
+
+// FileID implements LinkFS. It fails with errors.ErrUnsupported when the
+// wrapped filesystem does not.
+func (r *RecordingFileSystem) FileID(name string) (FileID, error) {
+	start := time.Now()
+	var id FileID
+	var err error
+	if linkFS, ok := r.fs.(LinkFS); ok {
+		id, err = linkFS.FileID(name)
+	} else {
+		err = &fs.PathError{Op: "fileid", Path: name, Err: errors.ErrUnsupported}
+	}
+	r.record(JournalEntry{Method: JournalFileID, Path: name}, start, err)
+	return id, err
+}
+
+var _ FileSystem = (*RecordingFileSystem)(nil)
@@ -0,0 +1,194 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+func TestRecordingFileSystem(t *testing.T) {
+	t.Run("Records calls in order", func(t *testing.T) {
+		rfs := filesystem.NewRecordingFileSystem(filesystem.NewTestFileSystem())
+
+		if err := rfs.MkdirAll("build", 0755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := rfs.WriteFile("build/out.txt", []byte("output"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if _, err := rfs.Stat("missing.txt"); err == nil {
+			t.Fatal("Expected Stat of missing file to fail")
+		}
+		if err := rfs.Rename("build/out.txt", "build/final.txt"); err != nil {
+			t.Fatalf("Rename failed: %v", err)
+		}
+
+		entries := rfs.Journal().Entries
+		if len(entries) != 4 {
+			t.Fatalf("Expected 4 entries, got %d", len(entries))
+		}
+
+		expectedMethods := []string{
+			filesystem.JournalMkdirAll,
+			filesystem.JournalWriteFile,
+			filesystem.JournalStat,
+			filesystem.JournalRename,
+		}
+		for i, method := range expectedMethods {
+			if entries[i].Method != method {
+				t.Errorf("Entry %d: expected method %s, got %s", i, method, entries[i].Method)
+			}
+			if entries[i].Seq != i+1 {
+				t.Errorf("Entry %d: expected seq %d, got %d", i, i+1, entries[i].Seq)
+			}
+		}
+
+		if string(entries[1].Data) != "output" || entries[1].Mode != 0644 {
+			t.Errorf("WriteFile entry missing data or mode: %+v", entries[1])
+		}
+		if entries[2].Error == "" {
+			t.Error("Expected Stat entry to record its error")
+		}
+		if entries[3].NewPath != "build/final.txt" {
+			t.Errorf("Expected rename destination to be recorded, got %q", entries[3].NewPath)
+		}
+
+		if writes := rfs.Journal().Writes(); len(writes) != 3 {
+			t.Errorf("Expected 3 writes, got %d", len(writes))
+		}
+	})
+
+	t.Run("Serializes and replays", func(t *testing.T) {
+		rfs := filesystem.NewRecordingFileSystem(filesystem.NewTestFileSystem())
+		_ = rfs.MkdirAll("dir", 0755)
+		_ = rfs.WriteFile("dir/a.txt", []byte("a"), 0600)
+		_ = rfs.WriteFile("dir/b.txt", []byte("b"), 0644)
+		_ = rfs.Remove("dir/b.txt")
+		_ = rfs.Remove("does-not-exist")
+
+		var buf bytes.Buffer
+		if err := rfs.Journal().WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte(`"mode": "0600"`)) {
+			t.Errorf("Expected modes as octal strings in JSON:\n%s", buf.String())
+		}
+
+		journal, err := filesystem.ReadJournal(&buf)
+		if err != nil {
+			t.Fatalf("ReadJournal failed: %v", err)
+		}
+		if len(journal.Entries) != 5 {
+			t.Fatalf("Expected 5 entries after round trip, got %d", len(journal.Entries))
+		}
+
+		target := filesystem.NewTestFileSystem()
+		if err := journal.Replay(target); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+
+		data, err := fs.ReadFile(target, "dir/a.txt")
+		if err != nil || string(data) != "a" {
+			t.Errorf("Expected replayed file content %q, got %q (err: %v)", "a", data, err)
+		}
+		info, err := target.Stat("dir/a.txt")
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected replayed mode 0600, got %v (err: %v)", info, err)
+		}
+		if _, err := target.Stat("dir/b.txt"); err == nil {
+			t.Error("Expected removed file to stay removed after replay")
+		}
+	})
+
+	t.Run("Records and replays chtimes", func(t *testing.T) {
+		rfs := filesystem.NewRecordingFileSystem(filesystem.NewTestFileSystem())
+		mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := rfs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := rfs.Chtimes("a.txt", mtime, mtime); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+
+		entries := rfs.Journal().Entries
+		if len(entries) != 2 || entries[1].Method != filesystem.JournalChtimes {
+			t.Fatalf("Expected a WriteFile and a Chtimes entry, got %+v", entries)
+		}
+		if writes := rfs.Journal().Writes(); len(writes) != 2 {
+			t.Errorf("Expected 2 writes, got %d", len(writes))
+		}
+
+		target := filesystem.NewTestFileSystem()
+		if err := rfs.Journal().Replay(target); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		info, err := target.Stat("a.txt")
+		if err != nil || !info.ModTime().Equal(mtime) {
+			t.Errorf("Expected replayed time %v, got %v (err: %v)", mtime, info, err)
+		}
+	})
+
+	t.Run("Records and replays chmod", func(t *testing.T) {
+		rfs := filesystem.NewRecordingFileSystem(filesystem.NewTestFileSystem())
+		if err := rfs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := rfs.Chmod("a.txt", 0600); err != nil {
+			t.Fatalf("Chmod failed: %v", err)
+		}
+
+		entries := rfs.Journal().Entries
+		if len(entries) != 2 || entries[1].Method != filesystem.JournalChmod || entries[1].Mode != 0600 {
+			t.Fatalf("Expected a WriteFile and a Chmod entry, got %+v", entries)
+		}
+
+		target := filesystem.NewTestFileSystem()
+		if err := rfs.Journal().Replay(target); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		info, err := target.Stat("a.txt")
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected replayed mode 0600, got %v (err: %v)", info, err)
+		}
+	})
+
+	t.Run("Records and replays hard links", func(t *testing.T) {
+		rfs := filesystem.NewRecordingFileSystem(filesystem.NewTestFileSystem())
+		if err := rfs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := rfs.Link("a.txt", "b.txt"); err != nil {
+			t.Fatalf("Link failed: %v", err)
+		}
+		if _, err := rfs.FileID("b.txt"); err != nil {
+			t.Fatalf("FileID failed: %v", err)
+		}
+
+		entries := rfs.Journal().Entries
+		expectedMethods := []string{filesystem.JournalWriteFile, filesystem.JournalLink, filesystem.JournalFileID}
+		if len(entries) != len(expectedMethods) {
+			t.Fatalf("Expected %d entries, got %d", len(expectedMethods), len(entries))
+		}
+		for i, method := range expectedMethods {
+			if entries[i].Method != method {
+				t.Errorf("Entry %d: expected method %s, got %s", i, method, entries[i].Method)
+			}
+		}
+		if writes := rfs.Journal().Writes(); len(writes) != 2 {
+			t.Errorf("Expected 2 writes, got %d", len(writes))
+		}
+
+		target := filesystem.NewTestFileSystem()
+		if err := rfs.Journal().Replay(target); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		a, _ := target.FileID("a.txt")
+		b, _ := target.FileID("b.txt")
+		if a != b {
+			t.Error("Expected replayed b.txt to be a hard link to a.txt")
+		}
+	})
+}
@@ -3,9 +3,11 @@ package filesystem
 import (
 	"context"
 	"io/fs"
+	"reflect"
 	"syscall"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 // TestFileSystem extends fstest.MapFS to implement our FileSystem interface
@@ -33,6 +35,11 @@ func (tfs *TestFileSystem) WriteFile(name string, data []byte, perm fs.FileMode)
 	if !fs.ValidPath(name) {
 		return &fs.PathError{Op: "writefile", Path: name, Err: fs.ErrInvalid}
 	}
+	// Overwrite regular files in place so hard links to them see the new content
+	if file, exists := tfs.MapFS[name]; exists && file.Mode.IsRegular() {
+		*file = fstest.MapFile{Data: data, Mode: perm}
+		return nil
+	}
 	tfs.MapFS[name] = &fstest.MapFile{
 		Data: data,
 		Mode: perm,
@@ -157,6 +164,65 @@ func (tfs *TestFileSystem) Stat(name string) (fs.FileInfo, error) {
 	return file.Stat()
 }
 
+// Chtimes implements ChtimesFS for testing
+func (tfs *TestFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrInvalid}
+	}
+	file, exists := tfs.MapFS[name]
+	if !exists {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	file.ModTime = mtime
+	return nil
+}
+
+// Chmod implements ChmodFS for testing
+func (tfs *TestFileSystem) Chmod(name string, mode fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrInvalid}
+	}
+	file, exists := tfs.MapFS[name]
+	if !exists {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	file.Mode = file.Mode&^fs.ModePerm | mode.Perm()
+	return nil
+}
+
+// Link implements LinkFS for testing. Both names share one entry, so content
+// and mode changes through either are seen by the other.
+func (tfs *TestFileSystem) Link(oldname, newname string) error {
+	if !fs.ValidPath(oldname) || !fs.ValidPath(newname) {
+		return &fs.PathError{Op: "link", Path: newname, Err: fs.ErrInvalid}
+	}
+	file, exists := tfs.MapFS[oldname]
+	if !exists {
+		return &fs.PathError{Op: "link", Path: oldname, Err: fs.ErrNotExist}
+	}
+	if !file.Mode.IsRegular() {
+		return &fs.PathError{Op: "link", Path: oldname, Err: syscall.EPERM}
+	}
+	if _, exists := tfs.MapFS[newname]; exists {
+		return &fs.PathError{Op: "link", Path: newname, Err: fs.ErrExist}
+	}
+	tfs.MapFS[newname] = file
+	return nil
+}
+
+// FileID implements LinkFS for testing. The inode is the address of the
+// entry, which every hard link to it shares.
+func (tfs *TestFileSystem) FileID(name string) (FileID, error) {
+	if !fs.ValidPath(name) {
+		return FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: fs.ErrInvalid}
+	}
+	file, exists := tfs.MapFS[name]
+	if !exists {
+		return FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: fs.ErrNotExist}
+	}
+	return FileID{Inode: uint64(reflect.ValueOf(file).Pointer())}, nil
+}
+
 // isSubPath returns true if child is a subpath of parent
 func isSubPath(parent, child string) bool {
 	if parent == "" || parent == "." {
@@ -0,0 +1,73 @@
+package synthfs
+
+import (
+	"io/fs"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// MatchGlob reports whether name matches pattern. Segments are path.Match
+// patterns and a "**" segment matches any number of directories. A trailing
+// "**" matches only paths below the ones before it.
+func MatchGlob(pattern, name string) bool {
+	return operations.MatchGlob(pattern, name)
+}
+
+// DeleteGlob creates an operation that deletes every path matching pattern.
+// Paths matching any exclude pattern are left alone; an exclude pattern without
+// a slash matches base names anywhere, like a .gitignore entry.
+//
+// Glob operations are expanded against the projected filesystem when the
+// operations are validated, so they see paths created earlier in the same run.
+// Each match becomes its own child operation with an ID derived from the
+// glob's ID, and the glob's description lists the matched paths.
+//
+// Example:
+//
+//	sfs.DeleteGlob("build/**/*.o", "vendor")
+func (s *SynthFS) DeleteGlob(pattern string, exclude ...string) Operation {
+	id := s.idGen("delete_glob", pattern)
+	return operations.NewDeleteGlobOperation(id, pattern, exclude)
+}
+
+// CopyGlob creates an operation that copies every file matching pattern into
+// dstDir, keeping their layout below the pattern's literal prefix:
+// CopyGlob("assets/**/*.png", "public") copies assets/img/a.png to public/img/a.png.
+func (s *SynthFS) CopyGlob(pattern, dstDir string, exclude ...string) Operation {
+	id := s.idGen("copy_glob", pattern)
+	return operations.NewCopyGlobOperation(id, pattern, dstDir, exclude)
+}
+
+// MoveGlob creates an operation that moves every path matching pattern into
+// dstDir, with the same layout rules as CopyGlob. Matched directories move whole.
+func (s *SynthFS) MoveGlob(pattern, dstDir string, exclude ...string) Operation {
+	id := s.idGen("move_glob", pattern)
+	return operations.NewMoveGlobOperation(id, pattern, dstDir, exclude)
+}
+
+// ChmodGlob creates an operation that sets mode on every path matching pattern.
+func (s *SynthFS) ChmodGlob(pattern string, mode fs.FileMode, exclude ...string) Operation {
+	id := s.idGen("chmod_glob", pattern)
+	return operations.NewChmodGlobOperation(id, pattern, mode, exclude)
+}
+
+// DeleteGlobWithID creates a delete glob operation with an explicit ID.
+func (s *SynthFS) DeleteGlobWithID(id string, pattern string, exclude ...string) Operation {
+	return operations.NewDeleteGlobOperation(core.OperationID(id), pattern, exclude)
+}
+
+// CopyGlobWithID creates a copy glob operation with an explicit ID.
+func (s *SynthFS) CopyGlobWithID(id string, pattern, dstDir string, exclude ...string) Operation {
+	return operations.NewCopyGlobOperation(core.OperationID(id), pattern, dstDir, exclude)
+}
+
+// MoveGlobWithID creates a move glob operation with an explicit ID.
+func (s *SynthFS) MoveGlobWithID(id string, pattern, dstDir string, exclude ...string) Operation {
+	return operations.NewMoveGlobOperation(core.OperationID(id), pattern, dstDir, exclude)
+}
+
+// ChmodGlobWithID creates a chmod glob operation with an explicit ID.
+func (s *SynthFS) ChmodGlobWithID(id string, pattern string, mode fs.FileMode, exclude ...string) Operation {
+	return operations.NewChmodGlobOperation(core.OperationID(id), pattern, mode, exclude)
+}
@@ -0,0 +1,182 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+// setupGlobTree builds a small project tree on the real filesystem.
+func setupGlobTree(t *testing.T) synthfs.FileSystem {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+
+	fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+	mustDo(t, fsys.MkdirAll("src/pkg", 0755))
+	mustDo(t, fsys.MkdirAll("src/keep", 0755))
+	mustDo(t, fsys.MkdirAll("scripts", 0755))
+	mustDo(t, fsys.WriteFile("src/main.go", []byte("package main"), 0644))
+	mustDo(t, fsys.WriteFile("src/a.tmp", []byte("tmp"), 0644))
+	mustDo(t, fsys.WriteFile("src/pkg/b.tmp", []byte("tmp"), 0644))
+	mustDo(t, fsys.WriteFile("src/keep/c.tmp", []byte("tmp"), 0644))
+	mustDo(t, fsys.WriteFile("scripts/build.sh", []byte("#!/bin/sh"), 0644))
+	mustDo(t, fsys.WriteFile("scripts/test.sh", []byte("#!/bin/sh"), 0644))
+	return fsys
+}
+
+func TestGlobOperations(t *testing.T) {
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	t.Run("plan lists the matched paths", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		clean := sfs.DeleteGlobWithID("clean", "**/*.tmp", "keep")
+
+		planned, err := synthfs.Plan(ctx, fsys, clean)
+		if err != nil {
+			t.Fatalf("plan failed: %v", err)
+		}
+		var ids []string
+		for _, op := range planned {
+			ids = append(ids, string(op.ID()))
+		}
+		wantIDs := []string{"clean:delete:src/a.tmp", "clean:delete:src/pkg/b.tmp"}
+		if !reflect.DeepEqual(ids, wantIDs) {
+			t.Errorf("expected %v, got %v", wantIDs, ids)
+		}
+		matched := clean.Describe().Details["matched_paths"]
+		if !reflect.DeepEqual(matched, []string{"src/a.tmp", "src/pkg/b.tmp"}) {
+			t.Errorf("unexpected matched paths %v", matched)
+		}
+
+		// Planning does not touch the filesystem
+		if _, err := fsys.Stat("src/a.tmp"); err != nil {
+			t.Errorf("plan removed a file: %v", err)
+		}
+	})
+
+	t.Run("delete glob", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		if _, err := synthfs.Run(ctx, fsys, sfs.DeleteGlob("**/*.tmp", "keep")); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		for _, p := range []string{"src/a.tmp", "src/pkg/b.tmp"} {
+			if _, err := fsys.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s should be deleted, got %v", p, err)
+			}
+		}
+		if _, err := fsys.Stat("src/keep/c.tmp"); err != nil {
+			t.Errorf("excluded file was deleted: %v", err)
+		}
+	})
+
+	t.Run("excludes inside matched directories are kept", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		if _, err := synthfs.Run(ctx, fsys, sfs.DeleteGlob("src/*", "c.tmp")); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if _, err := fsys.Stat("src/keep/c.tmp"); err != nil {
+			t.Errorf("excluded file was deleted: %v", err)
+		}
+		for _, p := range []string{"src/main.go", "src/a.tmp", "src/pkg"} {
+			if _, err := fsys.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s should be deleted, got %v", p, err)
+			}
+		}
+
+		if _, err := synthfs.Run(ctx, fsys, sfs.DeleteGlob("scripts/**")); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if _, err := fsys.Stat("scripts/build.sh"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("scripts/build.sh should be deleted, got %v", err)
+		}
+		if info, err := fsys.Stat("scripts"); err != nil || !info.IsDir() {
+			t.Errorf("scripts itself should be kept: %v", err)
+		}
+	})
+
+	t.Run("globs see paths created earlier in the run", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		_, err := synthfs.Run(ctx, fsys,
+			sfs.CreateDir("gen", 0755),
+			sfs.CreateFile("gen/version.txt", []byte("1.0"), 0644),
+			sfs.CopyGlob("gen/**", "dist"),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if content := readString(t, fsys, "dist/version.txt"); content != "1.0" {
+			t.Errorf("expected copied file, got %q", content)
+		}
+	})
+
+	t.Run("move glob moves directories whole", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		if _, err := synthfs.Run(ctx, fsys, sfs.MoveGlob("src/*", "archive", "*.go")); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if content := readString(t, fsys, "archive/pkg/b.tmp"); content != "tmp" {
+			t.Errorf("expected moved directory, got %q", content)
+		}
+		if _, err := fsys.Stat("src/main.go"); err != nil {
+			t.Errorf("excluded file was moved: %v", err)
+		}
+	})
+
+	t.Run("chmod glob rolls back", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.RollbackOnError = true
+		fail := sfs.CustomOperation("fail", func(ctx context.Context, fs synthfs.FileSystem) error {
+			return errors.New("boom")
+		})
+
+		_, err := synthfs.RunWithOptions(ctx, fsys, options, sfs.ChmodGlob("scripts/*.sh", 0755), fail)
+		if err == nil {
+			t.Fatal("expected failure")
+		}
+		info, err := fsys.Stat("scripts/build.sh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("expected mode restored to 0644, got %o", info.Mode().Perm())
+		}
+
+		if _, err := synthfs.Run(ctx, fsys, sfs.ChmodGlob("scripts/*.sh", 0755)); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		info, err = fsys.Stat("scripts/test.sh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("expected mode 0755, got %o", info.Mode().Perm())
+		}
+	})
+
+	t.Run("dry run leaves the filesystem untouched", func(t *testing.T) {
+		fsys := setupGlobTree(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.DryRun = true
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options, sfs.DeleteGlob("**/*.tmp"))
+		if err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if len(result.Operations) != 3 {
+			t.Errorf("expected 3 child results, got %d", len(result.Operations))
+		}
+		if _, err := fsys.Stat("src/a.tmp"); err != nil {
+			t.Errorf("dry run removed a file: %v", err)
+		}
+	})
+}
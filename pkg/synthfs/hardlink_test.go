@@ -0,0 +1,50 @@
+package synthfs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestCreateHardlink(t *testing.T) {
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	t.Run("links a file created earlier in the run", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		result, err := synthfs.Run(ctx, fsys,
+			sfs.CreateFile("store/abc123", []byte("artifact"), 0644),
+			sfs.CreateHardlink("store/abc123", "builds/1/app.bin"),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if !result.Success {
+			t.Fatalf("run was not successful: %v", result.Errors)
+		}
+		linkFS := fsys.(filesystem.LinkFS)
+		a, _ := linkFS.FileID("store/abc123")
+		b, _ := linkFS.FileID("builds/1/app.bin")
+		if a != b {
+			t.Error("expected the link to share the stored file")
+		}
+	})
+
+	t.Run("linking a directory fails validation", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("dir", 0755))
+		if _, err := synthfs.Run(ctx, fsys, sfs.CreateHardlink("dir", "link")); err == nil {
+			t.Error("expected an error linking a directory")
+		}
+	})
+
+	t.Run("linking a missing target fails validation", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		if _, err := synthfs.Run(ctx, fsys, sfs.CreateHardlink("missing", "link")); err == nil {
+			t.Error("expected an error linking a missing target")
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package synthfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/targets"
+)
+
+// Lazy is a value computed when the operation using it runs, from the outputs
+// of operations that ran before it. Build one with FromOutput or Computed.
+type Lazy[T any] struct {
+	deps    []Operation
+	resolve func() (T, error)
+}
+
+// FromOutput refers to the output key of op, read when the operation using
+// it runs. Using it makes that operation depend on op.
+func FromOutput[T any](op Operation, key string) Lazy[T] {
+	return Lazy[T]{
+		deps: []Operation{op},
+		resolve: func() (T, error) {
+			value, ok := Output[T](op, key)
+			if !ok {
+				return value, fmt.Errorf("%w: %q of operation %s", core.ErrOutputNotAvailable, key, op.ID())
+			}
+			return value, nil
+		},
+	}
+}
+
+// Computed is a value computed by compute, typically from the outputs of
+// deps, when the operation using it runs. Using it makes that operation
+// depend on deps.
+//
+// Example, a manifest of checksums:
+//
+//	sum := sfs.Checksum("app.tar.gz", synthfs.SHA256)
+//	manifest := synthfs.Computed(func() ([]byte, error) {
+//	    hash, _ := synthfs.Output[string](sum, "sha256")
+//	    return []byte(hash + "  app.tar.gz\n"), nil
+//	}, sum)
+//	synthfs.Run(ctx, fs, sum, sfs.CreateFileFrom("SHA256SUMS", manifest, 0644))
+func Computed[T any](compute func() (T, error), deps ...Operation) Lazy[T] {
+	return Lazy[T]{deps: deps, resolve: compute}
+}
+
+// inputTarget is implemented by operations that accept lazy inputs.
+type inputTarget interface {
+	AddInput(resolve func(context.Context) error, dependsOn ...core.OperationID)
+}
+
+// WithInput arranges for value to be resolved and passed to set right before
+// op runs, and makes op depend on the operations value reads from. It
+// returns op. Operations that do not accept lazy inputs are returned as they
+// are.
+func WithInput[T any](op Operation, value Lazy[T], set func(T) error) Operation {
+	target, ok := op.(inputTarget)
+	if !ok {
+		return op
+	}
+	deps := make([]core.OperationID, len(value.deps))
+	for i, dep := range value.deps {
+		deps[i] = dep.ID()
+	}
+	target.AddInput(func(ctx context.Context) error {
+		resolved, err := value.resolve()
+		if err != nil {
+			return err
+		}
+		return set(resolved)
+	}, deps...)
+	return op
+}
+
+// CreateFileFrom creates a file creation operation whose content is resolved
+// when it runs, such as the stdout of a shell command run before it.
+//
+// Example:
+//
+//	version := sfs.ShellCommand("git describe --tags", synthfs.WithCaptureOutput())
+//	synthfs.Run(ctx, fs, version,
+//	    sfs.CreateFileFrom("VERSION", synthfs.FromOutput[[]byte](version, "stdout"), 0644))
+func (s *SynthFS) CreateFileFrom(path string, content Lazy[[]byte], mode fs.FileMode) Operation {
+	id := s.idGen("create_file", path)
+	return s.CreateFileFromWithID(string(id), path, content, mode)
+}
+
+// CreateFileFromWithID creates a file creation operation with an explicit ID
+// whose content is resolved when it runs.
+func (s *SynthFS) CreateFileFromWithID(id string, path string, content Lazy[[]byte], mode fs.FileMode) Operation {
+	op := operations.NewCreateFileOperation(core.OperationID(id), path)
+	op.SetItem(targets.NewFile(path).WithMode(mode))
+	return WithInput(op, content, func(content []byte) error {
+		op.SetItem(targets.NewFile(path).WithContent(content).WithMode(mode))
+		return nil
+	})
+}
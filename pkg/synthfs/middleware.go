@@ -0,0 +1,43 @@
+package synthfs
+
+import (
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+)
+
+// Middleware wraps the validation, execution and rollback of every operation
+// of a run. Set it with PipelineOptions.Middleware; the first middleware is
+// the outermost. See core.Middleware.
+//
+// Example, skipping everything under vendor/:
+//
+//	options.Middleware = []synthfs.Middleware{
+//	    func(next synthfs.ExecFunc) synthfs.ExecFunc {
+//	        return func(ctx context.Context, call *synthfs.Call) error {
+//	            if strings.HasPrefix(call.Desc.Path, "vendor/") {
+//	                return synthfs.ErrSkip
+//	            }
+//	            return next(ctx, call)
+//	        }
+//	    },
+//	}
+type Middleware = core.Middleware
+
+// ExecFunc runs one step of an operation.
+type ExecFunc = core.ExecFunc
+
+// Call is one step of one operation, as middleware sees it.
+type Call = core.Call
+
+// Phase is the step of an operation that middleware wraps.
+type Phase = core.Phase
+
+// Phases of an operation, as seen by middleware
+const (
+	PhaseValidate = core.PhaseValidate
+	PhaseExecute  = core.PhaseExecute
+	PhaseRollback = core.PhaseRollback
+)
+
+// ErrSkip is returned by middleware to skip a step of an operation without
+// failing it. Skipped executions are reported as StatusSkipped.
+var ErrSkip = core.ErrSkip
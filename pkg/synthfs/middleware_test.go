@@ -0,0 +1,103 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+
+	// audit returns middleware logging each step as "phase path"
+	audit := func(calls *[]string) synthfs.Middleware {
+		return func(next synthfs.ExecFunc) synthfs.ExecFunc {
+			return func(ctx context.Context, call *synthfs.Call) error {
+				*calls = append(*calls, string(call.Phase)+" "+call.Desc.Path)
+				return next(ctx, call)
+			}
+		}
+	}
+	// skipUnder returns middleware skipping every step of operations on
+	// paths under dir
+	skipUnder := func(dir string) synthfs.Middleware {
+		return func(next synthfs.ExecFunc) synthfs.ExecFunc {
+			return func(ctx context.Context, call *synthfs.Call) error {
+				if strings.HasPrefix(call.Desc.Path, dir+"/") {
+					return synthfs.ErrSkip
+				}
+				return next(ctx, call)
+			}
+		}
+	}
+
+	t.Run("middleware wraps validation and execution and can skip", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		var calls []string
+		options := synthfs.DefaultPipelineOptions()
+		options.Middleware = []synthfs.Middleware{audit(&calls), skipUnder("vendor")}
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateFile("a.txt", []byte("a"), 0644),
+			// Would fail validation: the directory does not exist
+			sfs.CreateFile("vendor/lib.go", []byte("package lib"), 0644),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		want := []string{"validate a.txt", "validate vendor/lib.go", "execute a.txt", "execute vendor/lib.go"}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("calls = %v, want %v", calls, want)
+		}
+		if result.Operations[0].Status != synthfs.StatusSuccess || result.Operations[1].Status != synthfs.StatusSkipped {
+			t.Errorf("unexpected statuses: %s, %s", result.Operations[0].Status, result.Operations[1].Status)
+		}
+		if _, err := fsys.Stat("vendor"); err == nil {
+			t.Error("skipped operation ran")
+		}
+	})
+
+	t.Run("middleware errors fail the run and rollbacks pass through it", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		denied := errors.New("denied by policy")
+		var calls []string
+		options := synthfs.DefaultPipelineOptions()
+		options.RollbackOnError = true
+		options.Middleware = []synthfs.Middleware{
+			audit(&calls),
+			func(next synthfs.ExecFunc) synthfs.ExecFunc {
+				return func(ctx context.Context, call *synthfs.Call) error {
+					if call.Phase == synthfs.PhaseExecute && call.Desc.Path == "secret.txt" {
+						return denied
+					}
+					return next(ctx, call)
+				}
+			},
+		}
+
+		_, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateFile("a.txt", []byte("a"), 0644),
+			sfs.CreateFile("secret.txt", []byte("s"), 0600),
+		)
+		if !errors.Is(err, denied) {
+			t.Fatalf("expected the middleware error, got %v", err)
+		}
+		if last := calls[len(calls)-1]; last != "rollback a.txt" {
+			t.Errorf("expected the rollback to pass through middleware, calls = %v", calls)
+		}
+		if _, err := fsys.Stat("a.txt"); err == nil {
+			t.Error("a.txt was not rolled back")
+		}
+	})
+}
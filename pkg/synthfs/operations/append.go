@@ -0,0 +1,271 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// AppendFileOperation adds content to the end of a file, creating the file if
+// it does not exist. Its reverse is a truncate back to the original size, so
+// no copy of the file is kept.
+type AppendFileOperation struct {
+	*BaseOperation
+	content      []byte
+	previousSize int
+	created      bool
+	applied      bool
+}
+
+// NewAppendFileOperation creates a new append_file operation.
+func NewAppendFileOperation(id core.OperationID, path string, content []byte) *AppendFileOperation {
+	op := &AppendFileOperation{
+		BaseOperation: NewBaseOperation(id, "append_file", path),
+		content:       content,
+	}
+	op.SetDescriptionDetail("content_length", len(content))
+	return op
+}
+
+// Content returns the bytes the operation appends.
+func (op *AppendFileOperation) Content() []byte {
+	return op.content
+}
+
+// Validate checks that the path is not a directory.
+func (op *AppendFileOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	if info, err := fsys.Stat(op.description.Path); err == nil && info.IsDir() {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        "cannot append to a directory",
+		}
+	}
+	return nil
+}
+
+// Execute appends the content with event handling.
+func (op *AppendFileOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *AppendFileOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	p := op.description.Path
+	current, err := captureRewriteTarget(fsys, p)
+	if err != nil {
+		return err
+	}
+	mode := defaultFileMode
+	var existing []byte
+	if current != nil {
+		existing, mode = current.content, current.mode
+	}
+
+	updated := make([]byte, 0, len(existing)+len(op.content))
+	updated = append(append(updated, existing...), op.content...)
+	if err := fsys.WriteFile(p, updated, mode); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", p, err)
+	}
+	op.previousSize = len(existing)
+	op.created = current == nil
+	op.applied = true
+	return nil
+}
+
+// Satisfied reports whether the file already ends with the content, as it
+// does after the append ran once. Appending nothing is always satisfied.
+func (op *AppendFileOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	if len(op.content) == 0 {
+		return true, nil
+	}
+	current, err := captureEntry(fsys, op.description.Path)
+	if err != nil || current == nil || current.itemType != "file" {
+		return false, err
+	}
+	return bytes.HasSuffix(current.content, op.content), nil
+}
+
+// Rollback removes the appended content, or the file if Execute created it.
+func (op *AppendFileOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.applied {
+		return nil
+	}
+	p := op.description.Path
+	if op.created {
+		if err := fsys.Remove(p); err != nil {
+			return err
+		}
+	} else if err := truncateFile(fsys, p, op.previousSize, op.content); err != nil {
+		return err
+	}
+	op.applied = false
+	return nil
+}
+
+// ReverseOps returns a truncate back to the current size, or a delete when
+// the file does not exist yet.
+func (op *AppendFileOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	reverseID := core.OperationID(fmt.Sprintf("reverse_%s", op.ID()))
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return []Operation{NewDeleteOperation(reverseID, op.description.Path)}, nil, nil
+	}
+	if info.IsDir() {
+		return nil, nil, fmt.Errorf("cannot append to directory %s", op.description.Path)
+	}
+	return []Operation{NewTruncateOperation(reverseID, op.description.Path, info.Size())}, nil, nil
+}
+
+// TruncateOperation shortens a file to a given size. It is the reverse of
+// AppendFileOperation; its own reverse appends the removed bytes back.
+type TruncateOperation struct {
+	*BaseOperation
+	size    int64
+	removed []byte
+	applied bool
+}
+
+// NewTruncateOperation creates a new truncate operation.
+func NewTruncateOperation(id core.OperationID, path string, size int64) *TruncateOperation {
+	op := &TruncateOperation{
+		BaseOperation: NewBaseOperation(id, "truncate", path),
+		size:          size,
+	}
+	op.SetDescriptionDetail("size", size)
+	return op
+}
+
+// Size returns the length the file is truncated to.
+func (op *TruncateOperation) Size() int64 {
+	return op.size
+}
+
+// Prerequisites returns the prerequisites for truncating a file
+func (op *TruncateOperation) Prerequisites() []core.Prerequisite {
+	return []core.Prerequisite{core.NewSourceExistsPrerequisite(op.description.Path)}
+}
+
+// Validate checks that the file exists and is not shorter than the size.
+func (op *TruncateOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if op.size < 0 {
+		return invalid("size cannot be negative", nil)
+	}
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return invalid("file to truncate does not exist", err)
+	}
+	if info.IsDir() {
+		return invalid("cannot truncate a directory", nil)
+	}
+	return nil
+}
+
+// Execute truncates the file with event handling.
+func (op *TruncateOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *TruncateOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	content, err := fs.ReadFile(fsys, op.description.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", op.description.Path, err)
+	}
+	if int64(len(content)) < op.size {
+		return fmt.Errorf("cannot truncate %s to %d bytes: it is only %d bytes", op.description.Path, op.size, len(content))
+	}
+	removed := append([]byte(nil), content[op.size:]...)
+	if err := truncateFile(fsys, op.description.Path, int(op.size), nil); err != nil {
+		return err
+	}
+	op.removed = removed
+	op.applied = true
+	return nil
+}
+
+// Satisfied reports whether the file already has the target size.
+func (op *TruncateOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	info, err := fsys.Stat(op.description.Path)
+	return err == nil && info.Mode().IsRegular() && info.Size() == op.size, nil
+}
+
+// Rollback appends the removed bytes back.
+func (op *TruncateOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.applied {
+		return nil
+	}
+	content, err := fs.ReadFile(fsys, op.description.Path)
+	if err != nil {
+		return err
+	}
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return err
+	}
+	if err := fsys.WriteFile(op.description.Path, append(content, op.removed...), info.Mode().Perm()); err != nil {
+		return err
+	}
+	op.applied = false
+	return nil
+}
+
+// ReverseOps returns an append of the bytes beyond the size, which are
+// counted against the backup budget.
+func (op *TruncateOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	content, err := fs.ReadFile(fsys, op.description.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", op.description.Path, err)
+	}
+	if int64(len(content)) <= op.size {
+		return nil, nil, nil
+	}
+	tail := content[op.size:]
+	sizeMB := float64(len(tail)) / (1024 * 1024)
+	if backupBudget, ok := budget.(*core.BackupBudget); ok && backupBudget != nil {
+		if err := backupBudget.ConsumeBackup(sizeMB); err != nil {
+			return nil, nil, fmt.Errorf("budget exceeded: cannot backup file '%s' (%.2fMB): %w", op.description.Path, sizeMB, err)
+		}
+	}
+	reverseID := core.OperationID(fmt.Sprintf("reverse_%s", op.ID()))
+	return []Operation{NewAppendFileOperation(reverseID, op.description.Path, tail)}, nil, nil
+}
+
+// truncateFile cuts the file at p down to size bytes. When suffix is given,
+// the bytes removed must equal it, so content written since is not lost.
+func truncateFile(fsys filesystem.FileSystem, p string, size int, suffix []byte) error {
+	content, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	if len(content) < size || (suffix != nil && !bytes.Equal(content[size:], suffix)) {
+		return fmt.Errorf("cannot truncate %s: it changed since it was appended to", p)
+	}
+	info, err := fsys.Stat(p)
+	if err != nil {
+		return err
+	}
+	return fsys.WriteFile(p, content[:size], info.Mode().Perm())
+}
@@ -0,0 +1,88 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func TestAppendFileOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("appends and rolls back", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("app.log", []byte("first\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewAppendFileOperation("append", "app.log", []byte("second\n"))
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{})
+		if err != nil || backup != nil {
+			t.Fatalf("ReverseOps = %v, %v", backup, err)
+		}
+		truncate, ok := reverseOps[0].(*operations.TruncateOperation)
+		if !ok || truncate.Size() != 6 {
+			t.Fatalf("expected a truncate to 6 bytes, got %v", reverseOps)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "app.log"); got != "first\nsecond\n" {
+			t.Errorf("got %q", got)
+		}
+		info, _ := fsys.Stat("app.log")
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("mode changed to %o", info.Mode().Perm())
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "app.log"); got != "first\n" {
+			t.Errorf("after rollback got %q", got)
+		}
+	})
+
+	t.Run("creates a missing file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := operations.NewAppendFileOperation("append", "new.log", []byte("x"))
+		reverseOps, _, err := op.ReverseOps(ctx, fsys, nil)
+		if err != nil || reverseOps[0].Describe().Type != "delete" {
+			t.Fatalf("expected a delete reverse op, got %v %v", reverseOps, err)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("new.log"); err == nil {
+			t.Error("expected rollback to remove the file")
+		}
+	})
+
+	t.Run("truncate reverses to an append", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("f", []byte("keep drop"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewTruncateOperation("truncate", "f", 4)
+		reverseOps, _, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{TotalMB: 1, RemainingMB: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "f"); got != "keep" {
+			t.Errorf("got %q", got)
+		}
+		if err := reverseOps[0].Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "f"); got != "keep drop" {
+			t.Errorf("after reverse got %q", got)
+		}
+	})
+}
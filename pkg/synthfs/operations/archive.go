@@ -116,19 +116,19 @@ func (op *CreateArchiveOperation) execute(ctx context.Context, fsys filesystem.F
 	formatStr := fmt.Sprintf("%v", format)
 	switch strings.ToLower(formatStr) {
 	case "zip":
-		return op.createZipArchive(archivePath, sources, fsys)
+		return op.createZipArchive(ctx, archivePath, sources, fsys)
 	case "tar", "tar.gz", "tgz":
-		return op.createTarArchive(archivePath, sources, fsys, strings.HasSuffix(strings.ToLower(archivePath), ".gz"))
+		return op.createTarArchive(ctx, archivePath, sources, fsys, strings.HasSuffix(strings.ToLower(archivePath), ".gz"))
 	default:
 		// Try to determine from file extension
 		ext := strings.ToLower(filepath.Ext(archivePath))
 		switch ext {
 		case ".zip":
-			return op.createZipArchive(archivePath, sources, fsys)
+			return op.createZipArchive(ctx, archivePath, sources, fsys)
 		case ".tar":
-			return op.createTarArchive(archivePath, sources, fsys, false)
+			return op.createTarArchive(ctx, archivePath, sources, fsys, false)
 		case ".gz", ".tgz":
-			return op.createTarArchive(archivePath, sources, fsys, true)
+			return op.createTarArchive(ctx, archivePath, sources, fsys, true)
 		default:
 			return fmt.Errorf("unsupported archive format: %s", formatStr)
 		}
@@ -136,7 +136,7 @@ func (op *CreateArchiveOperation) execute(ctx context.Context, fsys filesystem.F
 }
 
 // createZipArchive creates a ZIP archive.
-func (op *CreateArchiveOperation) createZipArchive(archivePath string, sources []string, fsys filesystem.FileSystem) error {
+func (op *CreateArchiveOperation) createZipArchive(ctx context.Context, archivePath string, sources []string, fsys filesystem.FileSystem) error {
 
 	// Create a buffer to hold the archive data
 	var buf bytes.Buffer
@@ -144,6 +144,9 @@ func (op *CreateArchiveOperation) createZipArchive(archivePath string, sources [
 	// Create zip writer
 	zipWriter := zip.NewWriter(&buf)
 
+	progress := core.ProgressFromContext(ctx)
+	progress.AddTotal(archiveSourceTotals(fsys, sources))
+
 	// Add sources to archive
 	for _, source := range sources {
 		// Try to stat the source
@@ -160,14 +163,15 @@ func (op *CreateArchiveOperation) createZipArchive(archivePath string, sources [
 			continue
 		}
 
-		// Read file content
+		// Read file content, stopping if the run is cancelled
+		progress.StartEntry(source)
 		var content []byte
 		file, err := fsys.Open(source)
 		if err != nil {
 			return fmt.Errorf("failed to open source %s: %w", source, err)
 		}
 		if reader, ok := file.(io.Reader); ok {
-			content, err = io.ReadAll(reader)
+			content, err = io.ReadAll(progress.Reader(core.ContextReader(ctx, reader)))
 			if err != nil {
 				return fmt.Errorf("failed to read source %s: %w", source, err)
 			}
@@ -185,6 +189,7 @@ func (op *CreateArchiveOperation) createZipArchive(archivePath string, sources [
 		if _, err := writer.Write(content); err != nil {
 			return fmt.Errorf("failed to write content for %s: %w", source, err)
 		}
+		progress.EntryDone()
 	}
 
 	// Close the zip writer
@@ -197,7 +202,7 @@ func (op *CreateArchiveOperation) createZipArchive(archivePath string, sources [
 }
 
 // createTarArchive creates a TAR or TAR.GZ archive.
-func (op *CreateArchiveOperation) createTarArchive(archivePath string, sources []string, fsys filesystem.FileSystem, compress bool) error {
+func (op *CreateArchiveOperation) createTarArchive(ctx context.Context, archivePath string, sources []string, fsys filesystem.FileSystem, compress bool) error {
 
 	// Create a buffer to hold the archive data
 	var buf bytes.Buffer
@@ -212,6 +217,9 @@ func (op *CreateArchiveOperation) createTarArchive(archivePath string, sources [
 	}
 	defer func() { _ = tarWriter.Close() }()
 
+	progress := core.ProgressFromContext(ctx)
+	progress.AddTotal(archiveSourceTotals(fsys, sources))
+
 	// Add sources to archive
 	for _, source := range sources {
 		// Try to stat the source
@@ -229,14 +237,15 @@ func (op *CreateArchiveOperation) createTarArchive(archivePath string, sources [
 			continue
 		}
 
-		// Read file content
+		// Read file content, stopping if the run is cancelled
+		progress.StartEntry(source)
 		var content []byte
 		file, err := fsys.Open(source)
 		if err != nil {
 			return fmt.Errorf("failed to open source %s: %w", source, err)
 		}
 		if reader, ok := file.(io.Reader); ok {
-			content, err = io.ReadAll(reader)
+			content, err = io.ReadAll(progress.Reader(core.ContextReader(ctx, reader)))
 			if err != nil {
 				return fmt.Errorf("failed to read source %s: %w", source, err)
 			}
@@ -259,6 +268,7 @@ func (op *CreateArchiveOperation) createTarArchive(archivePath string, sources [
 		if _, err := tarWriter.Write(content); err != nil {
 			return fmt.Errorf("failed to write content for %s: %w", source, err)
 		}
+		progress.EntryDone()
 	}
 
 	// Close the tar writer
@@ -270,7 +280,19 @@ func (op *CreateArchiveOperation) createTarArchive(archivePath string, sources [
 	return fsys.WriteFile(archivePath, buf.Bytes(), 0644)
 }
 
-
+// archiveSourceTotals returns the bytes and number of the source files that
+// will be added to an archive, for progress reporting.
+func archiveSourceTotals(fsys filesystem.FileSystem, sources []string) (int64, int) {
+	var bytes int64
+	var files int
+	for _, source := range sources {
+		if info, err := fsys.Stat(source); err == nil && !info.IsDir() {
+			bytes += info.Size()
+			files++
+		}
+	}
+	return bytes, files
+}
 
 // Validate checks if the archive can be created.
 func (op *CreateArchiveOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
@@ -428,12 +450,12 @@ func (op *UnarchiveOperation) execute(ctx context.Context, fsys filesystem.FileS
 	ext := strings.ToLower(filepath.Ext(archivePath))
 	switch ext {
 	case ".zip":
-		return op.extractZipArchive(archivePath, extractPath, patterns, fsys)
+		return op.extractZipArchive(ctx, archivePath, extractPath, patterns, fsys)
 	case ".tar":
-		return op.extractTarArchive(archivePath, extractPath, patterns, fsys, false)
+		return op.extractTarArchive(ctx, archivePath, extractPath, patterns, fsys, false)
 	case ".gz", ".tgz":
 		if strings.HasSuffix(archivePath, ".tar.gz") || ext == ".tgz" {
-			return op.extractTarArchive(archivePath, extractPath, patterns, fsys, true)
+			return op.extractTarArchive(ctx, archivePath, extractPath, patterns, fsys, true)
 		}
 		return fmt.Errorf("unsupported archive format: %s", ext)
 	default:
@@ -442,7 +464,7 @@ func (op *UnarchiveOperation) execute(ctx context.Context, fsys filesystem.FileS
 }
 
 // extractZipArchive extracts a ZIP archive.
-func (op *UnarchiveOperation) extractZipArchive(archivePath, extractPath string, patterns []string, fsys filesystem.FileSystem) error {
+func (op *UnarchiveOperation) extractZipArchive(ctx context.Context, archivePath, extractPath string, patterns []string, fsys filesystem.FileSystem) error {
 
 	// Open archive file through filesystem interface
 	file, err := fsys.Open(archivePath)
@@ -471,6 +493,13 @@ func (op *UnarchiveOperation) extractZipArchive(archivePath, extractPath string,
 	if err != nil {
 		return fmt.Errorf("failed to create zip reader: %w", err)
 	}
+
+	progress := core.ProgressFromContext(ctx)
+	for _, file := range reader.File {
+		if !file.FileInfo().IsDir() && (len(patterns) == 0 || matchesPatterns(file.Name, patterns)) {
+			progress.AddTotal(int64(file.UncompressedSize64), 1)
+		}
+	}
 	for _, file := range reader.File {
 		// Check patterns if provided
 		if len(patterns) > 0 && !matchesPatterns(file.Name, patterns) {
@@ -488,21 +517,26 @@ func (op *UnarchiveOperation) extractZipArchive(archivePath, extractPath string,
 		_ = fsys.MkdirAll(filepath.Dir(path), 0755)
 
 		// Extract file
+		progress.StartEntry(path)
 		rc, err := file.Open()
 		if err != nil {
 			continue
 		}
-		content, _ := io.ReadAll(rc)
+		content, _ := io.ReadAll(progress.Reader(core.ContextReader(ctx, rc)))
 		_ = rc.Close()
-		
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		_ = fsys.WriteFile(path, content, file.Mode())
+		progress.EntryDone()
 	}
 
 	return nil
 }
 
 // extractTarArchive extracts a TAR or TAR.GZ archive.
-func (op *UnarchiveOperation) extractTarArchive(archivePath, extractPath string, patterns []string, fsys filesystem.FileSystem, compressed bool) error {
+func (op *UnarchiveOperation) extractTarArchive(ctx context.Context, archivePath, extractPath string, patterns []string, fsys filesystem.FileSystem, compressed bool) error {
 	// Open archive file through filesystem interface
 	file, err := fsys.Open(archivePath)
 	if err != nil {
@@ -514,10 +548,15 @@ func (op *UnarchiveOperation) extractTarArchive(archivePath, extractPath string,
 		}
 	}()
 
-	// Convert file to io.Reader
+	// Convert file to io.Reader. The entries of a tar stream are not known
+	// up front, so progress is counted on the bytes read from the archive.
+	progress := core.ProgressFromContext(ctx)
+	if info, err := fsys.Stat(archivePath); err == nil {
+		progress.AddTotal(info.Size(), 0)
+	}
 	var reader io.Reader
 	if r, ok := file.(io.Reader); ok {
-		reader = r
+		reader = progress.Reader(core.ContextReader(ctx, r))
 	} else {
 		return fmt.Errorf("file does not implement io.Reader")
 	}
@@ -557,8 +596,13 @@ func (op *UnarchiveOperation) extractTarArchive(archivePath, extractPath string,
 			_ = fsys.MkdirAll(filepath.Dir(path), 0755)
 
 			// Extract file
+			progress.StartEntry(path)
 			content, _ := io.ReadAll(tarReader)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			_ = fsys.WriteFile(path, content, os.FileMode(header.Mode))
+			progress.EntryDone()
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
@@ -15,10 +16,16 @@ type BaseOperation struct {
 	id           core.OperationID
 	dependencies []core.OperationID
 	description  core.OperationDesc
-	item         interface{}            // Generic item interface
-	srcPath      string                 // For Copy/Move operations
-	dstPath      string                 // For Copy/Move operations
-	checksums    map[string]interface{} // Generic checksum storage
+	item         interface{}                   // Generic item interface
+	srcPath      string                        // For Copy/Move operations
+	dstPath      string                        // For Copy/Move operations
+	checksums    map[string]interface{}        // Generic checksum storage
+	ensure       bool                          // Skip when the desired state already holds
+	timeout      time.Duration                 // Overrides the run's operation timeout when positive
+	retryPolicy  *core.RetryPolicy             // Overrides the run's retry policy when set
+	outputs      map[string]interface{}        // Values produced while executing
+	inputs       []func(context.Context) error // Lazy inputs, resolved right before executing
+	condition    Condition                     // Runs the operation only when it holds
 }
 
 // NewBaseOperation creates a new base operation.
@@ -78,6 +85,11 @@ func (op *BaseOperation) AddDependency(depID core.OperationID) {
 	op.dependencies = append(op.dependencies, depID)
 }
 
+// Dependencies returns the IDs of the operations this one depends on.
+func (op *BaseOperation) Dependencies() []core.OperationID {
+	return op.dependencies
+}
+
 // SetDescriptionDetail sets a detail in the operation's description.
 func (op *BaseOperation) SetDescriptionDetail(key string, value interface{}) {
 	if op.description.Details == nil {
@@ -86,6 +98,50 @@ func (op *BaseOperation) SetDescriptionDetail(key string, value interface{}) {
 	op.description.Details[key] = value
 }
 
+// SetEnsure marks the operation to run in ensure mode: when it implements
+// EnsurableOperation and its desired state already holds, it is skipped.
+func (op *BaseOperation) SetEnsure(ensure bool) {
+	op.ensure = ensure
+}
+
+// EnsureMode reports whether the operation was marked with SetEnsure.
+func (op *BaseOperation) EnsureMode() bool {
+	return op.ensure
+}
+
+// SetTimeout bounds the execution of the operation, overriding the timeout
+// of the run it is part of. Zero keeps the run's timeout.
+func (op *BaseOperation) SetTimeout(timeout time.Duration) {
+	op.timeout = timeout
+}
+
+// Timeout returns the timeout set with SetTimeout, or zero.
+func (op *BaseOperation) Timeout() time.Duration {
+	return op.timeout
+}
+
+// SetRetryPolicy decides how the operation is retried when it fails,
+// overriding the policy of the run it is part of. Nil keeps the run's policy.
+func (op *BaseOperation) SetRetryPolicy(policy *core.RetryPolicy) {
+	op.retryPolicy = policy
+}
+
+// RetryPolicy returns the policy set with SetRetryPolicy, or nil.
+func (op *BaseOperation) RetryPolicy() *core.RetryPolicy {
+	return op.retryPolicy
+}
+
+// SetCondition makes the operation run only when condition holds. Nil
+// removes the condition.
+func (op *BaseOperation) SetCondition(condition Condition) {
+	op.condition = condition
+}
+
+// Condition returns the condition set with SetCondition, or nil.
+func (op *BaseOperation) Condition() Condition {
+	return op.condition
+}
+
 // SetChecksum stores a checksum record for a file path
 func (op *BaseOperation) SetChecksum(path string, checksum interface{}) {
 	if op.checksums == nil {
@@ -107,6 +163,50 @@ func (op *BaseOperation) GetAllChecksums() map[string]interface{} {
 	return op.checksums
 }
 
+// SetOutput stores a value the operation produced while executing, apart
+// from its description, for callers and for the inputs of later operations.
+func (op *BaseOperation) SetOutput(key string, value interface{}) {
+	if op.outputs == nil {
+		op.outputs = make(map[string]interface{})
+	}
+	op.outputs[key] = value
+}
+
+// GetOutput retrieves a value stored with SetOutput.
+func (op *BaseOperation) GetOutput(key string) (interface{}, bool) {
+	value, ok := op.outputs[key]
+	return value, ok
+}
+
+// GetAllOutputs returns a copy of the values stored with SetOutput.
+func (op *BaseOperation) GetAllOutputs() map[string]interface{} {
+	outputs := make(map[string]interface{}, len(op.outputs))
+	for key, value := range op.outputs {
+		outputs[key] = value
+	}
+	return outputs
+}
+
+// AddInput registers resolve to run right before the operation executes,
+// once the operations it depends on have run, and adds those as
+// dependencies. resolve typically reads their outputs into the operation.
+func (op *BaseOperation) AddInput(resolve func(context.Context) error, dependsOn ...core.OperationID) {
+	op.inputs = append(op.inputs, resolve)
+	for _, dep := range dependsOn {
+		op.AddDependency(dep)
+	}
+}
+
+// ResolveInputs runs the functions registered with AddInput, in order.
+func (op *BaseOperation) ResolveInputs(ctx context.Context) error {
+	for _, resolve := range op.inputs {
+		if err := resolve(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Execute performs the actual filesystem operation.
 // Subclasses should override this method.
 func (op *BaseOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
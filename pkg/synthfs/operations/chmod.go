@@ -0,0 +1,112 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// ChmodOperation changes the permission bits of an existing path.
+// It requires a filesystem implementing filesystem.ChmodFS.
+type ChmodOperation struct {
+	*BaseOperation
+	mode         fs.FileMode
+	previousMode fs.FileMode
+	changed      bool
+}
+
+// NewChmodOperation creates a new chmod operation.
+func NewChmodOperation(id core.OperationID, path string, mode fs.FileMode) *ChmodOperation {
+	op := &ChmodOperation{
+		BaseOperation: NewBaseOperation(id, "chmod", path),
+		mode:          mode.Perm(),
+	}
+	op.SetDescriptionDetail("mode", fmt.Sprintf("%04o", mode.Perm()))
+	return op
+}
+
+// Mode returns the permission bits the operation applies.
+func (op *ChmodOperation) Mode() fs.FileMode {
+	return op.mode
+}
+
+// Prerequisites returns the prerequisites for changing permissions
+func (op *ChmodOperation) Prerequisites() []core.Prerequisite {
+	return []core.Prerequisite{core.NewSourceExistsPrerequisite(op.description.Path)}
+}
+
+// Execute performs the chmod with event handling.
+func (op *ChmodOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *ChmodOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	chmodFS, ok := fsys.(filesystem.ChmodFS)
+	if !ok {
+		return fmt.Errorf("filesystem does not support chmod")
+	}
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", op.description.Path, err)
+	}
+	if err := chmodFS.Chmod(op.description.Path, op.mode); err != nil {
+		return fmt.Errorf("failed to chmod %s: %w", op.description.Path, err)
+	}
+	op.previousMode = info.Mode().Perm()
+	op.changed = true
+	return nil
+}
+
+// Satisfied reports whether the path already has the operation's permission bits.
+func (op *ChmodOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	info, err := fsys.Stat(op.description.Path)
+	return err == nil && info.Mode().Perm() == op.mode, nil
+}
+
+// Validate checks that the path exists.
+func (op *ChmodOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	if _, err := fsys.Stat(op.description.Path); err != nil {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        "chmod target does not exist",
+			Cause:         err,
+		}
+	}
+	return nil
+}
+
+// Rollback restores the mode the path had before Execute.
+func (op *ChmodOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.changed {
+		return nil
+	}
+	chmodFS, ok := fsys.(filesystem.ChmodFS)
+	if !ok {
+		return fmt.Errorf("filesystem does not support chmod")
+	}
+	if err := chmodFS.Chmod(op.description.Path, op.previousMode); err != nil {
+		return err
+	}
+	op.changed = false
+	return nil
+}
+
+// ReverseOps returns a chmod back to the path's current mode.
+func (op *ChmodOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot reverse chmod: %w", err)
+	}
+	reverseOp := NewChmodOperation(core.OperationID("reverse_"+string(op.ID())), op.description.Path, info.Mode().Perm())
+	return []Operation{reverseOp}, nil, nil
+}
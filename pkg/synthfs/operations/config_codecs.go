@@ -0,0 +1,389 @@
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configCodec converts one config format to and from a node tree. Codecs are
+// created per file and may remember formatting details between decode and encode.
+type configCodec interface {
+	// decode parses content, which may be empty, and returns the top-level table.
+	decode(content []byte) (*yaml.Node, error)
+	// encode renders the top-level table returned by decode.
+	encode(root *yaml.Node) ([]byte, error)
+}
+
+func newConfigCodec(format ConfigFormat) (configCodec, error) {
+	switch format {
+	case ConfigFormatJSON:
+		return &jsonConfigCodec{}, nil
+	case ConfigFormatYAML:
+		return &yamlConfigCodec{}, nil
+	case ConfigFormatTOML:
+		return &tomlConfigCodec{}, nil
+	case ConfigFormatINI:
+		return &iniConfigCodec{}, nil
+	case "":
+		return nil, fmt.Errorf("config format is not set and cannot be inferred from the path")
+	}
+	return nil, fmt.Errorf("unsupported config format %q", format)
+}
+
+// configSource is the text a node was decoded from, with what the codec
+// would have written for it at the time.
+type configSource struct {
+	text    string
+	encoded string
+}
+
+// unlessChanged returns the decoded text when encoded is still what the codec
+// would write for it, so lines no edit touched keep their formatting.
+func (s configSource) unlessChanged(encoded string) string {
+	if s.text != "" && s.encoded == encoded {
+		return s.text
+	}
+	return encoded
+}
+
+// detectIndent returns the leading whitespace of the first indented line.
+func detectIndent(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || len(trimmed) == len(line) || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		return line[:len(line)-len(trimmed)]
+	}
+	return ""
+}
+
+// yamlConfigCodec edits YAML through yaml.v3, which keeps comments and key
+// order but normalises indentation and quoting of the values it rewrites.
+type yamlConfigCodec struct {
+	doc    *yaml.Node
+	indent int
+}
+
+func (c *yamlConfigCodec) decode(content []byte) (*yaml.Node, error) {
+	c.indent = len(detectIndent(content))
+	if c.indent < 2 {
+		c.indent = 2
+	}
+	c.doc = &yaml.Node{}
+	if len(bytes.TrimSpace(content)) > 0 {
+		if err := yaml.Unmarshal(content, c.doc); err != nil {
+			return nil, err
+		}
+	}
+	if c.doc.Kind != yaml.DocumentNode {
+		c.doc = &yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(c.doc.Content) == 0 {
+		c.doc.Content = []*yaml.Node{newConfigMapping()}
+	}
+	root := c.doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.ShortTag() == "!!null" {
+		root = newConfigMapping()
+		c.doc.Content[0] = root
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("top level is not a mapping")
+	}
+	return root, nil
+}
+
+func (c *yamlConfigCodec) encode(root *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(c.indent)
+	if err := encoder.Encode(c.doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonConfigCodec decodes JSON token by token so object keys keep their order,
+// and re-encodes it with the file's indentation.
+type jsonConfigCodec struct {
+	indent       string
+	finalNewline bool
+}
+
+func (c *jsonConfigCodec) decode(content []byte) (*yaml.Node, error) {
+	c.indent = detectIndent(content)
+	if c.indent == "" {
+		c.indent = "  "
+	}
+	c.finalNewline = len(content) == 0 || bytes.HasSuffix(content, []byte("\n"))
+	if len(bytes.TrimSpace(content)) == 0 {
+		return newConfigMapping(), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	root, err := decodeJSONNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("top level is not an object")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the top-level object")
+	}
+	return root, nil
+}
+
+func decodeJSONNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		var node *yaml.Node
+		switch t {
+		case '{':
+			node = newConfigMapping()
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeJSONNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, newConfigScalar("!!str", keyToken.(string)), value)
+			}
+		case '[':
+			node = newConfigSequence()
+			for decoder.More() {
+				value, err := decodeJSONNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, value)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", t)
+		}
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return newConfigScalar("!!str", t), nil
+	case json.Number:
+		if strings.ContainsAny(string(t), ".eE") {
+			return newConfigScalar("!!float", string(t)), nil
+		}
+		return newConfigScalar("!!int", string(t)), nil
+	case bool:
+		return newConfigScalar("!!bool", strconv.FormatBool(t)), nil
+	case nil:
+		return newConfigScalar("!!null", "null"), nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", token)
+}
+
+func (c *jsonConfigCodec) encode(root *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.writeNode(&buf, root, 0); err != nil {
+		return nil, err
+	}
+	if c.finalNewline {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *jsonConfigCodec) writeNode(buf *bytes.Buffer, node *yaml.Node, depth int) error {
+	node = resolveConfigAlias(node)
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return c.writeNode(buf, node.Content[0], depth)
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "[", "]", 1
+		if node.Kind == yaml.MappingNode {
+			open, close, step = "{", "}", 2
+		}
+		if len(node.Content) == 0 {
+			buf.WriteString(open + close)
+			return nil
+		}
+		buf.WriteString(open + "\n")
+		for i := 0; i < len(node.Content); i += step {
+			buf.WriteString(strings.Repeat(c.indent, depth+1))
+			value := node.Content[i]
+			if step == 2 {
+				buf.WriteString(jsonString(node.Content[i].Value) + ": ")
+				value = node.Content[i+1]
+			}
+			if err := c.writeNode(buf, value, depth+1); err != nil {
+				return err
+			}
+			if i+step < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(c.indent, depth) + close)
+		return nil
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float":
+			if !json.Valid([]byte(node.Value)) {
+				return fmt.Errorf("%s is not a valid JSON number", node.Value)
+			}
+			buf.WriteString(node.Value)
+		case "!!bool":
+			buf.WriteString(node.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			buf.WriteString(jsonString(node.Value))
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot encode node of kind %d as JSON", node.Kind)
+}
+
+// jsonString quotes s without escaping HTML characters, as package.json and
+// similar files expect "a && b" to stay readable.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// iniConfigCodec handles INI files: key = value lines, optionally grouped under
+// [section] headers. All values are strings, and sections are one level deep.
+// Comment and blank lines are kept with the entry that follows them, and
+// lines no edit changed are written back as they were read. New and edited
+// entries use the separator of the first entry.
+type iniConfigCodec struct {
+	separator string
+	source    map[*yaml.Node]configSource // Lines of keys and section names
+	trailer   string
+}
+
+func (c *iniConfigCodec) decode(content []byte) (*yaml.Node, error) {
+	c.separator = " = "
+	c.source = make(map[*yaml.Node]configSource)
+	root := newConfigMapping()
+	current := root
+	var pending strings.Builder
+	separatorSeen := false
+
+	for n, raw := range splitConfigLines(content) {
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+			pending.WriteString(raw + "\n")
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section header", n+1)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			section, _ := configChild(root, configPathSegment{key: name})
+			if section == nil {
+				key := newConfigScalar("!!str", name)
+				key.HeadComment, key.Line = pending.String(), n+1
+				c.source[key] = configSource{text: raw, encoded: "[" + name + "]"}
+				section = newConfigMapping()
+				root.Content = append(root.Content, key, section)
+			}
+			pending.Reset()
+			current = section
+		default:
+			idx := strings.IndexAny(line, "=:")
+			if idx <= 0 {
+				return nil, fmt.Errorf("line %d: expected key = value", n+1)
+			}
+			if !separatorSeen {
+				separatorSeen = true
+				c.separator = string(line[idx])
+				if line[idx-1] == ' ' {
+					c.separator = " " + c.separator + " "
+				}
+			}
+			key := newConfigScalar("!!str", strings.TrimSpace(line[:idx]))
+			key.HeadComment, key.Line = pending.String(), n+1
+			pending.Reset()
+			value := newConfigScalar("!!str", strings.TrimSpace(line[idx+1:]))
+			c.source[key] = configSource{text: raw, encoded: c.entry(key, value)}
+			current.Content = append(current.Content, key, value)
+		}
+	}
+	c.trailer = pending.String()
+	return root, nil
+}
+
+func (c *iniConfigCodec) encode(root *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	var sections []int
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], resolveConfigAlias(root.Content[i+1])
+		if value.Kind == yaml.MappingNode {
+			sections = append(sections, i)
+			continue
+		}
+		if err := c.writeEntry(&b, "", key, value); err != nil {
+			return nil, err
+		}
+	}
+	for _, i := range sections {
+		key, section := root.Content[i], resolveConfigAlias(root.Content[i+1])
+		if key.Line == 0 && b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(key.HeadComment)
+		b.WriteString(c.source[key].unlessChanged("["+key.Value+"]") + "\n")
+		for j := 0; j+1 < len(section.Content); j += 2 {
+			if err := c.writeEntry(&b, key.Value+".", section.Content[j], resolveConfigAlias(section.Content[j+1])); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b.WriteString(c.trailer)
+	return []byte(b.String()), nil
+}
+
+func (c *iniConfigCodec) writeEntry(b *strings.Builder, prefix string, key, value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("ini value %s%s must be a string, number or boolean", prefix, key.Value)
+	}
+	b.WriteString(key.HeadComment)
+	b.WriteString(c.source[key].unlessChanged(c.entry(key, value)) + "\n")
+	return nil
+}
+
+func (c *iniConfigCodec) entry(key, value *yaml.Node) string {
+	return key.Value + c.separator + value.Value
+}
+
+// splitConfigLines splits content into lines without their line endings.
+func splitConfigLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
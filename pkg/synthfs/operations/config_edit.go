@@ -0,0 +1,231 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// ConfigFormat names a structured file format that EditConfigOperation can edit.
+type ConfigFormat string
+
+const (
+	ConfigFormatJSON ConfigFormat = "json"
+	ConfigFormatYAML ConfigFormat = "yaml"
+	ConfigFormatTOML ConfigFormat = "toml"
+	ConfigFormatINI  ConfigFormat = "ini"
+)
+
+// ConfigFormatForPath infers the format of a config file from its extension.
+func ConfigFormatForPath(p string) (ConfigFormat, error) {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".json":
+		return ConfigFormatJSON, nil
+	case ".yaml", ".yml":
+		return ConfigFormatYAML, nil
+	case ".toml":
+		return ConfigFormatTOML, nil
+	case ".ini", ".cfg", ".conf":
+		return ConfigFormatINI, nil
+	}
+	return "", fmt.Errorf("cannot infer config format of %s", p)
+}
+
+// ConfigEditAction is the kind of change a ConfigEdit makes.
+type ConfigEditAction string
+
+const (
+	ConfigActionSet    ConfigEditAction = "set"
+	ConfigActionDelete ConfigEditAction = "delete"
+	ConfigActionMerge  ConfigEditAction = "merge"
+	ConfigActionAppend ConfigEditAction = "append"
+)
+
+// ConfigEdit is one change to a structured config file. Path is a key path
+// such as "server.port", "plugins[0].name" or `scripts["build:prod"]`.
+type ConfigEdit struct {
+	Action ConfigEditAction
+	Path   string
+	Value  interface{}
+}
+
+// ConfigSet sets the value at path, creating intermediate tables as needed.
+func ConfigSet(path string, value interface{}) ConfigEdit {
+	return ConfigEdit{Action: ConfigActionSet, Path: path, Value: value}
+}
+
+// ConfigDelete removes the value at path. A missing path is not an error.
+func ConfigDelete(path string) ConfigEdit {
+	return ConfigEdit{Action: ConfigActionDelete, Path: path}
+}
+
+// ConfigMerge deep-merges a map fragment into the table at path, or into the
+// document root when path is empty. Nested tables are merged key by key;
+// lists and scalars in the fragment replace existing values.
+func ConfigMerge(path string, fragment interface{}) ConfigEdit {
+	return ConfigEdit{Action: ConfigActionMerge, Path: path, Value: fragment}
+}
+
+// ConfigAppend appends value to the list at path unless an equal value is
+// already present. A missing list is created.
+func ConfigAppend(path string, value interface{}) ConfigEdit {
+	return ConfigEdit{Action: ConfigActionAppend, Path: path, Value: value}
+}
+
+func (e ConfigEdit) String() string {
+	if e.Path == "" {
+		return string(e.Action)
+	}
+	return fmt.Sprintf("%s %s", e.Action, e.Path)
+}
+
+// EditConfigOperation parses a JSON, YAML, TOML or INI file, applies a list of
+// edits and writes the result back. Key order is kept in every format, and
+// comments in YAML, TOML and INI. TOML and INI lines no edit touches are
+// written back as they were. When the edits change nothing the file is
+// left untouched. A missing file is created from an empty document.
+type EditConfigOperation struct {
+	*BaseOperation
+	fileRewrite
+	format ConfigFormat
+	edits  []ConfigEdit
+}
+
+// NewEditConfigOperation creates an edit_config operation. An empty format is
+// inferred from the file extension.
+func NewEditConfigOperation(id core.OperationID, path string, format ConfigFormat, edits ...ConfigEdit) *EditConfigOperation {
+	op := &EditConfigOperation{
+		BaseOperation: NewBaseOperation(id, "edit_config", path),
+		format:        format,
+		edits:         edits,
+	}
+	if format == "" {
+		if inferred, err := ConfigFormatForPath(path); err == nil {
+			op.format = inferred
+		}
+	}
+	descriptions := make([]string, len(edits))
+	for i, edit := range edits {
+		descriptions[i] = edit.String()
+	}
+	op.SetDescriptionDetail("format", string(op.format))
+	op.SetDescriptionDetail("edits", descriptions)
+	return op
+}
+
+// Format returns the format the file is parsed as.
+func (op *EditConfigOperation) Format() ConfigFormat {
+	return op.format
+}
+
+// Edits returns the edits the operation applies, in order.
+func (op *EditConfigOperation) Edits() []ConfigEdit {
+	return op.edits
+}
+
+// Validate checks the format, the edits and that the path is not a directory.
+func (op *EditConfigOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if _, err := newConfigCodec(op.format); err != nil {
+		return invalid("unsupported config format", err)
+	}
+	if len(op.edits) == 0 {
+		return invalid("no config edits given", nil)
+	}
+	for _, edit := range op.edits {
+		if err := validateConfigEdit(edit); err != nil {
+			return invalid(fmt.Sprintf("invalid edit %q", edit.String()), err)
+		}
+	}
+	if info, err := fsys.Stat(op.description.Path); err == nil && info.IsDir() {
+		return invalid("config path is a directory", nil)
+	}
+	return nil
+}
+
+// Execute applies the edits with event handling.
+func (op *EditConfigOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *EditConfigOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	changed, err := op.rewrite(fsys, op.description.Path, op.render)
+	if err != nil {
+		return err
+	}
+	op.SetOutput("changed", changed)
+	return nil
+}
+
+func (op *EditConfigOperation) render(content []byte) ([]byte, bool, error) {
+	output, changed, err := applyConfigEdits(op.format, content, op.edits)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to edit %s: %w", op.description.Path, err)
+	}
+	return output, changed, nil
+}
+
+// Satisfied reports whether the edits would leave the file unchanged.
+func (op *EditConfigOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	return rewriteSatisfied(fsys, op.description.Path, op.render)
+}
+
+// Rollback restores the file as it was before Execute, removing it if Execute created it.
+func (op *EditConfigOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.rollback(fsys, op.description.Path)
+}
+
+// ReverseOps backs up the current file into the budget. Edits that would not
+// change the file need no reverse operations.
+func (op *EditConfigOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	return reverseOpsForRewrite(op.ID(), fsys, op.description.Path, op.render, budget)
+}
+
+// applyConfigEdits parses content in the given format, applies the edits and
+// encodes the result. Content is only re-encoded when an edit changed the
+// document, so untouched files keep their exact bytes.
+func applyConfigEdits(format ConfigFormat, content []byte, edits []ConfigEdit) ([]byte, bool, error) {
+	codec, err := newConfigCodec(format)
+	if err != nil {
+		return nil, false, err
+	}
+	root, err := codec.decode(content)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	changed := false
+	for _, edit := range edits {
+		editChanged, err := applyConfigEdit(root, edit)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", edit.String(), err)
+		}
+		changed = changed || editChanged
+	}
+	if !changed {
+		return content, false, nil
+	}
+
+	output, err := codec.encode(root)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+	return output, true, nil
+}
@@ -0,0 +1,411 @@
+package operations_test
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// editConfig writes content to name, runs an edit_config operation on it and
+// returns the resulting file content.
+func editConfig(t *testing.T, name, content string, edits ...operations.ConfigEdit) string {
+	t.Helper()
+	fsys := filesystem.NewTestFileSystem()
+	if err := fsys.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	op := operations.NewEditConfigOperation("edit", name, "", edits...)
+	if err := op.Validate(context.Background(), nil, fsys); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if err := op.Execute(context.Background(), nil, fsys); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	output, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func TestEditConfigJSON(t *testing.T) {
+	input := `{
+  "name": "app",
+  "scripts": {
+    "build": "tsc && vite build"
+  },
+  "keywords": ["a"]
+}
+`
+
+	t.Run("set keeps key order and indentation", func(t *testing.T) {
+		got := editConfig(t, "package.json", input,
+			operations.ConfigSet("version", "1.2.0"),
+			operations.ConfigSet(`scripts["test:unit"]`, "vitest"),
+		)
+		want := `{
+  "name": "app",
+  "scripts": {
+    "build": "tsc && vite build",
+    "test:unit": "vitest"
+  },
+  "keywords": [
+    "a"
+  ],
+  "version": "1.2.0"
+}
+`
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("append if absent and delete", func(t *testing.T) {
+		got := editConfig(t, "package.json", input,
+			operations.ConfigAppend("keywords", "a"),
+			operations.ConfigAppend("keywords", "b"),
+			operations.ConfigDelete("scripts.build"),
+		)
+		if !strings.Contains(got, `"keywords": [
+    "a",
+    "b"
+  ]`) {
+			t.Errorf("expected b appended once, got:\n%s", got)
+		}
+		if !strings.Contains(got, `"scripts": {}`) {
+			t.Errorf("expected build script deleted, got:\n%s", got)
+		}
+	})
+
+	t.Run("deep merge", func(t *testing.T) {
+		got := editConfig(t, "package.json", input, operations.ConfigMerge("", map[string]interface{}{
+			"scripts": map[string]interface{}{"lint": "eslint ."},
+			"private": true,
+		}))
+		for _, want := range []string{`"build": "tsc && vite build",`, `"lint": "eslint ."`, `"private": true`} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected %s in:\n%s", want, got)
+			}
+		}
+	})
+
+	t.Run("tab indentation", func(t *testing.T) {
+		got := editConfig(t, "a.json", "{\n\t\"a\": 1\n}\n", operations.ConfigSet("b", 2.5))
+		if got != "{\n\t\"a\": 1,\n\t\"b\": 2.5\n}\n" {
+			t.Errorf("got %q", got)
+		}
+	})
+}
+
+func TestEditConfigYAML(t *testing.T) {
+	input := `# Service config
+server:
+  port: 8080 # public port
+  hosts:
+    - a.example.com
+debug: false
+`
+	got := editConfig(t, "config.yaml", input,
+		operations.ConfigSet("server.port", 9090),
+		operations.ConfigAppend("server.hosts", "b.example.com"),
+		operations.ConfigSet("logging.level", "info"),
+	)
+	want := `# Service config
+server:
+  port: 9090 # public port
+  hosts:
+    - a.example.com
+    - b.example.com
+debug: false
+logging:
+  level: info
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEditConfigTOML(t *testing.T) {
+	input := `# Project metadata
+[project]
+name = "app" # the name
+version = "0.1.0"
+dependencies = [
+    "requests",
+]
+
+[tool.black]
+line-length = 88
+
+[[tool.targets]]
+name = "a"
+`
+
+	t.Run("round trip of untouched parts", func(t *testing.T) {
+		got := editConfig(t, "pyproject.toml", input, operations.ConfigSet("project.version", "0.2.0"))
+		want := strings.Replace(input, `"0.1.0"`, `"0.2.0"`, 1)
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("editing one key leaves other lines byte for byte", func(t *testing.T) {
+		input := `# Project metadata
+[ project ]   # main table
+  name="app"    # the name
+  version = "0.1.0"
+dependencies = [
+    "requests",  # http
+    "click",
+]
+point = {x=1,y=2}
+
+[tool.black]
+line-length=88
+`
+		got := editConfig(t, "pyproject.toml", input, operations.ConfigSet("tool.black.line-length", 100))
+		want := strings.Replace(input, "line-length=88", "line-length = 100", 1)
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("new tables, arrays and arrays of tables", func(t *testing.T) {
+		got := editConfig(t, "pyproject.toml", input,
+			operations.ConfigAppend("project.dependencies", "click"),
+			operations.ConfigSet("tool.ruff.select", []string{"E", "F"}),
+			operations.ConfigSet("tool.targets[0].enabled", true),
+		)
+		for _, want := range []string{
+			"dependencies = [\n    \"requests\",\n    \"click\",\n]",
+			"[tool.ruff]\nselect = [\n    \"E\",\n    \"F\",\n]",
+			"[[tool.targets]]\nname = \"a\"\nenabled = true\n",
+			"# Project metadata\n[project]\nname = \"app\" # the name\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected %q in:\n%s", want, got)
+			}
+		}
+	})
+
+	t.Run("scalar types and dotted keys", func(t *testing.T) {
+		got := editConfig(t, "a.toml", "a.b = 1\nwhen = 1979-05-27 07:32:00Z\npoint = { x = 1, y = 2 }\n",
+			operations.ConfigSet("a.c", 1.5),
+			operations.ConfigSet("point.y", 3),
+		)
+		want := "a.b = 1\na.c = 1.5\nwhen = 1979-05-27 07:32:00Z\npoint = { x = 1, y = 3 }\n"
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestEditConfigINI(t *testing.T) {
+	input := `; global settings
+name=app
+
+[database]
+host=localhost
+port=5432
+`
+	got := editConfig(t, "settings.ini", input,
+		operations.ConfigSet("database.port", 6543),
+		operations.ConfigDelete("database.host"),
+		operations.ConfigSet("cache.ttl", "60"),
+	)
+	want := `; global settings
+name=app
+
+[database]
+port=6543
+
+[cache]
+ttl=60
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	t.Run("editing one key leaves other lines byte for byte", func(t *testing.T) {
+		input := `; global settings
+name = app
+other=1
+  indented =  spaced  
+
+[ database ]
+host: localhost
+port=5432
+`
+		got := editConfig(t, "settings.ini", input, operations.ConfigSet("database.port", 6543))
+		want := strings.Replace(input, "port=5432", "port = 6543", 1)
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestEditConfigOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no change leaves the file untouched", func(t *testing.T) {
+		input := "{\"a\": 1, \"list\": [\"x\"]}"
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("a.json", []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditConfigOperation("edit", "a.json", "",
+			operations.ConfigSet("a", 1.0),
+			operations.ConfigAppend("list", "x"),
+			operations.ConfigDelete("missing.key"),
+		)
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{TotalMB: 1, RemainingMB: 1})
+		if err != nil || len(reverseOps) != 0 || backup != nil {
+			t.Errorf("expected no reverse ops for a no-op edit, got %v, %v, %v", reverseOps, backup, err)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "a.json"); got != input {
+			t.Errorf("file was rewritten: %q", got)
+		}
+		if output(op, "changed") != false {
+			t.Errorf("expected changed=false, got %v", output(op, "changed"))
+		}
+	})
+
+	t.Run("rollback restores the original", func(t *testing.T) {
+		input := "server:\n  port: 80\n"
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("c.yml", []byte(input), 0600); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditConfigOperation("edit", "c.yml", "", operations.ConfigSet("server.port", 81))
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "c.yml"); got != input {
+			t.Errorf("content after rollback = %q", got)
+		}
+		info, _ := fsys.Stat("c.yml")
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("mode after rollback = %o", info.Mode().Perm())
+		}
+	})
+
+	t.Run("missing file is created and removed on rollback", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := operations.NewEditConfigOperation("edit", "new.toml", "", operations.ConfigSet("a", "b"))
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "new.toml"); got != "a = \"b\"\n" {
+			t.Errorf("got %q", got)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("new.toml"); err == nil {
+			t.Error("expected new.toml to be removed")
+		}
+	})
+
+	t.Run("reverse ops back up the original", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("a.ini", []byte("a=1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditConfigOperation("edit", "a.ini", "", operations.ConfigSet("a", 2))
+		budget := &core.BackupBudget{TotalMB: 1, RemainingMB: 1}
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, budget)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, ok := backup.(*core.BackupData)
+		if !ok || string(data.BackupContent) != "a=1\n" {
+			t.Errorf("unexpected backup %v", backup)
+		}
+		if len(reverseOps) != 1 || reverseOps[0].ID() != "reverse_edit" || budget.UsedMB <= 0 {
+			t.Errorf("unexpected reverse ops %v (budget used %.6f)", reverseOps, budget.UsedMB)
+		}
+	})
+
+	t.Run("execution errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			file    string
+			content string
+			edit    operations.ConfigEdit
+			wantErr string
+		}{
+			{"set through scalar", "a.json", `{"a": 1}`, operations.ConfigSet("a.b", 2), "a is not a table"},
+			{"append to scalar", "a.yaml", "a: 1\n", operations.ConfigAppend("a", 2), "a is not a list"},
+			{"index out of range", "a.json", `{"a": []}`, operations.ConfigSet("a[0]", 2), "out of range"},
+			{"invalid json", "a.json", `{"a": `, operations.ConfigSet("b", 1), "failed to parse json"},
+			{"nested ini value", "a.ini", "", operations.ConfigSet("a.b.c", 1), "must be a string"},
+			{"null in toml", "a.toml", "", operations.ConfigSet("a", nil), "no null value"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				fsys := filesystem.NewTestFileSystem()
+				if err := fsys.WriteFile(tt.file, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				op := operations.NewEditConfigOperation("edit", tt.file, "", tt.edit)
+				err := op.Execute(ctx, nil, fsys)
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		tests := []struct {
+			name string
+			op   *operations.EditConfigOperation
+		}{
+			{"unknown format", operations.NewEditConfigOperation("edit", "a.txt", "", operations.ConfigSet("a", 1))},
+			{"no edits", operations.NewEditConfigOperation("edit", "a.json", "")},
+			{"bad path", operations.NewEditConfigOperation("edit", "a.json", "", operations.ConfigSet("a..b", 1))},
+			{"merge non-map", operations.NewEditConfigOperation("edit", "a.json", "", operations.ConfigMerge("", []int{1}))},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := tt.op.Validate(ctx, nil, fsys); err == nil {
+					t.Error("expected validation error")
+				}
+			})
+		}
+
+		explicit := operations.NewEditConfigOperation("edit", "settings", operations.ConfigFormatYAML, operations.ConfigSet("a", 1))
+		if err := explicit.Validate(ctx, nil, fsys); err != nil {
+			t.Errorf("explicit format should validate: %v", err)
+		}
+	})
+}
+
+func readFile(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// output returns the output key of op, or nil if it stored none.
+func output(op interface {
+	GetOutput(key string) (interface{}, bool)
+}, key string) interface{} {
+	value, _ := op.GetOutput(key)
+	return value
+}
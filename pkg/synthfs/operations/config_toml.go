@@ -0,0 +1,741 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tomlConfigCodec reads and writes TOML: tables, arrays of tables, dotted
+// keys, inline tables, arrays and all scalar types. Comment and blank lines
+// are kept with the entry or table that follows them, and trailing comments
+// with their value. Entries and headers that no edit changed are written
+// back as they were read; an edited value is re-emitted without the comments
+// inside a multi-line array. Plain keys of a table are written before its
+// sub-tables.
+type tomlConfigCodec struct {
+	explicit map[*yaml.Node]bool         // Tables declared with a [header]
+	dotted   map[*yaml.Node]bool         // Tables created by dotted keys such as a.b = 1
+	source   map[*yaml.Node]configSource // Lines of key nodes and table headers
+	trailer  string
+}
+
+var errTOMLIncomplete = errors.New("unterminated value")
+
+func (c *tomlConfigCodec) decode(content []byte) (*yaml.Node, error) {
+	c.explicit = make(map[*yaml.Node]bool)
+	c.dotted = make(map[*yaml.Node]bool)
+	c.source = make(map[*yaml.Node]configSource)
+	root := newConfigMapping()
+	current := root
+	var pending strings.Builder
+
+	lines := splitConfigLines(content)
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimSpace(lines[n])
+		lineNo := n + 1
+		if line == "" || line[0] == '#' {
+			pending.WriteString(lines[n] + "\n")
+			continue
+		}
+
+		if line[0] == '[' {
+			isArray := strings.HasPrefix(line, "[[")
+			p := &tomlParser{s: line, pos: 1}
+			closing := "]"
+			if isArray {
+				p.pos, closing = 2, "]]"
+			}
+			keys, err := p.key()
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			p.skipSpace(false)
+			if !strings.HasPrefix(p.s[p.pos:], closing) {
+				return nil, fmt.Errorf("line %d: expected %q after table name", lineNo, closing)
+			}
+			p.pos += len(closing)
+			comment, err := p.trailingComment()
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+
+			var table *yaml.Node
+			if isArray {
+				table, err = c.arrayTable(root, keys)
+			} else {
+				table, err = c.table(root, keys)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			c.explicit[table] = true
+			table.HeadComment, table.LineComment, table.Line = pending.String(), comment, lineNo
+			pending.Reset()
+			header := "[" + formatTOMLKeyPath(keys) + "]"
+			if isArray {
+				header = "[" + header + "]"
+			}
+			c.source[table] = configSource{text: lines[n], encoded: tomlHeader(header, table)}
+			current = table
+			continue
+		}
+
+		p := &tomlParser{s: line}
+		keys, err := p.key()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		p.skipSpace(false)
+		if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+			return nil, fmt.Errorf("line %d: expected '=' after key", lineNo)
+		}
+		p.pos++
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("line %d: missing value", lineNo)
+		}
+
+		// Multi-line arrays and strings continue on the following lines
+		first, start := n, p.pos
+		value, err := p.value()
+		for errors.Is(err, errTOMLIncomplete) && n+1 < len(lines) {
+			n++
+			p.s += "\n" + lines[n]
+			p.pos = start
+			value, err = p.value()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if value.LineComment, err = p.trailingComment(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		key, err := c.setDottedKey(current, keys, value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		key.HeadComment, key.Line = pending.String(), lineNo
+		pending.Reset()
+		if entry, err := tomlEntry(formatTOMLKeyPath(keys), value); err == nil {
+			c.source[key] = configSource{text: strings.Join(lines[first:n+1], "\n"), encoded: entry}
+		}
+	}
+	c.trailer = pending.String()
+	return root, nil
+}
+
+// table returns the table named by keys, creating missing tables. A key that
+// holds an array of tables refers to its last element.
+func (c *tomlConfigCodec) table(root *yaml.Node, keys []string) (*yaml.Node, error) {
+	node := root
+	for i, key := range keys {
+		child, _ := configChild(node, configPathSegment{key: key})
+		if child == nil {
+			child = newConfigMapping()
+			node.Content = append(node.Content, newConfigScalar("!!str", key), child)
+		}
+		if isTOMLArrayOfTables(child) && len(child.Content) > 0 {
+			child = child.Content[len(child.Content)-1]
+		}
+		if child.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a table", strings.Join(keys[:i+1], "."))
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// arrayTable appends a new table to the array of tables named by keys.
+func (c *tomlConfigCodec) arrayTable(root *yaml.Node, keys []string) (*yaml.Node, error) {
+	parent, err := c.table(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	name := keys[len(keys)-1]
+	list, _ := configChild(parent, configPathSegment{key: name})
+	if list == nil {
+		list = newConfigSequence()
+		parent.Content = append(parent.Content, newConfigScalar("!!str", name), list)
+	}
+	if list.Kind != yaml.SequenceNode || (len(list.Content) > 0 && !isTOMLArrayOfTables(list)) {
+		return nil, fmt.Errorf("%s is not an array of tables", strings.Join(keys, "."))
+	}
+	table := newConfigMapping()
+	list.Content = append(list.Content, table)
+	return table, nil
+}
+
+// setDottedKey adds keys = value to table, creating the tables implied by a
+// dotted key, and returns the node of the last key.
+func (c *tomlConfigCodec) setDottedKey(table *yaml.Node, keys []string, value *yaml.Node) (*yaml.Node, error) {
+	for i, key := range keys[:len(keys)-1] {
+		child, _ := configChild(table, configPathSegment{key: key})
+		if child == nil {
+			child = newConfigMapping()
+			if table.Style&yaml.FlowStyle != 0 {
+				child.Style = yaml.FlowStyle
+			} else {
+				c.dotted[child] = true
+			}
+			table.Content = append(table.Content, newConfigScalar("!!str", key), child)
+		}
+		if child.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a table", strings.Join(keys[:i+1], "."))
+		}
+		table = child
+	}
+	name := keys[len(keys)-1]
+	if existing, _ := configChild(table, configPathSegment{key: name}); existing != nil {
+		return nil, fmt.Errorf("duplicate key %s", strings.Join(keys, "."))
+	}
+	key := newConfigScalar("!!str", name)
+	table.Content = append(table.Content, key, value)
+	return key, nil
+}
+
+// isTOMLArrayOfTables reports whether node is written as [[name]] blocks.
+func isTOMLArrayOfTables(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		if item := resolveConfigAlias(item); item.Kind != yaml.MappingNode || item.Style&yaml.FlowStyle != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *tomlConfigCodec) encode(root *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	if err := c.writeTable(&b, nil, root); err != nil {
+		return nil, err
+	}
+	b.WriteString(c.trailer)
+	return []byte(b.String()), nil
+}
+
+// writeTable writes the plain keys of table, then its sub-tables and arrays of tables.
+func (c *tomlConfigCodec) writeTable(b *strings.Builder, path []string, table *yaml.Node) error {
+	var subTables []int
+	for i := 0; i+1 < len(table.Content); i += 2 {
+		value := resolveConfigAlias(table.Content[i+1])
+		if c.isSubTable(value) || isTOMLArrayOfTables(value) {
+			subTables = append(subTables, i)
+			continue
+		}
+		if err := c.writeKeyValue(b, "", table.Content[i], value); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range subTables {
+		name := table.Content[i].Value
+		childPath := append(append([]string(nil), path...), name)
+		value := resolveConfigAlias(table.Content[i+1])
+		if value.Kind == yaml.SequenceNode {
+			for _, item := range value.Content {
+				item = resolveConfigAlias(item)
+				c.writeHeader(b, "[["+formatTOMLKeyPath(childPath)+"]]", item)
+				if err := c.writeTable(b, childPath, item); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if c.explicit[value] || len(value.Content) == 0 || c.hasPlainKeys(value) || value.HeadComment != "" {
+			c.writeHeader(b, "["+formatTOMLKeyPath(childPath)+"]", value)
+		}
+		if err := c.writeTable(b, childPath, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSubTable reports whether a value is written under its own [header].
+func (c *tomlConfigCodec) isSubTable(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 && !c.dotted[node]
+}
+
+func (c *tomlConfigCodec) hasPlainKeys(table *yaml.Node) bool {
+	for i := 1; i < len(table.Content); i += 2 {
+		value := resolveConfigAlias(table.Content[i])
+		if !c.isSubTable(value) && !isTOMLArrayOfTables(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *tomlConfigCodec) writeHeader(b *strings.Builder, header string, table *yaml.Node) {
+	// Tables added by edits are separated from what precedes them by a blank line
+	if table.Line == 0 && table.HeadComment == "" && b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(table.HeadComment + c.source[table].unlessChanged(tomlHeader(header, table)) + "\n")
+}
+
+// tomlHeader returns the line declaring table under header.
+func tomlHeader(header string, table *yaml.Node) string {
+	if table.LineComment != "" {
+		return header + " " + table.LineComment
+	}
+	return header
+}
+
+func (c *tomlConfigCodec) writeKeyValue(b *strings.Builder, prefix string, key, value *yaml.Node) error {
+	name := prefix + formatTOMLKey(key.Value)
+	if c.dotted[value] {
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if err := c.writeKeyValue(b, name+".", value.Content[i], resolveConfigAlias(value.Content[i+1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	entry, err := tomlEntry(name, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	b.WriteString(key.HeadComment + c.source[key].unlessChanged(entry) + "\n")
+	return nil
+}
+
+// tomlEntry returns the name = value line, or lines, for value.
+func tomlEntry(name string, value *yaml.Node) (string, error) {
+	text, err := formatTOMLValue(value)
+	if err != nil {
+		return "", err
+	}
+	if value.LineComment != "" {
+		return name + " = " + text + " " + value.LineComment, nil
+	}
+	return name + " = " + text, nil
+}
+
+func formatTOMLValue(node *yaml.Node) (string, error) {
+	node = resolveConfigAlias(node)
+	switch node.Kind {
+	case yaml.SequenceNode:
+		items := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			text, err := formatTOMLValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		if node.Style&yaml.FlowStyle != 0 || len(items) == 0 {
+			return "[" + strings.Join(items, ", ") + "]", nil
+		}
+		return "[\n    " + strings.Join(items, ",\n    ") + ",\n]", nil
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			return "{}", nil
+		}
+		pairs := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			text, err := formatTOMLValue(node.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, formatTOMLKey(node.Content[i].Value)+" = "+text)
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!bool", "!!timestamp":
+			return node.Value, nil
+		case "!!float":
+			switch strings.ToLower(node.Value) {
+			case ".inf", "+.inf":
+				return "inf", nil
+			case "-.inf":
+				return "-inf", nil
+			case ".nan":
+				return "nan", nil
+			}
+			return node.Value, nil
+		case "!!null":
+			return "", fmt.Errorf("TOML has no null value")
+		}
+		if node.Style&yaml.SingleQuotedStyle != 0 && !strings.ContainsAny(node.Value, "'\n") {
+			return "'" + node.Value + "'", nil
+		}
+		if node.Style&yaml.LiteralStyle != 0 && strings.Contains(node.Value, "\n") {
+			return `"""` + "\n" + quoteTOMLString(node.Value, true) + `"""`, nil
+		}
+		return `"` + quoteTOMLString(node.Value, false) + `"`, nil
+	}
+	return "", fmt.Errorf("cannot encode node of kind %d as TOML", node.Kind)
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func formatTOMLKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return `"` + quoteTOMLString(key, false) + `"`
+}
+
+func formatTOMLKeyPath(keys []string) string {
+	formatted := make([]string, len(keys))
+	for i, key := range keys {
+		formatted[i] = formatTOMLKey(key)
+	}
+	return strings.Join(formatted, ".")
+}
+
+// quoteTOMLString escapes s for a basic string. Multi-line strings keep their newlines.
+func quoteTOMLString(s string, multiline bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\n' && multiline:
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// tomlParser parses keys and values from s, starting at pos.
+type tomlParser struct {
+	s   string
+	pos int
+}
+
+// skipSpace skips spaces and tabs, and newlines and comments when inside an array.
+func (p *tomlParser) skipSpace(newlines bool) {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t':
+			p.pos++
+		case '\n', '\r':
+			if !newlines {
+				return
+			}
+			p.pos++
+		case '#':
+			if !newlines {
+				return
+			}
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// trailingComment returns the comment after a value or header, which must be
+// all that is left on the line.
+func (p *tomlParser) trailingComment() (string, error) {
+	p.skipSpace(false)
+	rest := strings.TrimSpace(p.s[p.pos:])
+	if rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected %q after value", rest)
+	}
+	p.pos = len(p.s)
+	return rest, nil
+}
+
+// key parses a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("expected a key")
+		}
+		switch p.s[p.pos] {
+		case '"', '\'':
+			node, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, node.Value)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isTOMLBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, fmt.Errorf("expected a key at %q", p.s[start:])
+			}
+			keys = append(keys, p.s[start:p.pos])
+		}
+		p.skipSpace(false)
+		if p.pos >= len(p.s) || p.s[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (*yaml.Node, error) {
+	if p.pos >= len(p.s) {
+		return nil, errTOMLIncomplete
+	}
+	rest := p.s[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multilineString(`"""`)
+	case strings.HasPrefix(rest, `'''`):
+		return p.multilineString(`'''`)
+	case rest[0] == '"':
+		value, err := p.basicString()
+		if err != nil {
+			return nil, err
+		}
+		node := newConfigScalar("!!str", value)
+		node.Style = yaml.DoubleQuotedStyle
+		return node, nil
+	case rest[0] == '\'':
+		end := strings.IndexAny(rest[1:], "'\n")
+		if end < 0 || rest[1+end] != '\'' {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		p.pos += end + 2
+		node := newConfigScalar("!!str", rest[1:1+end])
+		node.Style = yaml.SingleQuotedStyle
+		return node, nil
+	case rest[0] == '[':
+		return p.array()
+	case rest[0] == '{':
+		return p.inlineTable()
+	}
+	return p.bareValue()
+}
+
+// basicString parses a single-line "..." string with escapes.
+func (p *tomlParser) basicString() (string, error) {
+	var b strings.Builder
+	for i := p.pos + 1; i < len(p.s); i++ {
+		switch c := p.s[i]; c {
+		case '"':
+			p.pos = i + 1
+			return b.String(), nil
+		case '\n':
+			return "", fmt.Errorf("unterminated string")
+		case '\\':
+			n, err := p.escape(&b, i)
+			if err != nil {
+				return "", err
+			}
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// escape decodes the escape sequence at s[i] and returns how many bytes
+// after the backslash it used.
+func (p *tomlParser) escape(b *strings.Builder, i int) (int, error) {
+	if i+1 >= len(p.s) {
+		return 0, errTOMLIncomplete
+	}
+	simple := map[byte]string{'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", '"': `"`, '\\': `\`}
+	if s, ok := simple[p.s[i+1]]; ok {
+		b.WriteString(s)
+		return 1, nil
+	}
+	digits := map[byte]int{'u': 4, 'U': 8}[p.s[i+1]]
+	if digits == 0 || i+2+digits > len(p.s) {
+		return 0, fmt.Errorf("invalid escape sequence at %q", p.s[i:])
+	}
+	code, err := strconv.ParseUint(p.s[i+2:i+2+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, fmt.Errorf("invalid unicode escape %q", p.s[i:i+2+digits])
+	}
+	b.WriteRune(rune(code))
+	return 1 + digits, nil
+}
+
+// multilineString parses a """ or ”' string, which may span lines.
+func (p *tomlParser) multilineString(delim string) (*yaml.Node, error) {
+	start := p.pos + len(delim)
+	if strings.HasPrefix(p.s[start:], "\n") {
+		start++
+	} else if strings.HasPrefix(p.s[start:], "\r\n") {
+		start += 2
+	}
+	var b strings.Builder
+	for i := start; i < len(p.s); i++ {
+		if strings.HasPrefix(p.s[i:], delim) {
+			// Up to two quotes directly before the closing delimiter belong to the string
+			for strings.HasPrefix(p.s[i+1:], delim) {
+				b.WriteByte(p.s[i])
+				i++
+			}
+			p.pos = i + len(delim)
+			node := newConfigScalar("!!str", b.String())
+			node.Style = yaml.LiteralStyle
+			return node, nil
+		}
+		if delim == `"""` && p.s[i] == '\\' {
+			// A backslash at the end of a line trims the newline and following whitespace
+			if rest := strings.TrimLeft(p.s[i+1:], " \t\r"); strings.HasPrefix(rest, "\n") {
+				i = len(p.s) - len(strings.TrimLeft(rest, " \t\r\n")) - 1
+				continue
+			}
+			n, err := p.escape(&b, i)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			continue
+		}
+		b.WriteByte(p.s[i])
+	}
+	return nil, errTOMLIncomplete
+}
+
+func (p *tomlParser) array() (*yaml.Node, error) {
+	start := p.pos
+	p.pos++
+	node := newConfigSequence()
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.s) {
+			return nil, errTOMLIncomplete
+		}
+		if p.s[p.pos] == ']' {
+			break
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, item)
+		p.skipSpace(true)
+		if p.pos >= len(p.s) {
+			return nil, errTOMLIncomplete
+		}
+		if p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.s[p.pos] != ']' {
+			return nil, fmt.Errorf("expected ',' or ']' in array at %q", p.s[p.pos:])
+		}
+		break
+	}
+	p.pos++
+	if !strings.Contains(p.s[start:p.pos], "\n") {
+		node.Style = yaml.FlowStyle
+	}
+	return node, nil
+}
+
+func (p *tomlParser) inlineTable() (*yaml.Node, error) {
+	p.pos++
+	node := newConfigMapping()
+	node.Style = yaml.FlowStyle
+	codec := &tomlConfigCodec{dotted: make(map[*yaml.Node]bool)}
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return nil, errTOMLIncomplete
+		}
+		if p.s[p.pos] == '}' && len(node.Content) == 0 {
+			break
+		}
+		keys, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+			return nil, fmt.Errorf("expected '=' in inline table")
+		}
+		p.pos++
+		p.skipSpace(false)
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := codec.setDottedKey(node, keys, value); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return nil, errTOMLIncomplete
+		}
+		if p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.s[p.pos] != '}' {
+			return nil, fmt.Errorf("expected ',' or '}' in inline table at %q", p.s[p.pos:])
+		}
+		break
+	}
+	p.pos++
+	return node, nil
+}
+
+var (
+	tomlInteger  = regexp.MustCompile(`^[+-]?(0x[0-9A-Fa-f_]+|0o[0-7_]+|0b[01_]+|[0-9_]+)$`)
+	tomlFloat    = regexp.MustCompile(`^[+-]?(inf|nan|[0-9_]+(\.[0-9_]+)?([eE][+-]?[0-9_]+)?)$`)
+	tomlDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?)?|\d{2}:\d{2}:\d{2}(\.\d+)?)([Zz]|[+-]\d{2}:\d{2})?$`)
+)
+
+// bareValue parses a boolean, number or date.
+func (p *tomlParser) bareValue() (*yaml.Node, error) {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	// A date may be followed by a space and a time
+	if tomlDate.MatchString(p.s[start:p.pos]) && p.pos+1 < len(p.s) && p.s[p.pos] == ' ' && p.s[p.pos+1] >= '0' && p.s[p.pos+1] <= '9' {
+		p.pos++
+		for p.pos < len(p.s) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.s[p.pos])) {
+			p.pos++
+		}
+	}
+
+	token := p.s[start:p.pos]
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("expected a value at %q", p.s[start:])
+	case token == "true" || token == "false":
+		return newConfigScalar("!!bool", token), nil
+	case tomlInteger.MatchString(token):
+		return newConfigScalar("!!int", token), nil
+	case tomlFloat.MatchString(token):
+		return newConfigScalar("!!float", token), nil
+	case tomlDateTime.MatchString(token):
+		return newConfigScalar("!!timestamp", token), nil
+	}
+	return nil, fmt.Errorf("invalid value %q", token)
+}
@@ -0,0 +1,411 @@
+package operations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Every config format is decoded into a yaml.v3 node tree, which keeps key
+// order and has room for comments, so the edits below are shared by all of
+// them. Values given to edits are converted the way yaml.v3 marshals them.
+
+// configPathSegment is one step of a key path: a table key or a list index.
+type configPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s configPathSegment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return s.key
+}
+
+// parseConfigPath splits a key path into segments. Keys are separated by dots;
+// [n] indexes a list and ["key"] or ['key'] quotes a key containing dots or
+// brackets. The empty path addresses the document root.
+func parseConfigPath(expr string) ([]configPathSegment, error) {
+	var segments []configPathSegment
+	for i := 0; i < len(expr); {
+		switch expr[i] {
+		case '.':
+			if i == 0 || i == len(expr)-1 || expr[i+1] == '.' || expr[i+1] == '[' {
+				return nil, fmt.Errorf("empty key in path %q", expr)
+			}
+			i++
+		case '[':
+			end, segment, err := parseConfigBracket(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			i = end
+		default:
+			if i > 0 && expr[i-1] != '.' {
+				return nil, fmt.Errorf("expected '.' before key at offset %d in path %q", i, expr)
+			}
+			j := i
+			for j < len(expr) && expr[j] != '.' && expr[j] != '[' {
+				j++
+			}
+			segments = append(segments, configPathSegment{key: expr[i:j]})
+			i = j
+		}
+	}
+	return segments, nil
+}
+
+// parseConfigBracket parses the [n] or ["key"] starting at expr[start] and
+// returns the offset just past it.
+func parseConfigBracket(expr string, start int) (int, configPathSegment, error) {
+	rest := expr[start+1:]
+	if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+		quote := rest[0]
+		for j := 1; j < len(rest); j++ {
+			if rest[j] == '\\' && quote == '"' {
+				j++
+				continue
+			}
+			if rest[j] != quote {
+				continue
+			}
+			if j+1 >= len(rest) || rest[j+1] != ']' {
+				return 0, configPathSegment{}, fmt.Errorf("expected ']' after quoted key in path %q", expr)
+			}
+			key := rest[1:j]
+			if quote == '"' {
+				unquoted, err := strconv.Unquote(rest[:j+1])
+				if err != nil {
+					return 0, configPathSegment{}, fmt.Errorf("invalid quoted key in path %q: %w", expr, err)
+				}
+				key = unquoted
+			}
+			return start + j + 3, configPathSegment{key: key}, nil
+		}
+		return 0, configPathSegment{}, fmt.Errorf("unterminated quoted key in path %q", expr)
+	}
+
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return 0, configPathSegment{}, fmt.Errorf("unterminated '[' in path %q", expr)
+	}
+	index, err := strconv.Atoi(rest[:end])
+	if err != nil || index < 0 {
+		return 0, configPathSegment{}, fmt.Errorf("invalid list index %q in path %q", rest[:end], expr)
+	}
+	return start + end + 2, configPathSegment{index: index, isIndex: true}, nil
+}
+
+func formatConfigPath(segments []configPathSegment) string {
+	var b strings.Builder
+	for i, segment := range segments {
+		if i > 0 && !segment.isIndex {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment.String())
+	}
+	if b.Len() == 0 {
+		return "the document root"
+	}
+	return b.String()
+}
+
+// validateConfigEdit checks an edit without looking at any file.
+func validateConfigEdit(edit ConfigEdit) error {
+	segments, err := parseConfigPath(edit.Path)
+	if err != nil {
+		return err
+	}
+	switch edit.Action {
+	case ConfigActionDelete:
+		if len(segments) == 0 {
+			return fmt.Errorf("delete needs a path")
+		}
+		return nil
+	case ConfigActionMerge:
+		fragment, err := configValueNode(edit.Value)
+		if err != nil {
+			return err
+		}
+		if fragment.Kind != yaml.MappingNode {
+			return fmt.Errorf("merge fragment must be a map")
+		}
+		return nil
+	case ConfigActionSet, ConfigActionAppend:
+		if len(segments) == 0 {
+			return fmt.Errorf("%s needs a path", edit.Action)
+		}
+		_, err := configValueNode(edit.Value)
+		return err
+	}
+	return fmt.Errorf("unknown config edit action %q", edit.Action)
+}
+
+// configValueNode converts a Go value into a node.
+func configValueNode(value interface{}) (*yaml.Node, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, fmt.Errorf("cannot convert value: %w", err)
+	}
+	return node, nil
+}
+
+func newConfigMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func newConfigSequence() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+func newConfigScalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func resolveConfigAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// configChild returns the child of node addressed by segment and its position
+// in node.Content, or nil and -1 when there is none.
+func configChild(node *yaml.Node, segment configPathSegment) (*yaml.Node, int) {
+	switch {
+	case node.Kind == yaml.MappingNode && !segment.isIndex:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment.key {
+				return node.Content[i+1], i
+			}
+		}
+	case node.Kind == yaml.SequenceNode && segment.isIndex:
+		if segment.index < len(node.Content) {
+			return node.Content[segment.index], segment.index
+		}
+	}
+	return nil, -1
+}
+
+// checkConfigContainer reports an error when node cannot hold segment.
+func checkConfigContainer(node *yaml.Node, segments []configPathSegment, segment configPathSegment) error {
+	if segment.isIndex && node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s is not a list", formatConfigPath(segments))
+	}
+	if !segment.isIndex && node.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a table", formatConfigPath(segments))
+	}
+	return nil
+}
+
+// configLookup walks segments from root. Missing keys are created as tables
+// when create is set, and the final node is created as leaf. Without create, a
+// missing path returns nil.
+func configLookup(root *yaml.Node, segments []configPathSegment, create bool, leaf func() *yaml.Node) (*yaml.Node, error) {
+	node := root
+	for i, segment := range segments {
+		node = resolveConfigAlias(node)
+		if err := checkConfigContainer(node, segments[:i], segment); err != nil {
+			return nil, err
+		}
+		child, _ := configChild(node, segment)
+		if child == nil {
+			if !create {
+				return nil, nil
+			}
+			if segment.isIndex {
+				return nil, fmt.Errorf("index %s is out of range", formatConfigPath(segments[:i+1]))
+			}
+			child = newConfigMapping()
+			if i == len(segments)-1 && leaf != nil {
+				child = leaf()
+			}
+			node.Content = append(node.Content, newConfigScalar("!!str", segment.key), child)
+		}
+		node = child
+	}
+	return resolveConfigAlias(node), nil
+}
+
+// applyConfigEdit applies one edit to the document root and reports whether it changed anything.
+func applyConfigEdit(root *yaml.Node, edit ConfigEdit) (bool, error) {
+	if err := validateConfigEdit(edit); err != nil {
+		return false, err
+	}
+	segments, _ := parseConfigPath(edit.Path)
+
+	switch edit.Action {
+	case ConfigActionDelete:
+		return deleteConfigValue(root, segments)
+	case ConfigActionMerge:
+		fragment, _ := configValueNode(edit.Value)
+		target, err := configLookup(root, segments, true, newConfigMapping)
+		if err != nil {
+			return false, err
+		}
+		if target.Kind != yaml.MappingNode {
+			return false, fmt.Errorf("%s is not a table", formatConfigPath(segments))
+		}
+		return mergeConfigNodes(target, fragment), nil
+	}
+
+	value, _ := configValueNode(edit.Value)
+	parent, err := configLookup(root, segments[:len(segments)-1], true, nil)
+	if err != nil {
+		return false, err
+	}
+	last := segments[len(segments)-1]
+	if err := checkConfigContainer(parent, segments[:len(segments)-1], last); err != nil {
+		return false, err
+	}
+
+	if edit.Action == ConfigActionSet {
+		return setConfigChild(parent, segments, value)
+	}
+
+	list, err := configLookup(parent, segments[len(segments)-1:], true, newConfigSequence)
+	if err != nil {
+		return false, err
+	}
+	if list.Kind != yaml.SequenceNode {
+		return false, fmt.Errorf("%s is not a list", formatConfigPath(segments))
+	}
+	for _, item := range list.Content {
+		if configNodesEqual(item, value) {
+			return false, nil
+		}
+	}
+	list.Content = append(list.Content, value)
+	return true, nil
+}
+
+// setConfigChild sets the last segment of path in parent to value. An existing
+// value keeps its comments and, where the kinds match, its style.
+func setConfigChild(parent *yaml.Node, path []configPathSegment, value *yaml.Node) (bool, error) {
+	segment := path[len(path)-1]
+	existing, pos := configChild(parent, segment)
+	if existing == nil {
+		if segment.isIndex {
+			return false, fmt.Errorf("index %s is out of range", formatConfigPath(path))
+		}
+		parent.Content = append(parent.Content, newConfigScalar("!!str", segment.key), value)
+		return true, nil
+	}
+	if configNodesEqual(existing, value) {
+		return false, nil
+	}
+
+	value.HeadComment = existing.HeadComment
+	value.LineComment = existing.LineComment
+	value.FootComment = existing.FootComment
+	if existing.Kind == value.Kind && (existing.Kind != yaml.ScalarNode || existing.ShortTag() == value.ShortTag()) {
+		value.Style = existing.Style
+	}
+	if segment.isIndex {
+		parent.Content[pos] = value
+	} else {
+		parent.Content[pos+1] = value
+	}
+	return true, nil
+}
+
+func deleteConfigValue(root *yaml.Node, segments []configPathSegment) (bool, error) {
+	parent, err := configLookup(root, segments[:len(segments)-1], false, nil)
+	if err != nil || parent == nil {
+		return false, err
+	}
+	last := segments[len(segments)-1]
+	if err := checkConfigContainer(parent, segments[:len(segments)-1], last); err != nil {
+		return false, err
+	}
+	existing, pos := configChild(parent, last)
+	if existing == nil {
+		return false, nil
+	}
+	if last.isIndex {
+		parent.Content = append(parent.Content[:pos], parent.Content[pos+1:]...)
+	} else {
+		parent.Content = append(parent.Content[:pos], parent.Content[pos+2:]...)
+	}
+	return true, nil
+}
+
+// mergeConfigNodes deep-merges the mapping fragment into target.
+func mergeConfigNodes(target, fragment *yaml.Node) bool {
+	changed := false
+	for i := 0; i+1 < len(fragment.Content); i += 2 {
+		key, value := fragment.Content[i].Value, fragment.Content[i+1]
+		segment := configPathSegment{key: key}
+		existing, _ := configChild(target, segment)
+		existing = resolveConfigAlias(existing)
+		if existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			changed = mergeConfigNodes(existing, value) || changed
+			continue
+		}
+		set, _ := setConfigChild(target, []configPathSegment{segment}, value)
+		changed = set || changed
+	}
+	return changed
+}
+
+// configNodesEqual compares two values, ignoring comments, style and the
+// order of table keys. Numbers compare by value, so 1 equals 1.0.
+func configNodesEqual(a, b *yaml.Node) bool {
+	a, b = resolveConfigAlias(a), resolveConfigAlias(b)
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind == yaml.DocumentNode && len(a.Content) == 1 {
+		a = a.Content[0]
+	}
+	if b.Kind == yaml.DocumentNode && len(b.Content) == 1 {
+		b = b.Content[0]
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+
+	switch a.Kind {
+	case yaml.ScalarNode:
+		aTag, bTag := a.ShortTag(), b.ShortTag()
+		if isConfigNumber(aTag) && isConfigNumber(bTag) {
+			x, errA := strconv.ParseFloat(strings.ReplaceAll(a.Value, "_", ""), 64)
+			y, errB := strconv.ParseFloat(strings.ReplaceAll(b.Value, "_", ""), 64)
+			if errA == nil && errB == nil {
+				return x == y
+			}
+		}
+		return aTag == bTag && a.Value == b.Value
+	case yaml.SequenceNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+		for i := range a.Content {
+			if !configNodesEqual(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+		return true
+	case yaml.MappingNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			other, _ := configChild(b, configPathSegment{key: a.Content[i].Value})
+			if other == nil || !configNodesEqual(a.Content[i+1], other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isConfigNumber(tag string) bool {
+	return tag == "!!int" || tag == "!!float"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path"
 	"path/filepath"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
@@ -11,9 +12,19 @@ import (
 	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
 )
 
+// CopyOptions controls how a directory is copied.
+type CopyOptions struct {
+	// PreserveHardlinks recreates files that are hard links to each other in
+	// the source directory as hard links in the copy, instead of separate
+	// files. It requires a filesystem that supports hard links.
+	PreserveHardlinks bool
+}
+
 // CopyOperation represents a file/directory copy operation.
 type CopyOperation struct {
 	*BaseOperation
+	options    CopyOptions
+	copiedTree bool
 }
 
 // NewCopyOperation creates a new copy operation.
@@ -23,6 +34,11 @@ func NewCopyOperation(id core.OperationID, srcPath string) *CopyOperation {
 	}
 }
 
+// SetOptions sets how a directory source is copied.
+func (op *CopyOperation) SetOptions(options CopyOptions) {
+	op.options = options
+}
+
 // Prerequisites returns the prerequisites for copying a file/directory
 func (op *CopyOperation) Prerequisites() []core.Prerequisite {
 	var prereqs []core.Prerequisite
@@ -94,10 +110,13 @@ func (op *CopyOperation) execute(ctx context.Context, fsys filesystem.FileSystem
 			}
 		}()
 
-		// Read content
+		// Read content, reporting the bytes read as progress
+		progress := core.ProgressFromContext(ctx)
+		progress.AddTotal(info.Size(), 1)
+		progress.StartEntry(src)
 		var content []byte
 		if reader, ok := srcFile.(io.Reader); ok {
-			content, err = io.ReadAll(reader)
+			content, err = io.ReadAll(progress.Reader(core.ContextReader(ctx, reader)))
 			if err != nil {
 				return fmt.Errorf("failed to read source file: %w", err)
 			}
@@ -112,18 +131,61 @@ func (op *CopyOperation) execute(ctx context.Context, fsys filesystem.FileSystem
 		if err := fsys.WriteFile(dst, content, mode); err != nil {
 			return fmt.Errorf("failed to write destination file: %w", err)
 		}
+		progress.EntryDone()
 
 		// Compute and store checksum for the source file
 		_ = op.computeAndStoreChecksum(fsys, src)
 		// Ignore checksum errors - checksums are nice-to-have, not critical
-	} else {
-		// TODO: Handle directory copy
-		return fmt.Errorf("directory copy not yet implemented")
+	} else if err := op.copyTree(ctx, fsys, src, dst); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// copyTree copies the directory src, with everything below it, to dst, which
+// must not exist yet.
+func (op *CopyOperation) copyTree(ctx context.Context, fsys filesystem.FileSystem, src, dst string) error {
+	if !pathAbsent(fsys, dst) {
+		return fmt.Errorf("destination %s already exists", dst)
+	}
+	tree, _, err := scanSyncTree(fsys, src, SyncOptions{})
+	if err != nil {
+		return err
+	}
+	var links map[string]string
+	if op.options.PreserveHardlinks {
+		if links, err = hardlinkGroups(fsys, src, tree); err != nil {
+			return err
+		}
+	}
+
+	op.copiedTree = true
+	for _, rel := range sortedSyncPaths(tree, false) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from, to := path.Join(src, rel), path.Join(dst, rel)
+		if leader, ok := links[rel]; ok {
+			if err := fsys.(filesystem.LinkFS).Link(path.Join(dst, leader), to); err != nil {
+				return fmt.Errorf("failed to link %s: %w", to, err)
+			}
+			continue
+		}
+		state, err := captureEntryContext(ctx, fsys, from)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", from, err)
+		}
+		if err := applyEntry(fsys, to, state, false); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", from, err)
+		}
+		if state.itemType == "file" {
+			core.ProgressFromContext(ctx).EntryDone()
+		}
+	}
+	return nil
+}
+
 
 // Validate checks if the copy operation can be performed.
 func (op *CopyOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
@@ -163,6 +225,16 @@ func (op *CopyOperation) Validate(ctx context.Context, execCtx *core.ExecutionCo
 }
 
 
+// Satisfied reports whether the destination already holds a copy of the
+// source file. Directory copies are never considered satisfied.
+func (op *CopyOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	src, dst := op.GetPaths()
+	if _, err := fsys.Readlink(dst); err == nil {
+		return false, nil
+	}
+	return sameChecksum(fsys, src, dst)
+}
+
 // Rollback removes the copied file/directory.
 func (op *CopyOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
 	_, dst := op.GetPaths()
@@ -170,6 +242,11 @@ func (op *CopyOperation) Rollback(ctx context.Context, fsys filesystem.FileSyste
 		return nil
 	}
 
+	// A copied directory did not exist before, so all of it goes
+	if op.copiedTree {
+		return fsys.RemoveAll(dst)
+	}
+
 	// Remove the destination
 	_ = fsys.Remove(dst) // Ignore error - might not exist
 	return nil
@@ -281,6 +358,16 @@ func (op *MoveOperation) Validate(ctx context.Context, execCtx *core.ExecutionCo
 }
 
 
+// Satisfied reports whether the move already happened: the source is gone
+// and the destination exists.
+func (op *MoveOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	src, dst := op.GetPaths()
+	if src == "" || dst == "" || !pathAbsent(fsys, src) {
+		return false, nil
+	}
+	return !pathAbsent(fsys, dst), nil
+}
+
 // Rollback attempts to restore the moved file to its original location.
 func (op *MoveOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
 	src, dst := op.GetPaths()
@@ -1 +1,73 @@
 package operations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func TestCopyOperation_Directory(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *filesystem.TestFileSystem {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.MkdirAll("src/sub", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.WriteFile("src/sub/a.txt", []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.Link("src/sub/a.txt", "src/b.txt"); err != nil {
+			t.Fatal(err)
+		}
+		return fsys
+	}
+
+	for _, preserve := range []bool{false, true} {
+		fsys := setup(t)
+		op := operations.NewCopyOperation("copy", "src")
+		op.SetPaths("src", "dst")
+		op.SetOptions(operations.CopyOptions{PreserveHardlinks: preserve})
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("preserve=%v: %v", preserve, err)
+		}
+		if got := readFile(t, fsys, "dst/b.txt"); got != "a" {
+			t.Errorf("preserve=%v: dst/b.txt = %q", preserve, got)
+		}
+		a, _ := fsys.FileID("dst/sub/a.txt")
+		b, _ := fsys.FileID("dst/b.txt")
+		if (a == b) != preserve {
+			t.Errorf("preserve=%v: linked = %v", preserve, a == b)
+		}
+
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("dst"); err == nil {
+			t.Errorf("preserve=%v: rollback left dst behind", preserve)
+		}
+	}
+
+	t.Run("refuses an existing destination", func(t *testing.T) {
+		fsys := setup(t)
+		if err := fsys.MkdirAll("dst", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.WriteFile("dst/keep.txt", []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewCopyOperation("copy", "src")
+		op.SetPaths("src", "dst")
+		if err := op.Execute(ctx, nil, fsys); err == nil {
+			t.Fatal("expected an error")
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("dst/keep.txt"); err != nil {
+			t.Errorf("rollback removed a directory it did not create: %v", err)
+		}
+	})
+}
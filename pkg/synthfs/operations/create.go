@@ -14,8 +14,12 @@ import (
 )
 
 // CreateFileOperation represents a file creation operation with clean interfaces.
+// An existing file is overwritten, which in ensure mode is how the operation
+// converges; Rollback restores it.
 type CreateFileOperation struct {
 	*BaseOperation
+	previous *entryState
+	applied  bool
 }
 
 // NewCreateFileOperation creates a new file creation operation.
@@ -89,13 +93,41 @@ func (op *CreateFileOperation) execute(ctx context.Context, fsys filesystem.File
 	if !ok {
 		fileMode = 0644 // Default
 	}
+	previous, err := captureEntry(fsys, fileItem.Path())
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", fileItem.Path(), err)
+	}
+	op.previous, op.applied = previous, true
 	if err := fsys.WriteFile(fileItem.Path(), content, fileMode); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
+	if previous != nil {
+		// The file was overwritten and kept its old mode
+		if err := fixMode(fsys, fileItem.Path(), fileMode); err != nil {
+			return fmt.Errorf("failed to set file mode: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// Satisfied reports whether the file already exists with the item's content and mode.
+func (op *CreateFileOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	item := op.GetItem()
+	if item == nil {
+		return false, nil
+	}
+	var content []byte
+	if contentGetter, ok := item.(interface{ Content() []byte }); ok {
+		content = contentGetter.Content()
+	}
+	mode := fs.FileMode(0644)
+	if modeGetter, ok := item.(interface{ Mode() fs.FileMode }); ok {
+		mode = modeGetter.Mode()
+	}
+	return FileMatches(fsys, op.description.Path, content, mode)
+}
+
 
 
 // Validate checks if the file can be created.
@@ -156,8 +188,16 @@ func (op *CreateFileOperation) Validate(ctx context.Context, execCtx *core.Execu
 }
 
 
-// Rollback removes the created file
+// Rollback removes the created file, or restores the file it overwrote.
 func (op *CreateFileOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if op.applied && op.previous != nil {
+		if err := restoreEntry(fsys, op.description.Path, op.previous); err != nil {
+			return err
+		}
+		op.applied = false
+		return nil
+	}
+	op.applied = false
 	return fsys.Remove(op.description.Path)
 }
 
@@ -0,0 +1,559 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
+)
+
+// DedupeStrategy selects what Dedupe does with duplicate files.
+type DedupeStrategy string
+
+const (
+	// DedupeHardlink replaces duplicates with hard links to the first copy.
+	// The filesystem must implement filesystem.LinkFS.
+	DedupeHardlink DedupeStrategy = "hardlink"
+	// DedupeSymlink replaces duplicates with symlinks to the first copy.
+	DedupeSymlink DedupeStrategy = "symlink"
+	// DedupeReport only reports duplicates in the operation's outputs.
+	DedupeReport DedupeStrategy = "report"
+)
+
+// DedupeOperation finds files with identical content under a directory. Files
+// are grouped by size, then by checksum, and confirmed byte for byte. In each
+// group the first file in path order is kept and the rest become links to it,
+// one DedupeFileOperation per duplicate. Files that are already hard links to
+// the kept copy, and empty files, are left alone.
+//
+// Outputs: "duplicates" maps each kept file to its duplicates,
+// "duplicate_files" counts them and "reclaimable_bytes" is the space they use.
+type DedupeOperation struct {
+	*BaseOperation
+	strategy DedupeStrategy
+	run      ExpandedRun
+}
+
+// NewDedupeOperation creates a new dedupe operation.
+func NewDedupeOperation(id core.OperationID, root string, strategy DedupeStrategy) *DedupeOperation {
+	op := &DedupeOperation{
+		BaseOperation: NewBaseOperation(id, "dedupe", root),
+		strategy:      strategy,
+	}
+	op.SetDescriptionDetail("strategy", string(strategy))
+	return op
+}
+
+// Strategy returns what the operation does with duplicates.
+func (op *DedupeOperation) Strategy() DedupeStrategy {
+	return op.strategy
+}
+
+// Prerequisites returns the prerequisites for deduplicating a tree
+func (op *DedupeOperation) Prerequisites() []core.Prerequisite {
+	return []core.Prerequisite{core.NewSourceExistsPrerequisite(op.description.Path)}
+}
+
+// Validate checks the strategy and that the root is a directory.
+func (op *DedupeOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if err := validateDedupeStrategy(op.strategy); err != nil {
+		return invalid(err.Error(), nil)
+	}
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return invalid("dedupe root does not exist", err)
+	}
+	if !info.IsDir() {
+		return invalid("dedupe root must be a directory", nil)
+	}
+	return nil
+}
+
+// Expand groups the files under the root by content and returns one child
+// per duplicate to replace. The report strategy returns no children.
+func (op *DedupeOperation) Expand(ctx context.Context, fsys filesystem.FileSystem) ([]Operation, error) {
+	groups, err := findDuplicates(ctx, fsys, op.description.Path, op.strategy != DedupeReport)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make(map[string][]string)
+	var children []Operation
+	var count int
+	var reclaimable int64
+	for _, group := range groups {
+		duplicates[group.canonical] = group.duplicates
+		count += len(group.duplicates)
+		reclaimable += group.size * int64(len(group.duplicates))
+		if op.strategy == DedupeReport {
+			continue
+		}
+		for _, dup := range group.duplicates {
+			rel := strings.TrimPrefix(dup, op.description.Path+"/")
+			childID := core.OperationID(fmt.Sprintf("%s:dedupe:%s", op.ID(), rel))
+			children = append(children, NewDedupeFileOperation(childID, dup, group.canonical, op.strategy))
+		}
+	}
+
+	ids := make([]string, len(children))
+	for i, child := range children {
+		ids[i] = string(child.ID())
+	}
+	op.SetDescriptionDetail("children", ids)
+	op.SetOutput("duplicates", duplicates)
+	op.SetOutput("duplicate_files", count)
+	op.SetOutput("reclaimable_bytes", reclaimable)
+	return children, nil
+}
+
+// Execute runs the dedupe directly. Pipelines normally expand the operation
+// first; this path serves callers that execute it on its own.
+func (op *DedupeOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *DedupeOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Run(ctx, fsys, op)
+}
+
+// Rollback puts back the duplicates that Execute replaced with links.
+func (op *DedupeOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Rollback(ctx, fsys)
+}
+
+// ReverseOps fails; each DedupeFileOperation restores its own duplicate.
+func (op *DedupeOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	return op.run.ReverseOps("dedupe")
+}
+
+func validateDedupeStrategy(strategy DedupeStrategy) error {
+	switch strategy {
+	case DedupeHardlink, DedupeSymlink, DedupeReport:
+		return nil
+	}
+	return fmt.Errorf("unknown dedupe strategy %q", strategy)
+}
+
+// duplicateGroup is a set of files with identical content.
+type duplicateGroup struct {
+	canonical  string
+	duplicates []string
+	size       int64
+}
+
+// findDuplicates groups the regular files under root by content. When
+// matchMode is set, files are only grouped with files of the same
+// permissions, since a hard link cannot keep two modes. Checksumming is
+// reported to the progress tracker carried by ctx.
+func findDuplicates(ctx context.Context, fsys filesystem.FileSystem, root string, matchMode bool) ([]duplicateGroup, error) {
+	type candidateKey struct {
+		size int64
+		mode fs.FileMode
+	}
+	candidates := make(map[candidateKey][]string)
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			return nil
+		}
+		key := candidateKey{size: info.Size()}
+		if matchMode {
+			key.mode = info.Mode().Perm()
+		}
+		candidates[key] = append(candidates[key], p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	progress := core.ProgressFromContext(ctx)
+	for key, paths := range candidates {
+		if len(paths) > 1 {
+			progress.AddTotal(key.size*int64(len(paths)), len(paths))
+		}
+	}
+
+	var groups []duplicateGroup
+	for key, paths := range candidates {
+		if len(paths) < 2 {
+			continue
+		}
+		byChecksum := make(map[string][]string)
+		for _, p := range paths {
+			checksum, err := validation.ComputeFileChecksumContext(ctx, fsys, p)
+			if err != nil {
+				return nil, err
+			}
+			byChecksum[checksum.MD5] = append(byChecksum[checksum.MD5], p)
+		}
+		for _, same := range byChecksum {
+			sort.Strings(same)
+			confirmed, err := confirmDuplicates(fsys, same)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range confirmed {
+				group.size = key.size
+				groups = append(groups, group)
+			}
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].canonical < groups[j].canonical })
+	return groups, nil
+}
+
+// confirmDuplicates splits files sharing a checksum into groups whose content
+// is byte-for-byte identical, dropping files already hard-linked to their
+// group's first file.
+func confirmDuplicates(fsys filesystem.FileSystem, paths []string) ([]duplicateGroup, error) {
+	var groups []duplicateGroup
+	for _, p := range paths {
+		placed := false
+		for i := range groups {
+			same, err := sameContent(fsys, groups[i].canonical, p)
+			if err != nil {
+				return nil, err
+			}
+			if !same {
+				continue
+			}
+			if !sameFile(fsys, groups[i].canonical, p) {
+				groups[i].duplicates = append(groups[i].duplicates, p)
+			}
+			placed = true
+			break
+		}
+		if !placed {
+			groups = append(groups, duplicateGroup{canonical: p})
+		}
+	}
+
+	kept := groups[:0]
+	for _, group := range groups {
+		if len(group.duplicates) > 0 {
+			kept = append(kept, group)
+		}
+	}
+	return kept, nil
+}
+
+// sameContent compares two files byte for byte without reading either whole.
+func sameContent(fsys filesystem.FileSystem, a, b string) (bool, error) {
+	fileA, err := fsys.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = fileA.Close() }()
+	fileB, err := fsys.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = fileB.Close() }()
+
+	bufA, bufB := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		n, errA := io.ReadFull(fileA, bufA)
+		m, errB := io.ReadFull(fileB, bufB)
+		if n != m || !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false, nil
+		}
+		doneA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		doneB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+	}
+}
+
+// DedupeFileOperation replaces one duplicate file with a hard link or symlink
+// to a file with the same content. Its reverse is a MaterializeOperation,
+// which copies the shared content back, so no backup is needed.
+type DedupeFileOperation struct {
+	*BaseOperation
+	canonical string
+	strategy  DedupeStrategy
+	mode      fs.FileMode
+	modTime   time.Time
+	applied   bool
+}
+
+// NewDedupeFileOperation creates a new dedupe_file operation that links p to canonical.
+func NewDedupeFileOperation(id core.OperationID, p, canonical string, strategy DedupeStrategy) *DedupeFileOperation {
+	op := &DedupeFileOperation{
+		BaseOperation: NewBaseOperation(id, "dedupe_file", p),
+		canonical:     canonical,
+		strategy:      strategy,
+	}
+	op.SetDescriptionDetail("canonical", canonical)
+	op.SetDescriptionDetail("strategy", string(strategy))
+	return op
+}
+
+// Canonical returns the file p is linked to.
+func (op *DedupeFileOperation) Canonical() string {
+	return op.canonical
+}
+
+// Strategy returns how p is linked to the canonical file.
+func (op *DedupeFileOperation) Strategy() DedupeStrategy {
+	return op.strategy
+}
+
+// Validate checks that both files exist and the strategy links.
+func (op *DedupeFileOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if op.strategy != DedupeHardlink && op.strategy != DedupeSymlink {
+		return invalid(fmt.Sprintf("dedupe_file needs a hardlink or symlink strategy, got %q", op.strategy), nil)
+	}
+	for _, p := range []string{op.description.Path, op.canonical} {
+		info, err := fsys.Stat(p)
+		if err != nil {
+			return invalid(fmt.Sprintf("%s does not exist", p), err)
+		}
+		if !info.Mode().IsRegular() {
+			return invalid(fmt.Sprintf("%s is not a regular file", p), nil)
+		}
+	}
+	return nil
+}
+
+// Execute replaces the duplicate with event handling.
+func (op *DedupeFileOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *DedupeFileOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	p := op.description.Path
+	if _, ok := fsys.(filesystem.LinkFS); !ok && op.strategy == DedupeHardlink {
+		return fmt.Errorf("filesystem does not support hard links")
+	}
+	info, err := fsys.Stat(p)
+	if err != nil {
+		return err
+	}
+	same, err := sameContent(fsys, op.canonical, p)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("%s no longer has the same content as %s", p, op.canonical)
+	}
+
+	mode, modTime := info.Mode().Perm(), info.ModTime()
+	if err := fsys.Remove(p); err != nil {
+		return fmt.Errorf("failed to remove duplicate %s: %w", p, err)
+	}
+	if err := linkDuplicate(fsys, p, op.canonical, op.strategy); err != nil {
+		// The content is still in the canonical copy; put the file back
+		if restoreErr := materializeFile(fsys, p, op.canonical, mode, modTime); restoreErr != nil {
+			return fmt.Errorf("failed to link %s: %w (restore failed: %v)", p, err, restoreErr)
+		}
+		return fmt.Errorf("failed to link %s: %w", p, err)
+	}
+	op.mode, op.modTime, op.applied = mode, modTime, true
+	return nil
+}
+
+// Rollback turns the link back into an independent copy.
+func (op *DedupeFileOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.applied {
+		return nil
+	}
+	if err := materializeFile(fsys, op.description.Path, op.canonical, op.mode, op.modTime); err != nil {
+		return err
+	}
+	op.applied = false
+	return nil
+}
+
+// ReverseOps returns a materialize operation that copies the content back
+// with the file's current mode and modification time.
+func (op *DedupeFileOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	info, err := fsys.Stat(op.description.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	reverseID := core.OperationID(fmt.Sprintf("reverse_%s", op.ID()))
+	reverseOp := NewMaterializeOperation(reverseID, op.description.Path, op.canonical, op.strategy, info.Mode().Perm(), info.ModTime())
+	return []Operation{reverseOp}, nil, nil
+}
+
+// MaterializeOperation replaces a link made by deduplication with an
+// independent copy of the content it shares. It is the reverse of
+// DedupeFileOperation, and its own reverse links the file again.
+type MaterializeOperation struct {
+	*BaseOperation
+	canonical string
+	strategy  DedupeStrategy
+	mode      fs.FileMode
+	modTime   time.Time
+	applied   bool
+}
+
+// NewMaterializeOperation creates a new materialize operation for the link at
+// p to canonical. The copy gets mode and modTime.
+func NewMaterializeOperation(id core.OperationID, p, canonical string, strategy DedupeStrategy, mode fs.FileMode, modTime time.Time) *MaterializeOperation {
+	op := &MaterializeOperation{
+		BaseOperation: NewBaseOperation(id, "materialize", p),
+		canonical:     canonical,
+		strategy:      strategy,
+		mode:          mode.Perm(),
+		modTime:       modTime,
+	}
+	op.SetDescriptionDetail("canonical", canonical)
+	return op
+}
+
+// Canonical returns the file the link points to.
+func (op *MaterializeOperation) Canonical() string {
+	return op.canonical
+}
+
+// Strategy returns how the link was made.
+func (op *MaterializeOperation) Strategy() DedupeStrategy {
+	return op.strategy
+}
+
+// Mode returns the permission bits of the copy.
+func (op *MaterializeOperation) Mode() fs.FileMode {
+	return op.mode
+}
+
+// ModTime returns the modification time of the copy.
+func (op *MaterializeOperation) ModTime() time.Time {
+	return op.modTime
+}
+
+// Execute copies the content back with event handling.
+func (op *MaterializeOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *MaterializeOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !linkedTo(fsys, op.description.Path, op.canonical, op.strategy) {
+		return fmt.Errorf("%s is not a %s to %s", op.description.Path, op.strategy, op.canonical)
+	}
+	if err := materializeFile(fsys, op.description.Path, op.canonical, op.mode, op.modTime); err != nil {
+		return err
+	}
+	op.applied = true
+	return nil
+}
+
+// Rollback links the file to the canonical copy again.
+func (op *MaterializeOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.applied {
+		return nil
+	}
+	if err := fsys.Remove(op.description.Path); err != nil {
+		return err
+	}
+	if err := linkDuplicate(fsys, op.description.Path, op.canonical, op.strategy); err != nil {
+		return err
+	}
+	op.applied = false
+	return nil
+}
+
+// ReverseOps returns a dedupe_file operation that links the file again.
+func (op *MaterializeOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	reverseID := core.OperationID(fmt.Sprintf("reverse_%s", op.ID()))
+	return []Operation{NewDedupeFileOperation(reverseID, op.description.Path, op.canonical, op.strategy)}, nil, nil
+}
+
+// linkDuplicate creates p as a link to canonical. p must not exist. A symlink
+// that does not read back as canonical is removed again.
+func linkDuplicate(fsys filesystem.FileSystem, p, canonical string, strategy DedupeStrategy) error {
+	if strategy == DedupeSymlink {
+		if err := fsys.Symlink(canonical, p); err != nil {
+			return err
+		}
+		if same, err := sameContent(fsys, canonical, p); err != nil || !same {
+			_ = fsys.Remove(p)
+			return fmt.Errorf("symlink %s does not resolve to %s", p, canonical)
+		}
+		return nil
+	}
+	linkFS, ok := fsys.(filesystem.LinkFS)
+	if !ok {
+		return fmt.Errorf("filesystem does not support hard links")
+	}
+	return linkFS.Link(canonical, p)
+}
+
+// linkedTo reports whether p is a link to canonical made with strategy.
+func linkedTo(fsys filesystem.FileSystem, p, canonical string, strategy DedupeStrategy) bool {
+	if strategy == DedupeSymlink {
+		target, err := fsys.Readlink(p)
+		return err == nil && path.Clean(target) == path.Clean(canonical)
+	}
+	return sameFile(fsys, p, canonical)
+}
+
+// materializeFile replaces whatever is at p with a copy of canonical.
+func materializeFile(fsys filesystem.FileSystem, p, canonical string, mode fs.FileMode, modTime time.Time) error {
+	content, err := fs.ReadFile(fsys, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", canonical, err)
+	}
+	if err := fsys.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove link %s: %w", p, err)
+	}
+	return applyEntry(fsys, p, &entryState{itemType: "file", mode: mode, modTime: modTime, content: content}, true)
+}
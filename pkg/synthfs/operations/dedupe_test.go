@@ -0,0 +1,183 @@
+package operations_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func setupDedupeTree(t *testing.T) *filesystem.TestFileSystem {
+	t.Helper()
+	fsys := filesystem.NewTestFileSystem()
+	files := map[string]string{
+		"vendor/a/lib.js":  "shared",
+		"vendor/b/lib.js":  "shared",
+		"vendor/c/lib.js":  "shared",
+		"vendor/a/other":   "differs",
+		"vendor/b/other":   "differz",
+		"vendor/empty":     "",
+		"vendor/empty.bak": "",
+	}
+	for name, content := range files {
+		if err := fsys.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fsys
+}
+
+func TestDedupeOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("expands into one child per duplicate", func(t *testing.T) {
+		fsys := setupDedupeTree(t)
+		op := operations.NewDedupeOperation("dedupe", "vendor", operations.DedupeHardlink)
+		if err := op.Validate(ctx, nil, fsys); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		children, err := op.Expand(ctx, fsys)
+		if err != nil {
+			t.Fatalf("Expand failed: %v", err)
+		}
+		if len(children) != 2 {
+			t.Fatalf("expected 2 children, got %d", len(children))
+		}
+		for i, want := range []string{"vendor/b/lib.js", "vendor/c/lib.js"} {
+			if got := children[i].Describe().Path; got != want {
+				t.Errorf("child %d path = %s, want %s", i, got, want)
+			}
+		}
+		duplicates := output(op, "duplicates").(map[string][]string)
+		if got := duplicates["vendor/a/lib.js"]; len(got) != 2 {
+			t.Errorf("duplicates = %v", duplicates)
+		}
+		if got := output(op, "reclaimable_bytes"); got != int64(12) {
+			t.Errorf("reclaimable_bytes = %v, want 12", got)
+		}
+	})
+
+	t.Run("links duplicates and rolls back", func(t *testing.T) {
+		fsys := setupDedupeTree(t)
+		op := operations.NewDedupeOperation("dedupe", "vendor", operations.DedupeHardlink)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		a, _ := fsys.FileID("vendor/a/lib.js")
+		c, _ := fsys.FileID("vendor/c/lib.js")
+		if a != c {
+			t.Error("expected vendor/c/lib.js to be linked to vendor/a/lib.js")
+		}
+
+		again, err := op.Expand(ctx, fsys)
+		if err != nil || len(again) != 0 {
+			t.Errorf("expected linked files to be skipped, got %d children (%v)", len(again), err)
+		}
+
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		c, _ = fsys.FileID("vendor/c/lib.js")
+		if a == c {
+			t.Error("expected rollback to give vendor/c/lib.js its own copy")
+		}
+		if got := readFile(t, fsys, "vendor/c/lib.js"); got != "shared" {
+			t.Errorf("content after rollback = %q", got)
+		}
+	})
+
+	t.Run("report strategy changes nothing", func(t *testing.T) {
+		fsys := setupDedupeTree(t)
+		op := operations.NewDedupeOperation("dedupe", "vendor", operations.DedupeReport)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if got := output(op, "duplicate_files"); got != 2 {
+			t.Errorf("duplicate_files = %v, want 2", got)
+		}
+		a, _ := fsys.FileID("vendor/a/lib.js")
+		b, _ := fsys.FileID("vendor/b/lib.js")
+		if a == b {
+			t.Error("report strategy should not link files")
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		fsys := setupDedupeTree(t)
+		tests := []struct {
+			root     string
+			strategy operations.DedupeStrategy
+			reason   string
+		}{
+			{"vendor", "copy", "unknown dedupe strategy"},
+			{"missing", operations.DedupeReport, "does not exist"},
+			{"vendor/a/lib.js", operations.DedupeReport, "must be a directory"},
+		}
+		for _, tt := range tests {
+			op := operations.NewDedupeOperation("dedupe", tt.root, tt.strategy)
+			err := op.Validate(ctx, nil, fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("%s %s: expected error containing %q, got %v", tt.root, tt.strategy, tt.reason, err)
+			}
+		}
+	})
+}
+
+func TestDedupeFileOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refuses a file whose content changed", func(t *testing.T) {
+		fsys := setupDedupeTree(t)
+		op := operations.NewDedupeFileOperation("dup", "vendor/a/other", "vendor/b/other", operations.DedupeHardlink)
+		if err := op.Execute(ctx, nil, fsys); err == nil {
+			t.Fatal("expected an error for different content")
+		}
+		if got := readFile(t, fsys, "vendor/a/other"); got != "differs" {
+			t.Errorf("file changed: %q", got)
+		}
+	})
+
+	t.Run("reverse materializes and its reverse links again", func(t *testing.T) {
+		fsys := setupDedupeTree(t)
+		op := operations.NewDedupeFileOperation("dup", "vendor/b/lib.js", "vendor/a/lib.js", operations.DedupeHardlink)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		reverseOps, _, err := op.ReverseOps(ctx, fsys, nil)
+		if err != nil || len(reverseOps) != 1 || reverseOps[0].Describe().Type != "materialize" {
+			t.Fatalf("expected a materialize reverse op, got %v %v", reverseOps, err)
+		}
+		if err := reverseOps[0].Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("materialize failed: %v", err)
+		}
+		a, _ := fsys.FileID("vendor/a/lib.js")
+		b, _ := fsys.FileID("vendor/b/lib.js")
+		if a == b {
+			t.Error("expected materialize to break the link")
+		}
+
+		relink, _, err := reverseOps[0].ReverseOps(ctx, fsys, nil)
+		if err != nil || len(relink) != 1 || relink[0].Describe().Type != "dedupe_file" {
+			t.Fatalf("expected a dedupe_file reverse op, got %v %v", relink, err)
+		}
+	})
+
+	t.Run("hardlinks need a LinkFS", func(t *testing.T) {
+		fsys := NewMockFilesystem()
+		if err := fsys.WriteFile("a", []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.WriteFile("b", []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewDedupeFileOperation("dup", "b", "a", operations.DedupeHardlink)
+		if err := op.Execute(ctx, nil, fsys); err == nil {
+			t.Fatal("expected an error without hard link support")
+		}
+		if got := readFile(t, fsys, "b"); got != "x" {
+			t.Errorf("duplicate lost: %q", got)
+		}
+	})
+}
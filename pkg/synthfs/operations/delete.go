@@ -96,6 +96,11 @@ func (op *DeleteOperation) Validate(ctx context.Context, execCtx *core.Execution
 	return nil
 }
 
+// Satisfied reports whether the path is already gone.
+func (op *DeleteOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	return pathAbsent(fsys, op.description.Path), nil
+}
+
 // Rollback for delete would require backup data, which isn't implemented yet.
 func (op *DeleteOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
 	return fmt.Errorf("rollback not implemented for delete operations")
@@ -356,13 +361,14 @@ func (op *DeleteOperation) ReverseOps(ctx context.Context, fsys filesystem.FileS
 			path:     path,
 			itemType: "file",
 			content:  backupData.BackupContent,
+			mode:     info.Mode(),
 		})
 		reverseOps = append(reverseOps, fileOp)
 	}
 
 	// Check if we skipped files due to budget
 	if skippedFiles, ok := backupData.Metadata["skipped_files"].(int); ok && skippedFiles > 0 {
-		return reverseOps, backupData, fmt.Errorf("budget exceeded: skipped %d files", skippedFiles)
+		return reverseOps, backupData, fmt.Errorf("%w: skipped %d files", core.ErrBudgetExceeded, skippedFiles)
 	}
 
 	return reverseOps, backupData, nil
@@ -79,6 +79,15 @@ func (op *CreateDirectoryOperation) execute(ctx context.Context, fsys filesystem
 
 
 
+// Satisfied reports whether a directory, not a symlink to one, already exists at the path.
+func (op *CreateDirectoryOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	if _, err := fsys.Readlink(op.description.Path); err == nil {
+		return false, nil
+	}
+	info, err := fsys.Stat(op.description.Path)
+	return err == nil && info.IsDir(), nil
+}
+
 // Validate checks if the directory can be created.
 func (op *CreateDirectoryOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
 	// First do base validation
@@ -0,0 +1,80 @@
+package operations
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
+)
+
+// FileMatches reports whether p is a regular file, not a symlink, with the
+// given content and permission bits. Sizes are compared before checksums, so
+// a file of a different size is never read.
+func FileMatches(fsys filesystem.FileSystem, p string, content []byte, mode fs.FileMode) (bool, error) {
+	if _, err := fsys.Readlink(p); err == nil {
+		return false, nil
+	}
+	info, err := fsys.Stat(p)
+	if err != nil || !info.Mode().IsRegular() {
+		return false, nil
+	}
+	if info.Mode().Perm() != mode.Perm() || info.Size() != int64(len(content)) {
+		return false, nil
+	}
+	checksum, err := validation.ComputeFileChecksum(fsys, p)
+	if err != nil {
+		return false, err
+	}
+	return checksum.MD5 == fmt.Sprintf("%x", md5.Sum(content)), nil
+}
+
+// sameChecksum reports whether a and b are regular files with equal content.
+func sameChecksum(fsys filesystem.FileSystem, a, b string) (bool, error) {
+	infoA, errA := fsys.Stat(a)
+	infoB, errB := fsys.Stat(b)
+	if errA != nil || errB != nil || !infoA.Mode().IsRegular() || !infoB.Mode().IsRegular() {
+		return false, nil
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	checksumA, err := validation.ComputeFileChecksum(fsys, a)
+	if err != nil {
+		return false, err
+	}
+	checksumB, err := validation.ComputeFileChecksum(fsys, b)
+	if err != nil {
+		return false, err
+	}
+	return checksumA.MD5 == checksumB.MD5, nil
+}
+
+// pathAbsent reports whether nothing, not even a dangling symlink, exists at p.
+func pathAbsent(fsys filesystem.FileSystem, p string) bool {
+	if _, err := fsys.Readlink(p); err == nil {
+		return false
+	}
+	_, err := fsys.Stat(p)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// fixMode sets the permission bits of p when they differ from mode. Writing
+// over an existing file keeps its old mode, so operations that overwrite call
+// this to leave the mode they were asked for.
+func fixMode(fsys filesystem.FileSystem, p string, mode fs.FileMode) error {
+	info, err := fsys.Stat(p)
+	if err != nil || info.Mode().Perm() == mode.Perm() {
+		return err
+	}
+	chmodFS, ok := fsys.(filesystem.ChmodFS)
+	if !ok {
+		return nil
+	}
+	if err := chmodFS.Chmod(p, mode.Perm()); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package operations_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func TestSatisfied(t *testing.T) {
+	ctx := context.Background()
+	fsys := filesystem.NewTestFileSystem()
+	for name, content := range map[string]string{
+		"config.yml": "port: 80\n",
+		"copy.yml":   "port: 80\n",
+		"moved.txt":  "x",
+		"app.log":    "start\nmore\n",
+		"main.txt":   "a\nB\nc\n",
+		"hosts":      "127.0.0.1 localhost\n",
+	} {
+		if err := fsys.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fsys.MkdirAll("app", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Symlink("config.yml", "current.yml"); err != nil {
+		t.Fatal(err)
+	}
+
+	createFile := func(content string, mode fs.FileMode) operations.Operation {
+		op := operations.NewCreateFileOperation("create", "config.yml")
+		op.SetItem(&TestFileItem{path: "config.yml", content: []byte(content), mode: mode})
+		return op
+	}
+	createDir := func(p string) operations.Operation {
+		op := operations.NewCreateDirectoryOperation("mkdir", p)
+		op.SetItem(&TestDirItem{path: p, mode: 0755})
+		return op
+	}
+	symlink := func(target string) operations.Operation {
+		op := operations.NewCreateSymlinkOperation("link", "current.yml")
+		op.SetDescriptionDetail("target", target)
+		return op
+	}
+	copyOp := func(src, dst string) operations.Operation {
+		op := operations.NewCopyOperation("copy", src)
+		op.SetPaths(src, dst)
+		return op
+	}
+	moveOp := func(src, dst string) operations.Operation {
+		op := operations.NewMoveOperation("move", src)
+		op.SetPaths(src, dst)
+		return op
+	}
+	const patch = "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+
+	tests := []struct {
+		name string
+		op   operations.Operation
+		want bool
+	}{
+		{"file with same content and mode", createFile("port: 80\n", 0644), true},
+		{"file with other content", createFile("port: 81\n", 0644), false},
+		{"file with other mode", createFile("port: 80\n", 0600), false},
+		{"existing directory", createDir("app"), true},
+		{"file where a directory is wanted", createDir("config.yml"), false},
+		{"symlink to the same target", symlink("config.yml"), true},
+		{"symlink to another target", symlink("copy.yml"), false},
+		{"delete of a missing path", operations.NewDeleteOperation("delete", "missing"), true},
+		{"delete of an existing path", operations.NewDeleteOperation("delete", "config.yml"), false},
+		{"copy with equal content", copyOp("config.yml", "copy.yml"), true},
+		{"copy with other content", copyOp("config.yml", "app.log"), false},
+		{"move already done", moveOp("gone.txt", "moved.txt"), true},
+		{"move not done yet", moveOp("moved.txt", "new.txt"), false},
+		{"chmod to the current mode", operations.NewChmodOperation("chmod", "config.yml", 0644), true},
+		{"chmod to another mode", operations.NewChmodOperation("chmod", "config.yml", 0600), false},
+		{"append already at the end", operations.NewAppendFileOperation("append", "app.log", []byte("more\n")), true},
+		{"append not at the end", operations.NewAppendFileOperation("append", "app.log", []byte("start\n")), false},
+		{"truncate to the current size", operations.NewTruncateOperation("truncate", "moved.txt", 1), true},
+		{"patch already applied", operations.NewApplyPatchOperation("patch", "main.txt", patch), true},
+		{"patch not applied", operations.NewApplyPatchOperation("patch", "main.txt", "@@ -1,3 +1,3 @@\n a\n-B\n+b\n c\n"), false},
+		{"text edit with nothing to do", operations.NewEditTextOperation("edit", "hosts", operations.EnsureLine("127.0.0.1 localhost", operations.LineOptions{})), true},
+		{"text edit with a missing line", operations.NewEditTextOperation("edit", "hosts", operations.EnsureLine("::1 localhost", operations.LineOptions{})), false},
+	}
+	for _, tt := range tests {
+		ensurable, ok := tt.op.(operations.EnsurableOperation)
+		if !ok {
+			t.Errorf("%s: %T does not implement EnsurableOperation", tt.name, tt.op)
+			continue
+		}
+		got, err := ensurable.Satisfied(ctx, fsys)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("%s: Satisfied = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package operations
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
@@ -20,7 +21,7 @@ func ExecuteWithEvents(op Operation, ctx context.Context, execCtx *core.Executio
 			op.Describe().Path,
 			op.Describe().Details,
 		)
-		execCtx.EventBus.PublishAsync(ctx, startEvent)
+		execCtx.Publish(ctx, startEvent)
 	}
 
 	// Execute the operation and measure duration
@@ -32,7 +33,15 @@ func ExecuteWithEvents(op Operation, ctx context.Context, execCtx *core.Executio
 		Str("path", op.Describe().Path).
 		Msg("executing operation")
 
-	err := executeFunc(ctx, fsys)
+	// Operations that move bytes report progress through the context
+	progress := core.NewProgress(ctx, execCtx, core.OperationEventData{
+		OperationID:   op.ID(),
+		OperationType: op.Describe().Type,
+		Path:          op.Describe().Path,
+		Details:       op.Describe().Details,
+	})
+	err := executeFunc(core.WithProgress(ctx, progress), fsys)
+	progress.Finish()
 	duration := time.Since(startTime)
 
 	// Emit completion or failure event
@@ -46,7 +55,7 @@ func ExecuteWithEvents(op Operation, ctx context.Context, execCtx *core.Executio
 				err,
 				duration,
 			)
-			execCtx.EventBus.PublishAsync(ctx, failEvent)
+			execCtx.Publish(ctx, failEvent)
 		} else {
 			completeEvent := core.NewOperationCompletedEvent(
 				op.ID(),
@@ -55,7 +64,7 @@ func ExecuteWithEvents(op Operation, ctx context.Context, execCtx *core.Executio
 				op.Describe().Details,
 				duration,
 			)
-			execCtx.EventBus.PublishAsync(ctx, completeEvent)
+			execCtx.Publish(ctx, completeEvent)
 		}
 	}
 
@@ -79,3 +88,62 @@ func ExecuteWithEvents(op Operation, ctx context.Context, execCtx *core.Executio
 	return err
 }
 
+// ExecuteChildren runs the children of an expandable operation in order and
+// returns the ones that completed, for use by the parent's Rollback. It is how
+// an expandable operation executes when it is run without being expanded first.
+func ExecuteChildren(ctx context.Context, fsys filesystem.FileSystem, children []Operation) ([]Operation, error) {
+	var executed []Operation
+	for _, child := range children {
+		if err := ctx.Err(); err != nil {
+			return executed, err
+		}
+		if err := child.Execute(ctx, nil, fsys); err != nil {
+			return executed, fmt.Errorf("%s of %s failed: %w", child.Describe().Type, child.Describe().Path, err)
+		}
+		executed = append(executed, child)
+	}
+	return executed, nil
+}
+
+// RollbackChildren undoes children returned by ExecuteChildren, in reverse order.
+func RollbackChildren(ctx context.Context, fsys filesystem.FileSystem, executed []Operation) error {
+	for i := len(executed) - 1; i >= 0; i-- {
+		if err := executed[i].Rollback(ctx, fsys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpandedRun lets an expandable operation run without being expanded first.
+// The operation keeps one in a field and delegates to it from Execute,
+// Rollback and ReverseOps. It remembers the children that Run executed, which
+// are the ones Rollback undoes.
+type ExpandedRun struct {
+	executed []Operation
+}
+
+// Run expands op and executes its children in order.
+func (r *ExpandedRun) Run(ctx context.Context, fsys filesystem.FileSystem, op ExpandableOperation) error {
+	children, err := op.Expand(ctx, fsys)
+	if err != nil {
+		return err
+	}
+	r.executed, err = ExecuteChildren(ctx, fsys, children)
+	return err
+}
+
+// Rollback undoes the children executed by Run, in reverse order.
+func (r *ExpandedRun) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if err := RollbackChildren(ctx, fsys, r.executed); err != nil {
+		return err
+	}
+	r.executed = nil
+	return nil
+}
+
+// ReverseOps always fails: an expandable operation is expanded before its
+// reverse operations are computed, so only its children are ever asked.
+func (r *ExpandedRun) ReverseOps(opType string) ([]Operation, interface{}, error) {
+	return nil, nil, fmt.Errorf("reverse operations for %s are provided by its expanded children", opType)
+}
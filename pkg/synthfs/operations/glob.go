@@ -0,0 +1,356 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// MatchGlob reports whether name matches pattern. Each slash-separated segment
+// of the pattern is a path.Match pattern, and a "**" segment matches zero or
+// more whole segments, so "src/**/*.go" matches both "src/main.go" and
+// "src/pkg/util/util.go". A trailing "**" matches one or more segments, as in
+// .gitignore: "build/**" matches everything below build, but not build.
+func MatchGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(name) > 0 // A trailing ** needs at least one segment
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// validateGlob reports a malformed pattern.
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// globBase returns the leading segments of pattern that contain no glob
+// metacharacters. It is where the walk for matches starts.
+func globBase(pattern string) string {
+	var literal []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.ContainsAny(segment, "*?[\\") {
+			break
+		}
+		literal = append(literal, segment)
+	}
+	if len(literal) == 0 {
+		return "."
+	}
+	return path.Join(literal...)
+}
+
+// isGlobExcluded reports whether p matches any exclude pattern. Patterns
+// without a slash are matched against the base name, like .gitignore entries.
+func isGlobExcluded(p string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := path.Match(pattern, path.Base(p)); matched {
+				return true
+			}
+			continue
+		}
+		if MatchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// GlobOperation applies delete, copy, move or chmod to every path matching a
+// pattern. It expands into one child operation per matched path.
+//
+// Delete and move take a matched directory as a whole, unless something
+// below it is excluded; then they act on each entry of the directory that is
+// not excluded, and leave the directory itself in place. Copy only copies
+// files and symlinks, so "src/**" copies every file below src. Copy and move
+// keep the matched paths' layout relative to the pattern's literal prefix:
+// CopyGlob("src/**/*.go", "out") copies src/pkg/a.go to out/pkg/a.go.
+type GlobOperation struct {
+	*BaseOperation
+	action  string
+	dstDir  string
+	mode    fs.FileMode
+	exclude []string
+	run     ExpandedRun
+}
+
+func newGlobOperation(id core.OperationID, action, pattern string, exclude []string) *GlobOperation {
+	op := &GlobOperation{
+		BaseOperation: NewBaseOperation(id, action+"_glob", pattern),
+		action:        action,
+		exclude:       exclude,
+	}
+	op.SetDescriptionDetail("pattern", pattern)
+	if len(exclude) > 0 {
+		op.SetDescriptionDetail("exclude", exclude)
+	}
+	return op
+}
+
+// NewDeleteGlobOperation creates an operation that deletes every path matching pattern.
+func NewDeleteGlobOperation(id core.OperationID, pattern string, exclude []string) *GlobOperation {
+	return newGlobOperation(id, "delete", pattern, exclude)
+}
+
+// NewCopyGlobOperation creates an operation that copies every file matching pattern into dstDir.
+func NewCopyGlobOperation(id core.OperationID, pattern, dstDir string, exclude []string) *GlobOperation {
+	op := newGlobOperation(id, "copy", pattern, exclude)
+	op.dstDir = dstDir
+	op.SetDescriptionDetail("dst", dstDir)
+	return op
+}
+
+// NewMoveGlobOperation creates an operation that moves every path matching pattern into dstDir.
+func NewMoveGlobOperation(id core.OperationID, pattern, dstDir string, exclude []string) *GlobOperation {
+	op := newGlobOperation(id, "move", pattern, exclude)
+	op.dstDir = dstDir
+	op.SetDescriptionDetail("dst", dstDir)
+	return op
+}
+
+// NewChmodGlobOperation creates an operation that sets mode on every path matching pattern.
+func NewChmodGlobOperation(id core.OperationID, pattern string, mode fs.FileMode, exclude []string) *GlobOperation {
+	op := newGlobOperation(id, "chmod", pattern, exclude)
+	op.mode = mode.Perm()
+	op.SetDescriptionDetail("mode", fmt.Sprintf("%04o", mode.Perm()))
+	return op
+}
+
+// Validate checks the pattern and destination.
+func (op *GlobOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+
+	if err := validateGlob(op.description.Path); err != nil {
+		return invalid("invalid pattern", err)
+	}
+	for _, pattern := range op.exclude {
+		if err := validateGlob(pattern); err != nil {
+			return invalid("invalid exclude pattern", err)
+		}
+	}
+	if op.action == "copy" || op.action == "move" {
+		if op.dstDir == "" {
+			return invalid("destination directory cannot be empty", nil)
+		}
+		if info, err := fsys.Stat(op.dstDir); err == nil && !info.IsDir() {
+			return invalid("destination is not a directory", nil)
+		}
+	}
+	return nil
+}
+
+// Matches returns the paths matching the pattern, in walk order. Matched
+// directories are not descended into for delete and move, unless they hold
+// an excluded path; then their entries match instead. Copy skips matched
+// directories.
+func (op *GlobOperation) Matches(fsys filesystem.FileSystem) ([]string, error) {
+	pattern := op.description.Path
+	base := globBase(pattern)
+	if _, err := fsys.Stat(base); err != nil {
+		return nil, nil // Nothing can match below a missing base
+	}
+
+	var matches []string
+	split := make(map[string]bool) // Matched directories taken entry by entry
+	err := fs.WalkDir(fsys, base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isGlobExcluded(p, op.exclude) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if p == "." || !(split[path.Dir(p)] || MatchGlob(pattern, p)) {
+			return nil
+		}
+		switch {
+		case !d.IsDir():
+			matches = append(matches, p)
+		case op.action == "delete" || op.action == "move":
+			excluded, err := op.holdsExcluded(fsys, p)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				split[p] = true
+				return nil
+			}
+			matches = append(matches, p)
+			return fs.SkipDir
+		case op.action == "chmod":
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// holdsExcluded reports whether any path below dir is excluded.
+func (op *GlobOperation) holdsExcluded(fsys filesystem.FileSystem, dir string) (bool, error) {
+	if len(op.exclude) == 0 {
+		return false, nil
+	}
+	found := false
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && isGlobExcluded(p, op.exclude) {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+// Expand returns one child operation per matched path. Copy and move are
+// preceded by directory creations for missing destination parents. Child IDs
+// are derived from the parent ID, action and path, so they are stable.
+func (op *GlobOperation) Expand(ctx context.Context, fsys filesystem.FileSystem) ([]Operation, error) {
+	matches, err := op.Matches(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []Operation
+	created := make(map[string]bool)
+	ensureDir := func(dir string) {
+		var missing []string
+		for ; dir != "." && dir != "/" && !created[dir]; dir = path.Dir(dir) {
+			if _, err := fsys.Stat(dir); err == nil {
+				break
+			}
+			missing = append(missing, dir)
+		}
+		for i := len(missing) - 1; i >= 0; i-- {
+			mkdir := NewCreateDirectoryOperation(op.childID("mkdir", missing[i]), missing[i])
+			mkdir.SetItem(&MinimalItem{path: missing[i], itemType: "directory", mode: 0755})
+			children = append(children, mkdir)
+			created[missing[i]] = true
+		}
+	}
+
+	base := globBase(op.description.Path)
+	for _, p := range matches {
+		id := op.childID(op.action, p)
+		switch op.action {
+		case "delete":
+			children = append(children, NewDeleteOperation(id, p))
+		case "chmod":
+			children = append(children, NewChmodOperation(id, p, op.mode))
+		case "copy", "move":
+			dst := path.Join(op.dstDir, globRelative(base, p))
+			ensureDir(path.Dir(dst))
+			if op.action == "copy" {
+				child := NewCopyOperation(id, p)
+				child.SetPaths(p, dst)
+				children = append(children, child)
+			} else {
+				child := NewMoveOperation(id, p)
+				child.SetPaths(p, dst)
+				children = append(children, child)
+			}
+		}
+	}
+
+	ids := make([]string, len(children))
+	for i, child := range children {
+		ids[i] = string(child.ID())
+	}
+	op.SetDescriptionDetail("matched_paths", matches)
+	op.SetDescriptionDetail("children", ids)
+	return children, nil
+}
+
+// globRelative returns p relative to the pattern's literal base. A pattern
+// without metacharacters matches its base itself, which keeps its name.
+func globRelative(base, p string) string {
+	switch {
+	case base == ".":
+		return p
+	case p == base:
+		return path.Base(p)
+	default:
+		return strings.TrimPrefix(p, base+"/")
+	}
+}
+
+func (op *GlobOperation) childID(action, p string) core.OperationID {
+	return core.OperationID(fmt.Sprintf("%s:%s:%s", op.ID(), action, p))
+}
+
+// Execute runs the operation directly. Pipelines normally expand it first and
+// run the children instead.
+func (op *GlobOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *GlobOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Run(ctx, fsys, op)
+}
+
+// Rollback undoes the per-match operations run by Execute.
+func (op *GlobOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Rollback(ctx, fsys)
+}
+
+// ReverseOps fails; each matched path's operation reverses itself.
+func (op *GlobOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	return op.run.ReverseOps(op.description.Type)
+}
@@ -0,0 +1,161 @@
+package operations_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"src/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"src/**/*.go", "other/a.go", false},
+		{"**/*.tmp", "a.tmp", true},
+		{"**/*.tmp", "x/y/a.tmp", true},
+		{"build/**", "build/a/b", true},
+		{"build/**", "build", false},
+		{"build/**", "build/a", true},
+		{"**", "a", true},
+		{"a/**/b/*.txt", "a/x/b/c.txt", true},
+		{"a/**/b/*.txt", "a/x/c.txt", false},
+		{"data/file?.csv", "data/file1.csv", true},
+		{"data/[ab].csv", "data/c.csv", false},
+	}
+	for _, tt := range tests {
+		if got := operations.MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGlobOperation(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) *filesystem.TestFileSystem {
+		t.Helper()
+		fsys := filesystem.NewTestFileSystem()
+		for _, dir := range []string{"src", "src/pkg", "src/vendor", "build"} {
+			if err := fsys.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, file := range []string{"src/main.go", "src/pkg/util.go", "src/vendor/dep.go", "src/notes.txt", "build/out.o"} {
+			if err := fsys.WriteFile(file, []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fsys
+	}
+
+	t.Run("matches with ** and excludes", func(t *testing.T) {
+		fsys := setup(t)
+		op := operations.NewDeleteGlobOperation("del", "src/**/*.go", []string{"vendor"})
+
+		matches, err := op.Matches(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"src/main.go", "src/pkg/util.go"}
+		if len(matches) != len(want) {
+			t.Fatalf("expected %v, got %v", want, matches)
+		}
+		for i := range want {
+			if matches[i] != want[i] {
+				t.Errorf("match %d: expected %s, got %s", i, want[i], matches[i])
+			}
+		}
+	})
+
+	t.Run("delete takes matched directories whole", func(t *testing.T) {
+		fsys := setup(t)
+		op := operations.NewDeleteGlobOperation("del", "src/*", nil)
+
+		children, err := op.Expand(ctx, fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// src/main.go, src/notes.txt, src/pkg and src/vendor; nothing below the directories
+		if len(children) != 4 {
+			t.Errorf("expected 4 children, got %d", len(children))
+		}
+		if got := children[0].ID(); got != "del:delete:src/main.go" {
+			t.Errorf("unexpected child ID %s", got)
+		}
+	})
+
+	t.Run("delete splits matched directories holding excluded paths", func(t *testing.T) {
+		fsys := setup(t)
+		if err := fsys.WriteFile("src/vendor/keep.txt", []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewDeleteGlobOperation("del", "src/*", []string{"keep.txt"})
+
+		matches, err := op.Matches(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"src/main.go", "src/notes.txt", "src/pkg", "src/vendor/dep.go"}
+		if !reflect.DeepEqual(matches, want) {
+			t.Errorf("expected %v, got %v", want, matches)
+		}
+	})
+
+	t.Run("trailing ** does not match the directory itself", func(t *testing.T) {
+		fsys := setup(t)
+		op := operations.NewDeleteGlobOperation("del", "src/**", nil)
+
+		matches, err := op.Matches(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"src/main.go", "src/notes.txt", "src/pkg", "src/vendor"}
+		if !reflect.DeepEqual(matches, want) {
+			t.Errorf("expected %v, got %v", want, matches)
+		}
+	})
+
+	t.Run("copy creates missing destination directories first", func(t *testing.T) {
+		fsys := setup(t)
+		op := operations.NewCopyGlobOperation("cp", "src/**/*.go", "out", []string{"vendor"})
+
+		children, err := op.Expand(ctx, fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"cp:mkdir:out", "cp:copy:src/main.go", "cp:mkdir:out/pkg", "cp:copy:src/pkg/util.go"}
+		if len(children) != len(want) {
+			t.Fatalf("expected %d children, got %d", len(want), len(children))
+		}
+		for i := range want {
+			if string(children[i].ID()) != want[i] {
+				t.Errorf("child %d: expected %s, got %s", i, want[i], children[i].ID())
+			}
+		}
+
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("execute failed: %v", err)
+		}
+		data, err := fsys.ReadFile("out/pkg/util.go")
+		if err != nil || string(data) != "src/pkg/util.go" {
+			t.Errorf("expected copied content, got %q (%v)", data, err)
+		}
+	})
+
+	t.Run("invalid pattern fails validation", func(t *testing.T) {
+		fsys := setup(t)
+		op := operations.NewChmodGlobOperation("ch", "src/[", 0600, nil)
+		if err := op.Validate(ctx, nil, fsys); err == nil {
+			t.Error("expected validation error")
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// CreateHardlinkOperation creates a hard link to an existing regular file.
+// It requires a filesystem implementing filesystem.LinkFS.
+type CreateHardlinkOperation struct {
+	*BaseOperation
+	target  string
+	created bool
+}
+
+// NewCreateHardlinkOperation creates a new hard link creation operation.
+func NewCreateHardlinkOperation(id core.OperationID, linkPath, target string) *CreateHardlinkOperation {
+	op := &CreateHardlinkOperation{
+		BaseOperation: NewBaseOperation(id, "create_hardlink", linkPath),
+		target:        target,
+	}
+	op.SetDescriptionDetail("target", target)
+	return op
+}
+
+// Target returns the file the link points to.
+func (op *CreateHardlinkOperation) Target() string {
+	return op.target
+}
+
+// GetSrcPath returns the link target, for path state tracking.
+func (op *CreateHardlinkOperation) GetSrcPath() string {
+	return op.target
+}
+
+// Prerequisites returns the prerequisites for creating a hard link.
+func (op *CreateHardlinkOperation) Prerequisites() []core.Prerequisite {
+	var prereqs []core.Prerequisite
+	if dir := path.Dir(op.description.Path); dir != "." && dir != "/" {
+		prereqs = append(prereqs, core.NewParentDirPrerequisite(op.description.Path))
+	}
+	if op.target != "" {
+		prereqs = append(prereqs, core.NewSourceExistsPrerequisite(op.target))
+	}
+	return append(prereqs, core.NewNoConflictPrerequisite(op.description.Path))
+}
+
+// Validate checks that the target is a regular file, the link path is free
+// and both are on the same filesystem.
+func (op *CreateHardlinkOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+
+	if op.target == "" {
+		return invalid("hard link target cannot be empty", nil)
+	}
+	if op.target == op.description.Path {
+		return invalid("hard link cannot point to itself", nil)
+	}
+	if _, err := fsys.Readlink(op.target); err == nil {
+		return invalid("hard link target must be a regular file, not a symlink", nil)
+	}
+	info, err := fsys.Stat(op.target)
+	if err != nil {
+		return invalid("hard link target does not exist", err)
+	}
+	if !info.Mode().IsRegular() {
+		return invalid("hard link target must be a regular file", nil)
+	}
+	if _, err := fsys.Stat(op.description.Path); err == nil {
+		return invalid("hard link path already exists", nil)
+	}
+	if crossDevice(fsys, op.target, op.description.Path) {
+		return invalid("hard link and target must be on the same filesystem", nil)
+	}
+	return nil
+}
+
+// Execute creates the hard link with event handling.
+func (op *CreateHardlinkOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *CreateHardlinkOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	linkFS, ok := fsys.(filesystem.LinkFS)
+	if !ok {
+		return fmt.Errorf("filesystem does not support hard links")
+	}
+	if dir := path.Dir(op.description.Path); dir != "." {
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create parent directory: %w", err)
+		}
+	}
+	if err := linkFS.Link(op.target, op.description.Path); err != nil {
+		return fmt.Errorf("failed to create hard link: %w", err)
+	}
+	op.created = true
+	return nil
+}
+
+// Satisfied reports whether the path is already a hard link to the target.
+func (op *CreateHardlinkOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	return op.target != op.description.Path && sameFile(fsys, op.description.Path, op.target), nil
+}
+
+// Rollback removes the link. The target and its content are untouched.
+func (op *CreateHardlinkOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.created {
+		return nil
+	}
+	if err := fsys.Remove(op.description.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove hard link %s: %w", op.description.Path, err)
+	}
+	op.created = false
+	return nil
+}
+
+// ReverseOps returns a delete of the link.
+func (op *CreateHardlinkOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	reverseOp := NewDeleteOperation(core.OperationID(fmt.Sprintf("reverse_%s", op.ID())), op.description.Path)
+	return []Operation{reverseOp}, nil, nil
+}
+
+// crossDevice reports whether target and the directory that will hold
+// linkPath are known to be on different devices. It is false whenever the
+// filesystem cannot tell, for example for paths only projected to exist.
+func crossDevice(fsys filesystem.FileSystem, target, linkPath string) bool {
+	linkFS, ok := fsys.(filesystem.LinkFS)
+	if !ok {
+		return false
+	}
+	targetID, err := linkFS.FileID(target)
+	if err != nil {
+		return false
+	}
+	for dir := path.Dir(linkPath); ; dir = path.Dir(dir) {
+		if dirID, err := linkFS.FileID(dir); err == nil {
+			return dirID.Device != targetID.Device
+		}
+		if dir == "." || dir == "/" {
+			return false
+		}
+	}
+}
+
+// sameFile reports whether a and b are hard links to the same file.
+func sameFile(fsys filesystem.FileSystem, a, b string) bool {
+	linkFS, ok := fsys.(filesystem.LinkFS)
+	if !ok {
+		return false
+	}
+	idA, errA := linkFS.FileID(a)
+	idB, errB := linkFS.FileID(b)
+	return errA == nil && errB == nil && idA == idB
+}
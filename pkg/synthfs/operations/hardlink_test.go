@@ -0,0 +1,95 @@
+package operations_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func TestCreateHardlinkOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("link shares content and rolls back", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("blob", []byte("v1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewCreateHardlinkOperation("link", "cache/blob", "blob")
+		if err := op.Validate(ctx, nil, fsys); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if err := fsys.WriteFile("blob", []byte("v2"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "cache/blob"); got != "v2" {
+			t.Errorf("link content = %q, want the target's new content", got)
+		}
+		a, _ := fsys.FileID("blob")
+		b, _ := fsys.FileID("cache/blob")
+		if a != b {
+			t.Errorf("expected equal file IDs, got %v and %v", a, b)
+		}
+
+		reverseOps, _, err := op.ReverseOps(ctx, fsys, nil)
+		if err != nil || len(reverseOps) != 1 || reverseOps[0].Describe().Type != "delete" {
+			t.Errorf("expected a delete reverse op, got %v %v", reverseOps, err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("cache/blob"); err == nil {
+			t.Error("expected rollback to remove the link")
+		}
+		if got := readFile(t, fsys, "blob"); got != "v2" {
+			t.Errorf("rollback changed the target: %q", got)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.MkdirAll("dir", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.WriteFile("file", []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.Symlink("file", "symlink"); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			link, target, reason string
+		}{
+			{"link", "", "cannot be empty"},
+			{"link", "missing", "does not exist"},
+			{"link", "dir", "must be a regular file"},
+			{"link", "symlink", "not a symlink"},
+			{"file", "file", "cannot point to itself"},
+			{"dir", "file", "already exists"},
+		}
+		for _, tt := range tests {
+			op := operations.NewCreateHardlinkOperation("link", tt.link, tt.target)
+			err := op.Validate(ctx, nil, fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("link %s -> %q: expected error containing %q, got %v", tt.link, tt.target, tt.reason, err)
+			}
+		}
+	})
+
+	t.Run("filesystem without link support", func(t *testing.T) {
+		fsys := NewMockFilesystem()
+		if err := fsys.WriteFile("file", []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewCreateHardlinkOperation("link", "link", "file")
+		if err := op.Execute(ctx, nil, fsys); err == nil || !strings.Contains(err.Error(), "does not support hard links") {
+			t.Errorf("expected an unsupported error, got %v", err)
+		}
+	})
+}
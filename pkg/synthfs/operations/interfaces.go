@@ -54,3 +54,23 @@ type ChecksumInterface interface {
 	GetMD5() string
 	GetSize() int64
 }
+
+// ExpandableOperation is implemented by operations that stand for a set of
+// finer-grained child operations. Expand is called at validation time, against
+// the projected filesystem, and the children are executed in place of the parent.
+type ExpandableOperation interface {
+	Operation
+	Expand(ctx context.Context, fsys filesystem.FileSystem) ([]Operation, error)
+}
+
+// EnsurableOperation is implemented by operations that can tell whether the
+// state they would produce already holds. In ensure mode such operations are
+// skipped instead of executed when Satisfied reports true.
+type EnsurableOperation interface {
+	Operation
+	Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error)
+}
+
+// Condition reports whether an operation should run, given the state of fsys
+// right before it would. See BaseOperation.SetCondition.
+type Condition func(ctx context.Context, fsys filesystem.FileSystem) (bool, error)
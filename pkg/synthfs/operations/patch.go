@@ -0,0 +1,505 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// DefaultPatchFuzz is the number of context lines a hunk may ignore at each
+// end when it does not apply cleanly, the same default as patch(1).
+const DefaultPatchFuzz = 2
+
+// noNewlineMarker follows a diff line that has no trailing newline.
+const noNewlineMarker = `\ No newline at end of file`
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ApplyPatchOperation applies a unified diff to a single file. Like patch(1),
+// a hunk may apply at an offset from the line its header names and, with
+// fuzz, ignore context lines at its ends. If any hunk does not apply the file
+// is left unchanged. A patch whose new file is /dev/null must remove every
+// line, and deletes the file. Its reverse is the inverted patch, so no copy
+// of the file is kept.
+type ApplyPatchOperation struct {
+	*BaseOperation
+	fileRewrite
+	diff    string
+	fuzz    int
+	deletes bool
+	reverse string
+}
+
+// NewApplyPatchOperation creates a new apply_patch operation with
+// DefaultPatchFuzz.
+func NewApplyPatchOperation(id core.OperationID, path string, diff string) *ApplyPatchOperation {
+	op := &ApplyPatchOperation{
+		BaseOperation: NewBaseOperation(id, "apply_patch", path),
+		diff:          diff,
+		deletes:       patchDeletesFile(diff),
+	}
+	op.WithFuzz(DefaultPatchFuzz)
+	if hunks, err := parsePatch(diff); err == nil {
+		op.SetDescriptionDetail("hunks", len(hunks))
+	}
+	if op.deletes {
+		op.SetDescriptionDetail("deletes_file", true)
+	}
+	return op
+}
+
+// WithFuzz sets how many context lines a hunk may ignore at each end. Zero
+// requires every context line to match.
+func (op *ApplyPatchOperation) WithFuzz(fuzz int) *ApplyPatchOperation {
+	op.fuzz = fuzz
+	op.SetDescriptionDetail("fuzz", fuzz)
+	return op
+}
+
+// Diff returns the unified diff the operation applies.
+func (op *ApplyPatchOperation) Diff() string {
+	return op.diff
+}
+
+// Fuzz returns how many context lines a hunk may ignore at each end.
+func (op *ApplyPatchOperation) Fuzz() int {
+	return op.fuzz
+}
+
+// Validate checks that the diff parses and the path is not a directory.
+func (op *ApplyPatchOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if op.fuzz < 0 {
+		return invalid("fuzz cannot be negative", nil)
+	}
+	if _, err := parsePatch(op.diff); err != nil {
+		return invalid("invalid patch", err)
+	}
+	if info, err := fsys.Stat(op.description.Path); err == nil && info.IsDir() {
+		return invalid("cannot patch a directory", nil)
+	}
+	return nil
+}
+
+// Execute applies the patch with event handling.
+func (op *ApplyPatchOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *ApplyPatchOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	if op.deletes {
+		return op.deleteFile(fsys)
+	}
+	var applied *patchResult
+	_, err := op.rewrite(fsys, op.description.Path, func(content []byte) ([]byte, bool, error) {
+		result, err := applyPatch(op.description.Path, content, op.diff, op.fuzz)
+		if err != nil {
+			return nil, false, err
+		}
+		applied = result
+		return result.content, true, nil
+	})
+	if err != nil {
+		return err
+	}
+	op.reverse = applied.reverse
+	op.SetOutput("offsets", applied.offsets)
+	op.SetOutput("fuzz_used", applied.fuzz)
+	return nil
+}
+
+// deleteFile applies a patch that removes the whole file, then removes the
+// file itself.
+func (op *ApplyPatchOperation) deleteFile(fsys filesystem.FileSystem) error {
+	p := op.description.Path
+	previous, err := captureRewriteTarget(fsys, p)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return fmt.Errorf("patch deletes %s, which does not exist", p)
+	}
+	result, err := applyPatch(p, previous.content, op.diff, op.fuzz)
+	if err != nil {
+		return err
+	}
+	if len(result.content) > 0 {
+		return fmt.Errorf("patch deletes %s but leaves %d bytes of it", p, len(result.content))
+	}
+	if err := fsys.Remove(p); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", p, err)
+	}
+	op.previous, op.applied = previous, true
+	op.reverse = result.reverse
+	op.SetOutput("offsets", result.offsets)
+	op.SetOutput("fuzz_used", result.fuzz)
+	return nil
+}
+
+// Satisfied reports whether the patch has already been applied: it no
+// longer applies, but its inverse applies exactly, as patch(1) detects a
+// reversed patch. A patch that deletes the file is satisfied once it is gone.
+func (op *ApplyPatchOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	current, err := captureRewriteTarget(fsys, op.description.Path)
+	if err != nil || current == nil {
+		return op.deletes && err == nil, err
+	}
+	hunks, err := parsePatch(op.diff)
+	if err != nil {
+		return false, nil
+	}
+	if _, err := applyPatch(op.description.Path, current.content, op.diff, op.fuzz); err == nil {
+		return false, nil
+	}
+	_, err = applyPatch(op.description.Path, current.content, invertPatch(hunks), 0)
+	return err == nil, nil
+}
+
+// Rollback applies the reverse patch recorded by Execute, removes the file if
+// Execute created it, or recreates it if Execute deleted it.
+func (op *ApplyPatchOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.applied || op.previous == nil || op.deletes {
+		return op.rollback(fsys, op.description.Path)
+	}
+	_, err := op.rewrite(fsys, op.description.Path, func(content []byte) ([]byte, bool, error) {
+		result, err := applyPatch(op.description.Path, content, op.reverse, 0)
+		if err != nil {
+			return nil, false, err
+		}
+		return result.content, true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reverse patch: %w", err)
+	}
+	op.applied = false
+	return nil
+}
+
+// ReverseOps dry-runs the patch against the current file and returns an
+// exact inverse: a patch with the added and removed lines swapped and the
+// hunks placed where they applied. A patch that creates the file is reversed
+// by deleting it, and one that deletes the file by the inverted patch, which
+// recreates it, and a chmod back to its mode.
+func (op *ApplyPatchOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	current, err := captureRewriteTarget(fsys, op.description.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	reverseID := core.OperationID(fmt.Sprintf("reverse_%s", op.ID()))
+	if op.deletes && current != nil {
+		result, err := applyPatch(op.description.Path, current.content, op.diff, op.fuzz)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []Operation{
+			NewApplyPatchOperation(reverseID, op.description.Path, result.reverse).WithFuzz(0),
+			NewChmodOperation(core.OperationID(fmt.Sprintf("%s_mode", reverseID)), op.description.Path, current.mode),
+		}, nil, nil
+	}
+	if current == nil {
+		if _, err := applyPatch(op.description.Path, nil, op.diff, op.fuzz); err != nil {
+			return nil, nil, err
+		}
+		return []Operation{NewDeleteOperation(reverseID, op.description.Path)}, nil, nil
+	}
+	result, err := applyPatch(op.description.Path, current.content, op.diff, op.fuzz)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []Operation{NewApplyPatchOperation(reverseID, op.description.Path, result.reverse).WithFuzz(0)}, nil, nil
+}
+
+// patchHunk is one @@ section of a unified diff.
+type patchHunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	lines              []patchLine
+}
+
+// patchLine is a hunk line: ' ' for context, '-' for removed and '+' for
+// added. text includes the line's newline unless the diff marked it missing.
+type patchLine struct {
+	kind byte
+	text string
+}
+
+// parsePatch reads the hunks of a unified diff for a single file. File
+// headers and other preamble lines are skipped.
+func parsePatch(diff string) ([]patchHunk, error) {
+	lines := strings.SplitAfter(diff, "\n")
+	var hunks []patchHunk
+	headers := 0
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "--- ") {
+			if headers++; headers > 1 || len(hunks) > 0 {
+				return nil, fmt.Errorf("patch changes more than one file")
+			}
+			continue
+		}
+		m := hunkHeaderRegex.FindStringSubmatch(line)
+		if m == nil {
+			if len(hunks) > 0 && strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("unexpected line %q after hunk %d", strings.TrimRight(line, "\n"), len(hunks))
+			}
+			continue
+		}
+
+		hunk := patchHunk{
+			oldStart: atoiDefault(m[1], 0), oldCount: atoiDefault(m[2], 1),
+			newStart: atoiDefault(m[3], 0), newCount: atoiDefault(m[4], 1),
+		}
+		oldSeen, newSeen := 0, 0
+		for oldSeen < hunk.oldCount || newSeen < hunk.newCount {
+			if i++; i >= len(lines) || lines[i] == "" {
+				return nil, fmt.Errorf("hunk %d is truncated", len(hunks)+1)
+			}
+			text := lines[i]
+			kind := text[0]
+			if text == "\n" || text == "\r\n" {
+				// Some editors strip the leading space of empty context lines
+				kind, text = ' ', " "+text
+			}
+			switch kind {
+			case ' ':
+				oldSeen++
+				newSeen++
+			case '-':
+				oldSeen++
+			case '+':
+				newSeen++
+			default:
+				return nil, fmt.Errorf("hunk %d: unexpected line %q", len(hunks)+1, strings.TrimRight(text, "\n"))
+			}
+			hunk.lines = append(hunk.lines, patchLine{kind: kind, text: text[1:]})
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], `\`) {
+				i++
+				last := &hunk.lines[len(hunk.lines)-1]
+				last.text = strings.TrimSuffix(last.text, "\n")
+			}
+		}
+		if oldSeen != hunk.oldCount || newSeen != hunk.newCount {
+			return nil, fmt.Errorf("hunk %d does not match its header line counts", len(hunks)+1)
+		}
+		hunks = append(hunks, hunk)
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch has no hunks")
+	}
+	return hunks, nil
+}
+
+// patchDeletesFile reports whether the diff's new file header is /dev/null.
+func patchDeletesFile(diff string) bool {
+	for _, line := range strings.Split(diff, "\n") {
+		if hunkHeaderRegex.MatchString(line) {
+			return false
+		}
+		if name, ok := strings.CutPrefix(line, "+++ "); ok {
+			name, _, _ = strings.Cut(name, "\t")
+			return strings.TrimSpace(name) == "/dev/null"
+		}
+	}
+	return false
+}
+
+func atoiDefault(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// side returns the lines a hunk expects (old) or produces (new).
+func (h patchHunk) side(old bool) []string {
+	var out []string
+	for _, line := range h.lines {
+		if line.kind == ' ' || (old && line.kind == '-') || (!old && line.kind == '+') {
+			out = append(out, line.text)
+		}
+	}
+	return out
+}
+
+// trimContext drops up to fuzz context lines from each end of the hunk and
+// reports how many were dropped from the start.
+func (h patchHunk) trimContext(fuzz int) (patchHunk, int) {
+	lead, trail := 0, 0
+	for lead < len(h.lines) && lead < fuzz && h.lines[lead].kind == ' ' {
+		lead++
+	}
+	for trail < len(h.lines)-lead && trail < fuzz && h.lines[len(h.lines)-1-trail].kind == ' ' {
+		trail++
+	}
+	trimmed := h
+	trimmed.lines = h.lines[lead : len(h.lines)-trail]
+	return trimmed, lead
+}
+
+// patchResult is the outcome of applying a patch: the new content, where each
+// hunk applied, and the inverse patch.
+type patchResult struct {
+	content []byte
+	offsets []int
+	fuzz    []int
+	reverse string
+}
+
+// applyPatch applies diff to content. Each hunk is searched for at the line
+// its header names, shifted by the lines earlier hunks added or removed and
+// by the offset the previous hunk needed, then at growing distances either
+// side. Hunks must apply in order; the first that cannot is an error.
+func applyPatch(path string, content []byte, diff string, maxFuzz int) (*patchResult, error) {
+	hunks, err := parsePatch(diff)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	result := &patchResult{}
+	var reverse strings.Builder
+	fmt.Fprintf(&reverse, "--- %s\n+++ %s\n", path, path)
+	shift, carried, floor := 0, 0, 0
+	for n, hunk := range hunks {
+		base := hunk.oldStart - 1
+		if hunk.oldCount == 0 {
+			base = hunk.oldStart
+		}
+
+		at, fuzz, expected := -1, 0, 0
+		var used patchHunk
+		for f := 0; f <= maxFuzz; f++ {
+			candidate, lead := hunk.trimContext(f)
+			if f > 0 && len(candidate.lines) == len(used.lines) {
+				break // nothing left to trim
+			}
+			used, fuzz, expected = candidate, f, base+lead+shift
+			if at = findHunk(lines, used.side(true), expected+carried, floor); at >= 0 {
+				break
+			}
+		}
+		if at < 0 {
+			return nil, fmt.Errorf("hunk #%d FAILED at %d: context does not match %s", n+1, hunk.oldStart, path)
+		}
+		carried = at - expected
+
+		oldLines, newLines := used.side(true), used.side(false)
+		updated := make([]string, 0, len(lines)-len(oldLines)+len(newLines))
+		updated = append(append(append(updated, lines[:at]...), newLines...), lines[at+len(oldLines):]...)
+		lines = updated
+
+		writeReverseHunk(&reverse, used, at, at-shift)
+		result.offsets = append(result.offsets, carried)
+		result.fuzz = append(result.fuzz, fuzz)
+		shift += len(newLines) - len(oldLines)
+		floor = at + len(newLines)
+	}
+	result.content = []byte(strings.Join(lines, ""))
+	result.reverse = reverse.String()
+	return result, nil
+}
+
+// findHunk returns the first index at or after floor where want matches
+// lines, trying expected first and then moving outward one line at a time.
+func findHunk(lines, want []string, expected, floor int) int {
+	last := len(lines) - len(want)
+	if last < floor {
+		return -1
+	}
+	matches := func(at int) bool {
+		if at < floor || at > last {
+			return false
+		}
+		for i, line := range want {
+			if lines[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	if expected < floor {
+		expected = floor
+	}
+	if expected > last {
+		expected = last
+	}
+	for distance := 0; expected-distance >= floor || expected+distance <= last; distance++ {
+		if matches(expected - distance) {
+			return expected - distance
+		}
+		if distance > 0 && matches(expected+distance) {
+			return expected + distance
+		}
+	}
+	return -1
+}
+
+// writeReverseHunk writes the inverse of a hunk that applied at index at in
+// the new content and index origAt in the old content.
+func writeReverseHunk(w *strings.Builder, hunk patchHunk, at, origAt int) {
+	oldCount, newCount := len(hunk.side(false)), len(hunk.side(true))
+	fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(at, oldCount), hunkRange(origAt, newCount))
+	writeInvertedLines(w, hunk)
+}
+
+// writeInvertedLines writes the lines of a hunk with additions and removals swapped.
+func writeInvertedLines(w *strings.Builder, hunk patchHunk) {
+	for _, line := range hunk.lines {
+		kind := line.kind
+		switch kind {
+		case '-':
+			kind = '+'
+		case '+':
+			kind = '-'
+		}
+		w.WriteByte(kind)
+		w.WriteString(line.text)
+		if !strings.HasSuffix(line.text, "\n") {
+			w.WriteString("\n" + noNewlineMarker + "\n")
+		}
+	}
+}
+
+// invertPatch writes hunks back out with the added and removed lines swapped.
+func invertPatch(hunks []patchHunk) string {
+	var w strings.Builder
+	for _, hunk := range hunks {
+		fmt.Fprintf(&w, "@@ -%d,%d +%d,%d @@\n", hunk.newStart, hunk.newCount, hunk.oldStart, hunk.oldCount)
+		writeInvertedLines(&w, hunk)
+	}
+	return w.String()
+}
+
+// hunkRange formats a hunk header range. An empty range names the line
+// before it, as diff(1) does.
+func hunkRange(index, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", index)
+	}
+	return fmt.Sprintf("%d,%d", index+1, count)
+}
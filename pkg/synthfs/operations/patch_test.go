@@ -0,0 +1,269 @@
+package operations_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func numberedLines(from, to int) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+func TestApplyPatchOperation(t *testing.T) {
+	ctx := context.Background()
+	original := "one\ntwo\nthree\nfour\nfive\nsix\nseven\n"
+	diff := `--- a/file
++++ b/file
+@@ -2,5 +2,5 @@
+ two
+ three
+-four
++FOUR
+ five
+ six
+`
+
+	tests := []struct {
+		name    string
+		content string
+		diff    string
+		fuzz    int
+		want    string
+		offsets []int
+		fuzzed  []int
+	}{
+		{
+			name:    "applies at the named line",
+			content: original,
+			diff:    diff,
+			fuzz:    operations.DefaultPatchFuzz,
+			want:    "one\ntwo\nthree\nFOUR\nfive\nsix\nseven\n",
+			offsets: []int{0},
+			fuzzed:  []int{0},
+		},
+		{
+			name:    "applies at an offset",
+			content: "zero\nzero\n" + original,
+			diff:    diff,
+			fuzz:    0,
+			want:    "zero\nzero\none\ntwo\nthree\nFOUR\nfive\nsix\nseven\n",
+			offsets: []int{2},
+			fuzzed:  []int{0},
+		},
+		{
+			name:    "applies with fuzz",
+			content: "one\nTWO\nthree\nfour\nfive\nSIX\nseven\n",
+			diff:    diff,
+			fuzz:    1,
+			want:    "one\nTWO\nthree\nFOUR\nfive\nSIX\nseven\n",
+			offsets: []int{0},
+			fuzzed:  []int{1},
+		},
+		{
+			name:    "creates a file from empty",
+			content: "",
+			diff:    "--- /dev/null\n+++ b/new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			want:    "a\nb\n",
+			offsets: []int{0},
+			fuzzed:  []int{0},
+		},
+		{
+			name:    "adds a missing final newline",
+			content: "a\nb",
+			diff:    "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			want:    "a\nb\n",
+			offsets: []int{0},
+			fuzzed:  []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := filesystem.NewTestFileSystem()
+			if err := fsys.WriteFile("file", []byte(tt.content), 0640); err != nil {
+				t.Fatal(err)
+			}
+			op := operations.NewApplyPatchOperation("patch", "file", tt.diff).WithFuzz(tt.fuzz)
+			if err := op.Validate(ctx, nil, fsys); err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			reverseOps, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{})
+			if err != nil {
+				t.Fatalf("ReverseOps failed: %v", err)
+			}
+			if backup != nil || len(reverseOps) != 1 {
+				t.Fatalf("expected a single reverse patch and no backup, got %v %v", reverseOps, backup)
+			}
+			if err := op.Execute(ctx, nil, fsys); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got := readFile(t, fsys, "file"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			offsets, fuzzed := output(op, "offsets"), output(op, "fuzz_used")
+			if !reflect.DeepEqual(offsets, tt.offsets) || !reflect.DeepEqual(fuzzed, tt.fuzzed) {
+				t.Errorf("offsets %v fuzz %v, want %v %v", offsets, fuzzed, tt.offsets, tt.fuzzed)
+			}
+
+			if err := reverseOps[0].Execute(ctx, nil, fsys); err != nil {
+				t.Fatalf("reverse patch failed: %v\n%s", err, reverseOps[0].(*operations.ApplyPatchOperation).Diff())
+			}
+			if got := readFile(t, fsys, "file"); got != tt.content {
+				t.Errorf("reverse patch gave %q, want %q", got, tt.content)
+			}
+		})
+	}
+
+	t.Run("rejects hunks that do not apply", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		content := "one\ntwo\nthree\nfour\nfive\nsix\nseven\n"
+		if err := fsys.WriteFile("file", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		twoHunks := "@@ -1,1 +1,1 @@\n-one\n+ONE\n@@ -4,1 +4,1 @@\n-missing\n+x\n"
+		op := operations.NewApplyPatchOperation("patch", "file", twoHunks)
+		if err := op.Execute(ctx, nil, fsys); err == nil || !strings.Contains(err.Error(), "hunk #2 FAILED") {
+			t.Errorf("expected hunk #2 to fail, got %v", err)
+		}
+		if got := readFile(t, fsys, "file"); got != content {
+			t.Errorf("file changed after a rejected patch: %q", got)
+		}
+	})
+
+	t.Run("fuzz zero needs all context", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("file", []byte("one\nTWO\nthree\nfour\nfive\nsix\nseven\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewApplyPatchOperation("patch", "file", diff).WithFuzz(0)
+		if err := op.Execute(ctx, nil, fsys); err == nil {
+			t.Error("expected the patch to be rejected without fuzz")
+		}
+	})
+
+	t.Run("rollback of a large file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		content := numberedLines(1, 2000)
+		if err := fsys.WriteFile("big", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.SplitAfter(content, "\n")
+		var d strings.Builder
+		d.WriteString("@@ -1000,3 +1000,2 @@\n")
+		d.WriteString(" " + lines[999])
+		d.WriteString("-" + lines[1000])
+		d.WriteString(" " + lines[1001])
+		op := operations.NewApplyPatchOperation("patch", "big", d.String())
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "big"); strings.Contains(got, lines[1000]) {
+			t.Fatal("line was not removed")
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "big"); got != content {
+			t.Error("rollback did not restore the original content")
+		}
+	})
+
+	t.Run("rollback removes a created file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := operations.NewApplyPatchOperation("patch", "new", "@@ -0,0 +1 @@\n+a\n")
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("new"); err == nil {
+			t.Error("expected the file to be removed")
+		}
+	})
+
+	t.Run("a patch to /dev/null deletes the file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("old", []byte("a\nb\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewApplyPatchOperation("patch", "old", "--- a/old\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n")
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{})
+		if err != nil || backup != nil || len(reverseOps) != 2 {
+			t.Fatalf("expected a reverse patch and chmod without backup, got %v %v %v", reverseOps, backup, err)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if _, err := fsys.Stat("old"); err == nil {
+			t.Fatal("expected the file to be deleted")
+		}
+		if satisfied, err := op.Satisfied(ctx, fsys); err != nil || !satisfied {
+			t.Errorf("expected the deletion to be satisfied, got %v %v", satisfied, err)
+		}
+
+		for _, reverseOp := range reverseOps {
+			if err := reverseOp.Execute(ctx, nil, fsys); err != nil {
+				t.Fatalf("reverse %s failed: %v", reverseOp.Describe().Type, err)
+			}
+		}
+		if got := readFile(t, fsys, "old"); got != "a\nb\n" {
+			t.Errorf("reverse ops recreated %q", got)
+		}
+		if info, _ := fsys.Stat("old"); info.Mode().Perm() != 0600 {
+			t.Errorf("reverse ops recreated mode %o", info.Mode().Perm())
+		}
+
+		if err := fsys.Remove("old"); err != nil {
+			t.Fatal(err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "old"); got != "a\nb\n" {
+			t.Errorf("rollback recreated %q", got)
+		}
+	})
+
+	t.Run("a patch to /dev/null must empty the file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("old", []byte("a\nb\nc\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewApplyPatchOperation("patch", "old", "--- a/old\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n")
+		if err := op.Execute(ctx, nil, fsys); err == nil || !strings.Contains(err.Error(), "leaves") {
+			t.Errorf("expected a partial deletion to fail, got %v", err)
+		}
+		if got := readFile(t, fsys, "old"); got != "a\nb\nc\n" {
+			t.Errorf("file changed after a rejected patch: %q", got)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		invalid := []string{
+			"",
+			"just text\n",
+			"@@ -1,2 +1,2 @@\n a\n",
+			"@@ -1 +1 @@\n*a\n",
+			"--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n--- a/y\n+++ b/y\n@@ -1 +1 @@\n-a\n+b\n",
+		}
+		for _, diff := range invalid {
+			op := operations.NewApplyPatchOperation("patch", "f", diff)
+			if err := op.Validate(ctx, nil, fsys); err == nil {
+				t.Errorf("expected %q to fail validation", diff)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// renderFunc computes the new content of a file from its current content,
+// which is nil when the file does not exist, and reports whether it changed.
+type renderFunc func(content []byte) ([]byte, bool, error)
+
+// defaultFileMode is the mode of files created by in-place edits.
+const defaultFileMode fs.FileMode = 0644
+
+// fileRewrite is the state shared by operations that edit a single file in
+// place: what the file held before, so Rollback can put it back.
+type fileRewrite struct {
+	previous *entryState
+	applied  bool
+}
+
+// rewrite renders the file at p and writes the result when it changed. A
+// missing file is rendered from empty content and created.
+func (r *fileRewrite) rewrite(fsys filesystem.FileSystem, p string, render renderFunc) (bool, error) {
+	previous, err := captureRewriteTarget(fsys, p)
+	if err != nil {
+		return false, err
+	}
+	output, changed, err := renderEntry(previous, render)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	mode := defaultFileMode
+	if previous != nil {
+		mode = previous.mode
+	}
+	if err := fsys.WriteFile(p, output, mode); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", p, err)
+	}
+	r.previous = previous
+	r.applied = true
+	return true, nil
+}
+
+// rollback restores the file as it was before rewrite, removing it if rewrite created it.
+func (r *fileRewrite) rollback(fsys filesystem.FileSystem, p string) error {
+	if !r.applied {
+		return nil
+	}
+	if err := restoreEntry(fsys, p, r.previous); err != nil {
+		return err
+	}
+	r.applied = false
+	return nil
+}
+
+// reverseOpsForRewrite backs up the file at p into the budget. An edit that
+// would not change the file needs no reverse operations.
+func reverseOpsForRewrite(id core.OperationID, fsys filesystem.FileSystem, p string, render renderFunc, budget interface{}) ([]Operation, interface{}, error) {
+	current, err := captureRewriteTarget(fsys, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, changed, err := renderEntry(current, render); err == nil && !changed {
+		return nil, nil, nil
+	}
+	return reverseOpsForEntry(id, p, current, budget)
+}
+
+// rewriteSatisfied reports whether render would leave the file at p unchanged.
+func rewriteSatisfied(fsys filesystem.FileSystem, p string, render renderFunc) (bool, error) {
+	current, err := captureRewriteTarget(fsys, p)
+	if err != nil {
+		return false, err
+	}
+	_, changed, err := renderEntry(current, render)
+	if err != nil {
+		return false, err
+	}
+	return !changed, nil
+}
+
+func captureRewriteTarget(fsys filesystem.FileSystem, p string) (*entryState, error) {
+	current, err := captureEntry(fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	if current != nil && current.itemType != "file" {
+		return nil, fmt.Errorf("cannot edit %s: it is a %s", p, current.itemType)
+	}
+	return current, nil
+}
+
+func renderEntry(entry *entryState, render renderFunc) ([]byte, bool, error) {
+	var content []byte
+	if entry != nil {
+		content = entry.content
+	}
+	return render(content)
+}
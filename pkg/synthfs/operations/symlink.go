@@ -134,6 +134,13 @@ func (op *CreateSymlinkOperation) Validate(ctx context.Context, execCtx *core.Ex
 	return nil
 }
 
+// Satisfied reports whether the path is already a symlink to the target.
+func (op *CreateSymlinkOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	target, _ := op.description.Details["target"].(string)
+	current, err := fsys.Readlink(op.description.Path)
+	return err == nil && target != "" && current == target, nil
+}
+
 // Rollback removes the created symlink.
 func (op *CreateSymlinkOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
 	// Remove the symlink
@@ -0,0 +1,789 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
+)
+
+// SyncCompare selects how a sync decides that a destination file is out of date.
+type SyncCompare int
+
+const (
+	// SyncCompareSizeMTime treats a file as changed when its size or modification time differ.
+	SyncCompareSizeMTime SyncCompare = iota
+	// SyncCompareChecksum treats a file as changed when its content checksum differs.
+	SyncCompareChecksum
+)
+
+// SyncOptions controls how a sync operation converges its destination.
+type SyncOptions struct {
+	// Compare selects how existing files are compared. Defaults to size and modification time.
+	Compare SyncCompare
+	// Delete removes destination entries that don't exist in the source.
+	Delete bool
+	// Include limits the sync to files and symlinks matching these patterns.
+	// Directories are always walked and mirrored. Empty includes everything.
+	Include []string
+	// Exclude skips paths matching these patterns, in both trees. An excluded
+	// directory is skipped with everything below it, and excluded destination
+	// entries are never deleted.
+	Exclude []string
+	// PreserveMetadata carries source permissions onto existing files and copies
+	// modification times when the filesystem implements filesystem.ChtimesFS.
+	// Without it, size+mtime comparison sees every copied file as changed.
+	PreserveMetadata bool
+	// PreserveHardlinks recreates files that are hard links to each other in
+	// the source as hard links in the destination, instead of separate copies.
+	// The filesystem must implement filesystem.LinkFS.
+	PreserveHardlinks bool
+}
+
+// matchesSyncPattern reports whether rel matches any of the patterns. Patterns
+// are path.Match globs, matched against the path relative to the sync root and
+// against its base name.
+func matchesSyncPattern(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(rel)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncOperation converges a destination directory to match a source directory,
+// in the spirit of rsync. It expands into one SyncFileOperation or
+// PruneOperation per path that needs to change.
+type SyncOperation struct {
+	*BaseOperation
+	options SyncOptions
+	run     ExpandedRun
+}
+
+// NewSyncOperation creates a new sync operation.
+func NewSyncOperation(id core.OperationID, src, dst string, options SyncOptions) *SyncOperation {
+	op := &SyncOperation{
+		BaseOperation: NewBaseOperation(id, "sync", src),
+		options:       options,
+	}
+	op.SetPaths(src, dst)
+	op.SetDescriptionDetail("src", src)
+	op.SetDescriptionDetail("dst", dst)
+	op.SetDescriptionDetail("delete", options.Delete)
+	return op
+}
+
+// Options returns the sync options.
+func (op *SyncOperation) Options() SyncOptions {
+	return op.options
+}
+
+// Prerequisites returns the prerequisites for syncing
+func (op *SyncOperation) Prerequisites() []core.Prerequisite {
+	src, _ := op.GetPaths()
+	if src == "" {
+		return nil
+	}
+	return []core.Prerequisite{core.NewSourceExistsPrerequisite(src)}
+}
+
+// Validate checks that the source exists and the destination can mirror it.
+func (op *SyncOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+
+	src, dst := op.GetPaths()
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+
+	if src == "" || dst == "" {
+		return invalid("sync requires both source and destination paths", nil)
+	}
+	if src == dst || strings.HasPrefix(dst, src+"/") || strings.HasPrefix(src, dst+"/") {
+		return invalid("source and destination must not overlap", nil)
+	}
+
+	srcInfo, err := fsys.Stat(src)
+	if err != nil {
+		return invalid("source does not exist", err)
+	}
+	if dstInfo, err := fsys.Stat(dst); err == nil && dstInfo.IsDir() != srcInfo.IsDir() {
+		return invalid("source and destination must both be files or both be directories", nil)
+	}
+	return nil
+}
+
+// Expand computes the child operations that bring the destination in line
+// with the source. Removals come first, deepest path first, followed by
+// creations and updates in path order. Child IDs are derived from the parent
+// ID and the relative path, so they are stable across runs.
+func (op *SyncOperation) Expand(ctx context.Context, fsys filesystem.FileSystem) ([]Operation, error) {
+	src, dst := op.GetPaths()
+	srcTree, _, err := scanSyncTree(fsys, src, op.options)
+	if err != nil {
+		return nil, err
+	}
+	dstTree, kept, err := scanSyncTree(fsys, dst, op.options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Destination directories replaced by a source file or symlink must be emptied
+	var conflicts []string
+	for rel, dstNode := range dstTree {
+		if srcNode, ok := srcTree[rel]; ok && dstNode.itemType == "directory" && srcNode.itemType != "directory" {
+			if kept[rel] {
+				return nil, fmt.Errorf("cannot replace directory %s: it contains excluded entries", path.Join(dst, rel))
+			}
+			conflicts = append(conflicts, rel)
+		}
+	}
+
+	var children []Operation
+	pruned := make(map[string]bool)
+	for _, rel := range sortedSyncPaths(dstTree, true) {
+		srcNode, inSrc := srcTree[rel]
+		replaced := underSyncDir(rel, conflicts) || (inSrc && srcNode.itemType != dstTree[rel].itemType)
+		extraneous := !inSrc && op.options.Delete && !kept[rel]
+		if !replaced && !extraneous {
+			continue
+		}
+		pruned[rel] = true
+		children = append(children, NewPruneOperation(op.childID("prune", rel), path.Join(dst, rel)))
+	}
+
+	// Comparing checksums reads both files, so it is reported as progress
+	progress := core.ProgressFromContext(ctx)
+	for rel, srcNode := range srcTree {
+		if dstNode, inDst := dstTree[rel]; inDst && !pruned[rel] && op.needsChecksum(srcNode, dstNode) {
+			progress.AddTotal(srcNode.size+dstNode.size, 2)
+		}
+	}
+
+	// syncNeeded memoizes whether a source entry must be copied over its destination
+	syncNeeded := make(map[string]bool)
+	needsSync := func(rel string) (bool, error) {
+		if needed, ok := syncNeeded[rel]; ok {
+			return needed, nil
+		}
+		needed := true
+		if dstNode, inDst := dstTree[rel]; inDst && !pruned[rel] {
+			changed, err := op.changed(ctx, fsys, path.Join(src, rel), path.Join(dst, rel), srcTree[rel], dstNode)
+			if err != nil {
+				return false, err
+			}
+			needed = changed
+		}
+		syncNeeded[rel] = needed
+		return needed, nil
+	}
+
+	// Hard links in the destination are kept while their group's first file is
+	// unchanged; otherwise the stale destination entry is pruned and relinked
+	links := make(map[string]string)
+	relink := make(map[string]bool)
+	if op.options.PreserveHardlinks {
+		if links, err = hardlinkGroups(fsys, src, srcTree); err != nil {
+			return nil, err
+		}
+		for _, rel := range sortedSyncPaths(srcTree, false) {
+			leader, ok := links[rel]
+			if !ok {
+				continue
+			}
+			resync, err := needsSync(leader)
+			if err != nil {
+				return nil, err
+			}
+			dstNode, inDst := dstTree[rel]
+			if !resync && inDst && !pruned[rel] && dstNode.itemType == "file" &&
+				sameFile(fsys, path.Join(dst, rel), path.Join(dst, leader)) {
+				continue
+			}
+			relink[rel] = true
+			if inDst && !pruned[rel] {
+				pruned[rel] = true
+				children = append(children, NewPruneOperation(op.childID("prune", rel), path.Join(dst, rel)))
+			}
+		}
+	}
+
+	var paths []string
+	for _, rel := range sortedSyncPaths(srcTree, false) {
+		if leader, ok := links[rel]; ok {
+			if relink[rel] {
+				children = append(children, NewCreateHardlinkOperation(op.childID("link", rel), path.Join(dst, rel), path.Join(dst, leader)))
+				paths = append(paths, rel)
+			}
+			continue
+		}
+		needed, err := needsSync(rel)
+		if err != nil {
+			return nil, err
+		}
+		if !needed {
+			continue
+		}
+		child := NewSyncFileOperation(op.childID("sync", rel), path.Join(src, rel), path.Join(dst, rel))
+		child.preserve = op.options.PreserveMetadata
+		children = append(children, child)
+		paths = append(paths, rel)
+	}
+
+	ids := make([]string, len(children))
+	for i, child := range children {
+		ids[i] = string(child.ID())
+	}
+	op.SetDescriptionDetail("children", ids)
+	op.SetDescriptionDetail("synced_paths", paths)
+	return children, nil
+}
+
+func (op *SyncOperation) childID(action, rel string) core.OperationID {
+	return core.OperationID(fmt.Sprintf("%s:%s:%s", op.ID(), action, rel))
+}
+
+// changed reports whether an existing destination entry differs from its source.
+func (op *SyncOperation) changed(ctx context.Context, fsys filesystem.FileSystem, srcPath, dstPath string, srcNode, dstNode syncNode) (bool, error) {
+	switch srcNode.itemType {
+	case "directory":
+		return false, nil
+	case "symlink":
+		return srcNode.target != dstNode.target, nil
+	}
+
+	if !op.needsChecksum(srcNode, dstNode) {
+		if op.options.PreserveMetadata && srcNode.mode != dstNode.mode {
+			return true, nil
+		}
+		if srcNode.size != dstNode.size {
+			return true, nil
+		}
+		return !srcNode.modTime.Equal(dstNode.modTime), nil
+	}
+
+	srcSum, err := validation.ComputeFileChecksumContext(ctx, fsys, srcPath)
+	if err != nil {
+		return false, err
+	}
+	dstSum, err := validation.ComputeFileChecksumContext(ctx, fsys, dstPath)
+	if err != nil {
+		return false, err
+	}
+	return srcSum.MD5 != dstSum.MD5, nil
+}
+
+// needsChecksum reports whether changed compares the content of two files,
+// which is the case when nothing cheaper tells them apart.
+func (op *SyncOperation) needsChecksum(srcNode, dstNode syncNode) bool {
+	if srcNode.itemType != "file" || op.options.Compare != SyncCompareChecksum {
+		return false
+	}
+	if op.options.PreserveMetadata && srcNode.mode != dstNode.mode {
+		return false
+	}
+	return srcNode.size == dstNode.size
+}
+
+// Execute runs the sync directly. Pipelines normally expand the operation
+// first; this path serves callers that execute it on its own.
+func (op *SyncOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *SyncOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Run(ctx, fsys, op)
+}
+
+// Rollback restores every destination path that Execute changed.
+func (op *SyncOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Rollback(ctx, fsys)
+}
+
+// ReverseOps fails; the sync_file and prune operations reverse themselves.
+func (op *SyncOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	return op.run.ReverseOps("sync")
+}
+
+// syncNode is the state of one path in a sync tree.
+type syncNode struct {
+	itemType string
+	mode     fs.FileMode
+	size     int64
+	modTime  time.Time
+	target   string
+}
+
+// scanSyncTree records every path below root, keyed by its path relative to
+// root ("." for root itself). It also returns the directories that contain
+// entries skipped by the include and exclude patterns. A missing root yields
+// an empty tree.
+func scanSyncTree(fsys filesystem.FileSystem, root string, options SyncOptions) (map[string]syncNode, map[string]bool, error) {
+	tree := make(map[string]syncNode)
+	kept := make(map[string]bool)
+	if _, err := fsys.Stat(root); errors.Is(err, fs.ErrNotExist) {
+		return tree, kept, nil
+	}
+
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := "."
+		if p != root {
+			rel = strings.TrimPrefix(p, root+"/")
+		}
+
+		isDir := d.IsDir()
+		if rel != "." {
+			skip := matchesSyncPattern(rel, options.Exclude)
+			if !isDir && len(options.Include) > 0 && !matchesSyncPattern(rel, options.Include) {
+				skip = true
+			}
+			if skip {
+				for dir := path.Dir(rel); ; dir = path.Dir(dir) {
+					kept[dir] = true
+					if dir == "." {
+						break
+					}
+				}
+				if isDir {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		node := syncNode{mode: info.Mode().Perm(), size: info.Size(), modTime: info.ModTime()}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			node.itemType = "symlink"
+			if node.target, err = fsys.Readlink(p); err != nil {
+				return err
+			}
+		case isDir:
+			node.itemType = "directory"
+		default:
+			node.itemType = "file"
+		}
+		tree[rel] = node
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return tree, kept, nil
+}
+
+// hardlinkGroups maps each file below root that is a hard link to an earlier
+// file, in path order, to that earlier file. Files without an identity, such
+// as ones created earlier in the run, are treated as unlinked.
+func hardlinkGroups(fsys filesystem.FileSystem, root string, tree map[string]syncNode) (map[string]string, error) {
+	linkFS, ok := fsys.(filesystem.LinkFS)
+	if !ok {
+		return nil, fmt.Errorf("filesystem does not support hard links")
+	}
+	leaders := make(map[filesystem.FileID]string)
+	links := make(map[string]string)
+	for _, rel := range sortedSyncPaths(tree, false) {
+		if tree[rel].itemType != "file" {
+			continue
+		}
+		id, err := linkFS.FileID(path.Join(root, rel))
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, fmt.Errorf("filesystem does not support hard links")
+		}
+		if err != nil {
+			continue
+		}
+		if leader, ok := leaders[id]; ok {
+			links[rel] = leader
+		} else {
+			leaders[id] = rel
+		}
+	}
+	return links, nil
+}
+
+func sortedSyncPaths(tree map[string]syncNode, reverse bool) []string {
+	paths := make([]string, 0, len(tree))
+	for rel := range tree {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	if reverse {
+		for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+			paths[i], paths[j] = paths[j], paths[i]
+		}
+	}
+	return paths
+}
+
+func underSyncDir(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// entryState is a captured copy of a single path, used to undo sync children.
+type entryState struct {
+	itemType string
+	mode     fs.FileMode
+	modTime  time.Time
+	content  []byte
+	target   string
+}
+
+// captureEntry records the state of p, or returns nil if it does not exist.
+func captureEntry(fsys filesystem.FileSystem, p string) (*entryState, error) {
+	return captureEntryContext(context.Background(), fsys, p)
+}
+
+// captureEntryContext is captureEntry, reporting the content of a file read
+// to the progress tracker carried by ctx and stopping when ctx is done.
+func captureEntryContext(ctx context.Context, fsys filesystem.FileSystem, p string) (*entryState, error) {
+	if target, err := fsys.Readlink(p); err == nil {
+		return &entryState{itemType: "symlink", target: target}, nil
+	}
+	info, err := fsys.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &entryState{mode: info.Mode().Perm(), modTime: info.ModTime()}
+	if info.IsDir() {
+		state.itemType = "directory"
+		return state, nil
+	}
+	state.itemType = "file"
+	progress := core.ProgressFromContext(ctx)
+	progress.AddTotal(info.Size(), 1)
+	progress.StartEntry(p)
+	file, err := fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	if state.content, err = io.ReadAll(progress.Reader(core.ContextReader(ctx, file))); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// restoreEntry puts p back into a captured state. A nil state removes p.
+func restoreEntry(fsys filesystem.FileSystem, p string, state *entryState) error {
+	current, err := captureEntry(fsys, p)
+	if err != nil {
+		return err
+	}
+	if current != nil && (state == nil || current.itemType != state.itemType || state.itemType != "directory") {
+		if err := fsys.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+	if state == nil {
+		return nil
+	}
+	return applyEntry(fsys, p, state, true)
+}
+
+// applyEntry creates p from state. Parent directories are created as needed.
+func applyEntry(fsys filesystem.FileSystem, p string, state *entryState, preserveTimes bool) error {
+	if dir := path.Dir(p); dir != "." {
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create parent directory: %w", err)
+		}
+	}
+	switch state.itemType {
+	case "directory":
+		return fsys.MkdirAll(p, state.mode)
+	case "symlink":
+		return fsys.Symlink(state.target, p)
+	}
+	if err := fsys.WriteFile(p, state.content, state.mode); err != nil {
+		return err
+	}
+	if chtimes, ok := fsys.(filesystem.ChtimesFS); ok && preserveTimes {
+		if err := chtimes.Chtimes(p, state.modTime, state.modTime); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseOpsForEntry returns operations that restore a captured state, with
+// backup data for file content.
+func reverseOpsForEntry(id core.OperationID, p string, state *entryState, budget interface{}) ([]Operation, interface{}, error) {
+	reverseID := core.OperationID(fmt.Sprintf("reverse_%s", id))
+	if state == nil {
+		return []Operation{NewDeleteOperation(reverseID, p)}, nil, nil
+	}
+
+	switch state.itemType {
+	case "directory":
+		reverseOp := NewCreateDirectoryOperation(reverseID, p)
+		reverseOp.SetItem(&MinimalItem{path: p, itemType: "directory", mode: state.mode})
+		return []Operation{reverseOp}, nil, nil
+	case "symlink":
+		reverseOp := NewCreateSymlinkOperation(reverseID, p)
+		reverseOp.SetItem(&MinimalItem{path: p, itemType: "symlink"})
+		reverseOp.SetDescriptionDetail("target", state.target)
+		return []Operation{reverseOp}, nil, nil
+	}
+
+	sizeMB := float64(len(state.content)) / (1024 * 1024)
+	if backupBudget, ok := budget.(*core.BackupBudget); ok && backupBudget != nil {
+		if err := backupBudget.ConsumeBackup(sizeMB); err != nil {
+			return nil, nil, fmt.Errorf("budget exceeded: cannot backup file '%s' (%.2fMB): %w", p, sizeMB, err)
+		}
+	}
+	backupData := &core.BackupData{
+		OperationID:   id,
+		BackupType:    "file",
+		OriginalPath:  p,
+		BackupContent: state.content,
+		SizeMB:        sizeMB,
+		BackupTime:    time.Now(),
+		Metadata: map[string]interface{}{
+			"mode": state.mode,
+		},
+	}
+	reverseOp := NewCreateFileOperation(reverseID, p)
+	reverseOp.SetItem(&MinimalItem{path: p, itemType: "file", content: state.content, mode: state.mode})
+	return []Operation{reverseOp}, backupData, nil
+}
+
+// SyncFileOperation makes one destination path a copy of its source: a file,
+// a symlink or a directory. An existing destination of the same type is
+// overwritten; Rollback restores whatever was there before.
+type SyncFileOperation struct {
+	*BaseOperation
+	preserve bool
+	previous *entryState
+	applied  bool
+}
+
+// NewSyncFileOperation creates a new sync_file operation.
+func NewSyncFileOperation(id core.OperationID, src, dst string) *SyncFileOperation {
+	op := &SyncFileOperation{
+		BaseOperation: NewBaseOperation(id, "sync_file", dst),
+	}
+	op.SetPaths(src, dst)
+	op.SetDescriptionDetail("src", src)
+	op.SetDescriptionDetail("dst", dst)
+	return op
+}
+
+// Execute performs the sync_file operation with event handling.
+func (op *SyncFileOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *SyncFileOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	src, dst := op.GetPaths()
+	source, err := captureEntryContext(ctx, fsys, src)
+	if err != nil {
+		return fmt.Errorf("failed to read source %s: %w", src, err)
+	}
+	if source == nil {
+		return fmt.Errorf("source not found: %s", src)
+	}
+	previous, err := captureEntry(fsys, dst)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", dst, err)
+	}
+
+	if previous != nil && previous.itemType != "directory" {
+		keepMode := previous.itemType == "file" && source.itemType == "file" && !op.preserve
+		if keepMode {
+			source.mode = previous.mode
+		}
+		if err := fsys.Remove(dst); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", dst, err)
+		}
+	}
+	op.previous, op.applied = previous, true
+
+	if err := applyEntry(fsys, dst, source, op.preserve); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dst, err)
+	}
+	if source.itemType == "file" {
+		core.ProgressFromContext(ctx).EntryDone()
+		_ = op.computeAndStoreChecksum(fsys, dst)
+	}
+	return nil
+}
+
+// Validate checks that the source exists.
+func (op *SyncFileOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	src, _ := op.GetPaths()
+	if _, err := fsys.Stat(src); err != nil {
+		if _, linkErr := fsys.Readlink(src); linkErr != nil {
+			return &core.ValidationError{
+				OperationID:   op.ID(),
+				OperationDesc: op.Describe(),
+				Reason:        "source does not exist",
+				Cause:         err,
+			}
+		}
+	}
+	return nil
+}
+
+// Rollback restores the destination to its state before Execute.
+func (op *SyncFileOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if !op.applied {
+		return nil
+	}
+	_, dst := op.GetPaths()
+	if op.previous == nil {
+		if err := fsys.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", dst, err)
+		}
+		op.applied = false
+		return nil
+	}
+	if err := restoreEntry(fsys, dst, op.previous); err != nil {
+		return err
+	}
+	op.applied = false
+	return nil
+}
+
+// ReverseOps returns operations that restore the current destination.
+func (op *SyncFileOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	_, dst := op.GetPaths()
+	state, err := captureEntry(fsys, dst)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reverseOpsForEntry(op.ID(), dst, state, budget)
+}
+
+func (op *SyncFileOperation) computeAndStoreChecksum(fsys filesystem.FileSystem, p string) error {
+	checksum, err := validation.ComputeFileChecksum(fsys, p)
+	if err != nil {
+		return err
+	}
+	op.SetChecksum(p, checksum)
+	return nil
+}
+
+// PruneOperation removes a single file, symlink or empty directory and keeps
+// a copy so Rollback can put it back.
+type PruneOperation struct {
+	*BaseOperation
+	previous *entryState
+}
+
+// NewPruneOperation creates a new prune operation.
+func NewPruneOperation(id core.OperationID, p string) *PruneOperation {
+	return &PruneOperation{
+		BaseOperation: NewBaseOperation(id, "prune", p),
+	}
+}
+
+// Execute performs the prune operation with event handling.
+func (op *PruneOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *PruneOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	p := op.description.Path
+	previous, err := captureEntry(fsys, p)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", p, err)
+	}
+	if previous == nil {
+		return nil
+	}
+	if err := fsys.Remove(p); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", p, err)
+	}
+	op.previous = previous
+	return nil
+}
+
+// Validate checks that the path exists.
+func (op *PruneOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	if _, err := fsys.Stat(op.description.Path); err != nil {
+		if _, linkErr := fsys.Readlink(op.description.Path); linkErr != nil {
+			return &core.ValidationError{
+				OperationID:   op.ID(),
+				OperationDesc: op.Describe(),
+				Reason:        "path to prune does not exist",
+				Cause:         err,
+			}
+		}
+	}
+	return nil
+}
+
+// Rollback restores the removed entry.
+func (op *PruneOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	if op.previous == nil {
+		return nil
+	}
+	if err := applyEntry(fsys, op.description.Path, op.previous, true); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", op.description.Path, err)
+	}
+	op.previous = nil
+	return nil
+}
+
+// ReverseOps returns operations that recreate the entry.
+func (op *PruneOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	state, err := captureEntry(fsys, op.description.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state == nil {
+		return nil, nil, fmt.Errorf("cannot reverse prune operation: path %s does not exist", op.description.Path)
+	}
+	return reverseOpsForEntry(op.ID(), op.description.Path, state, budget)
+}
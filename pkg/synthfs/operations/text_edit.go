@@ -0,0 +1,495 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// TextEdit is one line-oriented change to a text file, in the spirit of
+// Ansible's lineinfile and blockinfile. Edits are idempotent: applying an edit
+// to its own result changes nothing.
+type TextEdit interface {
+	String() string
+	validate() error
+	apply(text *textLines) (bool, error)
+}
+
+// LineOptions controls where EnsureLine puts its line.
+type LineOptions struct {
+	// Match is a regular expression selecting the line to replace. The last
+	// matching line is replaced; without a match the line is inserted.
+	Match string
+	// After inserts the line after the last line matching this regular expression.
+	After string
+	// Before inserts the line before the first line matching this regular expression.
+	Before string
+}
+
+// DefaultBlockMarker delimits blocks managed by EnsureBlock. {mark} is
+// replaced with BEGIN and END.
+const DefaultBlockMarker = "# {mark} SYNTHFS MANAGED BLOCK"
+
+// BlockOptions controls the markers of a managed block and where a new block goes.
+type BlockOptions struct {
+	// Marker is the line that delimits the block, with {mark} standing for
+	// BEGIN or END. Defaults to DefaultBlockMarker; use a different one for
+	// files where # does not start a comment, or to manage several blocks.
+	Marker string
+	// After inserts a new block after the last line matching this regular expression.
+	After string
+	// Before inserts a new block before the first line matching this regular expression.
+	Before string
+}
+
+// EnsureLine makes sure line is present. With options.Match, the last
+// matching line is replaced by line. Otherwise, or when nothing matches, the
+// line is inserted at the anchor given by After or Before, or at the end of the file.
+func EnsureLine(line string, options LineOptions) TextEdit {
+	return &lineEdit{line: line, options: options}
+}
+
+// RemoveLine removes every line equal to line.
+func RemoveLine(line string) TextEdit {
+	return &lineEdit{line: line, absent: true}
+}
+
+// RemoveLines removes every line matching the regular expression pattern.
+func RemoveLines(pattern string) TextEdit {
+	return &lineEdit{absent: true, options: LineOptions{Match: pattern}}
+}
+
+// ReplaceRegexp replaces every match of pattern with replacement, which may
+// refer to groups as $1 or ${name}. Patterns match against the whole file;
+// use (?m) to anchor ^ and $ at line boundaries.
+func ReplaceRegexp(pattern, replacement string) TextEdit {
+	return &replaceEdit{pattern: pattern, replacement: replacement}
+}
+
+// EnsureBlock makes sure the lines of block sit between a pair of marker
+// lines, replacing the content of an existing block with the same markers.
+func EnsureBlock(block string, options BlockOptions) TextEdit {
+	return &blockEdit{block: block, options: options}
+}
+
+// RemoveBlock removes a managed block and its markers.
+func RemoveBlock(options BlockOptions) TextEdit {
+	return &blockEdit{absent: true, options: options}
+}
+
+// textLines is a text file split into lines, remembering its line endings.
+type textLines struct {
+	lines   []string
+	newline string
+	final   bool // The last line ends with a newline
+}
+
+func parseTextLines(content []byte) *textLines {
+	text := &textLines{newline: "\n", final: true}
+	s := string(content)
+	if s == "" {
+		return text
+	}
+	if strings.Contains(s, "\r\n") {
+		text.newline = "\r\n"
+	}
+	text.final = strings.HasSuffix(s, "\n")
+	text.lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range text.lines {
+		text.lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return text
+}
+
+func (t *textLines) bytes() []byte {
+	if len(t.lines) == 0 {
+		return nil
+	}
+	s := strings.Join(t.lines, t.newline)
+	if t.final {
+		s += t.newline
+	}
+	return []byte(s)
+}
+
+func (t *textLines) insert(at int, lines ...string) {
+	updated := make([]string, 0, len(t.lines)+len(lines))
+	updated = append(updated, t.lines[:at]...)
+	updated = append(updated, lines...)
+	t.lines = append(updated, t.lines[at:]...)
+}
+
+// insertIndex returns where new lines go: after the last line matching after,
+// before the first line matching before, or at the end.
+func (t *textLines) insertIndex(after, before *regexp.Regexp) int {
+	if after != nil {
+		for i := len(t.lines) - 1; i >= 0; i-- {
+			if after.MatchString(t.lines[i]) {
+				return i + 1
+			}
+		}
+	}
+	if before != nil {
+		for i, line := range t.lines {
+			if before.MatchString(line) {
+				return i
+			}
+		}
+	}
+	return len(t.lines)
+}
+
+// compileOptional compiles pattern, returning nil for an empty pattern.
+func compileOptional(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern: %w", name, err)
+	}
+	return re, nil
+}
+
+func compileAnchors(after, before string) (*regexp.Regexp, *regexp.Regexp, error) {
+	if after != "" && before != "" {
+		return nil, nil, fmt.Errorf("After and Before cannot both be set")
+	}
+	afterRe, err := compileOptional("After", after)
+	if err != nil {
+		return nil, nil, err
+	}
+	beforeRe, err := compileOptional("Before", before)
+	return afterRe, beforeRe, err
+}
+
+type lineEdit struct {
+	line    string
+	absent  bool
+	options LineOptions
+}
+
+func (e *lineEdit) String() string {
+	switch {
+	case e.absent && e.options.Match != "":
+		return fmt.Sprintf("remove lines matching %q", e.options.Match)
+	case e.absent:
+		return fmt.Sprintf("remove line %q", e.line)
+	}
+	return fmt.Sprintf("ensure line %q", e.line)
+}
+
+func (e *lineEdit) validate() error {
+	if strings.ContainsAny(e.line, "\r\n") {
+		return fmt.Errorf("line must not contain a newline")
+	}
+	if !e.absent && e.line == "" && e.options.Match == "" {
+		return fmt.Errorf("line cannot be empty")
+	}
+	if _, err := compileOptional("Match", e.options.Match); err != nil {
+		return err
+	}
+	_, _, err := compileAnchors(e.options.After, e.options.Before)
+	return err
+}
+
+func (e *lineEdit) apply(text *textLines) (bool, error) {
+	if err := e.validate(); err != nil {
+		return false, err
+	}
+	match, _ := compileOptional("Match", e.options.Match)
+	matches := func(line string) bool {
+		if match != nil {
+			return match.MatchString(line)
+		}
+		return line == e.line
+	}
+
+	if e.absent {
+		kept := make([]string, 0, len(text.lines))
+		for _, line := range text.lines {
+			if !matches(line) {
+				kept = append(kept, line)
+			}
+		}
+		changed := len(kept) != len(text.lines)
+		text.lines = kept
+		return changed, nil
+	}
+
+	if match != nil {
+		for i := len(text.lines) - 1; i >= 0; i-- {
+			if match.MatchString(text.lines[i]) {
+				changed := text.lines[i] != e.line
+				text.lines[i] = e.line
+				return changed, nil
+			}
+		}
+	}
+	for _, line := range text.lines {
+		if line == e.line {
+			return false, nil
+		}
+	}
+	after, before, _ := compileAnchors(e.options.After, e.options.Before)
+	text.insert(text.insertIndex(after, before), e.line)
+	return true, nil
+}
+
+type replaceEdit struct {
+	pattern     string
+	replacement string
+}
+
+func (e *replaceEdit) String() string {
+	return fmt.Sprintf("replace %q", e.pattern)
+}
+
+func (e *replaceEdit) validate() error {
+	if e.pattern == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+	_, err := compileOptional("replace", e.pattern)
+	return err
+}
+
+func (e *replaceEdit) apply(text *textLines) (bool, error) {
+	if err := e.validate(); err != nil {
+		return false, err
+	}
+	re := regexp.MustCompile(e.pattern)
+	original := text.bytes()
+	replaced := re.ReplaceAll(original, []byte(e.replacement))
+	if bytes.Equal(original, replaced) {
+		return false, nil
+	}
+	*text = *parseTextLines(replaced)
+	return true, nil
+}
+
+type blockEdit struct {
+	block   string
+	absent  bool
+	options BlockOptions
+}
+
+func (e *blockEdit) markers() (string, string) {
+	marker := e.options.Marker
+	if marker == "" {
+		marker = DefaultBlockMarker
+	}
+	return strings.ReplaceAll(marker, "{mark}", "BEGIN"), strings.ReplaceAll(marker, "{mark}", "END")
+}
+
+func (e *blockEdit) String() string {
+	begin, _ := e.markers()
+	if e.absent {
+		return fmt.Sprintf("remove block %q", begin)
+	}
+	return fmt.Sprintf("ensure block %q", begin)
+}
+
+func (e *blockEdit) validate() error {
+	if e.options.Marker != "" && !strings.Contains(e.options.Marker, "{mark}") {
+		return fmt.Errorf("block marker %q must contain {mark}", e.options.Marker)
+	}
+	if strings.ContainsAny(e.options.Marker, "\r\n") {
+		return fmt.Errorf("block marker must not contain a newline")
+	}
+	_, _, err := compileAnchors(e.options.After, e.options.Before)
+	return err
+}
+
+func (e *blockEdit) apply(text *textLines) (bool, error) {
+	if err := e.validate(); err != nil {
+		return false, err
+	}
+	begin, end := e.markers()
+	start, stop := -1, -1
+	for i, line := range text.lines {
+		line = strings.TrimRight(line, " \t")
+		if start < 0 && line == begin {
+			start = i
+		} else if start >= 0 && line == end {
+			stop = i
+			break
+		}
+	}
+	if start >= 0 && stop < 0 {
+		return false, fmt.Errorf("found %q without a matching %q", begin, end)
+	}
+
+	var content []string
+	if e.block != "" {
+		content = strings.Split(strings.TrimSuffix(strings.ReplaceAll(e.block, "\r\n", "\n"), "\n"), "\n")
+	}
+
+	if start < 0 {
+		if e.absent {
+			return false, nil
+		}
+		after, before, _ := compileAnchors(e.options.After, e.options.Before)
+		text.insert(text.insertIndex(after, before), append(append([]string{begin}, content...), end)...)
+		return true, nil
+	}
+
+	if e.absent {
+		text.lines = append(text.lines[:start], text.lines[stop+1:]...)
+		return true, nil
+	}
+	if equalLines(text.lines[start+1:stop], content) {
+		return false, nil
+	}
+	updated := append(append([]string{}, text.lines[:start+1]...), content...)
+	text.lines = append(updated, text.lines[stop:]...)
+	return true, nil
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyTextEdits applies edits in order and returns the new content and the
+// descriptions of the edits that changed something.
+func applyTextEdits(content []byte, edits []TextEdit) ([]byte, []string, error) {
+	text := parseTextLines(content)
+	var changed []string
+	for _, edit := range edits {
+		editChanged, err := edit.apply(text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", edit.String(), err)
+		}
+		if editChanged {
+			changed = append(changed, edit.String())
+		}
+	}
+	if len(changed) == 0 {
+		return content, nil, nil
+	}
+	return text.bytes(), changed, nil
+}
+
+// EditTextOperation applies line-oriented edits to a text file. It only
+// writes when an edit changes the file, and records in its description
+// whether it did ("changed") and which edits took effect ("changed_edits").
+// A missing file is treated as empty and created if an edit adds content.
+type EditTextOperation struct {
+	*BaseOperation
+	fileRewrite
+	edits []TextEdit
+}
+
+// NewEditTextOperation creates an edit_text operation.
+func NewEditTextOperation(id core.OperationID, path string, edits ...TextEdit) *EditTextOperation {
+	op := &EditTextOperation{
+		BaseOperation: NewBaseOperation(id, "edit_text", path),
+		edits:         edits,
+	}
+	descriptions := make([]string, len(edits))
+	for i, edit := range edits {
+		descriptions[i] = edit.String()
+	}
+	op.SetDescriptionDetail("edits", descriptions)
+	return op
+}
+
+// Edits returns the edits the operation applies, in order.
+func (op *EditTextOperation) Edits() []TextEdit {
+	return op.edits
+}
+
+// Validate checks the edits and that the path is not a directory.
+func (op *EditTextOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if len(op.edits) == 0 {
+		return invalid("no text edits given", nil)
+	}
+	for _, edit := range op.edits {
+		if err := edit.validate(); err != nil {
+			return invalid(fmt.Sprintf("invalid edit %s", edit.String()), err)
+		}
+	}
+	if info, err := fsys.Stat(op.description.Path); err == nil && info.IsDir() {
+		return invalid("text edit path is a directory", nil)
+	}
+	return nil
+}
+
+// Execute applies the edits with event handling.
+func (op *EditTextOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *EditTextOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	var changedEdits []string
+	changed, err := op.rewrite(fsys, op.description.Path, func(content []byte) ([]byte, bool, error) {
+		output, edits, err := op.render(content)
+		changedEdits = edits
+		return output, err == nil && !bytes.Equal(output, content), err
+	})
+	if err != nil {
+		return err
+	}
+	op.SetOutput("changed", changed)
+	if !changed {
+		changedEdits = nil
+	}
+	op.SetOutput("changed_edits", changedEdits)
+	return nil
+}
+
+func (op *EditTextOperation) render(content []byte) ([]byte, []string, error) {
+	output, changed, err := applyTextEdits(content, op.edits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to edit %s: %w", op.description.Path, err)
+	}
+	return output, changed, nil
+}
+
+// Satisfied reports whether the edits would leave the file unchanged.
+func (op *EditTextOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	return rewriteSatisfied(fsys, op.description.Path, func(content []byte) ([]byte, bool, error) {
+		output, _, err := op.render(content)
+		return output, err == nil && !bytes.Equal(output, content), err
+	})
+}
+
+// Rollback restores the file as it was before Execute, removing it if Execute created it.
+func (op *EditTextOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.rollback(fsys, op.description.Path)
+}
+
+// ReverseOps backs up the current file into the budget. Edits that would not
+// change the file need no reverse operations.
+func (op *EditTextOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]Operation, interface{}, error) {
+	return reverseOpsForRewrite(op.ID(), fsys, op.description.Path, func(content []byte) ([]byte, bool, error) {
+		output, _, err := op.render(content)
+		return output, err == nil && !bytes.Equal(output, content), err
+	}, budget)
+}
@@ -0,0 +1,235 @@
+package operations_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+func TestTextEdits(t *testing.T) {
+	sshdConfig := "Port 22\n#PermitRootLogin yes\nPasswordAuthentication yes\n"
+
+	tests := []struct {
+		name    string
+		content string
+		edits   []operations.TextEdit
+		want    string
+	}{
+		{
+			name:    "ensure line appends",
+			content: "a\nb\n",
+			edits:   []operations.TextEdit{operations.EnsureLine("c", operations.LineOptions{})},
+			want:    "a\nb\nc\n",
+		},
+		{
+			name:    "ensure line replaces last match",
+			content: sshdConfig,
+			edits: []operations.TextEdit{operations.EnsureLine("PermitRootLogin no", operations.LineOptions{
+				Match: `^#?PermitRootLogin`,
+			})},
+			want: "Port 22\nPermitRootLogin no\nPasswordAuthentication yes\n",
+		},
+		{
+			name:    "ensure line after anchor",
+			content: "[main]\nkey=1\n[other]\n",
+			edits:   []operations.TextEdit{operations.EnsureLine("new=2", operations.LineOptions{After: `^key=`})},
+			want:    "[main]\nkey=1\nnew=2\n[other]\n",
+		},
+		{
+			name:    "ensure line before anchor",
+			content: "a\nexit 0\n",
+			edits:   []operations.TextEdit{operations.EnsureLine("b", operations.LineOptions{Before: `^exit`})},
+			want:    "a\nb\nexit 0\n",
+		},
+		{
+			name:    "missing anchor appends",
+			content: "a\n",
+			edits:   []operations.TextEdit{operations.EnsureLine("b", operations.LineOptions{After: `^nothing`})},
+			want:    "a\nb\n",
+		},
+		{
+			name:    "remove line and lines",
+			content: "a\nb\n# c\n# d\n",
+			edits:   []operations.TextEdit{operations.RemoveLine("a"), operations.RemoveLines(`^#`)},
+			want:    "b\n",
+		},
+		{
+			name:    "replace all matches with groups",
+			content: "v=1.0\nw=1.0\n",
+			edits:   []operations.TextEdit{operations.ReplaceRegexp(`(?m)^(\w)=1\.0$`, "${1}=2.0")},
+			want:    "v=2.0\nw=2.0\n",
+		},
+		{
+			name:    "insert block",
+			content: "127.0.0.1 localhost\n",
+			edits:   []operations.TextEdit{operations.EnsureBlock("10.0.0.1 db\n10.0.0.2 cache\n", operations.BlockOptions{})},
+			want:    "127.0.0.1 localhost\n# BEGIN SYNTHFS MANAGED BLOCK\n10.0.0.1 db\n10.0.0.2 cache\n# END SYNTHFS MANAGED BLOCK\n",
+		},
+		{
+			name:    "replace block content",
+			content: "x\n// BEGIN app\nold\n// END app\ny\n",
+			edits:   []operations.TextEdit{operations.EnsureBlock("new", operations.BlockOptions{Marker: "// {mark} app"})},
+			want:    "x\n// BEGIN app\nnew\n// END app\ny\n",
+		},
+		{
+			name:    "remove block",
+			content: "x\n# BEGIN SYNTHFS MANAGED BLOCK\nold\n# END SYNTHFS MANAGED BLOCK\ny\n",
+			edits:   []operations.TextEdit{operations.RemoveBlock(operations.BlockOptions{})},
+			want:    "x\ny\n",
+		},
+		{
+			name:    "crlf line endings are kept",
+			content: "a\r\nb\r\n",
+			edits:   []operations.TextEdit{operations.EnsureLine("c", operations.LineOptions{After: "^a$"})},
+			want:    "a\r\nc\r\nb\r\n",
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := filesystem.NewTestFileSystem()
+			if err := fsys.WriteFile("file", []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			op := operations.NewEditTextOperation("edit", "file", tt.edits...)
+			if err := op.Validate(ctx, nil, fsys); err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			if err := op.Execute(ctx, nil, fsys); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got := readFile(t, fsys, "file"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			// Applying the same edits again is a no-op
+			again := operations.NewEditTextOperation("again", "file", tt.edits...)
+			if err := again.Execute(ctx, nil, fsys); err != nil {
+				t.Fatalf("second Execute failed: %v", err)
+			}
+			if output(again, "changed") != false {
+				t.Errorf("expected second run to change nothing, got %v", output(again, "changed_edits"))
+			}
+		})
+	}
+}
+
+func TestEditTextOperation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("outputs report the change", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile(".bashrc", []byte("export A=1\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditTextOperation("edit", ".bashrc",
+			operations.EnsureLine("export A=1", operations.LineOptions{}),
+			operations.EnsureLine("export B=2", operations.LineOptions{}),
+		)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if output(op, "changed") != true {
+			t.Errorf("expected changed=true")
+		}
+		if edits := output(op, "changed_edits"); !reflect.DeepEqual(edits, []string{`ensure line "export B=2"`}) {
+			t.Errorf("unexpected changed_edits %v", edits)
+		}
+		if _, found := op.Describe().Details["changed"]; found {
+			t.Error("outputs should not be mixed into the description")
+		}
+		info, _ := fsys.Stat(".bashrc")
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("mode changed to %o", info.Mode().Perm())
+		}
+	})
+
+	t.Run("backup and rollback", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("hosts", []byte("a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditTextOperation("edit", "hosts", operations.EnsureLine("b", operations.LineOptions{}))
+		_, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{TotalMB: 1, RemainingMB: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, ok := backup.(*core.BackupData); !ok || string(data.BackupContent) != "a\n" {
+			t.Errorf("expected the original in the backup, got %v", backup)
+		}
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "hosts"); got != "a\n" {
+			t.Errorf("content after rollback = %q", got)
+		}
+	})
+
+	t.Run("no-op needs no backup", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("hosts", []byte("a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditTextOperation("edit", "hosts", operations.RemoveLine("b"))
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{TotalMB: 1, RemainingMB: 1})
+		if err != nil || reverseOps != nil || backup != nil {
+			t.Errorf("expected nothing to back up, got %v %v %v", reverseOps, backup, err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		remove := operations.NewEditTextOperation("remove", "absent", operations.RemoveLine("x"))
+		if err := remove.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat("absent"); err == nil {
+			t.Error("removing from a missing file should not create it")
+		}
+
+		ensure := operations.NewEditTextOperation("ensure", "absent", operations.EnsureLine("x", operations.LineOptions{}))
+		if err := ensure.Execute(ctx, nil, fsys); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, fsys, "absent"); got != "x\n" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("unterminated block", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("f", []byte("# BEGIN SYNTHFS MANAGED BLOCK\nx\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := operations.NewEditTextOperation("edit", "f", operations.EnsureBlock("y", operations.BlockOptions{}))
+		if err := op.Execute(ctx, nil, fsys); err == nil || !strings.Contains(err.Error(), "without a matching") {
+			t.Errorf("expected unterminated block error, got %v", err)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		invalid := []operations.TextEdit{
+			operations.EnsureLine("a\nb", operations.LineOptions{}),
+			operations.EnsureLine("a", operations.LineOptions{Match: "("}),
+			operations.EnsureLine("a", operations.LineOptions{After: "x", Before: "y"}),
+			operations.ReplaceRegexp("", "x"),
+			operations.EnsureBlock("x", operations.BlockOptions{Marker: "# managed"}),
+		}
+		for _, edit := range invalid {
+			op := operations.NewEditTextOperation("edit", "f", edit)
+			if err := op.Validate(ctx, nil, fsys); err == nil {
+				t.Errorf("expected %s to fail validation", edit)
+			}
+		}
+	})
+}
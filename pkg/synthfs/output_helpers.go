@@ -1,28 +1,73 @@
 package synthfs
 
-// GetOperationOutput retrieves stored output from an operation's description details.
+// outputStore is implemented by operations that keep the values they produce
+// apart from their description.
+type outputStore interface {
+	GetOutput(key string) (interface{}, bool)
+	GetAllOutputs() map[string]interface{}
+}
+
+// Output retrieves the output key that op stored while running, as a T. The
+// bool is false if op stored no such output, for instance because it has not
+// run, or if the output is not a T. Strings and byte slices convert to each
+// other. Description details are not outputs and are never returned.
+//
+// Example:
+//
+//	check := sfs.Checksum("release.tar.gz", synthfs.SHA256)
+//	synthfs.Run(ctx, fs, check)
+//	sum, ok := synthfs.Output[string](check, "sha256")
+func Output[T any](op Operation, key string) (T, bool) {
+	var zero T
+	value, ok := operationOutput(op, key)
+	if !ok {
+		return zero, false
+	}
+	if typed, ok := value.(T); ok {
+		return typed, true
+	}
+
+	var converted interface{}
+	switch v := value.(type) {
+	case string:
+		converted = []byte(v)
+	case []byte:
+		converted = string(v)
+	default:
+		return zero, false
+	}
+	typed, ok := converted.(T)
+	return typed, ok
+}
+
+// operationOutput returns the output key of op, from its output store.
+func operationOutput(op Operation, key string) (interface{}, bool) {
+	if store, ok := op.(outputStore); ok {
+		return store.GetOutput(key)
+	}
+	return nil, false
+}
+
+// GetOperationOutput retrieves stored output from an operation as a string.
 // This is useful for accessing output from shell commands or custom operations after execution.
+// Use Output for other types.
 //
 // Example:
-//   result, err := synthfs.Run(ctx, fs, ops...)
-//   if err == nil {
-//       for _, opResult := range result.GetOperations() {
-//           if op, ok := opResult.(OperationResult); ok {
-//               stdout := GetOperationOutput(op.Operation, "stdout")
-//               if stdout != "" {
-//                   fmt.Printf("Command output: %s\n", stdout)
-//               }
-//           }
-//       }
-//   }
+//
+//	result, err := synthfs.Run(ctx, fs, ops...)
+//	if err == nil {
+//	    for _, opResult := range result.GetOperations() {
+//	        if op, ok := opResult.(OperationResult); ok {
+//	            stdout := GetOperationOutput(op.Operation, "stdout")
+//	            if stdout != "" {
+//	                fmt.Printf("Command output: %s\n", stdout)
+//	            }
+//	        }
+//	    }
+//	}
 func GetOperationOutput(op Operation, key string) string {
-	desc := op.Describe()
-	if desc.Details != nil {
-		if val, ok := desc.Details[key]; ok {
-			if str, ok := val.(string); ok {
-				return str
-			}
-		}
+	if str, ok := Output[string](op, key); ok {
+		return str
 	}
 	return ""
 }
@@ -30,23 +75,14 @@ func GetOperationOutput(op Operation, key string) string {
 // GetOperationOutputValue retrieves stored output as an interface{} value.
 // This is useful when the stored value is not a string.
 func GetOperationOutputValue(op Operation, key string) interface{} {
-	desc := op.Describe()
-	if desc.Details != nil {
-		return desc.Details[key]
-	}
-	return nil
+	value, _ := operationOutput(op, key)
+	return value
 }
 
 // GetAllOperationOutputs retrieves all stored outputs from an operation.
 func GetAllOperationOutputs(op Operation) map[string]interface{} {
-	desc := op.Describe()
-	if desc.Details != nil {
-		// Return a copy to prevent modification
-		result := make(map[string]interface{})
-		for k, v := range desc.Details {
-			result[k] = v
-		}
-		return result
+	if store, ok := op.(outputStore); ok {
+		return store.GetAllOutputs() // Already a copy
 	}
 	return make(map[string]interface{})
-}
\ No newline at end of file
+}
@@ -0,0 +1,115 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestTypedOutputs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+	mustDo(t, fsys.WriteFile("a.txt", []byte("hello"), 0644))
+	read := synthfs.New().ReadFile("a.txt")
+
+	if _, ok := synthfs.Output[string](read, "content"); ok {
+		t.Error("expected no output before the operation ran")
+	}
+	if _, err := synthfs.Run(context.Background(), fsys, read); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if content, ok := synthfs.Output[string](read, "content"); !ok || content != "hello" {
+		t.Errorf("content = %q, %v", content, ok)
+	}
+	if content, ok := synthfs.Output[[]byte](read, "content"); !ok || string(content) != "hello" {
+		t.Errorf("content as bytes = %q, %v", content, ok)
+	}
+	if size, ok := synthfs.Output[int64](read, "size"); !ok || size != 5 {
+		t.Errorf("size = %d, %v", size, ok)
+	}
+	if _, ok := synthfs.Output[int](read, "content"); ok {
+		t.Error("expected a string output not to read as an int")
+	}
+	if _, found := read.Describe().Details["content"]; found {
+		t.Error("outputs should not be mixed into the description")
+	}
+}
+
+func TestLazyInputs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+
+	t.Run("file content from a command's stdout", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		version := sfs.ShellCommand("echo v1.2.3", synthfs.WithCaptureOutput())
+		file := sfs.CreateFileFrom("VERSION", synthfs.FromOutput[[]byte](version, "stdout"), 0644)
+
+		if _, err := synthfs.Run(ctx, fsys, version, file); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if got := readString(t, fsys, "VERSION"); got != "v1.2.3\n" {
+			t.Errorf("VERSION = %q", got)
+		}
+	})
+
+	t.Run("pipelines run producers first", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("app.tar.gz", []byte("archive"), 0644))
+		sfs := synthfs.New()
+		sum := sfs.Checksum("app.tar.gz", synthfs.MD5)
+		manifest := sfs.CreateFileFrom("MD5SUMS", synthfs.Computed(func() ([]byte, error) {
+			hash, _ := synthfs.Output[string](sum, "md5")
+			return []byte(hash + "  app.tar.gz\n"), nil
+		}, sum), 0644)
+
+		pipeline := synthfs.NewMemPipeline()
+		mustDo(t, pipeline.Add(manifest, sum))
+		mustDo(t, pipeline.Resolve())
+		if first := pipeline.Operations()[0]; first.ID() != sum.ID() {
+			t.Fatalf("expected the checksum to run first, got %s", first.ID())
+		}
+		if _, err := synthfs.Run(ctx, fsys, pipeline.Operations()...); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if got := readString(t, fsys, "MD5SUMS"); got != "888d0ee361af3603736f32131e7b20a2  app.tar.gz\n" {
+			t.Errorf("MD5SUMS = %q", got)
+		}
+	})
+
+	t.Run("runs order producers first", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		version := sfs.ShellCommand("echo v1", synthfs.WithCaptureOutput())
+		file := sfs.CreateFileFrom("VERSION", synthfs.FromOutput[[]byte](version, "stdout"), 0644)
+
+		if _, err := synthfs.Run(ctx, fsys, file, version); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if got := readString(t, fsys, "VERSION"); got != "v1\n" {
+			t.Errorf("VERSION = %q", got)
+		}
+	})
+
+	t.Run("outputs must exist when the input is resolved", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		sfs := synthfs.New()
+		version := sfs.ShellCommand("echo v1", synthfs.WithCaptureOutput())
+		file := sfs.CreateFileFrom("VERSION", synthfs.FromOutput[[]byte](version, "stdout"), 0644)
+
+		_, err := synthfs.Run(ctx, fsys, file)
+		if !errors.Is(err, core.ErrOutputNotAvailable) {
+			t.Errorf("expected ErrOutputNotAvailable, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package synthfs
+
+import (
+	"context"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// DefaultPatchFuzz is the fuzz ApplyPatch uses, the same default as patch(1).
+const DefaultPatchFuzz = operations.DefaultPatchFuzz
+
+// AppendFile creates an operation that adds content to the end of a file,
+// creating it if needed. It is reversed by truncating the file back to its
+// original size, so the existing content is never copied.
+func (s *SynthFS) AppendFile(path string, content []byte) Operation {
+	id := s.idGen("append_file", path)
+	return operations.NewAppendFileOperation(id, path, content)
+}
+
+// AppendFileWithID creates an append operation with an explicit ID.
+func (s *SynthFS) AppendFileWithID(id string, path string, content []byte) Operation {
+	return operations.NewAppendFileOperation(core.OperationID(id), path, content)
+}
+
+// ApplyPatch creates an operation that applies a unified diff to a file. Like
+// patch(1), hunks may apply at an offset from the lines their headers name,
+// and up to DefaultPatchFuzz context lines at each end of a hunk may be
+// ignored. If any hunk does not apply the file is left unchanged. The
+// "offsets" and "fuzz_used" outputs report how each hunk applied, and the
+// reverse operation is the inverted patch rather than a backup of the file.
+//
+// Example:
+//
+//	sfs.ApplyPatch("src/main.go", diff)
+func (s *SynthFS) ApplyPatch(path string, diff string) Operation {
+	id := s.idGen("apply_patch", path)
+	return operations.NewApplyPatchOperation(id, path, diff)
+}
+
+// ApplyPatchWithFuzz creates a patch operation that ignores at most fuzz
+// context lines at each end of a hunk. Zero requires an exact match.
+func (s *SynthFS) ApplyPatchWithFuzz(path string, diff string, fuzz int) Operation {
+	id := s.idGen("apply_patch", path)
+	return operations.NewApplyPatchOperation(id, path, diff).WithFuzz(fuzz)
+}
+
+// ApplyPatchWithID creates a patch operation with an explicit ID.
+func (s *SynthFS) ApplyPatchWithID(id string, path string, diff string) Operation {
+	return operations.NewApplyPatchOperation(core.OperationID(id), path, diff)
+}
+
+// AppendFile appends content to a file immediately.
+func AppendFile(ctx context.Context, fs FileSystem, path string, content []byte) error {
+	_, err := Run(ctx, fs, New().AppendFile(path, content))
+	return err
+}
+
+// ApplyPatch applies a unified diff to a file immediately.
+func ApplyPatch(ctx context.Context, fs FileSystem, path string, diff string) error {
+	_, err := Run(ctx, fs, New().ApplyPatch(path, diff))
+	return err
+}
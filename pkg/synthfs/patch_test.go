@@ -0,0 +1,95 @@
+package synthfs_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestAppendAndPatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	t.Run("patch and append a file created earlier in the run", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		result, err := synthfs.Run(ctx, fsys,
+			sfs.CreateFile("notes.txt", []byte("a\nb\nc\n"), 0644),
+			sfs.ApplyPatch("notes.txt", "--- a/notes.txt\n+++ b/notes.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"),
+			sfs.AppendFile("notes.txt", []byte("d\n")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if !result.Success {
+			t.Fatalf("run was not successful: %v", result.Errors)
+		}
+		if got := readString(t, fsys, "notes.txt"); got != "a\nB\nc\nd\n" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("restore operations undo without a backup", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("app.log", []byte("start\n"), 0644))
+		mustDo(t, fsys.WriteFile("main.txt", []byte("x\ny\nz\n"), 0644))
+
+		options := synthfs.DefaultPipelineOptions()
+		options.Restorable = true
+		for _, op := range []synthfs.Operation{
+			sfs.AppendFileWithID("log", "app.log", []byte("more\n")),
+			sfs.ApplyPatchWithID("patch", "main.txt", "@@ -2 +2 @@\n-y\n+Y\n"),
+		} {
+			result, err := synthfs.RunWithOptions(ctx, fsys, options, op)
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			if result.Operations[0].BackupData != nil {
+				t.Errorf("%s should not need a backup", op.ID())
+			}
+			if len(result.RestoreOps) != 1 {
+				t.Fatalf("expected one restore operation, got %d", len(result.RestoreOps))
+			}
+			mustDo(t, result.RestoreOps[0].(synthfs.Operation).Execute(ctx, nil, fsys))
+		}
+		if got := readString(t, fsys, "app.log"); got != "start\n" {
+			t.Errorf("app.log after restore = %q", got)
+		}
+		if got := readString(t, fsys, "main.txt"); got != "x\ny\nz\n" {
+			t.Errorf("main.txt after restore = %q", got)
+		}
+	})
+
+	t.Run("rejected patch fails the run", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("main.txt", []byte("x\n"), 0644))
+		if err := synthfs.ApplyPatch(ctx, fsys, "main.txt", "@@ -1 +1 @@\n-nope\n+yes\n"); err == nil {
+			t.Error("expected the patch to be rejected")
+		}
+		if got := readString(t, fsys, "main.txt"); got != "x\n" {
+			t.Errorf("file changed: %q", got)
+		}
+	})
+
+	t.Run("a patch to /dev/null deletes the file", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("old.txt", []byte("x\n"), 0644))
+		remove := "--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n"
+
+		// Validation projects the file as gone
+		if _, err := synthfs.Plan(ctx, fsys, sfs.ApplyPatch("old.txt", remove), sfs.Delete("old.txt")); err == nil {
+			t.Error("expected deleting the patched-away file to conflict")
+		}
+		if err := synthfs.ApplyPatch(ctx, fsys, "old.txt", remove); err != nil {
+			t.Fatalf("patch failed: %v", err)
+		}
+		if _, err := fsys.Stat("old.txt"); err == nil {
+			t.Error("expected old.txt to be deleted")
+		}
+	})
+}
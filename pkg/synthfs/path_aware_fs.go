@@ -1,9 +1,11 @@
 package synthfs
 
 import (
+	"errors"
 	"io/fs"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 )
@@ -206,6 +208,67 @@ func (pfs *PathAwareFileSystem) Readlink(name string) (string, error) {
 	return pfs.fs.Readlink(resolved)
 }
 
+// Chmod implements filesystem.ChmodFS when the wrapped filesystem does
+func (pfs *PathAwareFileSystem) Chmod(name string, mode fs.FileMode) error {
+	chmodFS, ok := pfs.fs.(filesystem.ChmodFS)
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: errors.ErrUnsupported}
+	}
+	resolved, err := pfs.resolvePath(name)
+	if err != nil {
+		return &fs.PathError{Op: "chmod", Path: name, Err: err}
+	}
+
+	return chmodFS.Chmod(resolved, mode)
+}
+
+// Chtimes implements filesystem.ChtimesFS when the wrapped filesystem does
+func (pfs *PathAwareFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	chtimesFS, ok := pfs.fs.(filesystem.ChtimesFS)
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: errors.ErrUnsupported}
+	}
+	resolved, err := pfs.resolvePath(name)
+	if err != nil {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: err}
+	}
+
+	return chtimesFS.Chtimes(resolved, atime, mtime)
+}
+
+// Link implements filesystem.LinkFS when the wrapped filesystem does
+func (pfs *PathAwareFileSystem) Link(oldname, newname string) error {
+	linkFS, ok := pfs.fs.(filesystem.LinkFS)
+	if !ok {
+		return &fs.PathError{Op: "link", Path: newname, Err: errors.ErrUnsupported}
+	}
+	resolvedOld, err := pfs.resolvePath(oldname)
+	if err != nil {
+		return &fs.PathError{Op: "link", Path: oldname, Err: err}
+	}
+
+	resolvedNew, err := pfs.resolvePath(newname)
+	if err != nil {
+		return &fs.PathError{Op: "link", Path: newname, Err: err}
+	}
+
+	return linkFS.Link(resolvedOld, resolvedNew)
+}
+
+// FileID implements filesystem.LinkFS when the wrapped filesystem does
+func (pfs *PathAwareFileSystem) FileID(name string) (filesystem.FileID, error) {
+	linkFS, ok := pfs.fs.(filesystem.LinkFS)
+	if !ok {
+		return filesystem.FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: errors.ErrUnsupported}
+	}
+	resolved, err := pfs.resolvePath(name)
+	if err != nil {
+		return filesystem.FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: err}
+	}
+
+	return linkFS.FileID(resolved)
+}
+
 // resolvePath handles the path resolution, converting to relative for the underlying FS
 func (pfs *PathAwareFileSystem) resolvePath(path string) (string, error) {
 	// First resolve the path according to our rules
@@ -1,8 +1,10 @@
 package synthfs
 
 import (
+	"context"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 )
@@ -191,6 +193,47 @@ func TestPathAwareFS_SymlinkReadlink_RealFS(t *testing.T) {
 	}
 }
 
+// TestPathAwareFS_OptionalInterfaces_RealFS tests that chmod, chtimes and hard
+// links reach the wrapped filesystem with resolved paths
+func TestPathAwareFS_OptionalInterfaces_RealFS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+
+	tempDir := t.TempDir()
+	rawFS := filesystem.NewOSFileSystem(tempDir)
+	createTestFile(t, rawFS, "a.txt", []byte("a"))
+	pfs := NewOSFileSystemWithPaths(tempDir)
+
+	// Run a chmod operation through the wrapper, which needs ChmodFS
+	if _, err := Run(context.Background(), pfs, New().Chmod(tempDir+"/a.txt", 0600)); err != nil {
+		t.Fatalf("chmod through PathAwareFS failed: %v", err)
+	}
+	if info, err := rawFS.Stat("a.txt"); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v (%v)", info, err)
+	}
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := pfs.Chtimes(tempDir+"/a.txt", mtime, mtime); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	if info, err := rawFS.Stat("a.txt"); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("expected modification time %v, got %v (%v)", mtime, info, err)
+	}
+
+	if err := pfs.Link(tempDir+"/a.txt", "b.txt"); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	a, err := pfs.FileID("a.txt")
+	if err != nil {
+		t.Fatalf("FileID failed: %v", err)
+	}
+	b, _ := rawFS.FileID("b.txt")
+	if a != b {
+		t.Error("expected b.txt to be a hard link to a.txt")
+	}
+}
+
 // Helper functions for real filesystem testing
 
 func createTestFile(t *testing.T, fs *filesystem.OSFileSystem, path string, content []byte) {
@@ -1,15 +1,17 @@
 package synthfs
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
-	"text/template"
+	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/targets"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
 )
 
 // TemplateData holds template data for rendering
@@ -17,46 +19,51 @@ type TemplateData map[string]interface{}
 
 // WriteTemplateOperation writes files from templates
 type WriteTemplateOperation struct {
-	id       OperationID
-	desc     OperationDesc
+	*operations.BaseOperation
 	path     string
 	template string
 	data     TemplateData
 	mode     fs.FileMode
+	options  TemplateOptions
+
+	written  bool          // Execute wrote the file
+	previous *templateFile // What Execute overwrote, nil if the path was new
+}
+
+// templateFile is the content and mode of a file a template overwrote.
+type templateFile struct {
+	content []byte
+	mode    fs.FileMode
 }
 
 // NewWriteTemplateOperation creates a new template write operation
 func (s *SynthFS) NewWriteTemplateOperation(path, templateContent string, data TemplateData, mode fs.FileMode) *WriteTemplateOperation {
 	id := s.idGen("write_template", path)
-	return &WriteTemplateOperation{
-		id: id,
-		desc: OperationDesc{
-			Type: "write_template",
-			Path: path,
-			Details: map[string]interface{}{
-				"template": templateContent,
-				"data":     data,
-				"mode":     mode,
-			},
-		},
-		path:     path,
-		template: templateContent,
-		data:     data,
-		mode:     mode,
-	}
+	return newWriteTemplateOperation(id, path, templateContent, data, mode)
 }
 
-// ID returns the operation ID
-func (op *WriteTemplateOperation) ID() OperationID {
-	return op.id
+func newWriteTemplateOperation(id OperationID, path, templateContent string, data TemplateData, mode fs.FileMode) *WriteTemplateOperation {
+	op := &WriteTemplateOperation{
+		BaseOperation: operations.NewBaseOperation(id, "write_template", path),
+		path:          path,
+		template:      templateContent,
+		data:          data,
+		mode:          mode,
+	}
+	op.SetPaths("", path)
+	op.SetDescriptionDetail("template", templateContent)
+	op.SetDescriptionDetail("data", data)
+	op.SetDescriptionDetail("mode", mode)
+	return op
 }
 
-// Describe returns the operation description
-func (op *WriteTemplateOperation) Describe() OperationDesc {
-	return op.desc
+// WithOptions sets the template engine options and returns the operation.
+func (op *WriteTemplateOperation) WithOptions(options TemplateOptions) *WriteTemplateOperation {
+	op.options = options
+	op.SetDescriptionDetail("strict", options.Strict)
+	return op
 }
 
-
 // Prerequisites returns prerequisites for the operation
 func (op *WriteTemplateOperation) Prerequisites() []core.Prerequisite {
 	return []core.Prerequisite{
@@ -65,106 +72,157 @@ func (op *WriteTemplateOperation) Prerequisites() []core.Prerequisite {
 	}
 }
 
-// GetItem returns nil - no specific item
-func (op *WriteTemplateOperation) GetItem() interface{} {
-	return nil
-}
-
-// SetDescriptionDetail sets a detail in the description
-func (op *WriteTemplateOperation) SetDescriptionDetail(key string, value interface{}) {
-	if op.desc.Details == nil {
-		op.desc.Details = make(map[string]interface{})
+// Satisfied reports whether the file already holds the rendered content with
+// the operation's mode.
+func (op *WriteTemplateOperation) Satisfied(ctx context.Context, fsys filesystem.FileSystem) (bool, error) {
+	content, err := renderTemplate(op.path, op.template, op.data, op.options)
+	if err != nil {
+		return false, nil
 	}
-	op.desc.Details[key] = value
-}
-
-// AddDependency adds a dependency
-func (op *WriteTemplateOperation) AddDependency(depID OperationID) {
-	// Not implemented for this operation
-}
-
-// SetPaths sets source and destination paths
-func (op *WriteTemplateOperation) SetPaths(src, dst string) {
-	op.path = dst
-	op.desc.Path = dst
-}
-
-// GetChecksum returns nil
-func (op *WriteTemplateOperation) GetChecksum(path string) interface{} {
-	return nil
-}
-
-// GetAllChecksums returns nil
-func (op *WriteTemplateOperation) GetAllChecksums() map[string]interface{} {
-	return nil
+	return operations.FileMatches(fsys, op.path, content, op.mode)
 }
 
 // Execute with ExecutionContext support
 func (op *WriteTemplateOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
-	// Convert filesystem.FileSystem to FileSystem interface
+	if execCtx != nil {
+		return operations.ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
 	return op.execute(ctx, fsys)
 }
 
 // Validate with ExecutionContext support
 func (op *WriteTemplateOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
-	// Convert filesystem.FileSystem to FileSystem interface
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
 	return op.validate(ctx, fsys)
 }
 
-// Rollback removes the created file
+// Rollback restores the file the template overwrote, or removes the file
+// if the path was new.
 func (op *WriteTemplateOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
-	return fsys.Remove(op.path)
+	if !op.written {
+		return nil
+	}
+	if op.previous != nil {
+		if err := fsys.WriteFile(op.path, op.previous.content, op.previous.mode); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", op.path, err)
+		}
+		if chmodFS, ok := fsys.(filesystem.ChmodFS); ok {
+			if err := chmodFS.Chmod(op.path, op.previous.mode); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+				return fmt.Errorf("failed to restore mode of %s: %w", op.path, err)
+			}
+		}
+	} else if err := fsys.Remove(op.path); err != nil {
+		return err
+	}
+	op.written = false
+	return nil
 }
 
-// GetPaths returns empty source and the template path as destination
-func (op *WriteTemplateOperation) GetPaths() (src, dst string) {
-	return "", op.path
-}
+// ReverseOps returns a delete when the path is new. When the template will
+// overwrite a file, the current content is backed up into the budget and
+// restored by a create_file operation, as for CreateFileOperation.
+func (op *WriteTemplateOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]operations.Operation, interface{}, error) {
+	reverseID := OperationID(fmt.Sprintf("reverse_%s", op.ID()))
+	info, err := fsys.Stat(op.path)
+	if err != nil {
+		return []operations.Operation{operations.NewDeleteOperation(reverseID, op.path)}, nil, nil
+	}
+	if info.IsDir() {
+		return nil, nil, fmt.Errorf("cannot back up %s: it is a directory", op.path)
+	}
 
-// SetItem is a no-op for template operations
-func (op *WriteTemplateOperation) SetItem(item interface{}) {
-	// No-op
-}
+	content, err := fs.ReadFile(fsys, op.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read existing file for backup: %w", err)
+	}
+	sizeMB := float64(len(content)) / (1024 * 1024)
+	if backupBudget, ok := budget.(*core.BackupBudget); ok && backupBudget != nil {
+		if err := backupBudget.ConsumeBackup(sizeMB); err != nil {
+			return nil, nil, fmt.Errorf("budget exceeded: cannot backup file '%s' (%.2fMB): %w", op.path, sizeMB, err)
+		}
+	}
 
-// SetChecksum is a no-op for template operations
-func (op *WriteTemplateOperation) SetChecksum(path string, checksum interface{}) {
-	// No-op
-}
+	backupData := &core.BackupData{
+		OperationID:   op.ID(),
+		BackupType:    "file",
+		OriginalPath:  op.path,
+		BackupContent: content,
+		SizeMB:        sizeMB,
+		BackupTime:    time.Now(),
+		Metadata: map[string]interface{}{
+			"mode": info.Mode(),
+		},
+	}
 
-// ReverseOps generates reverse operations
-func (op *WriteTemplateOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]operations.Operation, interface{}, error) {
-	return []operations.Operation{}, nil, fmt.Errorf("reverse ops not implemented for WriteTemplateOperation")
+	reverseOp := operations.NewCreateFileOperation(reverseID, op.path)
+	reverseOp.SetItem(targets.NewFile(op.path).WithContent(content).WithMode(info.Mode().Perm()))
+	return []operations.Operation{reverseOp}, backupData, nil
 }
 
 
 // execute performs the template write operation
 func (op *WriteTemplateOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
 	// Parse and execute template
-	tmpl, err := template.New("file").Parse(op.template)
+	content, err := renderTemplate(op.path, op.template, op.data, op.options)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
+	}
+
+	writeFS, ok := fsys.(WriteFS)
+	if !ok {
+		return fmt.Errorf("filesystem does not support WriteFile")
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, op.data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	// Keep what is being overwritten so Rollback can put it back
+	op.previous = nil
+	if info, err := fsys.Stat(op.path); err == nil && !info.IsDir() {
+		previous, err := fs.ReadFile(fsys, op.path)
+		if err != nil {
+			return fmt.Errorf("failed to read existing file: %w", err)
+		}
+		op.previous = &templateFile{content: previous, mode: info.Mode().Perm()}
 	}
 
 	// Write the rendered content
-	if writeFS, ok := fsys.(WriteFS); ok {
-		return writeFS.WriteFile(op.path, buf.Bytes(), op.mode)
+	if err := writeFS.WriteFile(op.path, content, op.mode); err != nil {
+		return err
+	}
+	op.written = true
+	if chmodFS, ok := fsys.(filesystem.ChmodFS); ok && op.previous != nil && op.previous.mode != op.mode.Perm() {
+		// An overwritten file keeps its old mode unless it is set explicitly
+		if err := chmodFS.Chmod(op.path, op.mode.Perm()); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return fmt.Errorf("failed to set file mode: %w", err)
+		}
 	}
 
-	return fmt.Errorf("filesystem does not support WriteFile")
+	// Record the checksum of the rendered output
+	if checksum, err := validation.ComputeFileChecksum(fsys, op.path); err == nil && checksum != nil {
+		op.SetChecksum(op.path, checksum)
+		op.SetDescriptionDetail("content_checksum", checksum.MD5)
+	}
+	return nil
 }
 
 // validate checks if the operation can be performed
 func (op *WriteTemplateOperation) validate(ctx context.Context, fsys filesystem.FileSystem) error {
 	// Validate template syntax
-	if _, err := template.New("validate").Parse(op.template); err != nil {
+	if _, err := parseTemplate(op.path, op.template, op.options); err != nil {
 		return fmt.Errorf("invalid template syntax: %w", err)
 	}
 
+	// Render against the data so missing keys and failing functions surface
+	// before anything is written
+	if _, err := renderTemplate(op.path, op.template, op.data, op.options); err != nil {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        "template does not render",
+			Cause:         err,
+		}
+	}
+
 	// Check if filesystem supports write
 	if _, ok := fsys.(WriteFS); !ok {
 		return fmt.Errorf("filesystem does not support WriteFile")
@@ -195,6 +253,7 @@ type TemplateBuilder struct {
 	template string
 	data     TemplateData
 	mode     fs.FileMode
+	options  TemplateOptions
 }
 
 // NewTemplateBuilder creates a new template builder
@@ -230,9 +289,21 @@ func (tb *TemplateBuilder) WithMode(mode fs.FileMode) *TemplateBuilder {
 	return tb
 }
 
+// WithOptions sets the template engine options
+func (tb *TemplateBuilder) WithOptions(options TemplateOptions) *TemplateBuilder {
+	tb.options = options
+	return tb
+}
+
+// Strict makes missing keys an error
+func (tb *TemplateBuilder) Strict() *TemplateBuilder {
+	tb.options.Strict = true
+	return tb
+}
+
 // Build creates the write template operation
 func (tb *TemplateBuilder) Build() Operation {
-	return New().NewWriteTemplateOperation(tb.path, tb.template, tb.data, tb.mode)
+	return New().NewWriteTemplateOperation(tb.path, tb.template, tb.data, tb.mode).WithOptions(tb.options)
 }
 
 // Execute builds and executes the operation
@@ -241,13 +312,15 @@ func (tb *TemplateBuilder) Execute(ctx context.Context, fs filesystem.FileSystem
 	return op.Execute(ctx, nil, fs)
 }
 
-// BatchTemplateWriter helps write multiple templates
+// BatchTemplateWriter helps write multiple templates. Partials and options
+// set on the writer are shared by every template it builds.
 type BatchTemplateWriter struct {
 	templates map[string]struct {
 		template string
 		data     TemplateData
 		mode     fs.FileMode
 	}
+	options TemplateOptions
 }
 
 // NewBatchTemplateWriter creates a new batch template writer
@@ -289,12 +362,36 @@ func (btw *BatchTemplateWriter) AddWithMode(path, template string, data Template
 	return btw
 }
 
+// WithOptions sets the template engine options for every template
+func (btw *BatchTemplateWriter) WithOptions(options TemplateOptions) *BatchTemplateWriter {
+	partials := btw.options.Partials
+	btw.options = options
+	for name, content := range partials {
+		if _, exists := btw.options.Partials[name]; !exists {
+			btw.AddPartial(name, content)
+		}
+	}
+	return btw
+}
+
+// AddPartial adds a named template that every template can include with
+// {{template "name" .}}
+func (btw *BatchTemplateWriter) AddPartial(name, content string) *BatchTemplateWriter {
+	partials := make(map[string]string, len(btw.options.Partials)+1)
+	for k, v := range btw.options.Partials {
+		partials[k] = v
+	}
+	partials[name] = content
+	btw.options.Partials = partials
+	return btw
+}
+
 // BuildOperations creates all template operations
 func (btw *BatchTemplateWriter) BuildOperations() []Operation {
 	sfs := New()
 	var ops []Operation
 	for path, tmpl := range btw.templates {
-		op := sfs.NewWriteTemplateOperation(path, tmpl.template, tmpl.data, tmpl.mode)
+		op := sfs.NewWriteTemplateOperation(path, tmpl.template, tmpl.data, tmpl.mode).WithOptions(btw.options)
 		ops = append(ops, op)
 	}
 	return ops
@@ -0,0 +1,264 @@
+package synthfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/targets"
+)
+
+// DefaultTemplateSuffix marks files that RenderTemplateDir renders as templates.
+const DefaultTemplateSuffix = ".tmpl"
+
+// TemplateDirOptions controls how RenderTemplateDir turns a template tree into files.
+type TemplateDirOptions struct {
+	// Suffix marks template files. It is stripped from the output name.
+	// Defaults to DefaultTemplateSuffix.
+	Suffix string
+	// RenderAll renders every text file, not only those with Suffix.
+	// Binary files are always copied verbatim.
+	RenderAll bool
+	// Skip is called with each output path, relative to the destination, and
+	// the template data. Returning true skips the file, or the whole
+	// directory. Use it for conditional files; a name that renders empty is
+	// an error.
+	Skip func(path string, data TemplateData) bool
+	// Template configures the engine for file contents and path names.
+	Template TemplateOptions
+}
+
+// TemplateDirOperation renders a tree of templates into a destination
+// directory. It expands into one create_directory, write_template or
+// create_file operation per output path.
+type TemplateDirOperation struct {
+	*operations.BaseOperation
+	srcFS   fs.FS
+	dstDir  string
+	data    TemplateData
+	options TemplateDirOptions
+	run     operations.ExpandedRun
+}
+
+// RenderTemplateDir creates an operation that renders the template tree in
+// srcFS, which may be an embed.FS, into dstDir. File contents and path names
+// are templates: "{{.Name}}/cmd/{{.Name}}.go.tmpl" renders to
+// "myapp/cmd/myapp.go". Files ending in .tmpl are rendered and lose the
+// suffix, other files are copied verbatim, and file modes are preserved,
+// made writable by the owner: embed.FS reports its files as 0444 and its
+// directories as 0555, which are rendered as 0644 and 0755. Like rendered
+// templates, copied files overwrite what is already at their output path,
+// so the tree can be rendered again over an earlier rendering.
+//
+// Example:
+//
+//	//go:embed all:skeleton
+//	var skeleton embed.FS
+//
+//	tree, _ := fs.Sub(skeleton, "skeleton")
+//	op := sfs.RenderTemplateDir(tree, "myapp", synthfs.TemplateData{"Name": "myapp"})
+func (s *SynthFS) RenderTemplateDir(srcFS fs.FS, dstDir string, data TemplateData) Operation {
+	return s.RenderTemplateDirWithOptions(srcFS, dstDir, data, TemplateDirOptions{})
+}
+
+// RenderTemplateDirWithOptions creates a template directory operation with custom options.
+func (s *SynthFS) RenderTemplateDirWithOptions(srcFS fs.FS, dstDir string, data TemplateData, options TemplateDirOptions) Operation {
+	id := s.idGen("render_template_dir", dstDir)
+	return NewTemplateDirOperation(id, srcFS, dstDir, data, options)
+}
+
+// NewTemplateDirOperation creates a template directory operation with an explicit ID.
+func NewTemplateDirOperation(id OperationID, srcFS fs.FS, dstDir string, data TemplateData, options TemplateDirOptions) *TemplateDirOperation {
+	if options.Suffix == "" {
+		options.Suffix = DefaultTemplateSuffix
+	}
+	op := &TemplateDirOperation{
+		BaseOperation: operations.NewBaseOperation(id, "render_template_dir", dstDir),
+		srcFS:         srcFS,
+		dstDir:        dstDir,
+		data:          data,
+		options:       options,
+	}
+	op.SetDescriptionDetail("dst", dstDir)
+	return op
+}
+
+// Validate checks that there is a template tree and a destination.
+func (op *TemplateDirOperation) Validate(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if err := op.BaseOperation.Validate(ctx, execCtx, fsys); err != nil {
+		return err
+	}
+	invalid := func(reason string, cause error) error {
+		return &core.ValidationError{
+			OperationID:   op.ID(),
+			OperationDesc: op.Describe(),
+			Reason:        reason,
+			Cause:         cause,
+		}
+	}
+	if op.srcFS == nil {
+		return invalid("template filesystem cannot be nil", nil)
+	}
+	if op.dstDir == "" {
+		return invalid("destination directory cannot be empty", nil)
+	}
+	if info, err := fsys.Stat(op.dstDir); err == nil && !info.IsDir() {
+		return invalid("destination is not a directory", nil)
+	}
+	return nil
+}
+
+// Expand walks the template tree and returns the child operations, in walk
+// order so directories come before their contents. Child IDs are derived from
+// the parent ID and the output path.
+func (op *TemplateDirOperation) Expand(ctx context.Context, fsys filesystem.FileSystem) ([]Operation, error) {
+	var children []Operation
+	var rendered []string
+	addDir := func(dst string, mode fs.FileMode) {
+		if info, err := fsys.Stat(dst); err == nil && info.IsDir() {
+			return
+		}
+		mkdir := operations.NewCreateDirectoryOperation(op.childID("mkdir", dst), dst)
+		mkdir.SetItem(targets.NewDirectory(dst).WithMode(mode))
+		children = append(children, mkdir)
+	}
+	addDir(op.dstDir, 0755)
+
+	// Output directories of the walk, keyed by source directory
+	outDirs := map[string]string{".": ""}
+	err := fs.WalkDir(op.srcFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+
+		name, err := op.renderName(d.Name())
+		if err != nil {
+			return fmt.Errorf("failed to render name of %s: %w", p, err)
+		}
+		if !d.IsDir() {
+			if stripped, ok := strings.CutSuffix(name, op.options.Suffix); ok {
+				name = stripped
+			}
+			if name == "" {
+				return fmt.Errorf("name of %s is empty without the %s suffix", p, op.options.Suffix)
+			}
+		}
+		rel := path.Join(outDirs[path.Dir(p)], name)
+		if op.options.Skip != nil && op.options.Skip(rel, op.data) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dst := path.Join(op.dstDir, rel)
+		if d.IsDir() {
+			outDirs[p] = rel
+			addDir(dst, info.Mode().Perm()|0700)
+			return nil
+		}
+
+		content, err := fs.ReadFile(op.srcFS, p)
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		mode |= 0600
+		isTemplate := strings.HasSuffix(d.Name(), op.options.Suffix) || (op.options.RenderAll && !isBinaryContent(content))
+		if isTemplate {
+			render := newWriteTemplateOperation(op.childID("render", dst), dst, string(content), op.data, mode)
+			children = append(children, render.WithOptions(op.options.Template))
+		} else {
+			create := operations.NewCreateFileOperation(op.childID("copy", dst), dst)
+			create.SetItem(targets.NewFile(dst).WithContent(content).WithMode(mode))
+			// In ensure mode the copy skips an identical file and overwrites any other
+			children = append(children, Ensure(create))
+		}
+		rendered = append(rendered, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(children))
+	for i, child := range children {
+		ids[i] = string(child.ID())
+	}
+	op.SetDescriptionDetail("files", rendered)
+	op.SetDescriptionDetail("children", ids)
+	return children, nil
+}
+
+// renderName renders a path segment as a template when it contains an action.
+// The result must still be a single segment, so a name cannot vanish or move
+// the output outside its directory.
+func (op *TemplateDirOperation) renderName(name string) (string, error) {
+	if !strings.Contains(name, "{{") {
+		return name, nil
+	}
+	content, err := renderTemplate(name, name, op.data, op.options.Template)
+	if err != nil {
+		return "", err
+	}
+	rendered := strings.TrimSpace(string(content))
+	switch {
+	case rendered == "", rendered == ".", rendered == "..":
+		return "", fmt.Errorf("rendered name %q is not a valid path segment", rendered)
+	case strings.Contains(rendered, "/"):
+		return "", fmt.Errorf("rendered name %q contains a path separator", rendered)
+	}
+	return rendered, nil
+}
+
+func (op *TemplateDirOperation) childID(action, p string) OperationID {
+	return OperationID(fmt.Sprintf("%s:%s:%s", op.ID(), action, p))
+}
+
+// isBinaryContent reports whether content looks binary, using the same NUL
+// byte heuristic as git.
+func isBinaryContent(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// Execute renders the tree directly. Pipelines normally expand the operation
+// first and run the children instead.
+func (op *TemplateDirOperation) Execute(ctx context.Context, execCtx *core.ExecutionContext, fsys filesystem.FileSystem) error {
+	if execCtx != nil {
+		return operations.ExecuteWithEvents(op, ctx, execCtx, fsys, op.execute)
+	}
+	return op.execute(ctx, fsys)
+}
+
+func (op *TemplateDirOperation) execute(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Run(ctx, fsys, op)
+}
+
+// Rollback removes the files and directories rendered by Execute.
+func (op *TemplateDirOperation) Rollback(ctx context.Context, fsys filesystem.FileSystem) error {
+	return op.run.Rollback(ctx, fsys)
+}
+
+// ReverseOps fails; each rendered path's operation reverses itself.
+func (op *TemplateDirOperation) ReverseOps(ctx context.Context, fsys filesystem.FileSystem, budget interface{}) ([]operations.Operation, interface{}, error) {
+	return op.run.ReverseOps("render_template_dir")
+}
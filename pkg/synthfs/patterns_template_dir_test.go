@@ -0,0 +1,186 @@
+package synthfs
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"io/fs"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+//go:embed testdata/skeleton
+var embeddedSkeleton embed.FS
+
+func skeletonFS() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md.tmpl":                  {Data: []byte("# {{.Name}}\n"), Mode: 0644},
+		"{{.Name}}/cmd/{{.Name}}.go.tmpl": {Data: []byte("package main // {{.Name}}\n"), Mode: 0644},
+		"scripts/run.sh.tmpl":             {Data: []byte("#!/bin/sh\nexec {{.Name}}\n"), Mode: 0755},
+		"assets/logo.png":                 {Data: []byte("\x89PNG\x00{{.Name}}"), Mode: 0644},
+		"LICENSE":                         {Data: []byte("MIT {{.Year}}"), Mode: 0644},
+	}
+}
+
+func TestRenderTemplateDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := New()
+	data := TemplateData{"Name": "myapp"}
+
+	t.Run("renders contents and names", func(t *testing.T) {
+		fsys := filesystem.NewOSFileSystem(t.TempDir())
+
+		_, err := Run(ctx, fsys, sfs.RenderTemplateDir(skeletonFS(), "out", data))
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+
+		expected := map[string]string{
+			"out/README.md":          "# myapp\n",
+			"out/myapp/cmd/myapp.go": "package main // myapp\n",
+			"out/scripts/run.sh":     "#!/bin/sh\nexec myapp\n",
+			"out/assets/logo.png":    "\x89PNG\x00{{.Name}}",
+			"out/LICENSE":            "MIT {{.Year}}",
+		}
+		for p, want := range expected {
+			content, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				t.Errorf("failed to read %s: %v", p, err)
+				continue
+			}
+			if string(content) != want {
+				t.Errorf("%s: expected %q, got %q", p, want, content)
+			}
+		}
+
+		info, err := fsys.Stat("out/scripts/run.sh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("expected preserved mode 0755, got %o", info.Mode().Perm())
+		}
+	})
+
+	t.Run("embedded trees render writable and can be rendered again", func(t *testing.T) {
+		fsys := filesystem.NewOSFileSystem(t.TempDir())
+		tree, err := fs.Sub(embeddedSkeleton, "testdata/skeleton")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if _, err := Run(ctx, fsys, sfs.RenderTemplateDir(tree, "out", data)); err != nil {
+				t.Fatalf("render %d failed: %v", i+1, err)
+			}
+		}
+
+		expected := map[string]fs.FileMode{
+			"out/README.md":      0644,
+			"out/config":         0755,
+			"out/config/app.yml": 0644,
+		}
+		for p, want := range expected {
+			info, err := fsys.Stat(p)
+			if err != nil {
+				t.Errorf("failed to stat %s: %v", p, err)
+				continue
+			}
+			if info.Mode().Perm() != want {
+				t.Errorf("%s: expected mode %o, got %o", p, want, info.Mode().Perm())
+			}
+		}
+		if content, _ := fs.ReadFile(fsys, "out/README.md"); string(content) != "# myapp\n" {
+			t.Errorf("unexpected README.md: %q", content)
+		}
+	})
+
+	t.Run("rejects names that are not a single segment", func(t *testing.T) {
+		fsys := filesystem.NewOSFileSystem(t.TempDir())
+		for _, name := range []string{"", " ", ".", "..", "a/b"} {
+			src := fstest.MapFS{"{{.Dir}}/f.txt": {Data: []byte("f"), Mode: 0644}}
+			_, err := Plan(ctx, fsys, sfs.RenderTemplateDir(src, "out", TemplateData{"Dir": name}))
+			if err == nil {
+				t.Errorf("expected an error for a name rendering to %q", name)
+			}
+		}
+		src := fstest.MapFS{"{{.Name}}.tmpl": {Data: []byte("x"), Mode: 0644}}
+		if _, err := Plan(ctx, fsys, sfs.RenderTemplateDir(src, "out", TemplateData{"Name": ""})); err == nil {
+			t.Error("expected an error for a name that is only the suffix")
+		}
+	})
+
+	t.Run("skip function and render all", func(t *testing.T) {
+		fsys := filesystem.NewOSFileSystem(t.TempDir())
+		options := TemplateDirOptions{
+			RenderAll: true,
+			Skip: func(p string, data TemplateData) bool {
+				return p == "scripts"
+			},
+		}
+
+		_, err := Run(ctx, fsys, sfs.RenderTemplateDirWithOptions(skeletonFS(), "out", TemplateData{"Name": "x", "Year": 2024}, options))
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if content, _ := fs.ReadFile(fsys, "out/LICENSE"); string(content) != "MIT 2024" {
+			t.Errorf("RenderAll should render text files, got %q", content)
+		}
+		if content, _ := fs.ReadFile(fsys, "out/assets/logo.png"); string(content) != "\x89PNG\x00{{.Name}}" {
+			t.Errorf("binary files should be copied verbatim, got %q", content)
+		}
+		if _, err := fsys.Stat("out/scripts"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("skipped directory was rendered: %v", err)
+		}
+	})
+
+	t.Run("every output is a child operation", func(t *testing.T) {
+		fsys := filesystem.NewOSFileSystem(t.TempDir())
+		op := NewTemplateDirOperation("scaffold", skeletonFS(), "out", data, TemplateDirOptions{})
+
+		planned, err := Plan(ctx, fsys, op)
+		if err != nil {
+			t.Fatalf("plan failed: %v", err)
+		}
+		types := make(map[string]int)
+		for _, child := range planned {
+			types[child.Describe().Type]++
+		}
+		if types["write_template"] != 3 || types["create_file"] != 2 {
+			t.Errorf("unexpected child operations: %v", types)
+		}
+		if planned[0].ID() != "scaffold:mkdir:out" {
+			t.Errorf("expected destination directory first, got %s", planned[0].ID())
+		}
+	})
+
+	t.Run("dry run and rollback", func(t *testing.T) {
+		fsys := filesystem.NewOSFileSystem(t.TempDir())
+		options := DefaultPipelineOptions()
+		options.DryRun = true
+		if _, err := RunWithOptions(ctx, fsys, options, sfs.RenderTemplateDir(skeletonFS(), "out", data)); err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if _, err := fsys.Stat("out"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("dry run created output: %v", err)
+		}
+
+		options = DefaultPipelineOptions()
+		options.RollbackOnError = true
+		fail := sfs.CustomOperation("fail", func(ctx context.Context, fs filesystem.FileSystem) error {
+			return errors.New("boom")
+		})
+		if _, err := RunWithOptions(ctx, fsys, options, sfs.RenderTemplateDir(skeletonFS(), "out", data), fail); err == nil {
+			t.Fatal("expected failure")
+		}
+		if _, err := fsys.Stat("out"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("rollback left output behind: %v", err)
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package synthfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateOptions configures how templates are parsed and rendered.
+type TemplateOptions struct {
+	// Strict makes a missing map key or field an error instead of rendering
+	// "<no value>". Because templates are rendered during validation, a strict
+	// template with missing data fails before anything is written.
+	Strict bool
+	// AllowEnv enables the env and expandenv functions, which read the
+	// process environment. They are off by default so rendering is reproducible.
+	AllowEnv bool
+	// Funcs adds functions to the library, replacing library functions of the same name.
+	Funcs template.FuncMap
+	// Partials are named templates available to every template through
+	// {{template "name" .}}. A partial may also hold {{define}} blocks.
+	Partials map[string]string
+}
+
+// TemplateFuncs returns the function library available to synthfs templates:
+//
+//	upper, lower, title, camelCase, pascalCase, snakeCase, kebabCase
+//	trim, replace, indent, nindent, quote
+//	default, toJson, toPrettyJson, toYaml, sha256
+//	env, expandenv (only when allowEnv is true)
+func TemplateFuncs(allowEnv bool) template.FuncMap {
+	funcs := template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      titleCase,
+		"camelCase":  camelCase,
+		"pascalCase": pascalCase,
+		"snakeCase":  func(s string) string { return strings.Join(lowerWords(s), "_") },
+		"kebabCase":  func(s string) string { return strings.Join(lowerWords(s), "-") },
+		"trim":       strings.TrimSpace,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"quote":      func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"default":    defaultValue,
+		"toJson":     toJSON,
+		"toPrettyJson": func(v interface{}) (string, error) {
+			data, err := json.MarshalIndent(v, "", "  ")
+			return string(data), err
+		},
+		"toYaml": toYAML,
+		"sha256": func(v interface{}) string {
+			sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+			return hex.EncodeToString(sum[:])
+		},
+	}
+	if allowEnv {
+		funcs["env"] = os.Getenv
+		funcs["expandenv"] = os.ExpandEnv
+	}
+	return funcs
+}
+
+// parseTemplate parses content into a template named name, with the function
+// library, the partials and the missing-key policy from options.
+func parseTemplate(name, content string, options TemplateOptions) (*template.Template, error) {
+	funcs := TemplateFuncs(options.AllowEnv)
+	for fname, fn := range options.Funcs {
+		funcs[fname] = fn
+	}
+
+	tmpl := template.New(name).Funcs(funcs)
+	if options.Strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	// Sorted so that redefinitions between partials resolve deterministically
+	partialNames := make([]string, 0, len(options.Partials))
+	for partial := range options.Partials {
+		partialNames = append(partialNames, partial)
+	}
+	sort.Strings(partialNames)
+	for _, partial := range partialNames {
+		if _, err := tmpl.New(partial).Parse(options.Partials[partial]); err != nil {
+			return nil, fmt.Errorf("failed to parse partial %q: %w", partial, err)
+		}
+	}
+
+	if _, err := tmpl.Parse(content); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// renderTemplate parses and executes content against data.
+func renderTemplate(name, content string, data interface{}, options TemplateOptions) ([]byte, error) {
+	tmpl, err := parseTemplate(name, content, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// splitWords breaks an identifier into words at separators and case changes,
+// keeping acronyms together: "HTTPServer_name" becomes HTTP, Server, name.
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+func lowerWords(s string) []string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+func pascalCase(s string) string {
+	var b strings.Builder
+	for _, word := range splitWords(s) {
+		b.WriteString(capitalize(word))
+	}
+	return b.String()
+}
+
+func camelCase(s string) string {
+	words := splitWords(s)
+	var b strings.Builder
+	for i, word := range words {
+		if i == 0 {
+			b.WriteString(strings.ToLower(word))
+		} else {
+			b.WriteString(capitalize(word))
+		}
+	}
+	return b.String()
+}
+
+// titleCase capitalizes each space-separated word and leaves the rest as is.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// indent prefixes every non-empty line of s with the given number of spaces.
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// defaultValue returns value unless it is missing or empty, in which case it
+// returns def. Used as {{.Port | default 8080}}.
+func defaultValue(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || value[0] == nil {
+		return def
+	}
+	v := reflect.ValueOf(value[0])
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	case reflect.Bool:
+		if !v.Bool() {
+			return def
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return def
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() == 0 {
+			return def
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Float() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return def
+		}
+	}
+	return value[0]
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func toYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
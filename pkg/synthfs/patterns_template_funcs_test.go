@@ -0,0 +1,145 @@
+package synthfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		data     TemplateData
+		want     string
+	}{
+		{"upper", `{{upper .Name}}`, TemplateData{"Name": "app"}, "APP"},
+		{"camelCase", `{{camelCase .Name}}`, TemplateData{"Name": "my-cool_app"}, "myCoolApp"},
+		{"pascalCase", `{{pascalCase .Name}}`, TemplateData{"Name": "http server"}, "HttpServer"},
+		{"snakeCase acronyms", `{{snakeCase .Name}}`, TemplateData{"Name": "HTTPServerName"}, "http_server_name"},
+		{"kebabCase", `{{kebabCase .Name}}`, TemplateData{"Name": "MyApp2Go"}, "my-app2-go"},
+		{"title", `{{title .Name}}`, TemplateData{"Name": "hello world"}, "Hello World"},
+		{"indent", `{{indent 2 .Body}}`, TemplateData{"Body": "a\nb"}, "  a\n  b"},
+		{"nindent", `x:{{nindent 2 .Body}}`, TemplateData{"Body": "a"}, "x:\n  a"},
+		{"default for missing", `{{.Port | default 8080}}`, TemplateData{}, "8080"},
+		{"default for empty", `{{.Name | default "app"}}`, TemplateData{"Name": ""}, "app"},
+		{"default keeps value", `{{.Port | default 8080}}`, TemplateData{"Port": 9000}, "9000"},
+		{"toJson", `{{toJson .Tags}}`, TemplateData{"Tags": []string{"a", "b"}}, `["a","b"]`},
+		{"toYaml", `{{toYaml .Config}}`, TemplateData{"Config": map[string]int{"port": 80}}, "port: 80"},
+		{"sha256", `{{sha256 "abc"}}`, nil, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"quote", `{{quote .Name}}`, TemplateData{"Name": `a"b`}, `"a\"b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate(tt.name, tt.template, tt.data, TemplateOptions{})
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("env requires opt-in", func(t *testing.T) {
+		t.Setenv("SYNTHFS_TEMPLATE_TEST", "from-env")
+		if _, err := renderTemplate("env", `{{env "SYNTHFS_TEMPLATE_TEST"}}`, nil, TemplateOptions{}); err == nil {
+			t.Error("env should not be available by default")
+		}
+		got, err := renderTemplate("env", `{{env "SYNTHFS_TEMPLATE_TEST"}}`, nil, TemplateOptions{AllowEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "from-env" {
+			t.Errorf("expected from-env, got %q", got)
+		}
+	})
+
+	t.Run("custom funcs override the library", func(t *testing.T) {
+		options := TemplateOptions{Funcs: map[string]interface{}{"upper": func(s string) string { return "custom" }}}
+		got, err := renderTemplate("custom", `{{upper "x"}}`, nil, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "custom" {
+			t.Errorf("expected custom, got %q", got)
+		}
+	})
+}
+
+func TestTemplateStrictModeAndPartials(t *testing.T) {
+	ctx := context.Background()
+	sfs := New()
+
+	t.Run("missing keys render as no value by default", func(t *testing.T) {
+		got, err := renderTemplate("lenient", `{{.Missing}}`, TemplateData{}, TemplateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "<no value>" {
+			t.Errorf("expected <no value>, got %q", got)
+		}
+	})
+
+	t.Run("strict mode fails validation before anything is written", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		first := sfs.CreateFile("first.txt", []byte("first"), 0644)
+		strict := sfs.NewWriteTemplateOperation("config.yml", "name: {{.Name}}\nport: {{.Port}}\n", TemplateData{"Name": "app"}, 0644).
+			WithOptions(TemplateOptions{Strict: true})
+
+		_, err := Run(ctx, fsys, first, strict)
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected a ValidationError, got %T: %v", err, err)
+		}
+		if !strings.Contains(err.Error(), "Port") {
+			t.Errorf("error should name the missing key: %v", err)
+		}
+		if _, err := fsys.Stat("first.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Error("no operation should run when validation fails")
+		}
+	})
+
+	t.Run("failing functions surface during validation", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := sfs.WriteTemplate("bad.txt", `{{toJson .Fn}}`, TemplateData{"Fn": func() {}})
+		if err := op.Validate(ctx, nil, fsys); err == nil {
+			t.Error("expected validation to render the template and fail")
+		}
+	})
+
+	t.Run("batch writer shares partials", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		writer := NewBatchTemplateWriter().
+			WithOptions(TemplateOptions{Strict: true}).
+			AddPartial("header", `# Generated for {{.Name}} - do not edit`).
+			AddPartial("helpers", `{{define "footer"}}-- {{upper .Name}}{{end}}`).
+			Add("a.txt", "{{template \"header\" .}}\nA\n{{template \"footer\" .}}", TemplateData{"Name": "a"}).
+			Add("b.txt", "{{template \"header\" .}}\nB", TemplateData{"Name": "b"})
+
+		if err := writer.Execute(ctx, fsys); err != nil {
+			t.Fatalf("execute failed: %v", err)
+		}
+		content, err := fsys.ReadFile("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "# Generated for a - do not edit\nA\n-- A" {
+			t.Errorf("unexpected a.txt: %q", content)
+		}
+		content, err = fsys.ReadFile("b.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "# Generated for b - do not edit\nB" {
+			t.Errorf("unexpected b.txt: %q", content)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package synthfs
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/validation"
+)
+
+func TestWriteTemplateRollback(t *testing.T) {
+	ctx := context.Background()
+	data := TemplateData{"Name": "new"}
+
+	t.Run("rollback restores overwritten file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("config.txt", []byte("old content"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		op := newWriteTemplateOperation("tmpl", "config.txt", "name={{.Name}}", data, 0644)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if got := readFileString(t, fsys, "config.txt"); got != "name=new" {
+			t.Fatalf("rendered content = %q", got)
+		}
+
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if got := readFileString(t, fsys, "config.txt"); got != "old content" {
+			t.Errorf("content after rollback = %q, want %q", got, "old content")
+		}
+		info, err := fsys.Stat("config.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("mode after rollback = %o, want 0600", info.Mode().Perm())
+		}
+	})
+
+	t.Run("rollback removes new file", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := newWriteTemplateOperation("tmpl", "new.txt", "name={{.Name}}", data, 0644)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if _, err := fsys.Stat("new.txt"); err == nil {
+			t.Error("expected new.txt to be removed by rollback")
+		}
+	})
+
+	t.Run("rollback before execute does nothing", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("keep.txt", []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := newWriteTemplateOperation("tmpl", "keep.txt", "x", nil, 0644)
+		if err := op.Rollback(ctx, fsys); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if got := readFileString(t, fsys, "keep.txt"); got != "keep" {
+			t.Errorf("content = %q, want untouched file", got)
+		}
+	})
+
+	t.Run("checksum of rendered output", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := newWriteTemplateOperation("tmpl", "out.txt", "name={{.Name}}", data, 0644)
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		record, ok := op.GetChecksum("out.txt").(*validation.ChecksumRecord)
+		if !ok {
+			t.Fatalf("expected a checksum record, got %T", op.GetChecksum("out.txt"))
+		}
+		want := fmt.Sprintf("%x", md5.Sum([]byte("name=new")))
+		if record.MD5 != want {
+			t.Errorf("MD5 = %s, want %s", record.MD5, want)
+		}
+		if got := op.Describe().Details["content_checksum"]; got != want {
+			t.Errorf("content_checksum detail = %v, want %s", got, want)
+		}
+		if len(op.GetAllChecksums()) != 1 {
+			t.Errorf("expected one checksum, got %d", len(op.GetAllChecksums()))
+		}
+	})
+
+	t.Run("dependencies are recorded", func(t *testing.T) {
+		op := newWriteTemplateOperation("tmpl", "out.txt", "x", nil, 0644)
+		op.AddDependency("mkdir")
+		op.AddDependency("other")
+		deps := op.Dependencies()
+		if len(deps) != 2 || deps[0] != "mkdir" || deps[1] != "other" {
+			t.Errorf("Dependencies() = %v", deps)
+		}
+	})
+}
+
+func TestWriteTemplateReverseOps(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new file reverses to delete", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		op := newWriteTemplateOperation("tmpl", "new.txt", "x", nil, 0644)
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{TotalMB: 1, RemainingMB: 1})
+		if err != nil {
+			t.Fatalf("ReverseOps failed: %v", err)
+		}
+		if backup != nil {
+			t.Errorf("expected no backup for a new file, got %v", backup)
+		}
+		if len(reverseOps) != 1 || reverseOps[0].Describe().Type != "delete" {
+			t.Fatalf("expected a single delete reverse op, got %v", reverseOps)
+		}
+		if reverseOps[0].ID() != "reverse_tmpl" {
+			t.Errorf("reverse op ID = %s", reverseOps[0].ID())
+		}
+	})
+
+	t.Run("overwrite backs up into the budget", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		content := strings.Repeat("a", 1024)
+		if err := fsys.WriteFile("config.txt", []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		budget := &core.BackupBudget{TotalMB: 1, RemainingMB: 1}
+		op := newWriteTemplateOperation("tmpl", "config.txt", "x", nil, 0644)
+
+		reverseOps, backup, err := op.ReverseOps(ctx, fsys, budget)
+		if err != nil {
+			t.Fatalf("ReverseOps failed: %v", err)
+		}
+		data, ok := backup.(*core.BackupData)
+		if !ok {
+			t.Fatalf("expected *core.BackupData, got %T", backup)
+		}
+		if string(data.BackupContent) != content || data.OriginalPath != "config.txt" {
+			t.Errorf("unexpected backup: %+v", data)
+		}
+		if budget.UsedMB <= 0 {
+			t.Errorf("expected budget to be consumed, used %.4fMB", budget.UsedMB)
+		}
+
+		// Overwrite, then run the reverse op to restore the backup
+		if err := op.Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(reverseOps) != 1 || reverseOps[0].Describe().Type != "create_file" {
+			t.Fatalf("expected a single create_file reverse op, got %v", reverseOps)
+		}
+		if err := fsys.Remove("config.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err := reverseOps[0].Execute(ctx, nil, fsys); err != nil {
+			t.Fatalf("reverse op failed: %v", err)
+		}
+		if got := readFileString(t, fsys, "config.txt"); got != content {
+			t.Errorf("restored content has %d bytes, want %d", len(got), len(content))
+		}
+	})
+
+	t.Run("budget exceeded", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("big.txt", make([]byte, 2*1024*1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := newWriteTemplateOperation("tmpl", "big.txt", "x", nil, 0644)
+		_, _, err := op.ReverseOps(ctx, fsys, &core.BackupBudget{TotalMB: 1, RemainingMB: 1})
+		if err == nil || !strings.Contains(err.Error(), "budget exceeded") {
+			t.Errorf("expected budget exceeded error, got %v", err)
+		}
+	})
+
+	t.Run("restorable run records restore ops", func(t *testing.T) {
+		fsys := filesystem.NewTestFileSystem()
+		if err := fsys.WriteFile("config.txt", []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		op := newWriteTemplateOperation("tmpl", "config.txt", "new", nil, 0644)
+		options := DefaultPipelineOptions()
+		options.Restorable = true
+
+		result, err := RunWithOptions(ctx, fsys, options, op)
+		if err != nil {
+			t.Fatalf("RunWithOptions failed: %v", err)
+		}
+		if len(result.RestoreOps) != 1 {
+			t.Fatalf("expected one restore op, got %d", len(result.RestoreOps))
+		}
+		if result.Operations[0].BackupData == nil {
+			t.Error("expected backup data on the operation result")
+		}
+		if got := readFileString(t, fsys, "config.txt"); got != "new" {
+			t.Errorf("content = %q, want %q", got, "new")
+		}
+	})
+}
+
+func readFileString(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return string(content)
+}
@@ -91,27 +91,88 @@ func (sp *simplePipeline) Resolve() error {
 	// Add creates first, then others
 	resolved = append(resolved, creates...)
 	resolved = append(resolved, others...)
-	
+
+	// Explicit dependencies, such as lazy inputs, come first
+	resolved, err := orderByDependencies(resolved)
+	if err != nil {
+		return err
+	}
+
 	sp.operations = resolved
 	sp.resolved = true
 	return nil
 }
 
+// orderByDependencies moves each operation after the operations of ops it
+// depends on, keeping the others in order.
+func orderByDependencies(ops []Operation) ([]Operation, error) {
+	inPipeline := make(map[core.OperationID]bool, len(ops))
+	for _, op := range ops {
+		inPipeline[op.ID()] = true
+	}
+
+	ordered := make([]Operation, 0, len(ops))
+	placed := make(map[core.OperationID]bool, len(ops))
+	pending := ops
+	for len(pending) > 0 {
+		var waiting []Operation
+		for _, op := range pending {
+			if dependenciesPlaced(op, inPipeline, placed) {
+				ordered = append(ordered, op)
+				placed[op.ID()] = true
+			} else {
+				waiting = append(waiting, op)
+			}
+		}
+		if len(waiting) == len(pending) {
+			return nil, fmt.Errorf("dependency cycle between operations starting at %s", waiting[0].ID())
+		}
+		pending = waiting
+	}
+	return ordered, nil
+}
+
+// dependenciesPlaced reports whether every dependency of op that is part of
+// the pipeline has been placed.
+func dependenciesPlaced(op Operation, inPipeline, placed map[core.OperationID]bool) bool {
+	dependent, ok := op.(interface{ Dependencies() []core.OperationID })
+	if !ok {
+		return true
+	}
+	for _, dep := range dependent.Dependencies() {
+		if inPipeline[dep] && !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
 // Validate checks if all operations in the pipeline are valid.
 func (sp *simplePipeline) Validate(ctx context.Context, fs FileSystem) error {
 	// First resolve dependencies to ensure proper order
 	if err := sp.Resolve(); err != nil {
 		return err
 	}
-	
+	return validateOperations(ctx, fs, sp.operations, false)
+}
+
+// validateOperations validates each operation against fs. Operations in
+// ensure mode whose state already holds are not validated, since they will
+// be skipped.
+func validateOperations(ctx context.Context, fs FileSystem, ops []Operation, ensure bool) error {
 	// Create execution context for validation
 	logger := DefaultLogger()
 	execCtx := &core.ExecutionContext{
 		Logger:   NewLoggerAdapter(&logger),
 		EventBus: core.NewMemoryEventBus(NewLoggerAdapter(&logger)),
 	}
-	
-	for _, op := range sp.operations {
+
+	for _, op := range ops {
+		if skip, err := ensureSatisfied(ctx, fs, op, ensure); err != nil {
+			return err
+		} else if skip {
+			continue
+		}
 		if err := op.Validate(ctx, execCtx, fs); err != nil {
 			return err
 		}
@@ -0,0 +1,206 @@
+package synthfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+)
+
+// progressLogInterval is the shortest time between two progress lines when
+// the renderer writes to a file or pipe instead of a terminal.
+const progressLogInterval = 5 * time.Second
+
+// ProgressRenderer draws the progress of runs published on an event bus. On a
+// terminal it keeps a single status line up to date; written to a file or a
+// pipe it prints a line per finished operation and, for long operations, a
+// progress line every few seconds.
+//
+// Example:
+//
+//	bus := synthfs.NewEventBus()
+//	renderer := synthfs.NewProgressRenderer(os.Stderr)
+//	renderer.Attach(bus)
+//	defer renderer.Detach()
+//	options := synthfs.DefaultPipelineOptions()
+//	options.EventBus = bus
+//	result, err := synthfs.RunWithOptions(ctx, fs, options, ops...)
+type ProgressRenderer struct {
+	mu          sync.Mutex
+	w           io.Writer
+	interactive bool
+	bus         EventBus
+	subs        []core.SubscriptionID
+	total       int
+	index       int
+	operation   string
+	logged      time.Time
+	lineWidth   int
+}
+
+// NewProgressRenderer creates a renderer writing to w. It redraws its status
+// line in place only when w is a terminal.
+func NewProgressRenderer(w io.Writer) *ProgressRenderer {
+	return &ProgressRenderer{w: w, interactive: isTerminal(w)}
+}
+
+// Attach subscribes the renderer to the events of bus.
+func (r *ProgressRenderer) Attach(bus EventBus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bus = bus
+	for eventType, handle := range map[string]func(Event){
+		core.EventValidationPassed:   r.validated,
+		core.EventOperationStarted:   r.started,
+		core.EventOperationProgress:  r.progressed,
+		core.EventOperationCompleted: r.finishedOperation,
+		core.EventOperationFailed:    r.finishedOperation,
+		core.EventPipelineFinished:   r.finishedPipeline,
+	} {
+		handle := handle
+		r.subs = append(r.subs, bus.Subscribe(eventType, EventHandlerFunc(func(ctx context.Context, event Event) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			handle(event)
+			return nil
+		})))
+	}
+}
+
+// Detach unsubscribes the renderer from its event bus.
+func (r *ProgressRenderer) Detach() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sub := range r.subs {
+		r.bus.Unsubscribe(sub)
+	}
+	r.subs, r.bus = nil, nil
+}
+
+func (r *ProgressRenderer) validated(event Event) {
+	r.total = event.(*core.ValidationPassedEvent).OperationCount
+	r.index = 0
+}
+
+func (r *ProgressRenderer) started(event Event) {
+	op := event.(*core.OperationStartedEvent).Operation
+	r.index++
+	r.operation = fmt.Sprintf("%s %s", op.OperationType, op.Path)
+	r.logged = time.Now()
+	if r.interactive {
+		r.draw(r.prefix() + r.operation)
+	}
+}
+
+func (r *ProgressRenderer) progressed(event Event) {
+	progress := event.(*core.OperationProgressEvent)
+	line := r.prefix() + r.operation + "  " + FormatProgress(progress)
+	if r.interactive {
+		r.draw(line)
+		return
+	}
+	if time.Since(r.logged) >= progressLogInterval {
+		r.logged = time.Now()
+		_, _ = fmt.Fprintln(r.w, line)
+	}
+}
+
+func (r *ProgressRenderer) finishedOperation(event Event) {
+	if r.interactive {
+		return
+	}
+	line := r.prefix() + r.operation
+	if failed, ok := event.(*core.OperationFailedEvent); ok {
+		line += fmt.Sprintf("  failed: %v", failed.Error)
+	}
+	_, _ = fmt.Fprintln(r.w, line)
+}
+
+func (r *ProgressRenderer) finishedPipeline(event Event) {
+	finished := event.(*core.PipelineFinishedEvent)
+	r.clear()
+	status := "done"
+	if !finished.Success {
+		status = "failed"
+	}
+	_, _ = fmt.Fprintf(r.w, "%s: %d operations in %s\n", status, r.index, finished.Duration.Round(time.Millisecond))
+	r.total, r.index = 0, 0
+}
+
+// prefix numbers the current operation, out of the total when it is known.
+func (r *ProgressRenderer) prefix() string {
+	if r.total > 0 {
+		return fmt.Sprintf("[%d/%d] ", r.index, r.total)
+	}
+	return fmt.Sprintf("[%d] ", r.index)
+}
+
+// draw replaces the status line with line.
+func (r *ProgressRenderer) draw(line string) {
+	padding := ""
+	if len(line) < r.lineWidth {
+		padding = strings.Repeat(" ", r.lineWidth-len(line))
+	}
+	_, _ = fmt.Fprintf(r.w, "\r%s%s", line, padding)
+	r.lineWidth = len(line)
+}
+
+// clear erases the status line.
+func (r *ProgressRenderer) clear() {
+	if r.interactive && r.lineWidth > 0 {
+		_, _ = fmt.Fprintf(r.w, "\r%s\r", strings.Repeat(" ", r.lineWidth))
+		r.lineWidth = 0
+	}
+}
+
+// FormatProgress describes a progress event in a few words, such as
+// "45% 450.0 MB/1.0 GB, 3/7 entries, ETA 12s".
+func FormatProgress(progress *core.OperationProgressEvent) string {
+	var parts []string
+	switch {
+	case progress.BytesTotal > 0:
+		percent := progress.BytesDone * 100 / progress.BytesTotal
+		parts = append(parts, fmt.Sprintf("%d%% %s/%s", percent, formatBytes(progress.BytesDone), formatBytes(progress.BytesTotal)))
+	case progress.BytesDone > 0:
+		parts = append(parts, formatBytes(progress.BytesDone))
+	}
+	switch {
+	case progress.EntriesTotal > 1:
+		parts = append(parts, fmt.Sprintf("%d/%d entries", progress.EntriesDone, progress.EntriesTotal))
+	case progress.EntriesTotal == 0 && progress.EntriesDone > 0:
+		parts = append(parts, fmt.Sprintf("%d entries", progress.EntriesDone))
+	}
+	if progress.ETA > 0 {
+		parts = append(parts, "ETA "+progress.ETA.Round(time.Second).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatBytes renders n with a decimal unit, such as "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "kMGTP"[exp])
+}
+
+// isTerminal reports whether w is a character device, such as a terminal.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,132 @@
+package synthfs_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestProgressEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789"), 100000)
+
+	// run executes ops and returns the final progress event of each operation
+	run := func(t *testing.T, fsys synthfs.FileSystem, ops ...synthfs.Operation) map[string]*core.OperationProgressEvent {
+		t.Helper()
+		bus := synthfs.NewEventBus()
+		final := make(map[string]*core.OperationProgressEvent)
+		bus.Subscribe(core.EventOperationProgress, synthfs.EventHandlerFunc(func(ctx context.Context, event synthfs.Event) error {
+			progress := event.(*core.OperationProgressEvent)
+			final[progress.Operation.OperationType] = progress
+			return nil
+		}))
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+		options.ProgressInterval = time.Nanosecond
+		if _, err := synthfs.RunWithOptions(ctx, fsys, options, ops...); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		return final
+	}
+
+	t.Run("copy and archives report their bytes", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("big.bin", content, 0644))
+		sfs := synthfs.New()
+
+		final := run(t, fsys,
+			sfs.Copy("big.bin", "copy.bin"),
+			sfs.CreateArchive("big.tar", "big.bin", "copy.bin"),
+		)
+		// Unarchive validates against the archive on disk, so it runs on its own
+		final["unarchive"] = run(t, fsys, sfs.Unarchive("big.tar", "out"))["unarchive"]
+
+		for opType, want := range map[string]int64{"copy": 1000000, "create_archive": 2000000} {
+			progress := final[opType]
+			if progress == nil {
+				t.Errorf("%s: no progress events", opType)
+				continue
+			}
+			if progress.BytesDone != want || progress.BytesTotal != want {
+				t.Errorf("%s: %d of %d bytes, want %d", opType, progress.BytesDone, progress.BytesTotal, want)
+			}
+		}
+		if progress := final["unarchive"]; progress == nil || progress.BytesDone != progress.BytesTotal || progress.EntriesDone != 2 {
+			t.Errorf("unarchive: unexpected final progress %+v", progress)
+		}
+	})
+
+	t.Run("sync reports checksums and copies", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.MkdirAll("src", 0755))
+		mustDo(t, fsys.MkdirAll("dst", 0755))
+		mustDo(t, fsys.WriteFile("src/same.bin", content, 0644))
+		mustDo(t, fsys.WriteFile("dst/same.bin", content, 0644))
+		mustDo(t, fsys.WriteFile("src/new.bin", content, 0644))
+
+		final := run(t, fsys, synthfs.New().Sync("src", "dst", synthfs.SyncOptions{Compare: synthfs.SyncCompareChecksum}))
+
+		if progress := final["sync"]; progress == nil || progress.BytesDone != 2000000 || progress.EntriesDone != 2 {
+			t.Errorf("sync: unexpected checksum progress %+v", progress)
+		}
+		if progress := final["sync_file"]; progress == nil || progress.BytesDone != 1000000 {
+			t.Errorf("sync_file: unexpected progress %+v", progress)
+		}
+	})
+}
+
+func TestProgressRenderer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+	mustDo(t, fsys.WriteFile("a.txt", []byte("a"), 0644))
+	sfs := synthfs.New()
+
+	var out bytes.Buffer
+	bus := synthfs.NewEventBus()
+	renderer := synthfs.NewProgressRenderer(&out)
+	renderer.Attach(bus)
+	defer renderer.Detach()
+	options := synthfs.DefaultPipelineOptions()
+	options.EventBus = bus
+
+	if _, err := synthfs.RunWithOptions(context.Background(), fsys, options,
+		sfs.Copy("a.txt", "b.txt"),
+		sfs.CreateDir("dir", 0755),
+	); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || lines[0] != "[1/2] copy a.txt" || lines[1] != "[2/2] create_directory dir" ||
+		!strings.HasPrefix(lines[2], "done: 2 operations in ") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		event *core.OperationProgressEvent
+		want  string
+	}{
+		{core.NewOperationProgressEvent(core.OperationEventData{}, 450_000_000, 1_000_000_000, 0, 1, "", 12*time.Second), "45% 450.0 MB/1.0 GB, ETA 12s"},
+		{core.NewOperationProgressEvent(core.OperationEventData{}, 512, 0, 3, 7, "", 0), "512 B, 3/7 entries"},
+		{core.NewOperationProgressEvent(core.OperationEventData{}, 0, 0, 4, 0, "", 0), "4 entries"},
+	}
+	for _, tt := range tests {
+		if got := synthfs.FormatProgress(tt.event); got != tt.want {
+			t.Errorf("FormatProgress = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package synthfs
 
 import (
+	"context"
+	"errors"
 	"io/fs"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
@@ -44,6 +49,45 @@ func (pfs *ProjectedFileSystem) UpdateProjectedState(op Operation) error {
 	return pfs.tracker.UpdateState(op)
 }
 
+// updatePlannedState updates the projected state for an operation being
+// planned, which in ensure mode converges on existing files.
+func (pfs *ProjectedFileSystem) updatePlannedState(op Operation, ensureAll bool) error {
+	if ensureRequested(op, ensureAll) {
+		return pfs.tracker.UpdateEnsuredState(op)
+	}
+	return pfs.tracker.UpdateState(op)
+}
+
+// touched reports whether an operation projected so far affects p, one of
+// its parent directories or anything below it.
+func (pfs *ProjectedFileSystem) touched(p string) bool {
+	for _, tracked := range pfs.tracker.Paths() {
+		if tracked != p && !strings.HasPrefix(p, tracked+"/") && !strings.HasPrefix(tracked, p+"/") {
+			continue
+		}
+		state, err := pfs.tracker.GetState(tracked)
+		if err == nil && (state.CreatedBy != "" || state.DeletedBy != "" || len(state.ModifiedBy) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureSkips reports whether op is in ensure mode and already satisfied.
+// The check runs against the real filesystem, so it is only made when no
+// operation projected so far affects the paths op depends on.
+func (pfs *ProjectedFileSystem) ensureSkips(ctx context.Context, op Operation, ensureAll bool) (bool, error) {
+	if !ensureRequested(op, ensureAll) {
+		return false, nil
+	}
+	for _, p := range ensurePaths(op) {
+		if pfs.touched(p) {
+			return false, nil
+		}
+	}
+	return ensureSatisfied(ctx, pfs.realFS, op, ensureAll)
+}
+
 // Stat returns file info, checking projected state first
 func (pfs *ProjectedFileSystem) Stat(path string) (fs.FileInfo, error) {
 	// Check projected state first
@@ -91,6 +135,75 @@ func (pfs *ProjectedFileSystem) Lstat(path string) (fs.FileInfo, error) {
 	return pfs.Stat(path)
 }
 
+// ReadDir lists a directory as it will look once the operations applied so far
+// have run: entries scheduled for deletion are hidden and entries created earlier
+// in the run are included. Implementing fs.ReadDirFS makes fs.WalkDir see the
+// projected tree, which is what expandable operations walk.
+func (pfs *ProjectedFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, err := pfs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	entries := make(map[string]fs.DirEntry)
+	realEntries, err := fs.ReadDir(pfs.realFS, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range realEntries {
+		entries[entry.Name()] = entry
+	}
+
+	for _, p := range pfs.tracker.Paths() {
+		state, err := pfs.tracker.GetState(p)
+		if err != nil {
+			return nil, err
+		}
+		// Walk up to the child of name that this tracked path lives under
+		child := p
+		for child != "." && path.Dir(child) != name {
+			child = path.Dir(child)
+		}
+		if child == "." || child == name {
+			continue
+		}
+		childName := path.Base(child)
+
+		if child == p {
+			touched := state.CreatedBy != "" || state.DeletedBy != "" || len(state.ModifiedBy) > 0
+			if !touched {
+				continue // Untouched paths keep their real entry
+			}
+			if !state.WillExist {
+				delete(entries, childName)
+				continue
+			}
+		} else if !state.WillExist {
+			continue // A removed descendant doesn't make its ancestors appear
+		}
+		if entry, ok := entries[childName]; ok && child != p && entry.IsDir() {
+			continue
+		}
+		childInfo, err := pfs.Stat(child)
+		if err != nil {
+			// An ancestor of a created path that no operation declared is
+			// created implicitly as a directory
+			childInfo = &projectedFileInfo{name: childName, mode: fs.ModeDir | 0755, modTime: time.Now(), isDir: true}
+		}
+		entries[childName] = fs.FileInfoToDirEntry(childInfo)
+	}
+
+	result := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
+
 // The following methods simply delegate to the real filesystem
 // since they don't need projected state handling
 
@@ -126,5 +239,27 @@ func (pfs *ProjectedFileSystem) WriteFile(name string, data []byte, perm fs.File
 	return pfs.realFS.WriteFile(name, data, perm)
 }
 
+// Link delegates to the real filesystem when it supports hard links.
+func (pfs *ProjectedFileSystem) Link(oldname, newname string) error {
+	linkFS, ok := pfs.realFS.(filesystem.LinkFS)
+	if !ok {
+		return &fs.PathError{Op: "link", Path: newname, Err: errors.ErrUnsupported}
+	}
+	return linkFS.Link(oldname, newname)
+}
+
+// FileID reports the identity of paths that exist on the real filesystem.
+// Paths only projected to exist have no identity yet.
+func (pfs *ProjectedFileSystem) FileID(name string) (filesystem.FileID, error) {
+	linkFS, ok := pfs.realFS.(filesystem.LinkFS)
+	if !ok {
+		return filesystem.FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: errors.ErrUnsupported}
+	}
+	return linkFS.FileID(name)
+}
+
 // Ensure ProjectedFileSystem implements FileSystem
-var _ filesystem.FileSystem = (*ProjectedFileSystem)(nil)
\ No newline at end of file
+var _ filesystem.FileSystem = (*ProjectedFileSystem)(nil)
+
+// Ensure ProjectedFileSystem implements fs.ReadDirFS
+var _ fs.ReadDirFS = (*ProjectedFileSystem)(nil)
\ No newline at end of file
@@ -0,0 +1,361 @@
+package synthfs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+)
+
+// ReportVersion is the version of the JSON document written by
+// ResultReport.WriteJSON. It changes only when fields are removed or change
+// meaning.
+const ReportVersion = 1
+
+// ResultReport is a serializable summary of a Result, for tools and CI.
+//
+// Example:
+//
+//	result, err := synthfs.Run(ctx, fs, ops...)
+//	report := synthfs.NewResultReport(result)
+//	_ = report.WriteJUnit(junitFile)
+//	_ = report.WriteTable(os.Stdout)
+type ResultReport struct {
+	Version    int                     `json:"version"`
+	Success    bool                    `json:"success"`
+	DurationMS float64                 `json:"duration_ms"`
+	Counts     map[OperationStatus]int `json:"counts"`
+	Errors     []string                `json:"errors,omitempty"`
+	Backup     *BackupReport           `json:"backup,omitempty"`
+	Operations []OperationReport       `json:"operations"`
+	Metadata   map[string]interface{}  `json:"metadata,omitempty"`
+}
+
+// OperationReport is the serializable summary of one operation of a Result.
+type OperationReport struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Path         string                 `json:"path"`
+	Src          string                 `json:"src,omitempty"`
+	Dst          string                 `json:"dst,omitempty"`
+	Status       OperationStatus        `json:"status"`
+	DurationMS   float64                `json:"duration_ms"`
+	Attempts     int                    `json:"attempts,omitempty"`
+	Errors       []ErrorReport          `json:"errors,omitempty"`
+	BackupSizeMB float64                `json:"backup_size_mb,omitempty"`
+	Outputs      map[string]interface{} `json:"outputs,omitempty"`
+}
+
+// ErrorReport is one error of an error chain, outermost first.
+type ErrorReport struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// BackupReport summarizes the backup budget of a restorable run.
+type BackupReport struct {
+	TotalMB float64 `json:"total_mb"`
+	UsedMB  float64 `json:"used_mb"`
+}
+
+// NewResultReport summarizes result. Outputs are those of GetAllOperationOutputs;
+// byte slices holding text are reported as strings and values that cannot be
+// serialized as their fmt representation.
+func NewResultReport(result *Result) *ResultReport {
+	report := &ResultReport{
+		Version:    ReportVersion,
+		Counts:     make(map[OperationStatus]int),
+		Operations: []OperationReport{},
+	}
+	if result == nil {
+		return report
+	}
+	report.Success = result.Success
+	report.DurationMS = milliseconds(result.Duration)
+	report.Metadata = reportValues(result.Metadata)
+	for _, err := range result.Errors {
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+	if result.Budget != nil {
+		report.Backup = &BackupReport{TotalMB: result.Budget.TotalMB, UsedMB: result.Budget.UsedMB}
+	}
+
+	for _, opResult := range result.Operations {
+		entry := OperationReport{
+			ID:           string(opResult.OperationID),
+			Status:       opResult.Status,
+			DurationMS:   milliseconds(opResult.Duration),
+			Attempts:     opResult.Attempts,
+			Errors:       errorChain(opResult.Error),
+			BackupSizeMB: opResult.BackupSizeMB,
+		}
+		if op, ok := opResult.Operation.(Operation); ok {
+			desc := op.Describe()
+			entry.Type = desc.Type
+			entry.Path = desc.Path
+			entry.Src, entry.Dst = op.GetPaths()
+			entry.Outputs = reportValues(GetAllOperationOutputs(op))
+		}
+		report.Counts[entry.Status]++
+		report.Operations = append(report.Operations, entry)
+	}
+	return report
+}
+
+// WriteJSON serializes the report as indented JSON.
+func (r *ResultReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with one test case per
+// operation, named after its ID and classed by its type. Failed operations
+// are failures, cancelled ones errors, and skipped ones, including those
+// whose condition did not hold, are skipped.
+func (r *ResultReport) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:  "synthfs",
+		Tests: len(r.Operations),
+		Time:  junitSeconds(r.DurationMS),
+	}
+	for _, op := range r.Operations {
+		testCase := junitTestCase{
+			Name:      op.ID,
+			ClassName: "synthfs." + op.Type,
+			Time:      junitSeconds(op.DurationMS),
+			SystemOut: op.displayPath(),
+		}
+		switch op.Status {
+		case core.StatusSuccess:
+		case core.StatusSkipped, core.StatusConditionUnmet:
+			testCase.Skipped = &junitProblem{Message: string(op.Status)}
+			suite.Skipped++
+		case core.StatusCancelled:
+			testCase.Error = op.junitProblem()
+			suite.Errors++
+		default:
+			testCase.Failure = op.junitProblem()
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteMarkdown writes the report as a Markdown summary line and table.
+func (r *ResultReport) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n\n", r.summary())
+	b.WriteString("| Status | ID | Type | Path | Duration | Error |\n")
+	b.WriteString("|---|---|---|---|---:|---|\n")
+	for _, op := range r.Operations {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			op.Status, markdownCell(op.ID), markdownCell(op.Type), markdownCell(op.displayPath()),
+			formatMilliseconds(op.DurationMS), markdownCell(op.errorMessage()))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteTable writes the report as an aligned table for terminals, followed by
+// a summary line.
+func (r *ResultReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tID\tTYPE\tPATH\tDURATION\tERROR")
+	for _, op := range r.Operations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			op.Status, op.ID, op.Type, op.displayPath(), formatMilliseconds(op.DurationMS), op.errorMessage())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w, r.summary())
+	return err
+}
+
+// summary describes the outcome of the run in one line, such as
+// "Succeeded in 12ms: 3 operations (2 SUCCESS, 1 SKIPPED)".
+func (r *ResultReport) summary() string {
+	outcome := "Failed"
+	if r.Success {
+		outcome = "Succeeded"
+	}
+	var counts []string
+	for _, status := range []OperationStatus{
+		core.StatusSuccess, core.StatusSkipped, core.StatusConditionUnmet,
+		core.StatusFailure, core.StatusValidation, core.StatusCancelled,
+	} {
+		if n := r.Counts[status]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, status))
+		}
+	}
+	line := fmt.Sprintf("%s in %s: %d operations", outcome, formatMilliseconds(r.DurationMS), len(r.Operations))
+	if len(counts) > 0 {
+		line += " (" + strings.Join(counts, ", ") + ")"
+	}
+	return line
+}
+
+// displayPath is the path of the operation, or "src -> dst" for operations
+// with both.
+func (op OperationReport) displayPath() string {
+	if op.Src != "" && op.Dst != "" {
+		return op.Src + " -> " + op.Dst
+	}
+	return op.Path
+}
+
+// errorMessage is the message of the outermost error of the operation.
+func (op OperationReport) errorMessage() string {
+	if len(op.Errors) == 0 {
+		return ""
+	}
+	return op.Errors[0].Message
+}
+
+func (op OperationReport) junitProblem() *junitProblem {
+	problem := &junitProblem{Message: op.errorMessage(), Type: string(op.Status)}
+	var lines []string
+	for _, err := range op.Errors {
+		lines = append(lines, err.Type+": "+err.Message)
+	}
+	problem.Text = strings.Join(lines, "\n")
+	return problem
+}
+
+// errorChain lists err and the errors it wraps, outermost first. Errors
+// joining several errors are followed depth first.
+func errorChain(err error) []ErrorReport {
+	var chain []ErrorReport
+	var walk func(error)
+	walk = func(err error) {
+		for err != nil {
+			chain = append(chain, ErrorReport{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, inner := range joined.Unwrap() {
+					walk(inner)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// reportValues makes values safe to serialize.
+func reportValues(values map[string]interface{}) map[string]interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	safe := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			if utf8.Valid(v) {
+				safe[key] = string(v)
+				continue
+			}
+		case error:
+			safe[key] = v.Error()
+			continue
+		}
+		if _, err := json.Marshal(value); err != nil {
+			safe[key] = fmt.Sprint(value)
+			continue
+		}
+		safe[key] = value
+	}
+	return safe
+}
+
+// markdownCell escapes s for a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatMilliseconds(ms float64) string {
+	d := time.Duration(ms * float64(time.Millisecond))
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+func junitSeconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
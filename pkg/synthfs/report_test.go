@@ -0,0 +1,172 @@
+package synthfs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestResultReport(t *testing.T) {
+	sfs := synthfs.New()
+	created := sfs.CreateFile("app/config.yml", []byte("x"), 0644)
+	copied := sfs.Copy("app/config.yml", "backup/config.yml")
+	skipped := sfs.CreateDir("app", 0755)
+	cause := &fs.PathError{Op: "open", Path: "backup/config.yml", Err: fs.ErrPermission}
+	result := &synthfs.Result{
+		Success:  false,
+		Duration: 1500 * time.Millisecond,
+		Errors:   []error{fmt.Errorf("operation %s failed: %w", copied.ID(), cause)},
+		Operations: []core.OperationResult{
+			{OperationID: created.ID(), Operation: created, Status: synthfs.StatusSuccess, Duration: 2 * time.Millisecond, Attempts: 1},
+			{OperationID: skipped.ID(), Operation: skipped, Status: synthfs.StatusSkipped},
+			{OperationID: copied.ID(), Operation: copied, Status: synthfs.StatusFailure, Error: fmt.Errorf("copy failed: %w", cause), Attempts: 3},
+		},
+	}
+	report := synthfs.NewResultReport(result)
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Version    int            `json:"version"`
+			Success    bool           `json:"success"`
+			DurationMS float64        `json:"duration_ms"`
+			Counts     map[string]int `json:"counts"`
+			Operations []struct {
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Src      string `json:"src"`
+				Dst      string `json:"dst"`
+				Status   string `json:"status"`
+				Attempts int    `json:"attempts"`
+				Errors   []struct {
+					Type    string `json:"type"`
+					Message string `json:"message"`
+				} `json:"errors"`
+			} `json:"operations"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+		}
+		if doc.Version != synthfs.ReportVersion || doc.Success || doc.DurationMS != 1500 {
+			t.Errorf("unexpected header: %+v", doc)
+		}
+		if doc.Counts["SUCCESS"] != 1 || doc.Counts["SKIPPED"] != 1 || doc.Counts["FAILURE"] != 1 {
+			t.Errorf("counts = %v", doc.Counts)
+		}
+		failed := doc.Operations[2]
+		if failed.Type != "copy" || failed.Src != "app/config.yml" || failed.Dst != "backup/config.yml" || failed.Attempts != 3 {
+			t.Errorf("unexpected operation: %+v", failed)
+		}
+		if len(failed.Errors) != 3 || failed.Errors[1].Type != "*fs.PathError" || failed.Errors[2].Message != "permission denied" {
+			t.Errorf("unexpected error chain: %+v", failed.Errors)
+		}
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteJUnit(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Skipped  int `xml:"skipped,attr"`
+			Suites   []struct {
+				Cases []struct {
+					Name      string `xml:"name,attr"`
+					ClassName string `xml:"classname,attr"`
+					Failure   *struct {
+						Message string `xml:"message,attr"`
+					} `xml:"failure"`
+					Skipped *struct{} `xml:"skipped"`
+				} `xml:"testcase"`
+			} `xml:"testsuite"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+		}
+		if doc.Tests != 3 || doc.Failures != 1 || doc.Skipped != 1 || len(doc.Suites) != 1 {
+			t.Fatalf("unexpected counts:\n%s", buf.String())
+		}
+		cases := doc.Suites[0].Cases
+		if cases[0].Name != string(created.ID()) || cases[0].ClassName != "synthfs.create_file" {
+			t.Errorf("unexpected test case: %+v", cases[0])
+		}
+		if cases[1].Skipped == nil || cases[2].Failure == nil || !strings.Contains(cases[2].Failure.Message, "copy failed") {
+			t.Errorf("unexpected outcomes:\n%s", buf.String())
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteMarkdown(&buf); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"**Failed in 1.5s: 3 operations (1 SUCCESS, 1 SKIPPED, 1 FAILURE)**",
+			"| SUCCESS | " + string(created.ID()) + " | create_file | app/config.yml | 2ms |  |",
+			"| copy | app/config.yml -> backup/config.yml |",
+		} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected %q in:\n%s", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteTable(&buf); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 5 || !strings.HasPrefix(lines[0], "STATUS   ID") || !strings.HasPrefix(lines[4], "Failed in 1.5s") {
+			t.Errorf("unexpected table:\n%s", buf.String())
+		}
+	})
+}
+
+func TestResultReportOutputs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+	sfs := synthfs.New()
+	echo := sfs.ShellCommand("echo hello", synthfs.WithCaptureOutput())
+
+	result, err := synthfs.Run(context.Background(), fsys, echo)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	report := synthfs.NewResultReport(result)
+	if got := report.Operations[0].Outputs["stdout"]; got != "hello\n" {
+		t.Errorf("stdout output = %#v", got)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("outputs not serializable: %v", err)
+	}
+}
+
+func TestResultReportNil(t *testing.T) {
+	var buf bytes.Buffer
+	if err := synthfs.NewResultReport(nil).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"operations": []`) {
+		t.Errorf("expected an empty operation list:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,330 @@
+package synthfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/targets"
+)
+
+// RestorePlanVersion is the version of the JSON document written by
+// RestorePlan.WriteJSON.
+const RestorePlanVersion = 1
+
+// RestoreStep is one operation of a RestorePlan.
+type RestoreStep struct {
+	// Undoes is the ID of the operation whose effect the step reverses.
+	Undoes    core.OperationID
+	Operation Operation
+}
+
+// RestorePlan undoes a restorable run. Its steps are the reverse operations
+// of the operations that succeeded, latest operation first, so running them
+// in order returns the filesystem to its state before the run. Plans can be
+// saved as JSON and executed later, by another process.
+//
+// Example:
+//
+//	options := synthfs.DefaultPipelineOptions()
+//	options.Restorable = true
+//	result, err := synthfs.RunWithOptions(ctx, fs, options, ops...)
+//	plan := synthfs.NewRestorePlan(result)
+//	err = plan.WriteJSON(file)
+//	// later
+//	plan, err = synthfs.ReadRestorePlan(file)
+//	result, err = plan.Execute(ctx, fs)
+type RestorePlan struct {
+	Steps []RestoreStep
+}
+
+// NewRestorePlan returns the plan that undoes result. Operations whose
+// reverse operations could not be generated, for instance because the backup
+// budget ran out, are missing from it.
+func NewRestorePlan(result *Result) *RestorePlan {
+	plan := &RestorePlan{}
+	if result == nil {
+		return plan
+	}
+	for i := len(result.Operations) - 1; i >= 0; i-- {
+		opResult := result.Operations[i]
+		if opResult.Status != core.StatusSuccess {
+			continue
+		}
+		for _, reverseOp := range opResult.ReverseOps {
+			if op, ok := reverseOp.(Operation); ok {
+				plan.Steps = append(plan.Steps, RestoreStep{Undoes: opResult.OperationID, Operation: op})
+			}
+		}
+	}
+	return plan
+}
+
+// Operations returns the operations of the plan, in the order they run.
+func (p *RestorePlan) Operations() []Operation {
+	ops := make([]Operation, len(p.Steps))
+	for i, step := range p.Steps {
+		ops[i] = step.Operation
+	}
+	return ops
+}
+
+// Execute runs the plan on fs with the default pipeline options. See
+// ExecuteWithOptions.
+func (p *RestorePlan) Execute(ctx context.Context, fs filesystem.FileSystem) (*Result, error) {
+	return p.ExecuteWithOptions(ctx, fs, DefaultPipelineOptions())
+}
+
+// ExecuteWithOptions runs the steps of the plan in order on fs, stopping at
+// the first one that fails unless options.ContinueOnError is set. Steps are
+// not validated first: what they restore usually conflicts with what is
+// there. With options.DryRun the steps run against an overlay and fs is left
+// untouched; with options.Restorable the result can itself be restored.
+func (p *RestorePlan) ExecuteWithOptions(ctx context.Context, fs filesystem.FileSystem, options PipelineOptions) (*Result, error) {
+	ops := p.Operations()
+	execCtx := newExecutionContext(options)
+	start := time.Now()
+	execCtx.Publish(ctx, core.NewPipelineStartedEvent(len(ops)))
+
+	if options.DryRun {
+		fs = NewDryRunOverlay(fs)
+		ctx = context.WithValue(ctx, dryRunKey{}, true)
+	}
+	result, err := executeOperationsDirect(ctx, fs, options, execCtx, ops)
+	if !result.Success && len(result.Errors) > 0 {
+		err = wrapExecutionError(result.Errors[0], result, ops)
+	}
+
+	execCtx.Publish(ctx, core.NewPipelineFinishedEvent(result.Success, result.Errors, time.Since(start)))
+	return result, err
+}
+
+// WriteText writes the plan as one line per step, naming the operation it
+// undoes.
+func (p *RestorePlan) WriteText(w io.Writer) error {
+	for _, step := range p.Steps {
+		desc := step.Operation.Describe()
+		path := desc.Path
+		if src, dst := step.Operation.GetPaths(); src != "" && dst != "" {
+			path = src + " -> " + dst
+		}
+		if _, err := fmt.Fprintf(w, "%-16s %s  # undoes %s\n", desc.Type, path, step.Undoes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON serializes the plan as indented JSON, backed up content included.
+// It fails for plans with steps that cannot be serialized, such as the
+// reverse of custom operations.
+func (p *RestorePlan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// ReadRestorePlan reads a plan written by WriteJSON.
+func ReadRestorePlan(r io.Reader) (*RestorePlan, error) {
+	var plan RestorePlan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// restorePlanJSON is the serialized form of a RestorePlan.
+type restorePlanJSON struct {
+	Version int               `json:"version"`
+	Steps   []restoreStepJSON `json:"steps"`
+}
+
+// restoreStepJSON is the serialized form of a RestoreStep. Only the fields
+// the operation type needs are set.
+type restoreStepJSON struct {
+	Undoes    string     `json:"undoes,omitempty"`
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	Path      string     `json:"path"`
+	Src       string     `json:"src,omitempty"`
+	Dst       string     `json:"dst,omitempty"`
+	Content   []byte     `json:"content,omitempty"`
+	Mode      string     `json:"mode,omitempty"`
+	Target    string     `json:"target,omitempty"`
+	Size      *int64     `json:"size,omitempty"`
+	Diff      string     `json:"diff,omitempty"`
+	Fuzz      *int       `json:"fuzz,omitempty"`
+	Canonical string     `json:"canonical,omitempty"`
+	Strategy  string     `json:"strategy,omitempty"`
+	ModTime   *time.Time `json:"mod_time,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *RestorePlan) MarshalJSON() ([]byte, error) {
+	doc := restorePlanJSON{Version: RestorePlanVersion, Steps: []restoreStepJSON{}}
+	for _, step := range p.Steps {
+		record, err := encodeRestoreStep(step)
+		if err != nil {
+			return nil, err
+		}
+		doc.Steps = append(doc.Steps, record)
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *RestorePlan) UnmarshalJSON(data []byte) error {
+	var doc restorePlanJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Version != RestorePlanVersion {
+		return fmt.Errorf("unsupported restore plan version %d", doc.Version)
+	}
+	p.Steps = nil
+	for _, record := range doc.Steps {
+		step, err := decodeRestoreStep(record)
+		if err != nil {
+			return err
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	return nil
+}
+
+// encodeRestoreStep records what is needed to rebuild the operation of step.
+func encodeRestoreStep(step RestoreStep) (restoreStepJSON, error) {
+	op := step.Operation
+	desc := op.Describe()
+	record := restoreStepJSON{
+		Undoes: string(step.Undoes),
+		ID:     string(op.ID()),
+		Type:   desc.Type,
+		Path:   desc.Path,
+	}
+	switch op := op.(type) {
+	case *operations.DeleteOperation:
+	case *operations.MoveOperation:
+		record.Src, record.Dst = op.GetPaths()
+	case *operations.TruncateOperation:
+		size := op.Size()
+		record.Size = &size
+	case *operations.AppendFileOperation:
+		record.Content = op.Content()
+	case *operations.ApplyPatchOperation:
+		fuzz := op.Fuzz()
+		record.Diff, record.Fuzz = op.Diff(), &fuzz
+	case *operations.CreateFileOperation:
+		if item, ok := op.GetItem().(interface{ Content() []byte }); ok {
+			record.Content = item.Content()
+		}
+		record.Mode = itemMode(op.GetItem())
+	case *operations.CreateDirectoryOperation:
+		record.Mode = itemMode(op.GetItem())
+	case *operations.CreateSymlinkOperation:
+		record.Target, _ = desc.Details["target"].(string)
+	case *operations.ChmodOperation:
+		record.Mode = formatMode(op.Mode())
+	case *operations.MaterializeOperation:
+		modTime := op.ModTime()
+		record.Canonical, record.Strategy = op.Canonical(), string(op.Strategy())
+		record.Mode, record.ModTime = formatMode(op.Mode()), &modTime
+	case *operations.DedupeFileOperation:
+		record.Canonical, record.Strategy = op.Canonical(), string(op.Strategy())
+	default:
+		return record, fmt.Errorf("cannot serialize restore step %s: unsupported operation type %s", op.ID(), desc.Type)
+	}
+	return record, nil
+}
+
+// decodeRestoreStep rebuilds a step recorded by encodeRestoreStep.
+func decodeRestoreStep(record restoreStepJSON) (RestoreStep, error) {
+	id := core.OperationID(record.ID)
+	mode, err := parseMode(record.Mode)
+	if err != nil {
+		return RestoreStep{}, fmt.Errorf("invalid restore step %s: %w", id, err)
+	}
+
+	var op Operation
+	switch record.Type {
+	case "delete":
+		op = operations.NewDeleteOperation(id, record.Path)
+	case "move":
+		op = operations.NewMoveOperation(id, record.Src)
+		op.SetPaths(record.Src, record.Dst)
+	case "truncate":
+		if record.Size == nil {
+			return RestoreStep{}, fmt.Errorf("invalid restore step %s: truncate without size", id)
+		}
+		op = operations.NewTruncateOperation(id, record.Path, *record.Size)
+	case "append_file":
+		op = operations.NewAppendFileOperation(id, record.Path, record.Content)
+	case "apply_patch":
+		patch := operations.NewApplyPatchOperation(id, record.Path, record.Diff)
+		if record.Fuzz != nil {
+			patch.WithFuzz(*record.Fuzz)
+		}
+		op = patch
+	case "create_file":
+		item := targets.NewFile(record.Path).WithContent(record.Content)
+		if record.Mode != "" {
+			item.WithMode(mode)
+		}
+		op = operations.NewCreateFileOperation(id, record.Path)
+		op.SetItem(item)
+	case "create_directory":
+		item := targets.NewDirectory(record.Path)
+		if record.Mode != "" {
+			item.WithMode(mode)
+		}
+		op = operations.NewCreateDirectoryOperation(id, record.Path)
+		op.SetItem(item)
+	case "create_symlink":
+		op = operations.NewCreateSymlinkOperation(id, record.Path)
+		op.SetItem(targets.NewSymlink(record.Path, record.Target))
+		op.SetDescriptionDetail("target", record.Target)
+	case "chmod":
+		op = operations.NewChmodOperation(id, record.Path, mode)
+	case "materialize":
+		var modTime time.Time
+		if record.ModTime != nil {
+			modTime = *record.ModTime
+		}
+		op = operations.NewMaterializeOperation(id, record.Path, record.Canonical, DedupeStrategy(record.Strategy), mode, modTime)
+	case "dedupe_file":
+		op = operations.NewDedupeFileOperation(id, record.Path, record.Canonical, DedupeStrategy(record.Strategy))
+	default:
+		return RestoreStep{}, fmt.Errorf("invalid restore step %s: unsupported operation type %q", id, record.Type)
+	}
+	return RestoreStep{Undoes: core.OperationID(record.Undoes), Operation: op}, nil
+}
+
+// itemMode returns the permission bits of item, or "" if it has none.
+func itemMode(item interface{}) string {
+	if moder, ok := item.(interface{ Mode() fs.FileMode }); ok && moder.Mode().Perm() != 0 {
+		return formatMode(moder.Mode())
+	}
+	return ""
+}
+
+func formatMode(mode fs.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+func parseMode(s string) (fs.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return fs.FileMode(mode).Perm(), nil
+}
@@ -0,0 +1,143 @@
+package synthfs_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestRestorePlan(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	// run applies a batch of changes restorably to a fresh filesystem
+	run := func(t *testing.T) (synthfs.FileSystem, *synthfs.Result) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("old.txt", []byte("old"), 0600))
+		mustDo(t, fsys.WriteFile("moved.txt", []byte("moved"), 0644))
+		mustDo(t, fsys.WriteFile("app.log", []byte("start\n"), 0644))
+
+		options := synthfs.DefaultPipelineOptions()
+		options.Restorable = true
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateDirWithID("dir", "app", 0755),
+			sfs.CreateFileWithID("file", "app/config.yml", []byte("new"), 0644),
+			sfs.DeleteWithID("delete", "old.txt"),
+			sfs.MoveWithID("move", "moved.txt", "app/moved.txt"),
+			sfs.AppendFileWithID("append", "app.log", []byte("more\n")),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		return fsys, result
+	}
+
+	assertRestored := func(t *testing.T, fsys synthfs.FileSystem) {
+		t.Helper()
+		if got := readString(t, fsys, "old.txt"); got != "old" {
+			t.Errorf("old.txt = %q", got)
+		}
+		if info, err := fsys.Stat("old.txt"); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("old.txt mode not restored: %v, %v", info, err)
+		}
+		if got := readString(t, fsys, "moved.txt"); got != "moved" {
+			t.Errorf("moved.txt = %q", got)
+		}
+		if got := readString(t, fsys, "app.log"); got != "start\n" {
+			t.Errorf("app.log = %q", got)
+		}
+		if _, err := fsys.Stat("app"); err == nil {
+			t.Error("app was not removed")
+		}
+	}
+
+	t.Run("restore ops are not duplicated", func(t *testing.T) {
+		_, result := run(t)
+		if len(result.RestoreOps) != 5 {
+			t.Errorf("expected one restore operation per operation, got %d", len(result.RestoreOps))
+		}
+	})
+
+	t.Run("steps undo the latest operation first", func(t *testing.T) {
+		fsys, result := run(t)
+		plan := synthfs.NewRestorePlan(result)
+		var undoes []string
+		for _, step := range plan.Steps {
+			undoes = append(undoes, string(step.Undoes))
+		}
+		if got := strings.Join(undoes, ","); got != "append,move,delete,file,dir" {
+			t.Errorf("steps undo %s", got)
+		}
+
+		restored, err := plan.Execute(ctx, fsys)
+		if err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		if len(restored.Operations) != 5 || !restored.Success {
+			t.Errorf("unexpected restore result: %+v", restored)
+		}
+		assertRestored(t, fsys)
+	})
+
+	t.Run("plans survive serialization", func(t *testing.T) {
+		fsys, result := run(t)
+		var buf bytes.Buffer
+		mustDo(t, synthfs.NewRestorePlan(result).WriteJSON(&buf))
+
+		plan, err := synthfs.ReadRestorePlan(&buf)
+		if err != nil {
+			t.Fatalf("failed to read plan: %v", err)
+		}
+		if _, err := plan.Execute(ctx, fsys); err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		assertRestored(t, fsys)
+	})
+
+	t.Run("dry run and plan report", func(t *testing.T) {
+		fsys, result := run(t)
+		plan := synthfs.NewRestorePlan(result)
+
+		var report bytes.Buffer
+		mustDo(t, plan.WriteText(&report))
+		if !strings.Contains(report.String(), "app/moved.txt -> moved.txt  # undoes move") {
+			t.Errorf("unexpected plan report:\n%s", report.String())
+		}
+
+		options := synthfs.DefaultPipelineOptions()
+		options.DryRun = true
+		restored, err := plan.ExecuteWithOptions(ctx, fsys, options)
+		if err != nil || !restored.Success {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if got := readString(t, fsys, "app/config.yml"); got != "new" {
+			t.Errorf("dry run changed the filesystem: %q", got)
+		}
+	})
+
+	t.Run("custom operations cannot be serialized", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		noop := func(ctx context.Context, fs filesystem.FileSystem) error { return nil }
+		custom := synthfs.NewCustomOperation("custom", noop).WithRollback(noop)
+
+		options := synthfs.DefaultPipelineOptions()
+		options.Restorable = true
+		result, err := synthfs.RunWithOptions(ctx, fsys, options, custom)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := synthfs.NewRestorePlan(result).WriteJSON(&buf); err == nil || !strings.Contains(err.Error(), "unsupported operation type") {
+			t.Errorf("expected a serialization error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package synthfs
+
+import (
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+)
+
+// RetryPolicy decides whether, and after how long, a failed operation runs
+// again. See core.RetryPolicy.
+type RetryPolicy = core.RetryPolicy
+
+// IsTransient reports whether err is a filesystem error that may not happen
+// again, such as EBUSY on a locked file or ESTALE on an NFS mount. It is what
+// a RetryPolicy without a Retryable function retries.
+func IsTransient(err error) bool {
+	return core.IsTransient(err)
+}
+
+// retryMarker is implemented by operations that can carry their own retry
+// policy.
+type retryMarker interface {
+	SetRetryPolicy(policy *RetryPolicy)
+	RetryPolicy() *RetryPolicy
+}
+
+// Retry sets how op is retried when it fails and returns it, overriding
+// PipelineOptions.RetryPolicy for this operation. Only execution is repeated:
+// the operation was validated once, before the run started. Expandable
+// operations retry each child with the same policy.
+//
+// Example:
+//
+//	synthfs.Run(ctx, fs,
+//	    synthfs.Retry(sfs.Copy("build/app", "/mnt/share/app"), &synthfs.RetryPolicy{
+//	        MaxAttempts:  5,
+//	        InitialDelay: 200 * time.Millisecond,
+//	        Jitter:       0.2,
+//	    }),
+//	)
+func Retry(op Operation, policy *RetryPolicy) Operation {
+	if marker, ok := op.(retryMarker); ok {
+		marker.SetRetryPolicy(policy)
+	}
+	return op
+}
+
+// inheritRetryPolicy gives child the retry policy of its parent, unless it
+// has its own.
+func inheritRetryPolicy(parent, child Operation) {
+	parentMarker, ok := parent.(retryMarker)
+	if !ok || parentMarker.RetryPolicy() == nil {
+		return
+	}
+	if childMarker, ok := child.(retryMarker); ok && childMarker.RetryPolicy() == nil {
+		childMarker.SetRetryPolicy(parentMarker.RetryPolicy())
+	}
+}
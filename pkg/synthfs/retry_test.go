@@ -0,0 +1,108 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestRetry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+
+	// flaky returns an operation that fails with err the first failures
+	// times it runs, and counts how often it was validated and executed
+	flaky := func(failures int, err error) (*synthfs.CustomOperation, *int, *int) {
+		var validated, executed int
+		op := synthfs.NewCustomOperation("flaky", func(ctx context.Context, fs filesystem.FileSystem) error {
+			executed++
+			if executed <= failures {
+				return fmt.Errorf("write share/app: %w", err)
+			}
+			return nil
+		}).WithValidation(func(ctx context.Context, fs filesystem.FileSystem) error {
+			validated++
+			return nil
+		})
+		return op, &validated, &executed
+	}
+	quick := &synthfs.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond}
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		op, validated, executed := flaky(2, syscall.EBUSY)
+		bus := synthfs.NewEventBus()
+		var retries []*core.OperationRetryEvent
+		bus.Subscribe(core.EventOperationRetry, synthfs.EventHandlerFunc(func(ctx context.Context, event synthfs.Event) error {
+			retries = append(retries, event.(*core.OperationRetryEvent))
+			return nil
+		}))
+		options := synthfs.DefaultPipelineOptions()
+		options.EventBus = bus
+		options.RetryPolicy = quick
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options, op)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if got := result.Operations[0].Attempts; got != 3 || *executed != 3 {
+			t.Errorf("expected 3 attempts, got %d (executed %d)", got, *executed)
+		}
+		if *validated != 1 {
+			t.Errorf("expected one validation, got %d", *validated)
+		}
+		if len(retries) != 2 || retries[0].Attempt != 1 || retries[1].Attempt != 2 ||
+			retries[1].MaxAttempts != 3 || !errors.Is(retries[0].Error, syscall.EBUSY) {
+			t.Errorf("unexpected retry events: %+v", retries)
+		}
+	})
+
+	t.Run("attempts are bounded", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		op, _, executed := flaky(5, syscall.ESTALE)
+		options := synthfs.DefaultPipelineOptions()
+		options.RetryPolicy = quick
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options, op)
+		if !errors.Is(err, syscall.ESTALE) || *executed != 3 || result.Operations[0].Attempts != 3 {
+			t.Errorf("expected 3 failed attempts, got %d: %v", *executed, err)
+		}
+	})
+
+	t.Run("other errors fail at once", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		op, _, executed := flaky(1, syscall.EACCES)
+		options := synthfs.DefaultPipelineOptions()
+		options.RetryPolicy = quick
+
+		if _, err := synthfs.RunWithOptions(ctx, fsys, options, op); err == nil || *executed != 1 {
+			t.Errorf("expected a single failed attempt, got %d: %v", *executed, err)
+		}
+	})
+
+	t.Run("operations can set their own policy", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		op, _, executed := flaky(1, syscall.EACCES)
+		policy := &synthfs.RetryPolicy{
+			MaxAttempts:  2,
+			InitialDelay: time.Millisecond,
+			Retryable:    func(err error) bool { return errors.Is(err, syscall.EACCES) },
+		}
+
+		result, err := synthfs.Run(ctx, fsys, synthfs.Retry(op, policy))
+		if err != nil || *executed != 2 || result.Operations[0].Attempts != 2 {
+			t.Errorf("expected success on the second attempt, got %d: %v", *executed, err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package synthfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -48,13 +49,42 @@ func Run(ctx context.Context, fs filesystem.FileSystem, ops ...Operation) (*Resu
 // RunWithOptions executes operations with custom options.
 // This function directly executes operations without using the pipeline/adapter system,
 // providing a simpler and more direct execution path.
+//
+// Set options.EventBus to observe the run: operation events, and lifecycle
+// events for the pipeline, validation, backups and rollback, are published
+// to it in order as they happen.
 func RunWithOptions(ctx context.Context, fs filesystem.FileSystem, options PipelineOptions, ops ...Operation) (*Result, error) {
+	execCtx := newExecutionContext(options)
+	start := time.Now()
+	execCtx.Publish(ctx, core.NewPipelineStartedEvent(len(ops)))
+
+	if options.UndoLog != nil {
+		options.Restorable = true
+	}
+	result, err := runOperations(ctx, fs, options, execCtx, ops)
+	if err == nil && result.Success && options.UndoLog != nil && !options.DryRun {
+		if result.RunID, err = options.UndoLog.RecordRun(ctx, result, fs); err != nil {
+			err = fmt.Errorf("failed to record the run in the undo log: %w", err)
+		}
+	}
+
+	errs := []error{err}
+	if result != nil {
+		errs = result.Errors
+	}
+	execCtx.Publish(ctx, core.NewPipelineFinishedEvent(result != nil && result.Success, errs, time.Since(start)))
+	return result, err
+}
+
+// runOperations plans and executes ops for RunWithOptions.
+func runOperations(ctx context.Context, fs filesystem.FileSystem, options PipelineOptions, execCtx *core.ExecutionContext, ops []Operation) (*Result, error) {
 	// For the simple API, we disable prerequisite resolution by default to allow for the straightforward,
 	// ordered execution of operations without requiring explicit dependency declarations.
 	options.ResolvePrerequisites = false
 
 	if options.DryRun {
-		fs = NewDryRunFS()
+		fs = NewDryRunOverlay(fs)
+		ctx = context.WithValue(ctx, dryRunKey{}, true)
 	}
 
 	if len(ops) == 0 {
@@ -65,53 +95,158 @@ func RunWithOptions(ctx context.Context, fs filesystem.FileSystem, options Pipel
 		}, nil
 	}
 
+	execCtx.Publish(ctx, core.NewValidationStartedEvent(len(ops)))
+	planned, err := planOperations(ctx, fs, execCtx, ops, options.Ensure)
+	if err != nil {
+		execCtx.Publish(ctx, validationFailedEvent(err))
+		if _, duplicate := err.(*duplicateIDError); duplicate {
+			return nil, err
+		}
+		// Return a failed result with the error
+		return &Result{
+			Success:    false,
+			Operations: []core.OperationResult{},
+			Duration:   0,
+			Errors:     []error{err},
+		}, err
+	}
+	ops = planned
+	execCtx.Publish(ctx, core.NewValidationPassedEvent(len(ops)))
+
+	// Execute operations directly
+	result, err := executeOperationsDirect(ctx, fs, options, execCtx, ops)
+	
+	// Wrap errors to match original batch API behavior
+	if !result.Success && len(result.Errors) > 0 {
+		err = wrapExecutionError(result.Errors[0], result, ops)
+	}
+
+	return result, err
+}
+
+// Plan validates operations against the projected state of fs without running
+// them, and returns the operations that would run. Expandable operations such
+// as Sync and the glob operations are replaced by their children, so the plan
+// lists every path that will be touched. Operations marked with Ensure whose
+// state already holds are included; running them reports StatusSkipped.
+// So are operations whose When condition does not hold against the projected
+// state, unvalidated and unexpanded; running them reports
+// StatusConditionUnmet unless the condition holds by then.
+func Plan(ctx context.Context, fs filesystem.FileSystem, ops ...Operation) ([]Operation, error) {
+	return planOperations(ctx, fs, nil, ops, false)
+}
+
+// duplicateIDError reports two operations sharing an ID.
+type duplicateIDError struct {
+	id core.OperationID
+}
+
+func (e *duplicateIDError) Error() string {
+	return fmt.Sprintf("operation with ID '%s' already exists", e.id)
+}
+
+// planOperations validates ops in order against a projected view of fs,
+// expanding expandable operations into their children. Operations are first
+// moved after the operations they depend on, such as the producers of their
+// lazy inputs. With ensure set, or
+// for operations marked with Ensure, operations whose state already holds are
+// planned without being validated or projected, for execution to skip.
+// Expansion, which may checksum whole trees, reports progress to the event
+// bus of execCtx when there is one.
+func planOperations(ctx context.Context, fs filesystem.FileSystem, execCtx *core.ExecutionContext, ops []Operation, ensure bool) ([]Operation, error) {
 	// Check for duplicate operation IDs
 	idsSeen := make(map[core.OperationID]bool)
 	for _, op := range ops {
 		id := op.ID()
 		if idsSeen[id] {
-			return nil, fmt.Errorf("operation with ID '%s' already exists", id)
+			return nil, &duplicateIDError{id: id}
 		}
 		idsSeen[id] = true
 	}
+	ops, err := orderByDependencies(ops)
+	if err != nil {
+		return nil, err
+	}
 
 	// For the simple API, we need to validate operations with projected state
 	// to support sequential operations where later ops depend on earlier ones
 	projectedFS := NewProjectedFileSystem(fs)
-	
-	// First, validate all operations with projected state
+
+	var planned []Operation
 	for _, op := range ops {
+		// Operations whose condition does not hold are neither validated nor
+		// projected; execution checks the condition again
+		if holds, err := plannedConditionHolds(ctx, projectedFS, op); err != nil {
+			return nil, fmt.Errorf("failed to check condition of %s: %w", op.ID(), err)
+		} else if !holds {
+			planned = append(planned, op)
+			continue
+		}
+
+		if skip, err := projectedFS.ensureSkips(ctx, op, ensure); err != nil {
+			return nil, err
+		} else if skip {
+			planned = append(planned, op)
+			continue
+		}
+
 		// Validate against projected filesystem state
-		if err := op.Validate(ctx, nil, projectedFS); err != nil {
-			// Return a failed result with the error
-			return &Result{
-				Success:    false,
-				Operations: []core.OperationResult{},
-				Duration:   0,
-				Errors:     []error{err},
-			}, err
+		if err := validateStep(ctx, execCtx, op, projectedFS); errors.Is(err, core.ErrSkip) {
+			planned = append(planned, op)
+			continue
+		} else if err != nil {
+			return nil, err
 		}
-		// Update projected state to reflect this operation
-		if err := projectedFS.UpdateProjectedState(op); err != nil {
-			// Return a failed result with the error
-			return &Result{
-				Success:    false,
-				Operations: []core.OperationResult{},
-				Duration:   0,
-				Errors:     []error{err},
-			}, err
+
+		expanded := []Operation{op}
+		if expandable, ok := op.(ExpandableOperation); ok {
+			progress := core.NewProgress(ctx, execCtx, operationEventData(op))
+			children, err := expandable.Expand(core.WithProgress(ctx, progress), projectedFS)
+			progress.Finish()
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if idsSeen[child.ID()] {
+					return nil, &duplicateIDError{id: child.ID()}
+				}
+				idsSeen[child.ID()] = true
+				if ensureRequested(op, false) {
+					Ensure(child)
+				}
+				inheritTimeout(op, child)
+				inheritRetryPolicy(op, child)
+				if skip, err := projectedFS.ensureSkips(ctx, child, ensure); err != nil {
+					return nil, err
+				} else if skip {
+					continue
+				}
+				if err := validateStep(ctx, execCtx, child, projectedFS); errors.Is(err, core.ErrSkip) {
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+				// Children see the effect of their earlier siblings
+				if err := projectedFS.updatePlannedState(child, ensure); err != nil {
+					return nil, err
+				}
+			}
+			inheritCondition(op, children)
+			expanded = children
+		} else if err := projectedFS.updatePlannedState(op, ensure); err != nil {
+			// Update projected state to reflect this operation
+			return nil, err
 		}
+		planned = append(planned, expanded...)
 	}
-	
-	// Execute operations directly
-	result, err := executeOperationsDirect(ctx, fs, options, ops)
-	
-	// Wrap errors to match original batch API behavior
-	if !result.Success && len(result.Errors) > 0 {
-		err = wrapExecutionError(result.Errors[0], result, ops)
-	}
+	return planned, nil
+}
 
-	return result, err
+// validateStep validates op against fs through the middleware of execCtx.
+func validateStep(ctx context.Context, execCtx *core.ExecutionContext, op Operation, fs filesystem.FileSystem) error {
+	return execCtx.RunStep(ctx, core.PhaseValidate, op, fs, func(ctx context.Context) error {
+		return op.Validate(ctx, nil, fs)
+	})
 }
 
 // wrapExecutionError wraps execution errors to match original batch API behavior
@@ -125,7 +260,7 @@ func wrapExecutionError(execErr error, result *Result, ops []Operation) error {
 		
 		// Determine failed operation by examining result
 		for i, op := range ops {
-			if i < len(result.Operations) && result.Operations[i].Status == StatusSuccess {
+			if i < len(result.Operations) && (result.Operations[i].Status == StatusSuccess || result.Operations[i].Status == StatusSkipped || result.Operations[i].Status == StatusConditionUnmet) {
 				successfulOps = append(successfulOps, op.ID())
 			} else {
 				failedIndex = i + 1 // 1-based index
@@ -149,7 +284,7 @@ func wrapExecutionError(execErr error, result *Result, ops []Operation) error {
 }
 
 // executeOperationsDirect executes operations directly without pipeline adapters
-func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, options PipelineOptions, ops []Operation) (*Result, error) {
+func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, options PipelineOptions, execCtx *core.ExecutionContext, ops []Operation) (*Result, error) {
 	start := time.Now()
 	
 	result := &Result{
@@ -170,21 +305,98 @@ func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, opti
 		result.Budget = budget
 	}
 
-	// Create execution context
-	logger := DefaultLogger()
-	execCtx := &core.ExecutionContext{
-		Logger:   NewLoggerAdapter(&logger),
-		Budget:   budget,
-		EventBus: nil, // We can add this later if needed
-	}
+	execCtx.Budget = budget
 
 	// Track successful operations for rollback
 	var successfulOps []Operation
-	var reverseOps []interface{}
 
 	// Execute operations
 	for _, op := range ops {
+		// Once the run is cancelled, nothing else starts
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusCancelled,
+				Error:       ctxErr,
+			})
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s cancelled: %w", op.ID(), ctxErr))
+			break
+		}
+
+		// Lazy inputs read the outputs of the operations that ran before
+		if inputErr := core.ResolveInputs(ctx, op); inputErr != nil {
+			inputErr = fmt.Errorf("failed to resolve inputs: %w", inputErr)
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusFailure,
+				Error:       inputErr,
+			})
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s failed: %w", op.ID(), inputErr))
+			if !options.ContinueOnError {
+				break
+			}
+			continue
+		}
+
+		// Conditional operations run only when their condition holds now
+		holds, conditionErr := conditionHolds(ctx, fs, op)
+		if conditionErr != nil {
+			conditionErr = fmt.Errorf("failed to check condition: %w", conditionErr)
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusFailure,
+				Error:       conditionErr,
+			})
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s failed: %w", op.ID(), conditionErr))
+			if !options.ContinueOnError {
+				break
+			}
+			continue
+		}
+		if !holds {
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusConditionUnmet,
+			})
+			continue
+		}
+
+		// In ensure mode, check the state right before acting, after the
+		// operations before this one have run
+		skip, checkErr := ensureSatisfied(ctx, fs, op, options.Ensure)
+		if checkErr != nil {
+			checkErr = fmt.Errorf("failed to check state: %w", checkErr)
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusFailure,
+				Error:       checkErr,
+			})
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s failed: %w", op.ID(), checkErr))
+			if !options.ContinueOnError {
+				break
+			}
+			continue
+		}
+		if skip {
+			result.Operations = append(result.Operations, core.OperationResult{
+				OperationID: op.ID(),
+				Operation:   op,
+				Status:      core.StatusSkipped,
+			})
+			continue
+		}
+
 		// Generate reverse operations if restorable mode is enabled
+		var reverseOps []interface{}
 		var backupData *core.BackupData
 		var reverseErr error
 
@@ -194,7 +406,10 @@ func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, opti
 			
 			opReverseOps, backupDataInterface, reverseErr = op.ReverseOps(ctx, fs, budget)
 			if reverseErr != nil {
-				// Log warning but continue - backup is nice-to-have
+				// Continue without a backup - backup is nice-to-have
+				if errors.Is(reverseErr, core.ErrBudgetExceeded) {
+					execCtx.Publish(ctx, core.NewBudgetExhaustedEvent(operationEventData(op), reverseErr, budget.RemainingMB))
+				}
 			} else {
 				// Convert to interface{} slice for result
 				for _, revOp := range opReverseOps {
@@ -203,15 +418,19 @@ func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, opti
 				
 				// Extract backup data if available
 				if backupDataInterface != nil {
-					if bd, ok := backupDataInterface.(*core.BackupData); ok {
+					if bd, ok := backupDataInterface.(*core.BackupData); ok && bd != nil {
 						backupData = bd
+						execCtx.Publish(ctx, core.NewBackupCapturedEvent(operationEventData(op), bd.SizeMB, budget.RemainingMB))
 					}
 				}
 			}
 		}
 
+		// Validation is not repeated between attempts
 		opStart := time.Now()
-		err := op.Execute(ctx, execCtx, fs)
+		attempts, cancelled, err := execCtx.ExecuteOperation(ctx, op, fs, options, func(ctx context.Context) error {
+			return op.Execute(ctx, execCtx, fs)
+		})
 		opDuration := time.Since(opStart)
 
 		opResult := core.OperationResult{
@@ -220,13 +439,31 @@ func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, opti
 			Duration:     opDuration,
 			BackupData:   backupData,
 			BackupSizeMB: 0,
+			Attempts:     attempts,
 		}
 
 		if backupData != nil {
 			opResult.BackupSizeMB = backupData.SizeMB
 		}
 
-		if err != nil {
+		if errors.Is(err, core.ErrSkip) {
+			// Middleware chose not to run the operation
+			opResult.Status = core.StatusSkipped
+			err = nil
+
+			if options.Restorable && backupData != nil && budget != nil {
+				budget.RestoreBackup(backupData.SizeMB)
+			}
+		} else if cancelled {
+			opResult.Status = core.StatusCancelled
+			opResult.Error = err
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("operation %s cancelled: %w", op.ID(), err))
+
+			if options.Restorable && backupData != nil && budget != nil {
+				budget.RestoreBackup(backupData.SizeMB)
+			}
+		} else if err != nil {
 			opResult.Status = core.StatusFailure
 			opResult.Error = err
 			result.Success = false
@@ -242,14 +479,17 @@ func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, opti
 
 			// Add reverse operations to result if available
 			if options.Restorable && len(reverseOps) > 0 {
+				opResult.ReverseOps = reverseOps
 				result.RestoreOps = append(result.RestoreOps, reverseOps...)
 			}
 		}
 
 		result.Operations = append(result.Operations, opResult)
 
-		// Break after recording the failed operation if we should not continue on error
-		if err != nil && !options.ContinueOnError {
+		// Break after recording the failed operation if we should not continue
+		// on error. A timed-out operation counts as failed; cancelling the run
+		// stops it regardless.
+		if err != nil && (!options.ContinueOnError || ctx.Err() != nil) {
 			break
 		}
 	}
@@ -258,25 +498,16 @@ func executeOperationsDirect(ctx context.Context, fs filesystem.FileSystem, opti
 
 	// Create rollback function
 	result.Rollback = func(ctx context.Context) error {
-		// Reverse the order of successful operations for rollback
-		for i := len(successfulOps) - 1; i >= 0; i-- {
-			if rollbackErr := successfulOps[i].Rollback(ctx, fs); rollbackErr != nil {
-				return rollbackErr
-			}
+		if errs := rollbackOperations(ctx, fs, execCtx, successfulOps, false); len(errs) > 0 {
+			return errs[0]
 		}
 		return nil
 	}
 
 	// Execute rollback if needed
 	if !result.Success && options.RollbackOnError && len(successfulOps) > 0 {
-		rollbackErrors := make([]error, 0)
-		
-		// Reverse the order of successful operations for rollback
-		for i := len(successfulOps) - 1; i >= 0; i-- {
-			if rollbackErr := successfulOps[i].Rollback(ctx, fs); rollbackErr != nil {
-				rollbackErrors = append(rollbackErrors, rollbackErr)
-			}
-		}
+		// Roll back even when the run was cancelled
+		rollbackErrors := rollbackOperations(context.WithoutCancel(ctx), fs, execCtx, successfulOps, true)
 
 		// If rollback had errors, wrap them
 		if len(rollbackErrors) > 0 {
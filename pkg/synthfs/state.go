@@ -54,6 +54,14 @@ func (pst *PathStateTracker) UpdateState(op Operation) error {
 			opType: "create_symlink",
 			path: desc.Path,
 		})
+	case "create_hardlink":
+		target, _ := desc.Details["target"].(string)
+		return pst.tracker.UpdateState(&simpleOpAdapter{
+			id:      op.ID(),
+			opType:  "create_hardlink",
+			path:    desc.Path,
+			srcPath: target,
+		})
 	case "delete":
 		return pst.tracker.UpdateState(&simpleOpAdapter{
 			id: op.ID(),
@@ -76,12 +84,60 @@ func (pst *PathStateTracker) UpdateState(op Operation) error {
 			path: dst,
 			srcPath: src,
 		})
+	case "sync_file":
+		src, _ := desc.Details["src"].(string)
+		return pst.tracker.UpdateState(&simpleOpAdapter{
+			id:      op.ID(),
+			opType:  "sync_file",
+			path:    desc.Path,
+			srcPath: src,
+		})
+	case "write_template", "edit_config", "edit_text", "append_file", "apply_patch":
+		return pst.tracker.UpdateState(&simpleOpAdapter{
+			id:      op.ID(),
+			opType:  desc.Type,
+			path:    desc.Path,
+			details: desc.Details, // A patch to /dev/null sets deletes_file
+		})
+	case "chmod", "truncate", "dedupe_file", "materialize":
+		return pst.tracker.UpdateState(&simpleOpAdapter{
+			id:     op.ID(),
+			opType: desc.Type,
+			path:   desc.Path,
+		})
+	case "prune":
+		return pst.tracker.UpdateState(&simpleOpAdapter{
+			id:     op.ID(),
+			opType: "prune",
+			path:   desc.Path,
+		})
 	default:
 		// For unknown operation types, just return nil
 		return nil
 	}
 }
 
+// UpdateEnsuredState is UpdateState for an operation in ensure mode: a file
+// created over an existing file is projected as a change to it, since the
+// operation converges on the file instead of conflicting with it.
+func (pst *PathStateTracker) UpdateEnsuredState(op Operation) error {
+	desc := op.Describe()
+	if desc.Type != "create_file" {
+		return pst.UpdateState(op)
+	}
+	return pst.tracker.UpdateState(&simpleOpAdapter{
+		id:      op.ID(),
+		opType:  "create_file",
+		path:    desc.Path,
+		details: map[string]interface{}{"converge": true},
+	})
+}
+
+// Paths returns every path the tracker holds state for, in sorted order.
+func (pst *PathStateTracker) Paths() []string {
+	return pst.tracker.Paths()
+}
+
 // IsDeleted returns true if the path is scheduled for deletion by any operation.
 func (pst *PathStateTracker) IsDeleted(path string) bool {
 	return pst.tracker.IsDeleted(path)
@@ -94,13 +150,15 @@ type simpleOpAdapter struct {
 	opType  string
 	path    string
 	srcPath string
+	details map[string]interface{}
 }
 
 func (soa *simpleOpAdapter) ID() core.OperationID { return soa.id }
 func (soa *simpleOpAdapter) Describe() core.OperationDesc {
 	return core.OperationDesc{
-		Type: soa.opType,
-		Path: soa.path,
+		Type:    soa.opType,
+		Path:    soa.path,
+		Details: soa.details,
 	}
 }
 func (soa *simpleOpAdapter) GetSrcPath() string { return soa.srcPath }
@@ -0,0 +1,47 @@
+package synthfs
+
+import (
+	"context"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// SyncOptions controls how Sync converges a destination directory.
+type SyncOptions = operations.SyncOptions
+
+// SyncCompare selects how Sync decides that a destination file is out of date.
+type SyncCompare = operations.SyncCompare
+
+const (
+	// SyncCompareSizeMTime treats a file as changed when its size or modification time differ.
+	SyncCompareSizeMTime = operations.SyncCompareSizeMTime
+	// SyncCompareChecksum treats a file as changed when its content checksum differs.
+	SyncCompareChecksum = operations.SyncCompareChecksum
+)
+
+// Sync creates an operation that makes dst mirror src, in the spirit of rsync.
+// When run, it expands into one "sync_file" or "prune" child operation per
+// path that needs to change, so dry runs, results and rollback are per file.
+//
+// Example:
+//
+//	op := sfs.Sync("site/build", "public", synthfs.SyncOptions{
+//	    Delete:  true,
+//	    Exclude: []string{".git", "*.tmp"},
+//	})
+func (s *SynthFS) Sync(src, dst string, options SyncOptions) Operation {
+	id := s.idGen("sync", src)
+	return operations.NewSyncOperation(id, src, dst, options)
+}
+
+// SyncWithID creates a sync operation with an explicit ID.
+func (s *SynthFS) SyncWithID(id string, src, dst string, options SyncOptions) Operation {
+	return operations.NewSyncOperation(core.OperationID(id), src, dst, options)
+}
+
+// Sync makes dst mirror src immediately.
+func Sync(ctx context.Context, fs FileSystem, src, dst string, options SyncOptions) error {
+	_, err := Run(ctx, fs, New().Sync(src, dst, options))
+	return err
+}
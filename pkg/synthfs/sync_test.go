@@ -0,0 +1,320 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+// setupSyncTrees builds a source tree and a partially out-of-date destination.
+func setupSyncTrees(t *testing.T) synthfs.FileSystem {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+
+	helper := testutil.NewRealFSTestHelper(t)
+	fsys := helper.FileSystem()
+	mustDo(t, fsys.MkdirAll("src/sub", 0755))
+	mustDo(t, fsys.WriteFile("src/same.txt", []byte("same"), 0644))
+	mustDo(t, fsys.WriteFile("src/changed.txt", []byte("after"), 0644))
+	mustDo(t, fsys.WriteFile("src/sub/new.txt", []byte("new"), 0644))
+	mustDo(t, fsys.WriteFile("src/skip.log", []byte("log"), 0644))
+	helper.CreateSymlink("same.txt", "src/link")
+
+	mustDo(t, fsys.MkdirAll("dst", 0755))
+	mustDo(t, fsys.WriteFile("dst/same.txt", []byte("same"), 0644))
+	mustDo(t, fsys.WriteFile("dst/changed.txt", []byte("befor"), 0644))
+	mustDo(t, fsys.WriteFile("dst/extra.txt", []byte("extra"), 0644))
+	mustDo(t, fsys.WriteFile("dst/keep.log", []byte("keep"), 0644))
+	return fsys
+}
+
+func readString(t *testing.T, fsys synthfs.FileSystem, name string) string {
+	t.Helper()
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func operationIDs(result *synthfs.Result) []string {
+	var ids []string
+	for _, op := range result.Operations {
+		ids = append(ids, string(op.OperationID))
+	}
+	return ids
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	t.Run("expands into per-file children with stable IDs", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+		options := synthfs.SyncOptions{
+			Compare: synthfs.SyncCompareChecksum,
+			Delete:  true,
+			Exclude: []string{"*.log"},
+		}
+
+		result, err := synthfs.Run(ctx, fsys, sfs.SyncWithID("mirror", "src", "dst", options))
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+
+		want := []string{
+			"mirror:prune:extra.txt",
+			"mirror:sync:changed.txt",
+			"mirror:sync:link",
+			"mirror:sync:sub",
+			"mirror:sync:sub/new.txt",
+		}
+		got := operationIDs(result)
+		if len(got) != len(want) {
+			t.Fatalf("expected operations %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("operation %d: expected %s, got %s", i, want[i], got[i])
+			}
+		}
+
+		if content := readString(t, fsys, "dst/changed.txt"); content != "after" {
+			t.Errorf("changed.txt not updated: %q", content)
+		}
+		if content := readString(t, fsys, "dst/sub/new.txt"); content != "new" {
+			t.Errorf("sub/new.txt not created: %q", content)
+		}
+		if target, err := fsys.Readlink("dst/link"); err != nil || target != "same.txt" {
+			t.Errorf("expected link -> same.txt, got %q (%v)", target, err)
+		}
+		if _, err := fsys.Stat("dst/extra.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("extra.txt should have been deleted, got %v", err)
+		}
+		if content := readString(t, fsys, "dst/keep.log"); content != "keep" {
+			t.Errorf("excluded destination file should be kept, got %q", content)
+		}
+		if _, err := fsys.Stat("dst/skip.log"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("excluded source file should not be copied, got %v", err)
+		}
+	})
+
+	t.Run("keeps extraneous entries without Delete", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+
+		_, err := synthfs.Run(ctx, fsys, sfs.Sync("src", "dst", synthfs.SyncOptions{Compare: synthfs.SyncCompareChecksum}))
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if content := readString(t, fsys, "dst/extra.txt"); content != "extra" {
+			t.Errorf("extra.txt should be kept, got %q", content)
+		}
+		if content := readString(t, fsys, "dst/skip.log"); content != "log" {
+			t.Errorf("skip.log should be copied without excludes, got %q", content)
+		}
+	})
+
+	t.Run("include limits synced files", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+
+		_, err := synthfs.Run(ctx, fsys, sfs.Sync("src", "dst", synthfs.SyncOptions{
+			Compare: synthfs.SyncCompareChecksum,
+			Include: []string{"new.txt"},
+			Delete:  true,
+		}))
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if content := readString(t, fsys, "dst/sub/new.txt"); content != "new" {
+			t.Errorf("included file not synced: %q", content)
+		}
+		if content := readString(t, fsys, "dst/changed.txt"); content != "befor" {
+			t.Errorf("non-included file should be left alone, got %q", content)
+		}
+	})
+
+	t.Run("compare modes", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+		chtimes := fsys.(filesystem.ChtimesFS)
+		mustDo(t, chtimes.Chtimes("src/same.txt", time.Now(), time.Now().Add(-time.Hour)))
+
+		bySize := sfs.Sync("src", "dst", synthfs.SyncOptions{}).(synthfs.ExpandableOperation)
+		children, err := bySize.Expand(ctx, fsys)
+		if err != nil {
+			t.Fatalf("expand failed: %v", err)
+		}
+		if !hasChildFor(children, "dst/same.txt") {
+			t.Error("size+mtime comparison should resync a file whose mtime differs")
+		}
+
+		byChecksum := sfs.Sync("src", "dst", synthfs.SyncOptions{Compare: synthfs.SyncCompareChecksum}).(synthfs.ExpandableOperation)
+		children, err = byChecksum.Expand(ctx, fsys)
+		if err != nil {
+			t.Fatalf("expand failed: %v", err)
+		}
+		if hasChildFor(children, "dst/same.txt") {
+			t.Error("checksum comparison should skip a file with identical content")
+		}
+		if !hasChildFor(children, "dst/changed.txt") {
+			t.Error("checksum comparison should resync a file with different content")
+		}
+	})
+
+	t.Run("preserved metadata makes a second run a no-op", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+		mustDo(t, fsys.WriteFile("src/run.sh", []byte("#!/bin/sh"), 0755))
+		options := synthfs.SyncOptions{PreserveMetadata: true, Delete: true}
+
+		if _, err := synthfs.Run(ctx, fsys, sfs.Sync("src", "dst", options)); err != nil {
+			t.Fatalf("first sync failed: %v", err)
+		}
+		info, err := fsys.Stat("dst/run.sh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("expected mode 0755, got %o", info.Mode().Perm())
+		}
+
+		second := sfs.Sync("src", "dst", options).(synthfs.ExpandableOperation)
+		children, err := second.Expand(ctx, fsys)
+		if err != nil {
+			t.Fatalf("expand failed: %v", err)
+		}
+		if len(children) != 0 {
+			t.Errorf("expected no children on second run, got %d", len(children))
+		}
+	})
+
+	t.Run("rollback restores the destination", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.RollbackOnError = true
+		fail := sfs.CustomOperation("fail", func(ctx context.Context, fs synthfs.FileSystem) error {
+			return errors.New("boom")
+		})
+
+		_, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.Sync("src", "dst", synthfs.SyncOptions{Compare: synthfs.SyncCompareChecksum, Delete: true}),
+			fail,
+		)
+		if err == nil {
+			t.Fatal("expected failure")
+		}
+
+		if content := readString(t, fsys, "dst/changed.txt"); content != "befor" {
+			t.Errorf("changed.txt not restored: %q", content)
+		}
+		if content := readString(t, fsys, "dst/extra.txt"); content != "extra" {
+			t.Errorf("extra.txt not restored: %q", content)
+		}
+		for _, p := range []string{"dst/sub", "dst/link"} {
+			if _, err := fsys.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s should have been removed by rollback, got %v", p, err)
+			}
+		}
+	})
+
+	t.Run("dry run lists children without touching the destination", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.DryRun = true
+
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.SyncWithID("mirror", "src", "dst", synthfs.SyncOptions{Compare: synthfs.SyncCompareChecksum, Delete: true, Exclude: []string{"*.log"}}))
+		if err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if len(result.Operations) != 5 {
+			t.Errorf("expected 5 child results, got %v", operationIDs(result))
+		}
+		if content := readString(t, fsys, "dst/changed.txt"); content != "befor" {
+			t.Errorf("dry run modified changed.txt: %q", content)
+		}
+		if _, err := fsys.Stat("dst/extra.txt"); err != nil {
+			t.Errorf("dry run deleted extra.txt: %v", err)
+		}
+		if _, err := fsys.Stat("dst/sub"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("dry run created dst/sub: %v", err)
+		}
+	})
+
+	t.Run("validation rejects overlapping trees", func(t *testing.T) {
+		fsys := setupSyncTrees(t)
+		_, err := synthfs.Run(ctx, fsys, sfs.Sync("src", "src/sub", synthfs.SyncOptions{}))
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+	})
+
+	t.Run("preserves hard link groups", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		linkFS := fsys.(filesystem.LinkFS)
+		mustDo(t, fsys.MkdirAll("cache/a", 0755))
+		mustDo(t, fsys.WriteFile("cache/a/blob", []byte("v1"), 0644))
+		mustDo(t, linkFS.Link("cache/a/blob", "cache/b"))
+		mustDo(t, fsys.WriteFile("cache/c", []byte("solo"), 0644))
+
+		options := synthfs.SyncOptions{Compare: synthfs.SyncCompareChecksum, PreserveHardlinks: true}
+		result, err := synthfs.Run(ctx, fsys, sfs.SyncWithID("mirror", "cache", "copy", options))
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		ids := operationIDs(result)
+		want := []string{"mirror:sync:.", "mirror:sync:a", "mirror:sync:a/blob", "mirror:link:b", "mirror:sync:c"}
+		if len(ids) != len(want) {
+			t.Fatalf("expected operations %v, got %v", want, ids)
+		}
+		for i := range want {
+			if ids[i] != want[i] {
+				t.Errorf("operation %d: expected %s, got %s", i, want[i], ids[i])
+			}
+		}
+
+		blob, _ := linkFS.FileID("copy/a/blob")
+		b, _ := linkFS.FileID("copy/b")
+		c, _ := linkFS.FileID("copy/c")
+		if blob != b || blob == c {
+			t.Errorf("expected copy/b to be linked to copy/a/blob only")
+		}
+
+		// Unchanged links are left alone; a changed group is relinked
+		result, err = synthfs.Run(ctx, fsys, sfs.SyncWithID("again", "cache", "copy", options))
+		if err != nil {
+			t.Fatalf("second sync failed: %v", err)
+		}
+		if ids := operationIDs(result); len(ids) != 0 {
+			t.Errorf("expected nothing to do, got %v", ids)
+		}
+		mustDo(t, fsys.Remove("cache/a/blob"))
+		mustDo(t, fsys.WriteFile("cache/a/blob", []byte("v2"), 0644))
+		mustDo(t, fsys.Remove("cache/b"))
+		mustDo(t, linkFS.Link("cache/a/blob", "cache/b"))
+		if _, err := synthfs.Run(ctx, fsys, sfs.SyncWithID("third", "cache", "copy", options)); err != nil {
+			t.Fatalf("third sync failed: %v", err)
+		}
+		blob, _ = linkFS.FileID("copy/a/blob")
+		b, _ = linkFS.FileID("copy/b")
+		if blob != b || readString(t, fsys, "copy/b") != "v2" {
+			t.Errorf("expected copy/b to be relinked to the updated blob")
+		}
+	})
+}
+
+func hasChildFor(children []synthfs.Operation, p string) bool {
+	for _, child := range children {
+		if child.Describe().Path == p {
+			return true
+		}
+	}
+	return false
+}
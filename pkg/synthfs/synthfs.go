@@ -68,6 +68,23 @@ func (s *SynthFS) Copy(src, dst string) Operation {
 	return op
 }
 
+// CopyOptions controls how Copy handles a directory source.
+type CopyOptions = operations.CopyOptions
+
+// CopyWithOptions creates a copy operation with an auto-generated ID. The
+// options only matter when src is a directory.
+//
+// Example:
+//
+//	op := sfs.CopyWithOptions("cache", "cache.bak", synthfs.CopyOptions{PreserveHardlinks: true})
+func (s *SynthFS) CopyWithOptions(src, dst string, options CopyOptions) Operation {
+	id := s.idGen("copy", src)
+	op := operations.NewCopyOperation(id, src)
+	op.SetPaths(src, dst)
+	op.SetOptions(options)
+	return op
+}
+
 // Move creates a move operation with an auto-generated ID.
 func (s *SynthFS) Move(src, dst string) Operation {
 	id := s.idGen("move", src)
@@ -76,6 +93,13 @@ func (s *SynthFS) Move(src, dst string) Operation {
 	return op
 }
 
+// Chmod creates an operation that sets the permission bits of an existing path.
+// The filesystem must implement filesystem.ChmodFS.
+func (s *SynthFS) Chmod(path string, mode fs.FileMode) Operation {
+	id := s.idGen("chmod", path)
+	return operations.NewChmodOperation(id, path, mode)
+}
+
 // CreateSymlink creates a symlink operation with an auto-generated ID.
 func (s *SynthFS) CreateSymlink(target, linkPath string) Operation {
 	id := s.idGen("create_symlink", linkPath)
@@ -86,6 +110,14 @@ func (s *SynthFS) CreateSymlink(target, linkPath string) Operation {
 	return op
 }
 
+// CreateHardlink creates a hard link operation with an auto-generated ID.
+// The target must be an existing regular file on the same filesystem, and
+// the filesystem must implement filesystem.LinkFS.
+func (s *SynthFS) CreateHardlink(target, linkPath string) Operation {
+	id := s.idGen("create_hardlink", linkPath)
+	return operations.NewCreateHardlinkOperation(id, linkPath, target)
+}
+
 // Unarchive creates an unarchive operation with an auto-generated ID.
 func (s *SynthFS) Unarchive(archivePath, extractPath string) Operation {
 	id := s.idGen("unarchive", archivePath)
@@ -139,6 +171,11 @@ func (s *SynthFS) MoveWithID(id string, src, dst string) Operation {
 	return op
 }
 
+// ChmodWithID creates a chmod operation with an explicit ID.
+func (s *SynthFS) ChmodWithID(id string, path string, mode fs.FileMode) Operation {
+	return operations.NewChmodOperation(core.OperationID(id), path, mode)
+}
+
 // CreateSymlinkWithID creates a symlink operation with an explicit ID.
 func (s *SynthFS) CreateSymlinkWithID(id string, target, linkPath string) Operation {
 	op := operations.NewCreateSymlinkOperation(core.OperationID(id), linkPath)
@@ -148,6 +185,11 @@ func (s *SynthFS) CreateSymlinkWithID(id string, target, linkPath string) Operat
 	return op
 }
 
+// CreateHardlinkWithID creates a hard link operation with an explicit ID.
+func (s *SynthFS) CreateHardlinkWithID(id string, target, linkPath string) Operation {
+	return operations.NewCreateHardlinkOperation(core.OperationID(id), linkPath, target)
+}
+
 // CustomOperation creates a custom operation with an auto-generated ID.
 // This allows users to define their own operations that integrate with SynthFS's pipeline system.
 //
@@ -300,18 +342,9 @@ func (s *SynthFS) ChecksumWithID(id string, path string, algorithm ChecksumAlgor
 		}()
 		
 		// Create appropriate hash algorithm
-		var hasher hash.Hash
-		switch algorithm {
-		case MD5:
-			hasher = md5.New()
-		case SHA1:
-			hasher = sha1.New()
-		case SHA256:
-			hasher = sha256.New()
-		case SHA512:
-			hasher = sha512.New()
-		default:
-			return fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+		hasher, err := newHasher(algorithm)
+		if err != nil {
+			return err
 		}
 		
 		// Calculate checksum
@@ -333,3 +366,19 @@ func (s *SynthFS) ChecksumWithID(id string, path string, algorithm ChecksumAlgor
 	op = op.WithDescription(fmt.Sprintf("Calculate %s checksum: %s", algorithm, path))
 	return op
 }
+
+// newHasher returns a hash for algorithm.
+func newHasher(algorithm ChecksumAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case MD5:
+		return md5.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+}
@@ -0,0 +1,36 @@
+package testutil
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Run("equal inputs", func(t *testing.T) {
+		if diff := unifiedDiff("a", "b", "same\n", "same\n"); diff != "" {
+			t.Errorf("expected empty diff, got:\n%s", diff)
+		}
+	})
+
+	t.Run("single change with context", func(t *testing.T) {
+		a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+		b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+		expected := "--- want\n+++ got\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"
+		if diff := unifiedDiff("want", "got", a, b); diff != expected {
+			t.Errorf("unexpected diff:\n%s\nexpected:\n%s", diff, expected)
+		}
+	})
+
+	t.Run("separate hunks", func(t *testing.T) {
+		a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+		b := "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nK\n"
+		expected := "--- want\n+++ got\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n@@ -8,4 +8,4 @@\n h\n i\n j\n-k\n+K\n"
+		if diff := unifiedDiff("want", "got", a, b); diff != expected {
+			t.Errorf("unexpected diff:\n%s\nexpected:\n%s", diff, expected)
+		}
+	})
+
+	t.Run("missing trailing newline", func(t *testing.T) {
+		expected := "--- want\n+++ got\n@@ -1,1 +1,1 @@\n-x\n+y\n\\ No newline at end of file\n"
+		if diff := unifiedDiff("want", "got", "x\n", "y"); diff != expected {
+			t.Errorf("unexpected diff:\n%s\nexpected:\n%s", diff, expected)
+		}
+	})
+}
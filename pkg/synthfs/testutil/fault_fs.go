@@ -0,0 +1,442 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// FaultMethod names a FileSystem method that a FaultRule can target.
+type FaultMethod string
+
+const (
+	FaultOpen      FaultMethod = "Open"
+	FaultStat      FaultMethod = "Stat"
+	FaultWriteFile FaultMethod = "WriteFile"
+	FaultMkdirAll  FaultMethod = "MkdirAll"
+	FaultRemove    FaultMethod = "Remove"
+	FaultRemoveAll FaultMethod = "RemoveAll"
+	FaultSymlink   FaultMethod = "Symlink"
+	FaultReadlink  FaultMethod = "Readlink"
+	FaultRename    FaultMethod = "Rename"
+	FaultChmod     FaultMethod = "Chmod"
+	FaultChtimes   FaultMethod = "Chtimes"
+	FaultLink      FaultMethod = "Link"
+	FaultFileID    FaultMethod = "FileID"
+)
+
+// MutatingFaultMethods lists the methods that change filesystem state.
+// A FaultRule with an empty Method matches any of these.
+var MutatingFaultMethods = []FaultMethod{
+	FaultWriteFile,
+	FaultMkdirAll,
+	FaultRemove,
+	FaultRemoveAll,
+	FaultSymlink,
+	FaultRename,
+	FaultChmod,
+	FaultChtimes,
+	FaultLink,
+}
+
+// PartialEffect describes work a faulted call performs before failing.
+type PartialEffect int
+
+const (
+	// PartialNone fails the call without touching the wrapped filesystem.
+	PartialNone PartialEffect = iota
+	// PartialWrite writes the first half of the data before failing a WriteFile,
+	// like a disk filling up mid-write.
+	PartialWrite
+	// PartialRename copies the source to the destination but leaves the source
+	// in place before failing a Rename, like an interrupted cross-device move.
+	PartialRename
+)
+
+// FaultRule declares when FaultFS should fail a call and how.
+type FaultRule struct {
+	// Method is the method to fail. Empty matches any mutating method.
+	Method FaultMethod
+	// Path is a path.Match pattern for the call's primary path. A trailing
+	// "/**" matches everything below a directory. Empty matches any path.
+	Path string
+	// Call is the 1-based index of the matching call to fail. Zero fails
+	// every matching call.
+	Call int
+	// Err is the error to return, wrapped in *fs.PathError. Defaults to syscall.EIO.
+	Err error
+	// Partial is the effect applied before the error is returned.
+	Partial PartialEffect
+
+	matched int
+}
+
+// FaultCall records a single call observed by FaultFS.
+type FaultCall struct {
+	Method   FaultMethod
+	Path     string
+	Injected error // Non-nil when the call was failed by a rule
+}
+
+// FaultFS wraps a FileSystem and fails calls according to a set of rules.
+// It is meant for exercising error and rollback paths in tests.
+type FaultFS struct {
+	mu    sync.Mutex
+	fs    synthfs.FileSystem
+	rules []*FaultRule
+	calls []FaultCall
+}
+
+// NewFaultFS wraps fsys with the given fault rules.
+func NewFaultFS(fsys synthfs.FileSystem, rules ...FaultRule) *FaultFS {
+	ffs := &FaultFS{fs: fsys}
+	for _, rule := range rules {
+		ffs.AddRule(rule)
+	}
+	return ffs
+}
+
+// AddRule adds a fault rule. Rules are evaluated in the order they were added.
+func (f *FaultFS) AddRule(rule FaultRule) *FaultFS {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rule.matched = 0
+	f.rules = append(f.rules, &rule)
+	return f
+}
+
+// Reset removes all rules and clears the call log.
+func (f *FaultFS) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+	f.calls = nil
+}
+
+// Unwrap returns the wrapped filesystem.
+func (f *FaultFS) Unwrap() synthfs.FileSystem {
+	return f.fs
+}
+
+// Calls returns every call observed so far, in order.
+func (f *FaultFS) Calls() []FaultCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FaultCall(nil), f.calls...)
+}
+
+// CallCount returns how many times method has been called.
+func (f *FaultFS) CallCount(method FaultMethod) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, call := range f.calls {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// Injected returns the calls that were failed by a rule.
+func (f *FaultFS) Injected() []FaultCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var injected []FaultCall
+	for _, call := range f.calls {
+		if call.Injected != nil {
+			injected = append(injected, call)
+		}
+	}
+	return injected
+}
+
+// check records the call and returns the rule that should fail it, if any.
+func (f *FaultFS) check(method FaultMethod, name string) *FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var fired *FaultRule
+	for _, rule := range f.rules {
+		if !rule.matches(method, name) {
+			continue
+		}
+		rule.matched++
+		if fired == nil && (rule.Call == 0 || rule.Call == rule.matched) {
+			fired = rule
+		}
+	}
+
+	call := FaultCall{Method: method, Path: name}
+	if fired != nil {
+		call.Injected = fired.err(method, name)
+	}
+	f.calls = append(f.calls, call)
+	return fired
+}
+
+func (r *FaultRule) matches(method FaultMethod, name string) bool {
+	if r.Method == "" {
+		if !isMutatingFaultMethod(method) {
+			return false
+		}
+	} else if r.Method != method {
+		return false
+	}
+	return matchFaultPath(r.Path, name)
+}
+
+func (r *FaultRule) err(method FaultMethod, name string) error {
+	err := r.Err
+	if err == nil {
+		err = syscall.EIO
+	}
+	return &fs.PathError{Op: strings.ToLower(string(method)), Path: name, Err: err}
+}
+
+func isMutatingFaultMethod(method FaultMethod) bool {
+	for _, m := range MutatingFaultMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func matchFaultPath(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	name = path.Clean(name)
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return name == dir || strings.HasPrefix(name, dir+"/")
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// --- FileSystem implementation ---
+
+func (f *FaultFS) Open(name string) (fs.File, error) {
+	if rule := f.check(FaultOpen, name); rule != nil {
+		return nil, rule.err(FaultOpen, name)
+	}
+	return f.fs.Open(name)
+}
+
+func (f *FaultFS) Stat(name string) (fs.FileInfo, error) {
+	if rule := f.check(FaultStat, name); rule != nil {
+		return nil, rule.err(FaultStat, name)
+	}
+	return f.fs.Stat(name)
+}
+
+func (f *FaultFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if rule := f.check(FaultWriteFile, name); rule != nil {
+		if rule.Partial == PartialWrite {
+			_ = f.fs.WriteFile(name, data[:len(data)/2], perm)
+		}
+		return rule.err(FaultWriteFile, name)
+	}
+	return f.fs.WriteFile(name, data, perm)
+}
+
+func (f *FaultFS) MkdirAll(name string, perm fs.FileMode) error {
+	if rule := f.check(FaultMkdirAll, name); rule != nil {
+		return rule.err(FaultMkdirAll, name)
+	}
+	return f.fs.MkdirAll(name, perm)
+}
+
+func (f *FaultFS) Remove(name string) error {
+	if rule := f.check(FaultRemove, name); rule != nil {
+		return rule.err(FaultRemove, name)
+	}
+	return f.fs.Remove(name)
+}
+
+func (f *FaultFS) RemoveAll(name string) error {
+	if rule := f.check(FaultRemoveAll, name); rule != nil {
+		return rule.err(FaultRemoveAll, name)
+	}
+	return f.fs.RemoveAll(name)
+}
+
+func (f *FaultFS) Symlink(oldname, newname string) error {
+	if rule := f.check(FaultSymlink, newname); rule != nil {
+		return rule.err(FaultSymlink, newname)
+	}
+	return f.fs.Symlink(oldname, newname)
+}
+
+func (f *FaultFS) Readlink(name string) (string, error) {
+	if rule := f.check(FaultReadlink, name); rule != nil {
+		return "", rule.err(FaultReadlink, name)
+	}
+	return f.fs.Readlink(name)
+}
+
+func (f *FaultFS) Rename(oldpath, newpath string) error {
+	if rule := f.check(FaultRename, oldpath); rule != nil {
+		if rule.Partial == PartialRename {
+			if data, err := fs.ReadFile(f.fs, oldpath); err == nil {
+				if info, err := f.fs.Stat(oldpath); err == nil {
+					_ = f.fs.WriteFile(newpath, data, info.Mode().Perm())
+				}
+			}
+		}
+		return rule.err(FaultRename, oldpath)
+	}
+	return f.fs.Rename(oldpath, newpath)
+}
+
+// Chmod fails with errors.ErrUnsupported when the wrapped filesystem does not
+// implement filesystem.ChmodFS.
+func (f *FaultFS) Chmod(name string, mode fs.FileMode) error {
+	if rule := f.check(FaultChmod, name); rule != nil {
+		return rule.err(FaultChmod, name)
+	}
+	chmodFS, ok := f.fs.(filesystem.ChmodFS)
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: errors.ErrUnsupported}
+	}
+	return chmodFS.Chmod(name, mode)
+}
+
+// Chtimes fails with errors.ErrUnsupported when the wrapped filesystem does not
+// implement filesystem.ChtimesFS.
+func (f *FaultFS) Chtimes(name string, atime, mtime time.Time) error {
+	if rule := f.check(FaultChtimes, name); rule != nil {
+		return rule.err(FaultChtimes, name)
+	}
+	chtimesFS, ok := f.fs.(filesystem.ChtimesFS)
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: errors.ErrUnsupported}
+	}
+	return chtimesFS.Chtimes(name, atime, mtime)
+}
+
+// Link fails with errors.ErrUnsupported when the wrapped filesystem does not
+// implement filesystem.LinkFS.
+func (f *FaultFS) Link(oldname, newname string) error {
+	if rule := f.check(FaultLink, newname); rule != nil {
+		return rule.err(FaultLink, newname)
+	}
+	linkFS, ok := f.fs.(filesystem.LinkFS)
+	if !ok {
+		return &fs.PathError{Op: "link", Path: newname, Err: errors.ErrUnsupported}
+	}
+	return linkFS.Link(oldname, newname)
+}
+
+// FileID fails with errors.ErrUnsupported when the wrapped filesystem does not
+// implement filesystem.LinkFS.
+func (f *FaultFS) FileID(name string) (filesystem.FileID, error) {
+	if rule := f.check(FaultFileID, name); rule != nil {
+		return filesystem.FileID{}, rule.err(FaultFileID, name)
+	}
+	linkFS, ok := f.fs.(filesystem.LinkFS)
+	if !ok {
+		return filesystem.FileID{}, &fs.PathError{Op: "fileid", Path: name, Err: errors.ErrUnsupported}
+	}
+	return linkFS.FileID(name)
+}
+
+var _ synthfs.FileSystem = (*FaultFS)(nil)
+
+// --- Fault sweeps ---
+
+// FaultSweep describes a pipeline to run once per possible fault injection point.
+type FaultSweep struct {
+	// NewFS returns a fresh, pre-populated filesystem for each run.
+	NewFS func(t *testing.T) synthfs.FileSystem
+	// Operations returns fresh operations for each run.
+	Operations func() []synthfs.Operation
+	// Methods restricts injection to these methods. Defaults to MutatingFaultMethods.
+	Methods []FaultMethod
+	// Err is the error injected at each point. Defaults to syscall.EIO.
+	Err error
+	// Partial is the partial effect applied at each point.
+	Partial PartialEffect
+}
+
+// RunFaultSweep runs the sweep's operations once without faults to discover
+// every call that could fail, then once per call with that single call failing
+// and RollbackOnError enabled. After a failed run the filesystem must match its
+// initial state; after a run that swallowed the fault it must match the clean run.
+func RunFaultSweep(t *testing.T, sweep FaultSweep) {
+	t.Helper()
+
+	methods := sweep.Methods
+	if len(methods) == 0 {
+		methods = MutatingFaultMethods
+	}
+
+	clean := NewFaultFS(sweep.NewFS(t))
+	initial := mustSnapshot(t, clean.Unwrap())
+	if _, err := runFaultSweepOps(clean, sweep.Operations()); err != nil {
+		t.Fatalf("fault sweep: pipeline fails without faults: %v", err)
+	}
+	expected := mustSnapshot(t, clean.Unwrap())
+
+	for _, method := range methods {
+		count := clean.CallCount(method)
+		for n := 1; n <= count; n++ {
+			t.Run(fmt.Sprintf("%s#%d", method, n), func(t *testing.T) {
+				ffs := NewFaultFS(sweep.NewFS(t), FaultRule{
+					Method:  method,
+					Call:    n,
+					Err:     sweep.Err,
+					Partial: sweep.Partial,
+				})
+
+				result, err := runFaultSweepOps(ffs, sweep.Operations())
+				actual := mustSnapshot(t, ffs.Unwrap())
+
+				if err == nil && result.Success {
+					if diff := unifiedDiff("clean run", "faulted run", expected.String(), actual.String()); diff != "" {
+						t.Errorf("fault in %s#%d was swallowed but the result differs from a clean run:\n%s", method, n, diff)
+					}
+					return
+				}
+
+				if result != nil {
+					for _, resultErr := range result.Errors {
+						var rollbackErr *synthfs.RollbackError
+						if errors.As(resultErr, &rollbackErr) {
+							t.Errorf("rollback failed after fault in %s#%d: %v", method, n, rollbackErr)
+						}
+					}
+				}
+				if diff := unifiedDiff("before", "after rollback", initial.String(), actual.String()); diff != "" {
+					t.Errorf("filesystem not restored after fault in %s#%d (%v):\n%s", method, n, err, diff)
+				}
+			})
+		}
+	}
+}
+
+func runFaultSweepOps(fsys synthfs.FileSystem, ops []synthfs.Operation) (*synthfs.Result, error) {
+	opts := synthfs.DefaultPipelineOptions()
+	opts.RollbackOnError = true
+	return synthfs.RunWithOptions(context.Background(), fsys, opts, ops...)
+}
+
+func mustSnapshot(t *testing.T, fsys synthfs.FileSystem) *Snapshot {
+	t.Helper()
+	snap, err := TakeSnapshot(fsys, SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("fault sweep: %v", err)
+	}
+	return snap
+}
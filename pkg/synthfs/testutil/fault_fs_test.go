@@ -0,0 +1,197 @@
+package testutil_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestFaultFS(t *testing.T) {
+	t.Run("fails the Nth matching call", func(t *testing.T) {
+		ffs := testutil.NewFaultFS(testutil.NewTestFileSystem(), testutil.FaultRule{
+			Method: testutil.FaultWriteFile,
+			Call:   2,
+			Err:    syscall.ENOSPC,
+		})
+
+		if err := ffs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatalf("first write should succeed: %v", err)
+		}
+		err := ffs.WriteFile("b.txt", []byte("b"), 0644)
+		if !errors.Is(err, syscall.ENOSPC) {
+			t.Fatalf("expected ENOSPC on second write, got %v", err)
+		}
+		if err := ffs.WriteFile("c.txt", []byte("c"), 0644); err != nil {
+			t.Fatalf("third write should succeed: %v", err)
+		}
+
+		if _, err := ffs.Stat("b.txt"); err == nil {
+			t.Error("faulted write should not create the file")
+		}
+		injected := ffs.Injected()
+		if len(injected) != 1 || injected[0].Path != "b.txt" {
+			t.Errorf("expected one injected fault on b.txt, got %+v", injected)
+		}
+	})
+
+	t.Run("matches paths by pattern", func(t *testing.T) {
+		ffs := testutil.NewFaultFS(testutil.NewTestFileSystem(),
+			testutil.FaultRule{Path: "build/**", Err: syscall.EACCES},
+			testutil.FaultRule{Path: "*.lock"},
+		)
+
+		if err := ffs.WriteFile("src.txt", []byte("x"), 0644); err != nil {
+			t.Errorf("unmatched path should succeed: %v", err)
+		}
+		if err := ffs.MkdirAll("build/out", 0755); !errors.Is(err, syscall.EACCES) {
+			t.Errorf("expected EACCES under build/, got %v", err)
+		}
+		if err := ffs.WriteFile("app.lock", nil, 0644); !errors.Is(err, syscall.EIO) {
+			t.Errorf("expected default EIO for *.lock, got %v", err)
+		}
+	})
+
+	t.Run("only mutating methods match by default", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		testutil.CreateTestFile(t, tfs, "file.txt", []byte("content"))
+		ffs := testutil.NewFaultFS(tfs, testutil.FaultRule{})
+
+		if _, err := ffs.Stat("file.txt"); err != nil {
+			t.Errorf("Stat should not be faulted: %v", err)
+		}
+		if err := ffs.Remove("file.txt"); err == nil {
+			t.Error("Remove should be faulted")
+		}
+		if ffs.CallCount(testutil.FaultStat) != 1 || ffs.CallCount(testutil.FaultRemove) != 1 {
+			t.Errorf("unexpected call log: %+v", ffs.Calls())
+		}
+	})
+
+	t.Run("partial write leaves truncated content", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		ffs := testutil.NewFaultFS(tfs, testutil.FaultRule{
+			Method:  testutil.FaultWriteFile,
+			Partial: testutil.PartialWrite,
+		})
+
+		if err := ffs.WriteFile("data.bin", []byte("12345678"), 0644); err == nil {
+			t.Fatal("expected write to fail")
+		}
+		testutil.AssertFileContent(t, tfs, "data.bin", []byte("1234"))
+	})
+
+	t.Run("partial rename leaves both paths", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		testutil.CreateTestFile(t, tfs, "old.txt", []byte("moved"))
+		ffs := testutil.NewFaultFS(tfs, testutil.FaultRule{
+			Method:  testutil.FaultRename,
+			Partial: testutil.PartialRename,
+		})
+
+		if err := ffs.Rename("old.txt", "new.txt"); err == nil {
+			t.Fatal("expected rename to fail")
+		}
+		testutil.AssertFileContent(t, tfs, "old.txt", []byte("moved"))
+		testutil.AssertFileContent(t, tfs, "new.txt", []byte("moved"))
+	})
+
+	t.Run("operation sees injected error", func(t *testing.T) {
+		ffs := testutil.NewFaultFS(testutil.NewTestFileSystem(), testutil.FaultRule{
+			Method: testutil.FaultMkdirAll,
+			Path:   "project",
+		})
+
+		sfs := synthfs.New()
+		_, err := synthfs.Run(context.Background(), ffs, sfs.CreateDir("project", 0755))
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Path != "project" {
+			t.Errorf("expected path error for project, got %v", err)
+		}
+	})
+
+	t.Run("forwards chmod", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		if err := tfs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ffs := testutil.NewFaultFS(tfs)
+
+		sfs := synthfs.New()
+		if _, err := synthfs.Run(context.Background(), ffs, sfs.Chmod("a.txt", 0600)); err != nil {
+			t.Fatalf("chmod through FaultFS failed: %v", err)
+		}
+		if info, _ := tfs.Stat("a.txt"); info.Mode().Perm() != 0600 {
+			t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+		}
+		if ffs.CallCount(testutil.FaultChmod) != 1 {
+			t.Errorf("expected one chmod call, got %+v", ffs.Calls())
+		}
+	})
+
+	t.Run("forwards chtimes", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		if err := tfs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ffs := testutil.NewFaultFS(tfs, testutil.FaultRule{Method: testutil.FaultChtimes, Call: 1})
+
+		mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := ffs.Chtimes("a.txt", mtime, mtime); !errors.Is(err, syscall.EIO) {
+			t.Fatalf("expected the first chtimes to fail, got %v", err)
+		}
+		if err := ffs.Chtimes("a.txt", mtime, mtime); err != nil {
+			t.Fatalf("second chtimes should succeed: %v", err)
+		}
+		if info, _ := tfs.Stat("a.txt"); !info.ModTime().Equal(mtime) {
+			t.Errorf("expected modification time %v, got %v", mtime, info.ModTime())
+		}
+		if ffs.CallCount(testutil.FaultChtimes) != 2 {
+			t.Errorf("expected chtimes calls to be recorded, got %+v", ffs.Calls())
+		}
+	})
+
+	t.Run("forwards links", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		if err := tfs.WriteFile("a.txt", []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ffs := testutil.NewFaultFS(tfs, testutil.FaultRule{Method: testutil.FaultLink, Call: 1})
+
+		if err := ffs.Link("a.txt", "b.txt"); !errors.Is(err, syscall.EIO) {
+			t.Fatalf("expected the first link to fail, got %v", err)
+		}
+		if err := ffs.Link("a.txt", "b.txt"); err != nil {
+			t.Fatalf("second link should succeed: %v", err)
+		}
+		a, _ := ffs.FileID("a.txt")
+		b, _ := ffs.FileID("b.txt")
+		if a != b {
+			t.Error("expected b.txt to be linked to a.txt")
+		}
+	})
+}
+
+func TestRunFaultSweep(t *testing.T) {
+	sfs := synthfs.New()
+
+	testutil.RunFaultSweep(t, testutil.FaultSweep{
+		NewFS: func(t *testing.T) synthfs.FileSystem {
+			helper := testutil.NewRealFSTestHelper(t)
+			testutil.CreateTestFile(t, helper.FileSystem(), "src.txt", []byte("source"))
+			return helper.FileSystem()
+		},
+		Operations: func() []synthfs.Operation {
+			return []synthfs.Operation{
+				sfs.CreateDir("out", 0755),
+				sfs.Copy("src.txt", "out/copy.txt"),
+				sfs.CreateSymlink("src.txt", "out/link"),
+			}
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package testutil
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// AssertNoWritesOutside fails the test if the journal records a write
+// to any path that is not inside one of the given directories.
+func AssertNoWritesOutside(t *testing.T, journal *filesystem.Journal, dirs ...string) {
+	t.Helper()
+	for _, entry := range journal.Writes() {
+		for _, p := range entry.Paths() {
+			if !isInsideAny(p, dirs) {
+				t.Errorf("unexpected %s outside %v: %s (seq %d)", entry.Method, dirs, p, entry.Seq)
+			}
+		}
+	}
+}
+
+// AssertNoWrites fails the test if the journal records any write.
+func AssertNoWrites(t *testing.T, journal *filesystem.Journal) {
+	t.Helper()
+	for _, entry := range journal.Writes() {
+		t.Errorf("unexpected %s: %s (seq %d)", entry.Method, entry.Path, entry.Seq)
+	}
+}
+
+func isInsideAny(p string, dirs []string) bool {
+	p = path.Clean(p)
+	for _, dir := range dirs {
+		dir = path.Clean(dir)
+		if dir == "." || p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
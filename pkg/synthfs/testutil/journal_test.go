@@ -0,0 +1,40 @@
+package testutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestJournalAssertions(t *testing.T) {
+	tfs := testutil.NewTestFileSystem()
+	testutil.CreateTestFile(t, tfs, "src.txt", []byte("source"))
+	rfs := filesystem.NewRecordingFileSystem(tfs)
+
+	sfs := synthfs.New()
+	_, err := synthfs.Run(context.Background(), rfs,
+		sfs.CreateDir("build", 0755),
+		sfs.Copy("src.txt", "build/src.txt"),
+	)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	journal := rfs.Journal()
+	testutil.AssertNoWritesOutside(t, journal, "build")
+
+	rfs.Reset()
+	if _, err := rfs.Stat("build/src.txt"); err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	testutil.AssertNoWrites(t, rfs.Journal())
+
+	replayed := testutil.NewTestFileSystem()
+	if err := journal.Replay(replayed); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	testutil.AssertFileContent(t, replayed, "build/src.txt", []byte("source"))
+}
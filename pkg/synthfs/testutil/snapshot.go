@@ -0,0 +1,374 @@
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+)
+
+// UpdateGoldenFlag names the test flag that makes AssertGolden rewrite golden
+// files instead of comparing against them:
+//
+//	go test ./... -update
+const UpdateGoldenFlag = "update"
+
+func init() {
+	// A package initialized earlier may already define -update. Share that
+	// flag instead of panicking on a second definition.
+	if flag.Lookup(UpdateGoldenFlag) == nil {
+		flag.Bool(UpdateGoldenFlag, false, "update golden snapshot files")
+	}
+}
+
+// updateGolden reports whether the -update flag is set, whichever package
+// defined it.
+func updateGolden() bool {
+	f := flag.Lookup(UpdateGoldenFlag)
+	if f == nil {
+		return false
+	}
+	update, _ := strconv.ParseBool(f.Value.String())
+	return update
+}
+
+// SnapshotOptions controls what a snapshot records.
+type SnapshotOptions struct {
+	// Root is the subtree to snapshot. Paths are recorded relative to it. Defaults to ".".
+	Root string
+	// HashContent records a sha256 of file content instead of the content itself.
+	HashContent bool
+	// IgnoreModes omits permission bits, which differ between filesystem implementations.
+	IgnoreModes bool
+	// Exclude lists path.Match patterns for paths to skip. A matching directory
+	// is skipped with everything below it.
+	Exclude []string
+}
+
+// SnapshotEntry is the recorded state of a single path.
+type SnapshotEntry struct {
+	Path    string
+	Type    string // ItemTypeFile, ItemTypeDirectory or ItemTypeSymlink
+	Mode    fs.FileMode
+	Content []byte // Full content, nil when Hash is set
+	Hash    string // sha256 of the content, set for hashed or binary files
+	Size    int64
+	Target  string // Symlink target
+}
+
+// Snapshot is the recorded state of a filesystem subtree, sorted by path.
+type Snapshot struct {
+	Entries []SnapshotEntry
+	options SnapshotOptions
+}
+
+// TakeSnapshot records every path under opts.Root in fsys.
+func TakeSnapshot(fsys synthfs.FileSystem, opts SnapshotOptions) (*Snapshot, error) {
+	root := opts.Root
+	if root == "" {
+		root = "."
+	}
+
+	snap := &Snapshot{options: opts}
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel := strings.TrimPrefix(p, root+"/")
+		if root == "." {
+			rel = p
+		}
+		if isExcluded(rel, opts.Exclude) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := SnapshotEntry{Path: rel, Mode: info.Mode().Perm()}
+		if opts.IgnoreModes {
+			entry.Mode = 0
+		}
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			entry.Type = synthfs.ItemTypeSymlink
+			entry.Mode = 0
+			if entry.Target, err = fsys.Readlink(p); err != nil {
+				return err
+			}
+		case d.IsDir():
+			entry.Type = synthfs.ItemTypeDirectory
+		default:
+			entry.Type = synthfs.ItemTypeFile
+			content, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			entry.Size = int64(len(content))
+			if opts.HashContent || !isGoldenText(content) {
+				entry.Hash = fmt.Sprintf("%x", sha256.Sum256(content))
+			} else {
+				entry.Content = content
+			}
+		}
+		snap.Entries = append(snap.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s: %w", root, err)
+	}
+
+	sort.Slice(snap.Entries, func(i, j int) bool {
+		return snap.Entries[i].Path < snap.Entries[j].Path
+	})
+	return snap, nil
+}
+
+// String renders the snapshot in the txtar-style golden format. Each path gets
+// a "-- path attrs --" header; full file content follows its header.
+func (s *Snapshot) String() string {
+	var b strings.Builder
+	for _, entry := range s.Entries {
+		name := entry.Path
+		if entry.Type == synthfs.ItemTypeDirectory {
+			name += "/"
+		}
+		attrs := []string{quoteGoldenField(name)}
+		if entry.Mode != 0 {
+			attrs = append(attrs, fmt.Sprintf("mode=%04o", entry.Mode))
+		}
+		switch {
+		case entry.Type == synthfs.ItemTypeSymlink:
+			attrs = append(attrs, "symlink="+quoteGoldenField(entry.Target))
+		case entry.Hash != "":
+			attrs = append(attrs, fmt.Sprintf("size=%d", entry.Size), "sha256="+entry.Hash)
+		case entry.Type == synthfs.ItemTypeFile && !bytes.HasSuffix(entry.Content, []byte("\n")) && len(entry.Content) > 0:
+			attrs = append(attrs, "noeol")
+		}
+
+		fmt.Fprintf(&b, "-- %s --\n", strings.Join(attrs, " "))
+		if entry.Type == synthfs.ItemTypeFile && entry.Hash == "" && len(entry.Content) > 0 {
+			b.Write(entry.Content)
+			if !bytes.HasSuffix(entry.Content, []byte("\n")) {
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// ParseSnapshot parses a snapshot rendered by String.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	snap := &Snapshot{}
+	var current *SnapshotEntry
+	var noEOL bool
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if noEOL {
+			current.Content = bytes.TrimSuffix(current.Content, []byte("\n"))
+		}
+		if current.Type == synthfs.ItemTypeFile && current.Hash == "" {
+			current.Size = int64(len(current.Content))
+		}
+		snap.Entries = append(snap.Entries, *current)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		header, ok := parseGoldenHeader(line)
+		if !ok {
+			if current == nil {
+				if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+					return nil, fmt.Errorf("line %d: content before first header", i+1)
+				}
+				continue
+			}
+			current.Content = append(current.Content, line...)
+			continue
+		}
+
+		flush()
+		entry, eol, err := parseGoldenEntry(header)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		current, noEOL = entry, eol
+	}
+	flush()
+
+	return snap, nil
+}
+
+// AssertSnapshotEqual fails the test with a unified diff if two snapshots differ.
+func AssertSnapshotEqual(t *testing.T, want, got *Snapshot) {
+	t.Helper()
+	if diff := unifiedDiff("want", "got", want.String(), got.String()); diff != "" {
+		t.Errorf("snapshot mismatch:\n%s", diff)
+	}
+}
+
+// AssertGolden snapshots fsys and compares it to the golden file at goldenPath.
+// Running the test with -update rewrites the golden file instead.
+func AssertGolden(t *testing.T, fsys synthfs.FileSystem, goldenPath string, opts SnapshotOptions) {
+	t.Helper()
+
+	snap, err := TakeSnapshot(fsys, opts)
+	if err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(snap.String()), 0644); err != nil {
+			t.Fatalf("failed to write golden file %s: %v", goldenPath, err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s (run with -update to create it): %v", goldenPath, err)
+	}
+	golden, err := ParseSnapshot(data)
+	if err != nil {
+		t.Fatalf("failed to parse golden file %s: %v", goldenPath, err)
+	}
+
+	if diff := unifiedDiff(goldenPath, "actual", golden.String(), snap.String()); diff != "" {
+		t.Errorf("filesystem does not match golden file %s (run with -update to accept):\n%s", goldenPath, diff)
+	}
+}
+
+func isExcluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(p)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isGoldenText reports whether content can be stored verbatim in a golden file.
+func isGoldenText(content []byte) bool {
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return false
+	}
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if _, ok := parseGoldenHeader(line); ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseGoldenHeader(line string) (string, bool) {
+	line = strings.TrimSuffix(line, "\n")
+	if !strings.HasPrefix(line, "-- ") || !strings.HasSuffix(line, " --") || len(line) < 7 {
+		return "", false
+	}
+	return line[3 : len(line)-3], true
+}
+
+func parseGoldenEntry(header string) (*SnapshotEntry, bool, error) {
+	fields, err := splitGoldenFields(header)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(fields) == 0 {
+		return nil, false, fmt.Errorf("empty header")
+	}
+
+	entry := &SnapshotEntry{Path: fields[0], Type: synthfs.ItemTypeFile}
+	if strings.HasSuffix(entry.Path, "/") {
+		entry.Path = strings.TrimSuffix(entry.Path, "/")
+		entry.Type = synthfs.ItemTypeDirectory
+	}
+
+	noEOL := false
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "mode":
+			mode, err := strconv.ParseUint(value, 8, 32)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid mode %q: %w", value, err)
+			}
+			entry.Mode = fs.FileMode(mode)
+		case "symlink":
+			entry.Type = synthfs.ItemTypeSymlink
+			entry.Target = value
+		case "sha256":
+			entry.Hash = value
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid size %q: %w", value, err)
+			}
+			entry.Size = size
+		case "noeol":
+			noEOL = true
+		default:
+			return nil, false, fmt.Errorf("unknown attribute %q", key)
+		}
+	}
+	return entry, noEOL, nil
+}
+
+// splitGoldenFields splits a header on spaces, honouring Go-quoted fields.
+func splitGoldenFields(header string) ([]string, error) {
+	var fields []string
+	for header = strings.TrimSpace(header); header != ""; header = strings.TrimSpace(header) {
+		key := ""
+		if i := strings.Index(header, "=\""); i >= 0 && !strings.Contains(header[:i], " ") {
+			key, header = header[:i+1], header[i+1:]
+		}
+		if strings.HasPrefix(header, "\"") {
+			quoted, err := strconv.QuotedPrefix(header)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted field: %w", err)
+			}
+			value, _ := strconv.Unquote(quoted)
+			fields = append(fields, key+value)
+			header = header[len(quoted):]
+			continue
+		}
+		field, rest, _ := strings.Cut(header, " ")
+		fields = append(fields, key+field)
+		header = rest
+	}
+	return fields, nil
+}
+
+func quoteGoldenField(s string) string {
+	if s == "" || strings.ContainsAny(s, " \"\t\n") || strings.Contains(s, "--") {
+		return strconv.Quote(s)
+	}
+	return s
+}
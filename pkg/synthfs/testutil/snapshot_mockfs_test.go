@@ -0,0 +1,21 @@
+//go:build !coverage
+
+package testutil_test
+
+import (
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestSnapshotMockFS(t *testing.T) {
+	mfs := testutil.NewMockFS()
+	buildSnapshotTree(t, mfs)
+	if err := mfs.Symlink("main.go", "app/link"); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+
+	testutil.AssertGolden(t, mfs, "testdata/snapshot.golden", testutil.SnapshotOptions{
+		IgnoreModes: true,
+	})
+}
@@ -0,0 +1,130 @@
+package testutil_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+// buildSnapshotTree populates fsys with the tree used by the golden snapshot tests.
+func buildSnapshotTree(t *testing.T, fsys synthfs.FileSystem) {
+	t.Helper()
+	testutil.CreateTestDir(t, fsys, "app/cmd")
+	testutil.CreateTestFile(t, fsys, "app/main.go", []byte("package main\n\nfunc main() {}\n"))
+	testutil.CreateTestFile(t, fsys, "app/VERSION", []byte("1.2.3"))
+	testutil.CreateTestFile(t, fsys, "app/logo.bin", []byte{0x89, 'P', 'N', 'G', 0x00, 0x01})
+	testutil.CreateTestFile(t, fsys, "app/cmd/tool.sh", []byte("#!/bin/sh\n-- not a header --\n"))
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("round trips through the golden format", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		buildSnapshotTree(t, tfs)
+
+		snap, err := testutil.TakeSnapshot(tfs, testutil.SnapshotOptions{})
+		if err != nil {
+			t.Fatalf("TakeSnapshot failed: %v", err)
+		}
+
+		parsed, err := testutil.ParseSnapshot([]byte(snap.String()))
+		if err != nil {
+			t.Fatalf("ParseSnapshot failed: %v", err)
+		}
+		if parsed.String() != snap.String() {
+			t.Errorf("round trip changed snapshot:\n%s\n---\n%s", snap, parsed)
+		}
+
+		rendered := snap.String()
+		for _, want := range []string{
+			"-- app/ ",
+			"-- app/VERSION mode=0644 noeol --\n1.2.3\n",
+			"-- app/logo.bin mode=0644 size=6 sha256=",
+			"-- app/cmd/tool.sh mode=0644 size=",
+		} {
+			if !strings.Contains(rendered, want) {
+				t.Errorf("expected snapshot to contain %q:\n%s", want, rendered)
+			}
+		}
+	})
+
+	t.Run("root and exclude", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		buildSnapshotTree(t, tfs)
+
+		snap, err := testutil.TakeSnapshot(tfs, testutil.SnapshotOptions{
+			Root:        "app",
+			HashContent: true,
+			Exclude:     []string{"cmd", "*.bin"},
+		})
+		if err != nil {
+			t.Fatalf("TakeSnapshot failed: %v", err)
+		}
+
+		var paths []string
+		for _, entry := range snap.Entries {
+			paths = append(paths, entry.Path)
+			if entry.Content != nil {
+				t.Errorf("expected hashed content for %s", entry.Path)
+			}
+		}
+		if strings.Join(paths, ",") != "VERSION,main.go" {
+			t.Errorf("unexpected paths: %v", paths)
+		}
+	})
+
+	t.Run("golden file against real filesystem", func(t *testing.T) {
+		helper := testutil.NewRealFSTestHelper(t)
+		buildSnapshotTree(t, helper.FileSystem())
+		helper.CreateSymlink("main.go", "app/link")
+
+		testutil.AssertGolden(t, helper.FileSystem(), "testdata/snapshot.golden", testutil.SnapshotOptions{
+			IgnoreModes: true,
+		})
+	})
+
+	t.Run("golden file against test filesystem", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		buildSnapshotTree(t, tfs)
+		if err := tfs.Symlink("main.go", "app/link"); err != nil {
+			t.Fatalf("Symlink failed: %v", err)
+		}
+
+		testutil.AssertGolden(t, tfs, "testdata/snapshot.golden", testutil.SnapshotOptions{
+			IgnoreModes: true,
+		})
+	})
+
+	t.Run("golden file is rewritten when -update is set", func(t *testing.T) {
+		tfs := testutil.NewTestFileSystem()
+		buildSnapshotTree(t, tfs)
+		goldenPath := filepath.Join(t.TempDir(), "nested", "new.golden")
+
+		setUpdateFlag(t, "true")
+		testutil.AssertGolden(t, tfs, goldenPath, testutil.SnapshotOptions{})
+		if _, err := os.Stat(goldenPath); err != nil {
+			t.Fatalf("expected the golden file to be written: %v", err)
+		}
+
+		setUpdateFlag(t, "false")
+		testutil.AssertGolden(t, tfs, goldenPath, testutil.SnapshotOptions{})
+	})
+}
+
+// setUpdateFlag sets -update for the rest of the test and restores it after.
+func setUpdateFlag(t *testing.T, value string) {
+	t.Helper()
+	f := flag.Lookup(testutil.UpdateGoldenFlag)
+	if f == nil {
+		t.Fatal("expected testutil to define the -update flag")
+	}
+	previous := f.Value.String()
+	if err := flag.Set(testutil.UpdateGoldenFlag, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = flag.Set(testutil.UpdateGoldenFlag, previous) })
+}
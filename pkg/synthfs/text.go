@@ -0,0 +1,79 @@
+package synthfs
+
+import (
+	"context"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/operations"
+)
+
+// TextEdit is one line-oriented change to a text file.
+type TextEdit = operations.TextEdit
+
+// LineOptions controls where EnsureLine puts its line.
+type LineOptions = operations.LineOptions
+
+// BlockOptions controls the markers of a managed block and where a new block goes.
+type BlockOptions = operations.BlockOptions
+
+// DefaultBlockMarker delimits blocks managed by EnsureBlock.
+const DefaultBlockMarker = operations.DefaultBlockMarker
+
+// EnsureLine makes sure line is present, replacing the last line matching
+// options.Match if given, otherwise inserting it after or before an anchor or
+// at the end of the file.
+func EnsureLine(line string, options LineOptions) TextEdit {
+	return operations.EnsureLine(line, options)
+}
+
+// RemoveLine removes every line equal to line.
+func RemoveLine(line string) TextEdit {
+	return operations.RemoveLine(line)
+}
+
+// RemoveLines removes every line matching the regular expression pattern.
+func RemoveLines(pattern string) TextEdit {
+	return operations.RemoveLines(pattern)
+}
+
+// ReplaceRegexp replaces every match of pattern in the file with replacement.
+func ReplaceRegexp(pattern, replacement string) TextEdit {
+	return operations.ReplaceRegexp(pattern, replacement)
+}
+
+// EnsureBlock makes sure block sits between a pair of marker lines.
+func EnsureBlock(block string, options BlockOptions) TextEdit {
+	return operations.EnsureBlock(block, options)
+}
+
+// RemoveBlock removes a managed block and its markers.
+func RemoveBlock(options BlockOptions) TextEdit {
+	return operations.RemoveBlock(options)
+}
+
+// EditText creates an operation that applies line-oriented edits to a text
+// file, like Ansible's lineinfile and blockinfile. The file is only written
+// when an edit changes it; the "changed" and "changed_edits" outputs say what
+// happened, and the original is backed up for rollback and restore.
+//
+// Example:
+//
+//	sfs.EditText("/etc/ssh/sshd_config",
+//	    synthfs.EnsureLine("PermitRootLogin no", synthfs.LineOptions{Match: `^#?PermitRootLogin`}),
+//	    synthfs.EnsureBlock("Match User deploy\n    PasswordAuthentication no", synthfs.BlockOptions{}),
+//	)
+func (s *SynthFS) EditText(path string, edits ...TextEdit) Operation {
+	id := s.idGen("edit_text", path)
+	return operations.NewEditTextOperation(id, path, edits...)
+}
+
+// EditTextWithID creates a text edit operation with an explicit ID.
+func (s *SynthFS) EditTextWithID(id string, path string, edits ...TextEdit) Operation {
+	return operations.NewEditTextOperation(core.OperationID(id), path, edits...)
+}
+
+// EditText applies line-oriented edits to a text file immediately.
+func EditText(ctx context.Context, fs FileSystem, path string, edits ...TextEdit) error {
+	_, err := Run(ctx, fs, New().EditText(path, edits...))
+	return err
+}
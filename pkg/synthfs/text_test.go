@@ -0,0 +1,48 @@
+package synthfs_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestEditText(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+	mustDo(t, fsys.WriteFile("sshd_config", []byte("Port 22\n#PermitRootLogin yes\n"), 0644))
+
+	edit := func(id string) synthfs.Operation {
+		return sfs.EditTextWithID(id, "sshd_config",
+			synthfs.EnsureLine("PermitRootLogin no", synthfs.LineOptions{Match: `^#?PermitRootLogin`}),
+			synthfs.EnsureBlock("Match User deploy", synthfs.BlockOptions{}),
+		)
+	}
+
+	first := edit("first")
+	if _, err := synthfs.Run(ctx, fsys, first); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	want := "Port 22\nPermitRootLogin no\n# BEGIN SYNTHFS MANAGED BLOCK\nMatch User deploy\n# END SYNTHFS MANAGED BLOCK\n"
+	if got := readString(t, fsys, "sshd_config"); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if synthfs.GetOperationOutputValue(first, "changed") != true {
+		t.Error("expected the first run to report a change")
+	}
+
+	second := edit("second")
+	if _, err := synthfs.Run(ctx, fsys, second); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if synthfs.GetOperationOutputValue(second, "changed") != false {
+		t.Error("expected the second run to change nothing")
+	}
+}
@@ -0,0 +1,40 @@
+package synthfs
+
+import (
+	"time"
+)
+
+// timeoutMarker is implemented by operations that can carry their own
+// timeout.
+type timeoutMarker interface {
+	SetTimeout(timeout time.Duration)
+	Timeout() time.Duration
+}
+
+// Timeout bounds how long op may run and returns it, overriding
+// PipelineOptions.OperationTimeout for this operation. An operation that runs
+// out of time is interrupted and reported as StatusCancelled. Expandable
+// operations give each child the same timeout.
+//
+// Example:
+//
+//	synthfs.RunWithOptions(ctx, fs, options,
+//	    synthfs.Timeout(sfs.Copy("images/base.img", "vm/disk.img"), 10*time.Minute),
+//	)
+func Timeout(op Operation, timeout time.Duration) Operation {
+	if marker, ok := op.(timeoutMarker); ok {
+		marker.SetTimeout(timeout)
+	}
+	return op
+}
+
+// inheritTimeout gives child the timeout of its parent, unless it has its own.
+func inheritTimeout(parent, child Operation) {
+	parentMarker, ok := parent.(timeoutMarker)
+	if !ok || parentMarker.Timeout() <= 0 {
+		return
+	}
+	if childMarker, ok := child.(timeoutMarker); ok && childMarker.Timeout() <= 0 {
+		childMarker.SetTimeout(parentMarker.Timeout())
+	}
+}
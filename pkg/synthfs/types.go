@@ -62,5 +62,11 @@ type Executable interface {
 // This provides backward compatibility while we complete the consolidation
 type Operation = operations.Operation
 
+// ExpandableOperation is an operation that expands into child operations at validation time
+type ExpandableOperation = operations.ExpandableOperation
+
+// EnsurableOperation is an operation that can tell whether its desired state already holds
+type EnsurableOperation = operations.EnsurableOperation
+
 // ValidationError is now defined in the core package
 type ValidationError = core.ValidationError
@@ -0,0 +1,539 @@
+package synthfs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+)
+
+// UndoLogVersion is the version of the entries written by UndoLog.
+const UndoLogVersion = 1
+
+// ErrRunNotFound is returned for run IDs that are not in an undo log.
+var ErrRunNotFound = errors.New("run not found in undo log")
+
+// ErrUndoConflict is wrapped by the errors returned when a run cannot be
+// undone because paths it touched changed after it.
+var ErrUndoConflict = errors.New("paths changed since the run")
+
+// UndoLog keeps the reverse operations of successful runs in a directory, so
+// they can be undone later, by another process. Each run is stored under its
+// own ID, with the content it overwrote or deleted, and the state it left
+// the paths it touched in. Undoing a run whose paths have changed since is
+// refused, unless forced.
+//
+// Runs are recorded by setting PipelineOptions.UndoLog, or with Record.
+// Recording makes the run restorable; operations whose backup does not fit
+// in MaxBackupSizeMB cannot be undone. Operations whose reverse operations
+// cannot be stored, such as custom operations with a rollback, are left out
+// of the entry, which is then marked partial.
+//
+// Example:
+//
+//	log := synthfs.NewUndoLog(filepath.Join(stateDir, "undo"))
+//	options := synthfs.DefaultPipelineOptions()
+//	options.UndoLog = log
+//	options.MaxBackupSizeMB = 100
+//	result, err := synthfs.RunWithOptions(ctx, fs, options, ops...)
+//	// later
+//	last, err := log.Last()
+//	result, err = log.Undo(ctx, fs, last.ID)
+type UndoLog struct {
+	dir string
+}
+
+// NewUndoLog returns the undo log stored in dir. The directory is created
+// when the first run is recorded.
+func NewUndoLog(dir string) *UndoLog {
+	return &UndoLog{dir: dir}
+}
+
+// Dir returns the directory the log is stored in.
+func (l *UndoLog) Dir() string {
+	return l.dir
+}
+
+// UndoEntry describes a run recorded in an UndoLog. Skipped lists the
+// operations of a partial entry that undoing the run leaves in place.
+type UndoEntry struct {
+	ID         string                 `json:"id"`
+	Time       time.Time              `json:"time"`
+	Operations []UndoOperation        `json:"operations"`
+	Steps      int                    `json:"steps"`
+	Partial    bool                   `json:"partial,omitempty"`
+	Skipped    []UndoOperation        `json:"skipped,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// UndoOperation is an operation of a recorded run that can be undone.
+type UndoOperation struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// UndoOptions controls UndoWithOptions.
+type UndoOptions struct {
+	// DryRun checks the steps against an overlay without changing anything,
+	// and keeps the run in the log.
+	DryRun bool
+
+	// Force undoes the run even if paths it touched have changed since.
+	Force bool
+
+	// EventBus, if set, receives the events of the undo.
+	EventBus EventBus
+}
+
+// UndoConflict is a path that changed after the run that touched it.
+type UndoConflict struct {
+	Path     string
+	Recorded string // State the run left the path in
+	Current  string // State of the path now
+}
+
+// UndoConflictError reports the paths that prevent undoing a run.
+type UndoConflictError struct {
+	RunID     string
+	Conflicts []UndoConflict
+}
+
+func (e *UndoConflictError) Error() string {
+	var paths []string
+	for _, conflict := range e.Conflicts {
+		paths = append(paths, fmt.Sprintf("%s (was %s, now %s)", conflict.Path, conflict.Recorded, conflict.Current))
+	}
+	return fmt.Sprintf("cannot undo run %s: %d paths changed since the run: %s", e.RunID, len(e.Conflicts), strings.Join(paths, ", "))
+}
+
+func (e *UndoConflictError) Unwrap() error {
+	return ErrUndoConflict
+}
+
+// undoEntryJSON is the content of the entry.json file of a run.
+type undoEntryJSON struct {
+	Version int `json:"version"`
+	UndoEntry
+	Plan  []undoStepJSON  `json:"plan"`
+	After []undoPathState `json:"after"`
+}
+
+// undoStepJSON is a restore step whose content, if any, is stored in a blob
+// named after its SHA-256.
+type undoStepJSON struct {
+	restoreStepJSON
+	Blob string `json:"blob,omitempty"`
+}
+
+// undoPathState is the state of a path: missing, or a file with its mode and
+// checksum, a directory with its mode and its entries when they matter, or a
+// symlink with its target.
+type undoPathState struct {
+	Path    string   `json:"path"`
+	Kind    string   `json:"kind"`
+	Mode    string   `json:"mode,omitempty"`
+	SHA256  string   `json:"sha256,omitempty"`
+	Target  string   `json:"target,omitempty"`
+	Entries []string `json:"entries,omitempty"`
+}
+
+func (s undoPathState) String() string {
+	switch s.Kind {
+	case "file":
+		return fmt.Sprintf("file %s sha256:%s", s.Mode, s.SHA256[:12])
+	case "symlink":
+		return "symlink to " + s.Target
+	case "directory":
+		if len(s.Entries) > 0 {
+			return fmt.Sprintf("directory %s with %d entries", s.Mode, len(s.Entries))
+		}
+		return "directory " + s.Mode
+	default:
+		return s.Kind
+	}
+}
+
+func (s undoPathState) equal(other undoPathState) bool {
+	return s.Kind == other.Kind && s.Mode == other.Mode && s.SHA256 == other.SHA256 && s.Target == other.Target &&
+		strings.Join(s.Entries, "\x00") == strings.Join(other.Entries, "\x00")
+}
+
+// RecordRun implements core.UndoRecorder, for PipelineOptions.UndoLog.
+func (l *UndoLog) RecordRun(ctx context.Context, result *Result, fsys interface{}) (string, error) {
+	fs, ok := fsys.(filesystem.FileSystem)
+	if !ok {
+		return "", fmt.Errorf("cannot record run: unsupported filesystem %T", fsys)
+	}
+	return l.Record(fs, result)
+}
+
+// Record stores the restore plan of result, a restorable run on fs, and
+// returns the ID of the new entry. The state of the paths the plan touches
+// is read from fs, so Record must be called right after the run. Runs with
+// nothing to undo are not recorded and get an empty ID.
+//
+// An operation with a reverse operation that cannot be stored is left out
+// with all its steps, and listed in the entry's Skipped operations. Record
+// fails if that leaves no step to store.
+func (l *UndoLog) Record(fs filesystem.FileSystem, result *Result) (string, error) {
+	plan := NewRestorePlan(result)
+	records := make([]restoreStepJSON, len(plan.Steps))
+	skipped := make(map[core.OperationID]bool)
+	for i, step := range plan.Steps {
+		record, err := encodeRestoreStep(step)
+		if err != nil {
+			skipped[step.Undoes] = true
+			continue
+		}
+		records[i] = record
+	}
+	stored := 0
+	for _, step := range plan.Steps {
+		if !skipped[step.Undoes] {
+			stored++
+		}
+	}
+	if stored == 0 {
+		if len(skipped) > 0 {
+			return "", fmt.Errorf("cannot record run: none of its %d undoable operations can be stored", len(skipped))
+		}
+		return "", nil
+	}
+
+	entry := undoEntryJSON{
+		Version: UndoLogVersion,
+		UndoEntry: UndoEntry{
+			ID:       newRunID(),
+			Time:     time.Now().UTC(),
+			Partial:  len(skipped) > 0,
+			Metadata: reportValues(result.Metadata),
+		},
+	}
+	for _, opResult := range result.Operations {
+		op, ok := opResult.Operation.(Operation)
+		if !ok || len(opResult.ReverseOps) == 0 {
+			continue
+		}
+		undoOp := UndoOperation{ID: string(op.ID()), Type: op.Describe().Type, Path: op.Describe().Path}
+		if skipped[opResult.OperationID] {
+			entry.Skipped = append(entry.Skipped, undoOp)
+		} else {
+			entry.Operations = append(entry.Operations, undoOp)
+		}
+	}
+
+	tmp := filepath.Join(l.dir, entry.ID+".tmp")
+	if err := os.MkdirAll(filepath.Join(tmp, "blobs"), 0700); err != nil {
+		return "", fmt.Errorf("failed to create undo log entry: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	for i, step := range plan.Steps {
+		if skipped[step.Undoes] {
+			continue
+		}
+		record := records[i]
+		undoStep := undoStepJSON{restoreStepJSON: record}
+		if len(record.Content) > 0 {
+			sum := sha256.Sum256(record.Content)
+			undoStep.Blob = hex.EncodeToString(sum[:])
+			undoStep.Content = nil
+			if err := os.WriteFile(filepath.Join(tmp, "blobs", undoStep.Blob), record.Content, 0600); err != nil {
+				return "", fmt.Errorf("failed to store backup of %s: %w", record.Path, err)
+			}
+		}
+		entry.Plan = append(entry.Plan, undoStep)
+	}
+	entry.Steps = len(entry.Plan)
+
+	for _, p := range undoPaths(entry.Plan) {
+		state, err := captureUndoPathState(fs, p.path, p.listEntries)
+		if err != nil {
+			return "", fmt.Errorf("failed to read state of %s: %w", p.path, err)
+		}
+		entry.After = append(entry.After, state)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "entry.json"), data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write undo log entry: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(l.dir, entry.ID)); err != nil {
+		return "", fmt.Errorf("failed to write undo log entry: %w", err)
+	}
+	return entry.ID, nil
+}
+
+// History returns the runs in the log, latest first.
+func (l *UndoLog) History() ([]UndoEntry, error) {
+	dirEntries, err := os.ReadDir(l.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []UndoEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.HasSuffix(dirEntry.Name(), ".tmp") {
+			continue
+		}
+		entry, err := l.read(dirEntry.Name())
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, entry.UndoEntry)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].Time.Equal(history[j].Time) {
+			return history[i].Time.After(history[j].Time)
+		}
+		return history[i].ID > history[j].ID
+	})
+	return history, nil
+}
+
+// Last returns the latest run in the log, or ErrRunNotFound if it is empty.
+func (l *UndoLog) Last() (UndoEntry, error) {
+	history, err := l.History()
+	if err != nil {
+		return UndoEntry{}, err
+	}
+	if len(history) == 0 {
+		return UndoEntry{}, fmt.Errorf("%w: the log is empty", ErrRunNotFound)
+	}
+	return history[0], nil
+}
+
+// Plan returns the restore plan of a recorded run.
+func (l *UndoLog) Plan(runID string) (*RestorePlan, error) {
+	entry, err := l.read(runID)
+	if err != nil {
+		return nil, err
+	}
+	return l.plan(entry)
+}
+
+// Conflicts returns the paths the run touched that have changed since, on fs.
+func (l *UndoLog) Conflicts(fs filesystem.FileSystem, runID string) ([]UndoConflict, error) {
+	entry, err := l.read(runID)
+	if err != nil {
+		return nil, err
+	}
+	return entry.conflicts(fs)
+}
+
+// Undo undoes a recorded run on fs. See UndoWithOptions.
+func (l *UndoLog) Undo(ctx context.Context, fs filesystem.FileSystem, runID string) (*Result, error) {
+	return l.UndoWithOptions(ctx, fs, runID, UndoOptions{})
+}
+
+// UndoWithOptions undoes a recorded run on fs, and removes it from the log.
+// Unless options.Force is set, it fails with an *UndoConflictError, and
+// changes nothing, if paths the run touched have changed since. The steps
+// that ran are rolled back if one of them fails.
+func (l *UndoLog) UndoWithOptions(ctx context.Context, fs filesystem.FileSystem, runID string, options UndoOptions) (*Result, error) {
+	entry, err := l.read(runID)
+	if err != nil {
+		return nil, err
+	}
+	if !options.Force {
+		conflicts, err := entry.conflicts(fs)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			return nil, &UndoConflictError{RunID: runID, Conflicts: conflicts}
+		}
+	}
+	plan, err := l.plan(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineOptions := DefaultPipelineOptions()
+	pipelineOptions.DryRun = options.DryRun
+	pipelineOptions.RollbackOnError = true
+	pipelineOptions.EventBus = options.EventBus
+	result, err := plan.ExecuteWithOptions(ctx, fs, pipelineOptions)
+	if err != nil || options.DryRun {
+		return result, err
+	}
+	return result, l.Drop(runID)
+}
+
+// Drop removes a run from the log without undoing it.
+func (l *UndoLog) Drop(runID string) error {
+	if _, err := l.read(runID); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(l.dir, runID))
+}
+
+// read loads the entry of runID.
+func (l *UndoLog) read(runID string) (*undoEntryJSON, error) {
+	if runID == "" || runID != filepath.Base(runID) || strings.HasPrefix(runID, ".") {
+		return nil, fmt.Errorf("%w: invalid run ID %q", ErrRunNotFound, runID)
+	}
+	data, err := os.ReadFile(filepath.Join(l.dir, runID, "entry.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry undoEntryJSON
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid undo log entry %s: %w", runID, err)
+	}
+	if entry.Version != UndoLogVersion {
+		return nil, fmt.Errorf("unsupported undo log entry version %d for run %s", entry.Version, runID)
+	}
+	return &entry, nil
+}
+
+// plan rebuilds the restore plan of entry, reading content from its blobs.
+func (l *UndoLog) plan(entry *undoEntryJSON) (*RestorePlan, error) {
+	plan := &RestorePlan{}
+	for _, undoStep := range entry.Plan {
+		record := undoStep.restoreStepJSON
+		if undoStep.Blob != "" {
+			content, err := os.ReadFile(filepath.Join(l.dir, entry.ID, "blobs", undoStep.Blob))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read backup of %s: %w", record.Path, err)
+			}
+			if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != undoStep.Blob {
+				return nil, fmt.Errorf("backup of %s is corrupt", record.Path)
+			}
+			record.Content = content
+		}
+		step, err := decodeRestoreStep(record)
+		if err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, nil
+}
+
+// conflicts compares the state the run left its paths in with their state
+// on fs.
+func (entry *undoEntryJSON) conflicts(fs filesystem.FileSystem) ([]UndoConflict, error) {
+	listEntries := make(map[string]bool)
+	for _, p := range undoPaths(entry.Plan) {
+		listEntries[p.path] = p.listEntries
+	}
+	var conflicts []UndoConflict
+	for _, recorded := range entry.After {
+		current, err := captureUndoPathState(fs, recorded.Path, listEntries[recorded.Path])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state of %s: %w", recorded.Path, err)
+		}
+		if !current.equal(recorded) {
+			conflicts = append(conflicts, UndoConflict{Path: recorded.Path, Recorded: recorded.String(), Current: current.String()})
+		}
+	}
+	return conflicts, nil
+}
+
+// undoPath is a path a restore plan touches. The entries of directories the
+// plan deletes matter: anything added to them since the run would be lost.
+type undoPath struct {
+	path        string
+	listEntries bool
+}
+
+// undoPaths returns the paths the steps touch, in order of first use.
+func undoPaths(steps []undoStepJSON) []undoPath {
+	var paths []undoPath
+	index := make(map[string]int)
+	add := func(p string, listEntries bool) {
+		if p == "" {
+			return
+		}
+		if i, ok := index[p]; ok {
+			paths[i].listEntries = paths[i].listEntries || listEntries
+			return
+		}
+		index[p] = len(paths)
+		paths = append(paths, undoPath{path: p, listEntries: listEntries})
+	}
+	for _, step := range steps {
+		add(step.Path, step.Type == "delete")
+		add(step.Src, false)
+		add(step.Dst, false)
+	}
+	return paths
+}
+
+// captureUndoPathState reads the state of p on fsys.
+func captureUndoPathState(fsys filesystem.FileSystem, p string, listEntries bool) (undoPathState, error) {
+	state := undoPathState{Path: p}
+	if target, err := fsys.Readlink(p); err == nil {
+		state.Kind, state.Target = "symlink", target
+		return state, nil
+	}
+	info, err := fsys.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		state.Kind = "missing"
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	state.Mode = formatMode(info.Mode())
+	if info.IsDir() {
+		state.Kind = "directory"
+		if listEntries {
+			dirEntries, err := fs.ReadDir(fsys, p)
+			if err != nil {
+				return state, err
+			}
+			for _, dirEntry := range dirEntries {
+				state.Entries = append(state.Entries, dirEntry.Name())
+			}
+		}
+		return state, nil
+	}
+
+	state.Kind = "file"
+	file, err := fsys.Open(p)
+	if err != nil {
+		return state, err
+	}
+	defer func() { _ = file.Close() }()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return state, err
+	}
+	state.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	return state, nil
+}
+
+// newRunID returns a run ID that sorts by time and is unique.
+func newRunID() string {
+	random := make([]byte, 3)
+	_, _ = rand.Read(random)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(random)
+}
+
+// UndoLog records the runs of PipelineOptions.UndoLog.
+var _ core.UndoRecorder = (*UndoLog)(nil)
@@ -0,0 +1,211 @@
+package synthfs_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/arthur-debert/synthfs/pkg/synthfs"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
+	"github.com/arthur-debert/synthfs/pkg/synthfs/testutil"
+)
+
+func TestUndoLog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SynthFS does not officially support Windows")
+	}
+	ctx := context.Background()
+	sfs := synthfs.New()
+
+	// provision runs a recorded batch of changes on a fresh filesystem
+	provision := func(t *testing.T) (synthfs.FileSystem, *synthfs.UndoLog, string) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		mustDo(t, fsys.WriteFile("hosts", []byte("127.0.0.1 localhost\n"), 0644))
+		log := synthfs.NewUndoLog(filepath.Join(t.TempDir(), "undo"))
+
+		options := synthfs.DefaultPipelineOptions()
+		options.UndoLog = log
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateDirWithID("dir", "app", 0755),
+			sfs.CreateFileWithID("config", "app/config.yml", []byte("port: 80\n"), 0644),
+			sfs.DeleteWithID("hosts", "hosts"),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if result.RunID == "" {
+			t.Fatal("run was not recorded")
+		}
+		return fsys, log, result.RunID
+	}
+
+	t.Run("undo restores the state before the run", func(t *testing.T) {
+		fsys, log, runID := provision(t)
+
+		history, err := log.History()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].ID != runID || len(history[0].Operations) != 3 || history[0].Steps != 3 {
+			t.Fatalf("unexpected history: %+v", history)
+		}
+
+		result, err := log.Undo(ctx, fsys, runID)
+		if err != nil {
+			t.Fatalf("undo failed: %v", err)
+		}
+		if !result.Success {
+			t.Errorf("undo did not succeed: %v", result.Errors)
+		}
+		if got := readString(t, fsys, "hosts"); got != "127.0.0.1 localhost\n" {
+			t.Errorf("hosts = %q", got)
+		}
+		if _, err := fsys.Stat("app"); err == nil {
+			t.Error("app was not removed")
+		}
+		if _, err := log.Last(); !errors.Is(err, synthfs.ErrRunNotFound) {
+			t.Errorf("undone run still in the log: %v", err)
+		}
+	})
+
+	t.Run("runs are recorded in order", func(t *testing.T) {
+		fsys, log, first := provision(t)
+		options := synthfs.DefaultPipelineOptions()
+		options.UndoLog = log
+		result, err := synthfs.RunWithOptions(ctx, fsys, options, sfs.CreateFile("app/extra.yml", nil, 0644))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last, err := log.Last()
+		if err != nil || last.ID != result.RunID || last.ID == first {
+			t.Fatalf("Last = %+v, %v", last, err)
+		}
+		if _, err := log.Undo(ctx, fsys, last.ID); err != nil {
+			t.Fatalf("undo of the last run failed: %v", err)
+		}
+		if _, err := log.Undo(ctx, fsys, first); err != nil {
+			t.Fatalf("undo of the first run failed: %v", err)
+		}
+	})
+
+	t.Run("changes after the run are conflicts", func(t *testing.T) {
+		fsys, log, runID := provision(t)
+		mustDo(t, fsys.WriteFile("app/config.yml", []byte("port: 8080\n"), 0644))
+		mustDo(t, fsys.WriteFile("app/notes.txt", []byte("mine"), 0644))
+
+		_, err := log.Undo(ctx, fsys, runID)
+		var conflictErr *synthfs.UndoConflictError
+		if !errors.As(err, &conflictErr) || !errors.Is(err, synthfs.ErrUndoConflict) {
+			t.Fatalf("expected a conflict, got %v", err)
+		}
+		paths := map[string]bool{}
+		for _, conflict := range conflictErr.Conflicts {
+			paths[conflict.Path] = true
+		}
+		if len(paths) != 2 || !paths["app"] || !paths["app/config.yml"] {
+			t.Errorf("unexpected conflicts: %+v", conflictErr.Conflicts)
+		}
+		if got := readString(t, fsys, "app/notes.txt"); got != "mine" {
+			t.Errorf("refused undo changed files: %q", got)
+		}
+
+		if _, err := log.UndoWithOptions(ctx, fsys, runID, synthfs.UndoOptions{Force: true}); err != nil {
+			t.Fatalf("forced undo failed: %v", err)
+		}
+		if got := readString(t, fsys, "hosts"); got != "127.0.0.1 localhost\n" {
+			t.Errorf("hosts = %q", got)
+		}
+	})
+
+	t.Run("mode changes after the run are conflicts", func(t *testing.T) {
+		fsys, log, runID := provision(t)
+		mustDo(t, fsys.(filesystem.ChmodFS).Chmod("app/config.yml", 0600))
+
+		_, err := log.Undo(ctx, fsys, runID)
+		var conflictErr *synthfs.UndoConflictError
+		if !errors.As(err, &conflictErr) || len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].Path != "app/config.yml" {
+			t.Fatalf("expected a conflict on app/config.yml, got %v", err)
+		}
+		if info, err := fsys.Stat("app/config.yml"); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("refused undo changed the mode: %v, %v", info, err)
+		}
+	})
+
+	t.Run("operations that cannot be stored make the run partial", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		log := synthfs.NewUndoLog(filepath.Join(t.TempDir(), "undo"))
+		noop := func(ctx context.Context, fs filesystem.FileSystem) error { return nil }
+
+		options := synthfs.DefaultPipelineOptions()
+		options.UndoLog = log
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			sfs.CreateFileWithID("config", "config.yml", []byte("port: 80\n"), 0644),
+			synthfs.NewCustomOperation("custom", noop).WithRollback(noop),
+		)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+
+		last, err := log.Last()
+		if err != nil || last.ID != result.RunID {
+			t.Fatalf("Last = %+v, %v", last, err)
+		}
+		if !last.Partial || len(last.Skipped) != 1 || last.Skipped[0].ID != "custom" || len(last.Operations) != 1 || last.Steps != 1 {
+			t.Errorf("unexpected entry: %+v", last)
+		}
+		if _, err := log.Undo(ctx, fsys, last.ID); err != nil {
+			t.Fatalf("undo failed: %v", err)
+		}
+		if _, err := fsys.Stat("config.yml"); err == nil {
+			t.Error("config.yml was not removed")
+		}
+	})
+
+	t.Run("runs with no storable step are not recorded", func(t *testing.T) {
+		fsys := testutil.NewRealFSTestHelper(t).FileSystem()
+		log := synthfs.NewUndoLog(filepath.Join(t.TempDir(), "undo"))
+		noop := func(ctx context.Context, fs filesystem.FileSystem) error { return nil }
+
+		options := synthfs.DefaultPipelineOptions()
+		options.UndoLog = log
+		result, err := synthfs.RunWithOptions(ctx, fsys, options,
+			synthfs.NewCustomOperation("first", noop).WithRollback(noop),
+			synthfs.NewCustomOperation("second", noop).WithRollback(noop),
+		)
+		if err == nil || !strings.Contains(err.Error(), "can be stored") {
+			t.Fatalf("expected the run not to be recordable, got %v", err)
+		}
+		if result == nil || result.RunID != "" {
+			t.Errorf("expected no run ID, got %+v", result)
+		}
+		if entries, err := log.History(); err != nil || len(entries) != 0 {
+			t.Errorf("expected an empty log, got %+v, %v", entries, err)
+		}
+	})
+
+	t.Run("dry runs keep the run", func(t *testing.T) {
+		fsys, log, runID := provision(t)
+		if _, err := log.UndoWithOptions(ctx, fsys, runID, synthfs.UndoOptions{DryRun: true}); err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if _, err := fsys.Stat("hosts"); err == nil {
+			t.Error("dry run restored hosts")
+		}
+		if last, err := log.Last(); err != nil || last.ID != runID {
+			t.Errorf("dry run removed the run: %+v, %v", last, err)
+		}
+	})
+
+	t.Run("unknown runs", func(t *testing.T) {
+		fsys, log, _ := provision(t)
+		for _, runID := range []string{"nope", "../undo", ""} {
+			if _, err := log.Undo(ctx, fsys, runID); !errors.Is(err, synthfs.ErrRunNotFound) {
+				t.Errorf("Undo(%q) = %v", runID, err)
+			}
+		}
+	})
+}
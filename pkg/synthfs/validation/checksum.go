@@ -1,11 +1,13 @@
 package validation
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
 	"time"
 
+	"github.com/arthur-debert/synthfs/pkg/synthfs/core"
 	"github.com/arthur-debert/synthfs/pkg/synthfs/filesystem"
 )
 
@@ -20,6 +22,12 @@ type ChecksumRecord struct {
 
 // ComputeFileChecksum calculates the MD5 checksum and gathers file metadata.
 func ComputeFileChecksum(fsys filesystem.FileSystem, filePath string) (*ChecksumRecord, error) {
+	return ComputeFileChecksumContext(context.Background(), fsys, filePath)
+}
+
+// ComputeFileChecksumContext is ComputeFileChecksum, reporting the bytes read
+// to the progress tracker carried by ctx, if any, and stopping when ctx is done.
+func ComputeFileChecksumContext(ctx context.Context, fsys filesystem.FileSystem, filePath string) (*ChecksumRecord, error) {
 	info, err := fsys.Stat(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat file %s: %w", filePath, err)
@@ -37,8 +45,10 @@ func ComputeFileChecksum(fsys filesystem.FileSystem, filePath string) (*Checksum
 		_ = file.Close()
 	}()
 
+	progress := core.ProgressFromContext(ctx)
+	progress.StartEntry(filePath)
 	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	if _, err := io.Copy(hash, progress.Reader(core.ContextReader(ctx, file))); err != nil {
 		return nil, fmt.Errorf("failed to calculate checksum for %s: %w", filePath, err)
 	}
 
@@ -49,6 +59,7 @@ func ComputeFileChecksum(fsys filesystem.FileSystem, filePath string) (*Checksum
 		ModTime:      info.ModTime(),
 		ChecksumTime: time.Now(),
 	}
+	progress.EntryDone()
 
 	return checksum, nil
 }
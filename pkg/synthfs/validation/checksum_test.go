@@ -217,19 +217,18 @@ func TestBatchChecksumming(t *testing.T) {
 		sfs := synthfs.New()
 		ctx := context.Background()
 
-		// Try to copy a directory (should fail because directory copy is not implemented)
+		// Copy a directory
 		op := sfs.Copy("testdir", "copydir")
 		if op == nil {
 			t.Fatal("Copy operation should not be nil")
 		}
 
-		// Execute the operation - this will fail because directory copy is not implemented
 		_, err = synthfs.Run(ctx, testFS, op)
-		if err == nil {
-			t.Skip("Directory copy is not implemented yet, skipping this test")
+		if err != nil {
+			t.Fatalf("Failed to copy directory: %v", err)
 		}
 
-		// Even with failure, checksum should be nil for directories
+		// Checksum should be nil for directories
 		checksum := op.GetChecksum("testdir")
 		if checksum != nil {
 			t.Error("Expected no checksum for directory, but got one")